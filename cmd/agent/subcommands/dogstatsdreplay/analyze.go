@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdreplay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DataDog/zstd"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const (
+	defaultTopCount = 10
+	// maxTagsPerMetric is the number of tags with the most values printed for each metric.
+	maxTagsPerMetric = 3
+)
+
+// analyzeCliParams are the command-line arguments for the analyze subcommand
+type analyzeCliParams struct {
+	*command.GlobalParams
+
+	dsdReplayFilePath string
+	dsdMmapReplay     bool
+	metricName        string
+	pids              []int32
+	top               int
+	outputFilePath    string
+	outputCompressed  bool
+}
+
+func analyzeCommand(globalParams *command.GlobalParams) *cobra.Command {
+	cliParams := &analyzeCliParams{
+		GlobalParams: globalParams,
+	}
+
+	analyzeCmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Summarize a dogstatsd traffic capture, optionally writing a filtered sub-capture",
+		Long: `Summarize a dogstatsd traffic capture: the metrics sent in the most packets with their
+number of contexts and the tags with the most values, the tags with the most values across
+all the metrics, and the origin PIDs which sent the most packets.
+
+The capture can be filtered by metric name and origin PID, and the filtered messages written
+to a new capture which can be replayed with 'dogstatsd-replay'.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(analyzeCapture,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	analyzeCmd.Flags().StringVarP(&cliParams.dsdReplayFilePath, "file", "f", "", "Input file with traffic captured with dogstatsd-capture.")
+	analyzeCmd.Flags().BoolVarP(&cliParams.dsdMmapReplay, "mmap", "m", true, "Mmap file for analysis. Set to false to load the entire file into memory instead")
+	analyzeCmd.Flags().StringVar(&cliParams.metricName, "metric", "", "Regular expression matching the names of the metrics to keep.")
+	analyzeCmd.Flags().Int32SliceVar(&cliParams.pids, "pid", nil, "Origin PID of the packets to keep, can be repeated.")
+	analyzeCmd.Flags().IntVarP(&cliParams.top, "top", "n", defaultTopCount, "Number of metrics, tags and origins to print, 0 to print all of them.")
+	analyzeCmd.Flags().StringVarP(&cliParams.outputFilePath, "output", "o", "", "File to write a capture with only the filtered messages to.")
+	analyzeCmd.Flags().BoolVarP(&cliParams.outputCompressed, "compressed", "z", true, "Should the filtered capture be zstd compressed.")
+	analyzeCmd.MarkFlagRequired("file") //nolint:errcheck
+
+	return analyzeCmd
+}
+
+func analyzeCapture(_ log.Component, cliParams *analyzeCliParams) error {
+	filter := replay.CaptureFilter{Pids: cliParams.pids}
+	if cliParams.metricName != "" {
+		re, err := regexp.Compile(cliParams.metricName)
+		if err != nil {
+			return fmt.Errorf("invalid metric name regular expression: %w", err)
+		}
+		filter.MetricName = re
+	}
+
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdReplayFilePath, 0, cliParams.dsdMmapReplay)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", cliParams.dsdReplayFilePath, err)
+	}
+	defer reader.Close()
+
+	var analysis *replay.CaptureAnalysis
+	if cliParams.outputFilePath == "" {
+		analysis, err = reader.Analyze(filter, nil)
+	} else {
+		analysis, err = writeSubCapture(reader, filter, cliParams.outputFilePath, cliParams.outputCompressed)
+	}
+	if err != nil {
+		return err
+	}
+
+	printAnalysis(os.Stdout, analysis, cliParams.top)
+	if cliParams.outputFilePath != "" {
+		fmt.Printf("\nFiltered capture written to %s\n", cliParams.outputFilePath)
+	}
+	return nil
+}
+
+// writeSubCapture analyzes the capture and writes its filtered messages to a new capture file.
+func writeSubCapture(reader *replay.TrafficCaptureReader, filter replay.CaptureFilter, path string, compressed bool) (*replay.CaptureAnalysis, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var target io.Writer = f
+	var zWriter *zstd.Writer
+	if compressed {
+		zWriter = zstd.NewWriter(f)
+		target = zWriter
+	}
+	writer := bufio.NewWriter(target)
+
+	analysis, err := reader.Analyze(filter, writer)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	if zWriter != nil {
+		if err := zWriter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return analysis, f.Close()
+}
+
+func printAnalysis(out io.Writer, analysis *replay.CaptureAnalysis, top int) {
+	fmt.Fprintf(out, "Packets: %d\n", analysis.Packets)
+	fmt.Fprintf(out, "Messages: %d (%d events, %d service checks, %d invalid)\n", analysis.Messages, analysis.Events, analysis.ServiceChecks, analysis.InvalidMessages)
+	fmt.Fprintf(out, "Metrics: %d\n", len(analysis.Metrics))
+	fmt.Fprintf(out, "Origins: %d\n", len(analysis.Origins))
+	if analysis.Packets > 0 {
+		fmt.Fprintf(out, "Time range: %s - %s (%s)\n", analysis.First.UTC().Format("2006-01-02T15:04:05Z"), analysis.Last.UTC().Format("2006-01-02T15:04:05Z"), analysis.Last.Sub(analysis.First))
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "\nTop metrics by packet count:\n")
+	fmt.Fprintf(w, "METRIC\tPACKETS\tMESSAGES\tCONTEXTS\tTOP TAGS (VALUES)\n")
+	for _, metric := range analysis.TopMetrics(top) {
+		cardinality := metric.TagCardinality()
+		if len(cardinality) > maxTagsPerMetric {
+			cardinality = cardinality[:maxTagsPerMetric]
+		}
+		tags := make([]string, 0, len(cardinality))
+		for _, tag := range cardinality {
+			tags = append(tags, fmt.Sprintf("%s (%d)", tag.Tag, tag.Values))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", metric.Name, metric.Packets, metric.Messages, metric.Contexts(), strings.Join(tags, ", "))
+	}
+
+	fmt.Fprintf(w, "\nTop tags by cardinality:\n")
+	fmt.Fprintf(w, "TAG\tVALUES\n")
+	for _, tag := range analysis.TopTags(top) {
+		fmt.Fprintf(w, "%s\t%d\n", tag.Tag, tag.Values)
+	}
+
+	fmt.Fprintf(w, "\nTop origins by packet count:\n")
+	fmt.Fprintf(w, "PID\tPACKETS\tMESSAGES\tMETRICS\tCONTAINER ID\n")
+	for _, origin := range analysis.TopOrigins(top) {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", origin.Pid, origin.Packets, origin.Messages, origin.Metrics(), origin.ContainerID)
+	}
+
+	w.Flush()
+}
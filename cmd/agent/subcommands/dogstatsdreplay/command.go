@@ -71,6 +71,7 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdVerboseReplay, "verbose", "v", false, "Verbose replay.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdMmapReplay, "mmap", "m", true, "Mmap file for replay. Set to false to load the entire file into memory instead")
 	dogstatsdReplayCmd.Flags().IntVarP(&cliParams.dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterations to replay.")
+	dogstatsdReplayCmd.AddCommand(analyzeCommand(globalParams))
 
 	return []*cobra.Command{dogstatsdReplayCmd}
 }
@@ -26,3 +26,17 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestAnalyzeCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "analyze", "-f", "capture.dog", "--metric", "^app\\.", "--pid", "10", "--pid", "12", "-o", "filtered.dog"},
+		analyzeCapture,
+		func(cliParams *analyzeCliParams) {
+			require.Equal(t, "capture.dog", cliParams.dsdReplayFilePath)
+			require.Equal(t, "^app\\.", cliParams.metricName)
+			require.Equal(t, []int32{10, 12}, cliParams.pids)
+			require.Equal(t, "filtered.dog", cliParams.outputFilePath)
+			require.True(t, cliParams.outputCompressed)
+		})
+}
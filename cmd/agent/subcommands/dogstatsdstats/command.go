@@ -11,7 +11,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
+	"strings"
 
 	"go.uber.org/fx"
 
@@ -20,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug/serverdebugimpl"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
@@ -94,6 +98,7 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 
 		if len(errMap["error_type"]) > 0 {
 			fmt.Println(e)
+			fmt.Print(requestContextLimits(c, ipcAddress))
 			return nil
 		}
 
@@ -115,6 +120,7 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
 			return nil
 		}
+		s += requestContextLimits(c, ipcAddress)
 	}
 
 	if cliParams.dsdStatsFilePath == "" {
@@ -138,3 +144,42 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 
 	return nil
 }
+
+// requestContextLimits returns the metric names and origins which exceeded their DogStatsD context
+// budget, formatted as a table, or an empty string if there's none.
+func requestContextLimits(c *http.Client, ipcAddress string) string {
+	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-context-limits", ipcAddress, pkgconfigsetup.Datadog().GetInt("cmd_port"))
+	r, err := util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	if err != nil {
+		return ""
+	}
+	var stats aggregator.ContextLimitsStats
+	if err := json.Unmarshal(r, &stats); err != nil {
+		return ""
+	}
+	return formatContextLimits(stats)
+}
+
+func formatContextLimits(stats aggregator.ContextLimitsStats) string {
+	if len(stats.Metrics) == 0 && len(stats.Origins) == 0 {
+		return ""
+	}
+	buf := bytes.NewBufferString("\nContext budgets exceeded:\n\n")
+	fmt.Fprintf(buf, "%-10s | %-40s | %-10s | %-10s | %-10s\n", "Budget", "Metric or Origin", "Contexts", "Dropped", "Stripped")
+	buf.WriteString(strings.Repeat("-", 10) + "-|-" + strings.Repeat("-", 40) + "-|-" + strings.Repeat("-", 10) + "-|-" + strings.Repeat("-", 10) + "-|-" + strings.Repeat("-", 10) + "\n")
+	for _, budget := range []struct {
+		name      string
+		offenders map[string]aggregator.ContextLimitOffender
+	}{{"metric", stats.Metrics}, {"origin", stats.Origins}} {
+		keys := make([]string, 0, len(budget.offenders))
+		for key := range budget.offenders {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			offender := budget.offenders[key]
+			fmt.Fprintf(buf, "%-10s | %-40s | %-10d | %-10d | %-10d\n", budget.name, key, offender.Contexts, offender.Dropped, offender.Stripped)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package forwarder implements 'agent forwarder'.
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	// subcommand-specific flags
+
+	from   string
+	to     string
+	dryRun bool
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	forwarderCmd := &cobra.Command{
+		Use:   "forwarder",
+		Short: "Manage the payloads sent by the forwarder",
+		Long:  ``,
+	}
+
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Resubmit the archived payloads of a time range",
+		Long: `Resubmit the payloads archived by the forwarder (see forwarder_archive_max_size_in_bytes)
+and created in a time range, to the endpoints and with the API keys of the configuration.
+
+The bounds of the range are RFC 3339 timestamps, like 2024-01-02T15:04:05Z, or durations
+before now, like 2h.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(replay,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: config.NewAgentParams(globalParams.ConfFilePath, config.WithExtraConfFiles(globalParams.ExtraConfFilePath), config.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					SecretParams: secrets.NewEnabledParams(),
+					LogParams:    log.ForOneShot(command.LoggerName, "warn", true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	replayCmd.Flags().StringVarP(&cliParams.from, "from", "f", "", "Start of the time range (required).")
+	replayCmd.Flags().StringVarP(&cliParams.to, "to", "t", "", "End of the time range, now by default.")
+	replayCmd.Flags().BoolVarP(&cliParams.dryRun, "dry-run", "n", false, "Only count the archived payloads of the time range.")
+	forwarderCmd.AddCommand(replayCmd)
+
+	return []*cobra.Command{forwarderCmd}
+}
+
+// parseTime parses an RFC 3339 timestamp or a duration before now.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected an RFC 3339 timestamp or a duration", value)
+}
+
+func replay(log log.Component, config config.Component, cliParams *cliParams) error {
+	if cliParams.from == "" {
+		return fmt.Errorf("the start of the time range is required, use --from")
+	}
+	now := time.Now()
+	from, err := parseTime(cliParams.from, now)
+	if err != nil {
+		return err
+	}
+	to := now
+	if cliParams.to != "" {
+		if to, err = parseTime(cliParams.to, now); err != nil {
+			return err
+		}
+	}
+	if to.Before(from) {
+		return fmt.Errorf("the end of the time range %s is before its start %s", to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if cliParams.dryRun {
+		fmt.Printf("Counting the payloads archived between %s and %s...\n\n", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	} else {
+		fmt.Printf("Replaying the payloads archived between %s and %s...\n\n", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	results, err := defaultforwarder.ReplayArchive(ctx, config, log, from, to, cliParams.dryRun)
+	for _, result := range results {
+		fmt.Printf("%s: %d payloads (%d bytes)", result.Domain, result.Transactions, result.Bytes)
+		if !cliParams.dryRun {
+			fmt.Printf(", %d sent, %d failed", result.Sent, result.Failed)
+		}
+		if result.ReadErrors > 0 {
+			fmt.Printf(", %d unreadable", result.ReadErrors)
+		}
+		fmt.Println()
+	}
+	if len(results) == 0 && err == nil {
+		fmt.Println("No archive found, is forwarder_archive_max_size_in_bytes set?")
+	}
+	return err
+}
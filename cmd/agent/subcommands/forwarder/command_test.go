@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestReplayCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"forwarder", "replay", "--from", "2h", "--to", "2024-01-02T15:04:05Z", "--dry-run"},
+		replay,
+		func(_ core.BundleParams, secretParams secrets.Params, cliParams *cliParams) {
+			require.Equal(t, true, secretParams.Enabled)
+			require.Equal(t, "2h", cliParams.from)
+			require.Equal(t, "2024-01-02T15:04:05Z", cliParams.to)
+			require.Equal(t, true, cliParams.dryRun)
+		})
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	parsed, err := parseTime("2024-01-01T10:00:00+02:00", now)
+	require.NoError(t, err)
+	assert.True(t, parsed.Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)))
+
+	parsed, err = parseTime("90m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), parsed)
+
+	_, err = parseTime("-1h", now)
+	assert.Error(t, err)
+	_, err = parseTime("yesterday", now)
+	assert.Error(t, err)
+}
@@ -479,6 +479,7 @@ func getSharedFxOption() fx.Option {
 					"runtime_block_profile_rate":             commonsettings.NewRuntimeBlockProfileRate(),
 					"dogstatsd_stats":                        internalsettings.NewDsdStatsRuntimeSetting(serverDebug),
 					"dogstatsd_capture_duration":             internalsettings.NewDsdCaptureDurationRuntimeSetting("dogstatsd_capture_duration"),
+					"dogstatsd_metric_relabel_configs":       internalsettings.NewDsdRelabelConfigsRuntimeSetting(),
 					"log_payloads":                           commonsettings.NewLogPayloadsRuntimeSetting(),
 					"internal_profiling_goroutines":          commonsettings.NewProfilingGoroutines(),
 					"multi_region_failover.enabled":          internalsettings.NewMultiRegionFailoverRuntimeSetting("multi_region_failover.enabled", "Enable/disable Multi-Region Failover support."),
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// DsdRelabelConfigsRuntimeSetting wraps operations to change the relabel rules of the dogstatsd
+// metrics at runtime. The dogstatsd server reloads the rules when the setting is updated.
+type DsdRelabelConfigsRuntimeSetting struct{}
+
+// NewDsdRelabelConfigsRuntimeSetting creates a new instance of DsdRelabelConfigsRuntimeSetting
+func NewDsdRelabelConfigsRuntimeSetting() *DsdRelabelConfigsRuntimeSetting {
+	return &DsdRelabelConfigsRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdRelabelConfigsRuntimeSetting) Description() string {
+	return "Set the relabel rules of the dogstatsd metrics. Possible values: a list of rules in JSON or YAML"
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdRelabelConfigsRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdRelabelConfigsRuntimeSetting) Name() string {
+	return mapper.RelabelConfigsSetting
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdRelabelConfigsRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return mapper.GetRelabelConfigs(config)
+}
+
+// Set changes the value of the runtime setting, the rules are validated before being set
+func (s *DsdRelabelConfigsRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("DsdRelabelConfigsRuntimeSetting: expected a list of rules in JSON or YAML, got %T", v)
+	}
+
+	var configs []mapper.RelabelConfig
+	if err := yaml.Unmarshal([]byte(str), &configs); err != nil {
+		return fmt.Errorf("DsdRelabelConfigsRuntimeSetting: %v", err)
+	}
+	if _, err := mapper.NewRelabeler(configs); err != nil {
+		return fmt.Errorf("DsdRelabelConfigsRuntimeSetting: %v", err)
+	}
+
+	// the rules are set as generic values, as if they were read from the configuration file
+	var rules []interface{}
+	if err := yaml.Unmarshal([]byte(str), &rules); err != nil {
+		return fmt.Errorf("DsdRelabelConfigsRuntimeSetting: %v", err)
+	}
+	config.Set(mapper.RelabelConfigsSetting, rules, source)
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
 	workloadmetafxmock "github.com/DataDog/datadog-agent/comp/core/workloadmeta/fx-mock"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/server"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
@@ -103,3 +104,33 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdRelabelConfigs(t *testing.T) {
+	cfg := config.NewMock(t)
+	s := NewDsdRelabelConfigsRuntimeSetting()
+
+	v, err := s.Get(cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+
+	err = s.Set(cfg, `[{"source_tags": ["env"], "regex": "test", "action": "drop"}]`, model.SourceCLI)
+	assert.NoError(t, err)
+	v, err = s.Get(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []mapper.RelabelConfig{{SourceTags: []string{"env"}, Regex: "test", Action: "drop"}}, v)
+
+	// YAML is accepted too
+	err = s.Set(cfg, "- action: tagdrop\n  regex: pod_name\n", model.SourceCLI)
+	assert.NoError(t, err)
+	v, err = s.Get(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []mapper.RelabelConfig{{Regex: "pod_name", Action: "tagdrop"}}, v)
+
+	// invalid rules aren't set
+	assert.Error(t, s.Set(cfg, `[{"action": "invalid"}]`, model.SourceCLI))
+	assert.Error(t, s.Set(cfg, `not a list`, model.SourceCLI))
+	assert.Error(t, s.Set(cfg, 42, model.SourceCLI))
+	v, err = s.Get(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []mapper.RelabelConfig{{Regex: "pod_name", Action: "tagdrop"}}, v)
+}
@@ -19,6 +19,7 @@ import (
 	cmddogstatsdreplay "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdreplay"
 	cmddogstatsdstats "github.com/DataDog/datadog-agent/cmd/agent/subcommands/dogstatsdstats"
 	cmdflare "github.com/DataDog/datadog-agent/cmd/agent/subcommands/flare"
+	cmdforwarder "github.com/DataDog/datadog-agent/cmd/agent/subcommands/forwarder"
 	cmdhealth "github.com/DataDog/datadog-agent/cmd/agent/subcommands/health"
 	cmdhostname "github.com/DataDog/datadog-agent/cmd/agent/subcommands/hostname"
 	cmdimport "github.com/DataDog/datadog-agent/cmd/agent/subcommands/import"
@@ -53,6 +54,7 @@ func AgentSubcommands() []command.SubcommandFactory {
 		cmddogstatsdreplay.Commands,
 		cmddogstatsdstats.Commands,
 		cmdflare.Commands,
+		cmdforwarder.Commands,
 		cmdhealth.Commands,
 		cmdhostname.Commands,
 		cmdimport.Commands,
@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+)
+
+const (
+	awsRequestTimeout = 10 * time.Second
+	maxSSMResponse    = 1024 * 1024
+)
+
+// AWSClient reads secrets from AWS Secrets Manager and from the SSM Parameter Store. It uses the
+// default credentials chain and region of the AWS SDK, and AWS_ENDPOINT_URL when set.
+type AWSClient struct {
+	config         aws.Config
+	secretsManager *secretsmanager.Client
+}
+
+// NewAWSClient creates an AWS client from the default configuration of the AWS SDK
+func NewAWSClient() (*AWSClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), awsRequestTimeout)
+	defer cancel()
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(awsRequestTimeout)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the AWS configuration: %v", err)
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("no AWS region configured")
+	}
+	return &AWSClient{
+		config:         cfg,
+		secretsManager: secretsmanager.NewFromConfig(cfg),
+	}, nil
+}
+
+// ReadAWSSecretsManagerSecret reads a secret of AWS Secrets Manager. The path follows this format:
+// "<secret name or ARN>[#key]", the key selects a field of a secret holding a JSON object.
+func ReadAWSSecretsManagerSecret(client *AWSClient, path string) secrets.SecretVal {
+	secretID, key, hasKey := strings.Cut(path, "#")
+	if secretID == "" || (hasKey && key == "") {
+		return secrets.SecretVal{ErrorMsg: "invalid format. Use: \"secret_id[#key]\""}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), awsRequestTimeout)
+	defer cancel()
+	output, err := client.secretsManager.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+
+	var value string
+	switch {
+	case output.SecretString != nil:
+		value = *output.SecretString
+	case output.SecretBinary != nil:
+		value = string(output.SecretBinary)
+	default:
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("secret %s has no value", secretID)}
+	}
+	if !hasKey {
+		return secrets.SecretVal{Value: value}
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("secret %s isn't a JSON object: %v", secretID, err)}
+	}
+	field, ok := fields[key]
+	if !ok {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("key %s not found in secret %s", key, secretID)}
+	}
+	if str, ok := field.(string); ok {
+		return secrets.SecretVal{Value: str}
+	}
+	encoded, err := json.Marshal(field)
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+	return secrets.SecretVal{Value: string(encoded)}
+}
+
+// ReadAWSSSMParameter reads a parameter of the SSM Parameter Store, the SecureString parameters are
+// decrypted.
+func ReadAWSSSMParameter(client *AWSClient, name string) secrets.SecretVal {
+	if name == "" {
+		return secrets.SecretVal{ErrorMsg: "invalid format. Use: \"parameter_name\""}
+	}
+
+	var response struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	if err := client.callSSM("GetParameter", map[string]interface{}{"Name": name, "WithDecryption": true}, &response); err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+	return secrets.SecretVal{Value: response.Parameter.Value}
+}
+
+// callSSM calls an action of the SSM JSON API. The SSM service client isn't a dependency of the
+// agent, the requests are signed with the signer of the AWS SDK.
+func (c *AWSClient) callSSM(action string, input interface{}, output interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), awsRequestTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("https://ssm.%s.amazonaws.com", c.config.Region)
+	if c.config.BaseEndpoint != nil {
+		endpoint = strings.TrimSuffix(*c.config.BaseEndpoint, "/")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonSSM."+action)
+
+	credentials, err := c.config.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the AWS credentials: %v", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(payloadHash[:]), "ssm", c.config.Region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSSMResponse))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(content, &apiErr) == nil && apiErr.Type != "" {
+			// the type may be prefixed by a namespace: "com.amazonaws.ssm#ParameterNotFound"
+			errType := apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+			return fmt.Errorf("SSM %s failed: %s: %s", action, errType, apiErr.Message)
+		}
+		return fmt.Errorf("SSM %s failed: %s", action, resp.Status)
+	}
+	if err := json.Unmarshal(content, output); err != nil {
+		return fmt.Errorf("invalid SSM %s response: %v", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAWSServer is a stand-in for the JSON APIs of Secrets Manager and SSM.
+func newTestAWSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var input map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		notFound := func(errType string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": errType, "message": "not found"}) //nolint:errcheck
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			switch input["SecretId"] {
+			case "datadog/api_key":
+				json.NewEncoder(w).Encode(map[string]interface{}{"Name": "datadog/api_key", "SecretString": "0123456789abcdef"}) //nolint:errcheck
+			case "datadog/db":
+				json.NewEncoder(w).Encode(map[string]interface{}{"Name": "datadog/db", "SecretString": `{"user":"datadog","password":"p@ssword","port":5432}`}) //nolint:errcheck
+			default:
+				notFound("ResourceNotFoundException")
+			}
+		case "AmazonSSM.GetParameter":
+			if input["Name"] != "/datadog/api_key" || input["WithDecryption"] != true {
+				notFound("com.amazonaws.ssm#ParameterNotFound")
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Parameter": map[string]interface{}{"Name": "/datadog/api_key", "Type": "SecureString", "Value": "fedcba9876543210"}}) //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAWSClient(t *testing.T) *AWSClient {
+	server := newTestAWSServer(t)
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	client, err := NewAWSClient()
+	require.NoError(t, err)
+	return client
+}
+
+func TestReadAWSSecretsManagerSecret(t *testing.T) {
+	client := newTestAWSClient(t)
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedValue string
+		expectedError string
+	}{
+		{
+			name:          "whole secret",
+			path:          "datadog/api_key",
+			expectedValue: "0123456789abcdef",
+		},
+		{
+			name:          "key of a JSON secret",
+			path:          "datadog/db#password",
+			expectedValue: "p@ssword",
+		},
+		{
+			name:          "key of a JSON secret, not a string",
+			path:          "datadog/db#port",
+			expectedValue: "5432",
+		},
+		{
+			name:          "missing key",
+			path:          "datadog/db#host",
+			expectedError: "key host not found in secret datadog/db",
+		},
+		{
+			name:          "not a JSON secret",
+			path:          "datadog/api_key#key",
+			expectedError: "secret datadog/api_key isn't a JSON object: invalid character '1' after top-level value",
+		},
+		{
+			name:          "invalid format",
+			path:          "datadog/db#",
+			expectedError: "invalid format. Use: \"secret_id[#key]\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret := ReadAWSSecretsManagerSecret(client, test.path)
+			assert.Equal(t, test.expectedError, secret.ErrorMsg)
+			assert.Equal(t, test.expectedValue, secret.Value)
+		})
+	}
+
+	secret := ReadAWSSecretsManagerSecret(client, "datadog/other")
+	assert.Contains(t, secret.ErrorMsg, "ResourceNotFoundException")
+}
+
+func TestReadAWSSSMParameter(t *testing.T) {
+	client := newTestAWSClient(t)
+
+	secret := ReadAWSSSMParameter(client, "/datadog/api_key")
+	assert.Empty(t, secret.ErrorMsg)
+	assert.Equal(t, "fedcba9876543210", secret.Value)
+
+	secret = ReadAWSSSMParameter(client, "/datadog/other")
+	assert.Equal(t, "SSM GetParameter failed: ParameterNotFound: not found", secret.ErrorMsg)
+}
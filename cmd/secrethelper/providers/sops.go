@@ -0,0 +1,411 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+)
+
+const (
+	// SopsAgeKeyEnv is the environment variable holding the age identities decrypting the sops files
+	SopsAgeKeyEnv = "SOPS_AGE_KEY"
+	// SopsAgeKeyFileEnv is the environment variable holding the path of the age identities file
+	SopsAgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+
+	sopsMetadataKey = "sops"
+	sopsNonceSize   = 32
+	maxSopsFileSize = 1024 * 1024
+)
+
+var sopsValueRegex = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified      string `yaml:"lastmodified"`
+	MAC               string `yaml:"mac"`
+	MACOnlyEncrypted  bool   `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string `yaml:"unencrypted_regex"`
+	EncryptedRegex    string `yaml:"encrypted_regex"`
+}
+
+// ReadSopsSecret reads a value of a YAML or JSON file encrypted by sops with age. The path follows
+// this format: "/path/to/file#key/nested_key". The age identities are read from the SOPS_AGE_KEY
+// environment variable, or from the file set by SOPS_AGE_KEY_FILE, like sops does.
+//
+// The whole file is decrypted to verify its MAC before any value is returned, so values removed
+// from the file or moved between files are detected. The files with comments aren't supported
+// since sops includes them in the MAC.
+func ReadSopsSecret(path string) secrets.SecretVal {
+	file, key, ok := strings.Cut(path, "#")
+	if !ok || file == "" || key == "" {
+		return secrets.SecretVal{ErrorMsg: "invalid format. Use: \"/path/to/file#key\""}
+	}
+	keyPath := strings.Split(key, "/")
+	if keyPath[0] == sopsMetadataKey {
+		return secrets.SecretVal{ErrorMsg: "the sops metadata can't be read"}
+	}
+
+	fi, err := os.Stat(file)
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+	if fi.Size() > maxSopsFileSize {
+		return secrets.SecretVal{ErrorMsg: "sops file exceeds max allowed size"}
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("failed to parse sops file: %v", err)}
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return secrets.SecretVal{ErrorMsg: "not a sops file: the sops metadata are missing"}
+	}
+	document, err := sopsDecryptDocument(root.Content[0])
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("failed to decrypt %s: %v", file, err)}
+	}
+
+	var value interface{} = document
+	for _, k := range keyPath {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return secrets.SecretVal{ErrorMsg: fmt.Sprintf("key %s not found in %s", key, file)}
+		}
+		if value, ok = m[k]; !ok {
+			return secrets.SecretVal{ErrorMsg: fmt.Sprintf("key %s not found in %s", key, file)}
+		}
+	}
+	plaintext, ok := value.(string)
+	if !ok {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("key %s of %s isn't a value", key, file)}
+	}
+	return secrets.SecretVal{Value: plaintext}
+}
+
+// sopsDecryptDocument decrypts all the values of a sops document and verifies its MAC. It returns
+// the document without its metadata, with the values as strings.
+func sopsDecryptDocument(node *yaml.Node) (map[string]interface{}, error) {
+	var metadataNode *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == sopsMetadataKey {
+			metadataNode = node.Content[i+1]
+		}
+	}
+	if metadataNode == nil {
+		return nil, errors.New("not a sops file: the sops metadata are missing")
+	}
+	var metadata sopsMetadata
+	if err := metadataNode.Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("invalid sops metadata: %v", err)
+	}
+
+	dataKey, err := sopsDataKey(metadata)
+	if err != nil {
+		return nil, err
+	}
+	d := &sopsDecryptor{dataKey: dataKey, metadata: metadata, hash: sha512.New()}
+	if metadata.UnencryptedRegex != "" {
+		if d.unencryptedRegex, err = regexp.Compile(metadata.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid sops metadata: %v", err)
+		}
+	}
+	if metadata.EncryptedRegex != "" {
+		if d.encryptedRegex, err = regexp.Compile(metadata.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid sops metadata: %v", err)
+		}
+	}
+
+	document := make(map[string]interface{}, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Value == sopsMetadataKey {
+			continue
+		}
+		if hasYAMLComment(key) {
+			return nil, errors.New("the sops files with comments aren't supported")
+		}
+		if document[key.Value], err = d.decryptNode(node.Content[i+1], []string{key.Value}); err != nil {
+			return nil, err
+		}
+	}
+
+	if metadata.MAC == "" {
+		return nil, errors.New("the sops MAC is missing")
+	}
+	// the MAC is authenticated with the last modification time of the file
+	lastModified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops metadata: %v", err)
+	}
+	mac, _, err := sopsDecryptValue(metadata.MAC, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the sops MAC: %v", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(mac), []byte(fmt.Sprintf("%X", d.hash.Sum(nil)))) != 1 {
+		return nil, errors.New("the sops MAC doesn't match, the file was modified")
+	}
+	return document, nil
+}
+
+// sopsDecryptor decrypts the values of a sops document, in the order of the document, and hashes
+// their plaintext like sops to compute the MAC.
+type sopsDecryptor struct {
+	dataKey          []byte
+	metadata         sopsMetadata
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+	hash             hash.Hash
+}
+
+func (d *sopsDecryptor) decryptNode(node *yaml.Node, path []string) (interface{}, error) {
+	if hasYAMLComment(node) {
+		return nil, errors.New("the sops files with comments aren't supported")
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if hasYAMLComment(key) {
+				return nil, errors.New("the sops files with comments aren't supported")
+			}
+			value, err := d.decryptNode(node.Content[i+1], append(path[:len(path):len(path)], key.Value))
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = value
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		// the items of the lists are authenticated with the path of the list
+		list := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := d.decryptNode(item, path)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		plaintext, macBytes, err := d.decryptScalar(node, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key %s: %v", strings.Join(path, "/"), err)
+		}
+		d.hash.Write([]byte(macBytes))
+		return plaintext, nil
+	default:
+		return nil, fmt.Errorf("unsupported YAML node for key %s", strings.Join(path, "/"))
+	}
+}
+
+// decryptScalar returns the plaintext of a value and its representation in the MAC.
+func (d *sopsDecryptor) decryptScalar(node *yaml.Node, path []string) (string, string, error) {
+	if !d.isEncrypted(path) {
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return "", "", err
+		}
+		macBytes, err := sopsMACBytes(value)
+		if err != nil {
+			return "", "", err
+		}
+		if d.metadata.MACOnlyEncrypted {
+			macBytes = ""
+		}
+		return node.Value, macBytes, nil
+	}
+
+	// sops doesn't encrypt the empty values
+	if node.Tag == "!!null" || node.Value == "" {
+		return "", "", nil
+	}
+	// sops authenticates each value with the path of its keys
+	plaintext, valueType, err := sopsDecryptValue(node.Value, d.dataKey, strings.Join(path, ":")+":")
+	if err != nil {
+		return "", "", err
+	}
+	var value interface{} = plaintext
+	switch valueType {
+	case "str", "bytes":
+	case "int":
+		value, err = strconv.Atoi(plaintext)
+	case "float":
+		value, err = strconv.ParseFloat(plaintext, 64)
+	case "bool":
+		value, err = strconv.ParseBool(plaintext)
+	default:
+		err = fmt.Errorf("unsupported value type %s", valueType)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	macBytes, err := sopsMACBytes(value)
+	return plaintext, macBytes, err
+}
+
+// isEncrypted returns whether sops encrypted the value of a path, following the rules of the
+// metadata of the file.
+func (d *sopsDecryptor) isEncrypted(path []string) bool {
+	encrypted := true
+	if suffix := d.metadata.UnencryptedSuffix; suffix != "" {
+		encrypted = !slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, suffix) })
+	}
+	if suffix := d.metadata.EncryptedSuffix; suffix != "" {
+		encrypted = slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, suffix) })
+	}
+	if d.unencryptedRegex != nil {
+		encrypted = !slices.ContainsFunc(path, d.unencryptedRegex.MatchString)
+	}
+	if d.encryptedRegex != nil {
+		encrypted = slices.ContainsFunc(path, d.encryptedRegex.MatchString)
+	}
+	return encrypted
+}
+
+// sopsMACBytes returns the representation of a value in the MAC computed by sops.
+func sopsMACBytes(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return "True", nil
+		}
+		return "False", nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", value)
+	}
+}
+
+func hasYAMLComment(node *yaml.Node) bool {
+	return node.HeadComment != "" || node.LineComment != "" || node.FootComment != ""
+}
+
+// sopsDataKey decrypts the data key of a sops document with the age identities.
+func sopsDataKey(metadata sopsMetadata) ([]byte, error) {
+	if len(metadata.Age) == 0 {
+		return nil, errors.New("the sops file isn't encrypted with age")
+	}
+
+	identities, err := readSopsAgeIdentities()
+	if err != nil {
+		return nil, err
+	}
+	for _, recipient := range metadata.Age {
+		decrypted, err := age.Decrypt(armor.NewReader(strings.NewReader(recipient.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		if dataKey, err := io.ReadAll(decrypted); err == nil {
+			return dataKey, nil
+		}
+	}
+	return nil, errors.New("no age identity can decrypt the sops data key")
+}
+
+func readSopsAgeIdentities() ([]age.Identity, error) {
+	if keys := os.Getenv(SopsAgeKeyEnv); keys != "" {
+		return parseAgeIdentities(keys)
+	}
+
+	keyFile := os.Getenv(SopsAgeKeyFileEnv)
+	if keyFile == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no age identity: %s and %s are not set", SopsAgeKeyEnv, SopsAgeKeyFileEnv)
+		}
+		keyFile = filepath.Join(configDir, "sops", "age", "keys.txt")
+	}
+	keys, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the age identities: %v", err)
+	}
+	return parseAgeIdentities(string(keys))
+}
+
+func parseAgeIdentities(keys string) ([]age.Identity, error) {
+	identities, err := age.ParseIdentities(strings.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identities: %v", err)
+	}
+	return identities, nil
+}
+
+// sopsDecryptValue decrypts a `ENC[AES256_GCM,data:...,iv:...,tag:...,type:...]` value, it returns
+// the plaintext and its type.
+func sopsDecryptValue(value string, dataKey []byte, additionalData string) (string, string, error) {
+	match := sopsValueRegex.FindStringSubmatch(value)
+	if match == nil {
+		return "", "", errors.New("the value isn't encrypted by sops")
+	}
+	var decoded [3][]byte
+	for i, encoded := range match[1:4] {
+		var err error
+		if decoded[i], err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return "", "", errors.New("invalid encoding of the encrypted value")
+		}
+	}
+	data, iv, tag := decoded[0], decoded[1], decoded[2]
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", "", err
+	}
+	if len(iv) != sopsNonceSize {
+		return "", "", errors.New("invalid IV size")
+	}
+	plaintext, err := aead.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", errors.New("authentication failed")
+	}
+	return string(plaintext), match[4], nil
+}
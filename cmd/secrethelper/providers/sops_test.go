@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAgeIdentity returns a random X25519 identity.
+func newTestAgeIdentity(t *testing.T) *age.X25519Identity {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return identity
+}
+
+// ageEncrypt encrypts the plaintext to the recipient in an armored age file, like sops does for its data key.
+func ageEncrypt(t *testing.T, plaintext []byte, recipient age.Recipient) string {
+	var file bytes.Buffer
+	armored := armor.NewWriter(&file)
+	w, err := age.Encrypt(armored, recipient)
+	require.NoError(t, err)
+	_, err = w.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, armored.Close())
+	return file.String()
+}
+
+const sopsTestLastModified = "2024-05-01T10:00:00Z"
+
+// sopsEncrypt encrypts a value like sops, authenticated with the path of its keys, or with the last
+// modification time for the MAC.
+func sopsEncrypt(t *testing.T, dataKey []byte, valueType string, value string, additionalData string) string {
+	block, err := aes.NewCipher(dataKey)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	require.NoError(t, err)
+	iv := make([]byte, sopsNonceSize)
+	_, err = rand.Read(iv)
+	require.NoError(t, err)
+	sealed := aead.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", enc(data), enc(iv), enc(tag), valueType)
+}
+
+// sopsMAC returns the encrypted MAC of the plaintext of the values of a file, in their order.
+func sopsMAC(t *testing.T, dataKey []byte, values ...string) string {
+	hash := sha512.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+	}
+	return sopsEncrypt(t, dataKey, "str", fmt.Sprintf("%X", hash.Sum(nil)), sopsTestLastModified)
+}
+
+// sopsYAMLMetadata returns the sops metadata of a YAML file, without the last line break.
+func sopsYAMLMetadata(t *testing.T, dataKey []byte, recipient age.Recipient, mac string) string {
+	enc := strings.ReplaceAll(strings.TrimSpace(ageEncrypt(t, dataKey, recipient)), "\n", "\n            ")
+	metadata := fmt.Sprintf(`sops:
+    age:
+        - recipient: age1test
+          enc: |
+            %s
+    lastmodified: "%s"
+`, enc, sopsTestLastModified)
+	if mac != "" {
+		metadata += fmt.Sprintf("    mac: %s\n", mac)
+	}
+	return metadata + "    unencrypted_suffix: _unencrypted\n    version: 3.9.0"
+}
+
+func TestParseAgeIdentities(t *testing.T) {
+	identity := newTestAgeIdentity(t)
+
+	identities, err := parseAgeIdentities("# created: today\n\n" + identity.String() + "\n")
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, identity.Recipient().String(), identities[0].(*age.X25519Identity).Recipient().String())
+
+	_, err = parseAgeIdentities(strings.Replace(identity.String(), "AGE-SECRET-KEY-1", "AGE-SECRET-KEY-1Q", 1))
+	assert.Error(t, err)
+}
+
+func TestReadSopsSecret(t *testing.T) {
+	identity := newTestAgeIdentity(t)
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600))
+	t.Setenv(SopsAgeKeyEnv, "")
+	t.Setenv(SopsAgeKeyFileEnv, keyFile)
+
+	apiKey := sopsEncrypt(t, dataKey, "str", "0123456789abcdef", "api_key:")
+	data := fmt.Sprintf(`api_key: %s
+db:
+    password: %s
+    port: %s
+    hosts:
+        - %s
+        - %s
+    user_unencrypted: admin
+    ssl_unencrypted: true
+`,
+		apiKey,
+		sopsEncrypt(t, dataKey, "str", "p@ssword", "db:password:"),
+		sopsEncrypt(t, dataKey, "int", "5432", "db:port:"),
+		sopsEncrypt(t, dataKey, "str", "db1", "db:hosts:"),
+		sopsEncrypt(t, dataKey, "str", "db2", "db:hosts:"))
+	mac := sopsMAC(t, dataKey, "0123456789abcdef", "p@ssword", "5432", "db1", "db2", "admin", "True")
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	path := writeFile("secrets.enc.yaml", data+sopsYAMLMetadata(t, dataKey, identity.Recipient(), mac))
+	tests := []struct {
+		name          string
+		path          string
+		expectedValue string
+		expectedError string
+	}{
+		{
+			name:          "top-level key",
+			path:          path + "#api_key",
+			expectedValue: "0123456789abcdef",
+		},
+		{
+			name:          "nested key",
+			path:          path + "#db/password",
+			expectedValue: "p@ssword",
+		},
+		{
+			name:          "integer",
+			path:          path + "#db/port",
+			expectedValue: "5432",
+		},
+		{
+			name:          "unencrypted key",
+			path:          path + "#db/user_unencrypted",
+			expectedValue: "admin",
+		},
+		{
+			name:          "missing key",
+			path:          path + "#db/user",
+			expectedError: fmt.Sprintf("key db/user not found in %s", path),
+		},
+		{
+			name:          "not a value",
+			path:          path + "#db",
+			expectedError: fmt.Sprintf("key db of %s isn't a value", path),
+		},
+		{
+			name:          "list",
+			path:          path + "#db/hosts",
+			expectedError: fmt.Sprintf("key db/hosts of %s isn't a value", path),
+		},
+		{
+			name:          "metadata",
+			path:          path + "#sops/version",
+			expectedError: "the sops metadata can't be read",
+		},
+		{
+			name:          "invalid format",
+			path:          path,
+			expectedError: "invalid format. Use: \"/path/to/file#key\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret := ReadSopsSecret(test.path)
+			assert.Equal(t, test.expectedError, secret.ErrorMsg)
+			assert.Equal(t, test.expectedValue, secret.Value)
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		enc, err := json.Marshal(ageEncrypt(t, dataKey, identity.Recipient()))
+		require.NoError(t, err)
+		jsonPath := writeFile("secrets.enc.json", fmt.Sprintf(`{
+	"token": "%s",
+	"retries": 3,
+	"sops": {
+		"age": [{"recipient": "age1test", "enc": %s}],
+		"lastmodified": "%s",
+		"mac": "%s",
+		"encrypted_regex": "^token$"
+	}
+}`, sopsEncrypt(t, dataKey, "str", "secret", "token:"), enc, sopsTestLastModified, sopsMAC(t, dataKey, "secret", "3")))
+		secret := ReadSopsSecret(jsonPath + "#token")
+		assert.Equal(t, "", secret.ErrorMsg)
+		assert.Equal(t, "secret", secret.Value)
+	})
+
+	// the file is rejected as soon as it was modified, whatever the key read
+	for _, test := range []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:          "value moved to another key",
+			content:       strings.Replace(data, "password: ENC", "password: "+apiKey+"\n    old_password: ENC", 1) + sopsYAMLMetadata(t, dataKey, identity.Recipient(), mac),
+			expectedError: "failed to decrypt key db/password: authentication failed",
+		},
+		{
+			name:          "value removed",
+			content:       strings.Replace(data, "    user_unencrypted: admin\n", "", 1) + sopsYAMLMetadata(t, dataKey, identity.Recipient(), mac),
+			expectedError: "the sops MAC doesn't match, the file was modified",
+		},
+		{
+			name:          "unencrypted value modified",
+			content:       strings.Replace(data, "admin", "root", 1) + sopsYAMLMetadata(t, dataKey, identity.Recipient(), mac),
+			expectedError: "the sops MAC doesn't match, the file was modified",
+		},
+		{
+			name:          "MAC missing",
+			content:       data + sopsYAMLMetadata(t, dataKey, identity.Recipient(), ""),
+			expectedError: "the sops MAC is missing",
+		},
+		{
+			name:          "comment",
+			content:       "# production\n" + data + sopsYAMLMetadata(t, dataKey, identity.Recipient(), mac),
+			expectedError: "the sops files with comments aren't supported",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			modified := writeFile("modified.enc.yaml", test.content)
+			secret := ReadSopsSecret(modified + "#api_key")
+			assert.Equal(t, fmt.Sprintf("failed to decrypt %s: %s", modified, test.expectedError), secret.ErrorMsg)
+			assert.Empty(t, secret.Value)
+		})
+	}
+
+	t.Run("wrong identity", func(t *testing.T) {
+		t.Setenv(SopsAgeKeyEnv, newTestAgeIdentity(t).String())
+		secret := ReadSopsSecret(path + "#api_key")
+		assert.Equal(t, fmt.Sprintf("failed to decrypt %s: no age identity can decrypt the sops data key", path), secret.ErrorMsg)
+	})
+}
@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+)
+
+// Environment variables configuring the Vault client, named after the ones of the Vault CLI
+const (
+	VaultAddrEnv         = "VAULT_ADDR"
+	VaultNamespaceEnv    = "VAULT_NAMESPACE"
+	VaultCACertEnv       = "VAULT_CACERT"
+	VaultTokenEnv        = "VAULT_TOKEN"
+	VaultAuthMethodEnv   = "VAULT_AUTH_METHOD"
+	VaultAuthMountEnv    = "VAULT_AUTH_MOUNT"
+	VaultRoleIDEnv       = "VAULT_ROLE_ID"
+	VaultSecretIDEnv     = "VAULT_SECRET_ID"
+	VaultK8sRoleEnv      = "VAULT_K8S_ROLE"
+	VaultK8sTokenPathEnv = "VAULT_K8S_TOKEN_PATH"
+)
+
+const (
+	vaultAuthToken      = "token"
+	vaultAuthAppRole    = "approle"
+	vaultAuthKubernetes = "kubernetes"
+
+	defaultVaultK8sTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	vaultRequestTimeout      = 10 * time.Second
+	maxVaultResponseSize     = 1024 * 1024
+)
+
+// VaultClient reads secrets from the KV secrets engines of HashiCorp Vault
+type VaultClient struct {
+	address    string
+	namespace  string
+	httpClient *http.Client
+
+	authMethod string
+	authMount  string
+	token      string
+	roleID     string
+	secretID   string
+	k8sRole    string
+	k8sToken   string
+
+	// the secrets already read, by path
+	cache map[string]*vaultSecret
+}
+
+type vaultSecret struct {
+	LeaseDuration int64                  `json:"lease_duration"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// NewVaultClientFromEnv creates a Vault client configured by the environment variables. The
+// authentication method is "token" (the default, using VAULT_TOKEN), "approle" (using
+// VAULT_ROLE_ID and VAULT_SECRET_ID) or "kubernetes" (using the VAULT_K8S_ROLE role and the
+// service account token). The client logs in on its first read.
+func NewVaultClientFromEnv() (*VaultClient, error) {
+	c := &VaultClient{
+		address:    strings.TrimSuffix(os.Getenv(VaultAddrEnv), "/"),
+		namespace:  os.Getenv(VaultNamespaceEnv),
+		authMethod: os.Getenv(VaultAuthMethodEnv),
+		authMount:  os.Getenv(VaultAuthMountEnv),
+		cache:      make(map[string]*vaultSecret),
+	}
+	if c.address == "" {
+		return nil, fmt.Errorf("%s is not set", VaultAddrEnv)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCert := os.Getenv(VaultCACertEnv); caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", VaultCACertEnv, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caCert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	c.httpClient = &http.Client{Transport: transport, Timeout: vaultRequestTimeout}
+
+	switch c.authMethod {
+	case "", vaultAuthToken:
+		c.authMethod = vaultAuthToken
+		c.token = os.Getenv(VaultTokenEnv)
+		if c.token == "" {
+			return nil, fmt.Errorf("%s is not set", VaultTokenEnv)
+		}
+	case vaultAuthAppRole:
+		c.roleID = os.Getenv(VaultRoleIDEnv)
+		c.secretID = os.Getenv(VaultSecretIDEnv)
+		if c.roleID == "" {
+			return nil, fmt.Errorf("%s is not set", VaultRoleIDEnv)
+		}
+	case vaultAuthKubernetes:
+		c.k8sRole = os.Getenv(VaultK8sRoleEnv)
+		if c.k8sRole == "" {
+			return nil, fmt.Errorf("%s is not set", VaultK8sRoleEnv)
+		}
+		tokenPath := os.Getenv(VaultK8sTokenPathEnv)
+		if tokenPath == "" {
+			tokenPath = defaultVaultK8sTokenPath
+		}
+		token, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the service account token: %v", err)
+		}
+		c.k8sToken = strings.TrimSpace(string(token))
+	default:
+		return nil, fmt.Errorf("unsupported %s %q, expected %q, %q or %q", VaultAuthMethodEnv, c.authMethod, vaultAuthToken, vaultAuthAppRole, vaultAuthKubernetes)
+	}
+	if c.authMount == "" {
+		c.authMount = c.authMethod
+	}
+
+	return c, nil
+}
+
+// ReadVaultSecret reads a key of a Vault secret. The path follows this format:
+// "<API path>#key", like "secret/data/datadog#api_key" for a KV version 2 engine mounted at
+// "secret", or "kv/datadog#api_key" for a KV version 1 engine. The lease duration of the secret,
+// if any, is returned as its TTL.
+func ReadVaultSecret(client *VaultClient, path string) secrets.SecretVal {
+	secretPath, key, ok := strings.Cut(path, "#")
+	secretPath = strings.Trim(secretPath, "/")
+	if !ok || secretPath == "" || key == "" {
+		return secrets.SecretVal{ErrorMsg: "invalid format. Use: \"path/to/secret#key\""}
+	}
+
+	secret, err := client.read(secretPath)
+	if err != nil {
+		return secrets.SecretVal{ErrorMsg: err.Error()}
+	}
+
+	data := secret.Data
+	// the KV version 2 engine nests the secret data with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"].(map[string]interface{}); ok {
+			data = nested
+		}
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return secrets.SecretVal{ErrorMsg: fmt.Sprintf("key %s not found in secret %s", key, secretPath)}
+	}
+	str, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return secrets.SecretVal{ErrorMsg: err.Error()}
+		}
+		str = string(encoded)
+	}
+
+	return secrets.SecretVal{Value: str, TTL: secret.LeaseDuration}
+}
+
+func (c *VaultClient) read(path string) (*vaultSecret, error) {
+	if secret, ok := c.cache[path]; ok {
+		return secret, nil
+	}
+	if c.token == "" {
+		if err := c.login(); err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		return nil, fmt.Errorf("secret %s has no data", path)
+	}
+	c.cache[path] = secret
+	return secret, nil
+}
+
+func (c *VaultClient) login() error {
+	var body map[string]string
+	switch c.authMethod {
+	case vaultAuthAppRole:
+		body = map[string]string{"role_id": c.roleID, "secret_id": c.secretID}
+	case vaultAuthKubernetes:
+		body = map[string]string{"role": c.k8sRole, "jwt": c.k8sToken}
+	default:
+		return fmt.Errorf("no Vault token for the %s auth method", c.authMethod)
+	}
+
+	secret, err := c.do(http.MethodPost, "auth/"+strings.Trim(c.authMount, "/")+"/login", body)
+	if err != nil {
+		return fmt.Errorf("failed to log in to Vault with the %s auth method: %v", c.authMethod, err)
+	}
+	if secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to log in to Vault with the %s auth method: no token returned", c.authMethod)
+	}
+	c.token = secret.Auth.ClientToken
+	return nil
+}
+
+func (c *VaultClient) do(method, path string, body interface{}) (*vaultSecret, error) {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.address+"/v1/"+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	req.Header.Set("X-Vault-Request", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxVaultResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	var secret vaultSecret
+	if err := json.Unmarshal(content, &secret); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid Vault response: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("secret %s not found", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(secret.Errors) > 0 {
+			return nil, fmt.Errorf("Vault returned %d: %s", resp.StatusCode, strings.Join(secret.Errors, ", "))
+		}
+		return nil, errors.New("Vault returned " + resp.Status)
+	}
+	return &secret, nil
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestVaultServer is a stand-in for Vault with a KV version 2 engine mounted at "secret" and a KV
+// version 1 engine mounted at "kv". It accepts the token "root", and the approle and kubernetes
+// logins of the "agent" role.
+func newTestVaultServer(t *testing.T) (*httptest.Server, *int) {
+	reads := 0
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body) //nolint:errcheck
+	}
+	login := func(field, expected string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+			if body[field] != expected {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid credentials"}})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{"client_token": "root", "lease_duration": 60}})
+		}
+	}
+	mux.HandleFunc("POST /v1/auth/approle/login", login("secret_id", "agent-secret-id"))
+	mux.HandleFunc("POST /v1/auth/k8s/login", login("jwt", "service-account-token"))
+	authenticated := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "root" {
+				writeJSON(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+				return
+			}
+			reads++
+			handler(w, r)
+		}
+	}
+	mux.HandleFunc("GET /v1/secret/data/datadog", authenticated(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"lease_duration": 0,
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"api_key": "0123456789abcdef", "port": 5432},
+				"metadata": map[string]interface{}{"version": 3},
+			},
+		})
+	}))
+	mux.HandleFunc("GET /v1/kv/datadog", authenticated(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"lease_duration": 3600,
+			"data":           map[string]interface{}{"password": "p@ssword", "ttl": "1h"},
+		})
+	}))
+	mux.HandleFunc("/", authenticated(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &reads
+}
+
+func TestReadVaultSecret(t *testing.T) {
+	server, reads := newTestVaultServer(t)
+	t.Setenv(VaultAddrEnv, server.URL)
+	t.Setenv(VaultAuthMethodEnv, "")
+	t.Setenv(VaultTokenEnv, "root")
+
+	client, err := NewVaultClientFromEnv()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedValue string
+		expectedTTL   int64
+		expectedError string
+	}{
+		{
+			name:          "KV version 2",
+			path:          "secret/data/datadog#api_key",
+			expectedValue: "0123456789abcdef",
+		},
+		{
+			name:          "KV version 2, not a string",
+			path:          "secret/data/datadog#port",
+			expectedValue: "5432",
+		},
+		{
+			name:          "KV version 1 with a lease",
+			path:          "/kv/datadog#password",
+			expectedValue: "p@ssword",
+			expectedTTL:   3600,
+		},
+		{
+			name:          "missing key",
+			path:          "kv/datadog#user",
+			expectedError: "key user not found in secret kv/datadog",
+		},
+		{
+			name:          "missing secret",
+			path:          "secret/data/other#api_key",
+			expectedError: "secret secret/data/other not found",
+		},
+		{
+			name:          "invalid format",
+			path:          "secret/data/datadog",
+			expectedError: "invalid format. Use: \"path/to/secret#key\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret := ReadVaultSecret(client, test.path)
+			assert.Equal(t, test.expectedError, secret.ErrorMsg)
+			assert.Equal(t, test.expectedValue, secret.Value)
+			assert.Equal(t, test.expectedTTL, secret.TTL)
+		})
+	}
+
+	// each secret is read once
+	assert.Equal(t, 3, *reads)
+}
+
+func TestVaultAuthMethods(t *testing.T) {
+	server, _ := newTestVaultServer(t)
+	t.Setenv(VaultAddrEnv, server.URL+"/")
+	t.Setenv(VaultTokenEnv, "")
+
+	t.Run("approle", func(t *testing.T) {
+		t.Setenv(VaultAuthMethodEnv, "approle")
+		t.Setenv(VaultRoleIDEnv, "agent")
+		t.Setenv(VaultSecretIDEnv, "agent-secret-id")
+		client, err := NewVaultClientFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789abcdef", ReadVaultSecret(client, "secret/data/datadog#api_key").Value)
+
+		t.Setenv(VaultSecretIDEnv, "wrong")
+		client, err = NewVaultClientFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "failed to log in to Vault with the approle auth method: Vault returned 400: invalid credentials", ReadVaultSecret(client, "secret/data/datadog#api_key").ErrorMsg)
+	})
+
+	t.Run("kubernetes", func(t *testing.T) {
+		tokenPath := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(tokenPath, []byte("service-account-token\n"), 0600))
+		t.Setenv(VaultAuthMethodEnv, "kubernetes")
+		t.Setenv(VaultAuthMountEnv, "k8s")
+		t.Setenv(VaultK8sRoleEnv, "agent")
+		t.Setenv(VaultK8sTokenPathEnv, tokenPath)
+		client, err := NewVaultClientFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "p@ssword", ReadVaultSecret(client, "kv/datadog#password").Value)
+	})
+
+	t.Run("token", func(t *testing.T) {
+		t.Setenv(VaultAuthMethodEnv, "token")
+		_, err := NewVaultClientFromEnv()
+		assert.EqualError(t, err, "VAULT_TOKEN is not set")
+
+		t.Setenv(VaultTokenEnv, "invalid")
+		client, err := NewVaultClientFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "Vault returned 403: permission denied", ReadVaultSecret(client, "kv/datadog#password").ErrorMsg)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Setenv(VaultAuthMethodEnv, "ldap")
+		_, err := NewVaultClientFromEnv()
+		assert.Error(t, err)
+	})
+}
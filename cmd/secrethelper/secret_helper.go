@@ -11,10 +11,22 @@
 //
 // 1) With the "--with-provider-prefixes" option enabled. Each input secret
 // should follow this format: "providerPrefix/some/path". The provider prefix
-// indicates where to fetch the secrets from. At the moment, we support "file"
-// and "k8s_secret". The path can mean different things depending on the
-// provider. In "file" it's a file system path. In "k8s_secret", it follows this
-// format: "namespace/name/key".
+// indicates where to fetch the secrets from. At the moment, we support "file",
+// "k8s_secret", "vault", "aws_secrets_manager", "aws_ssm" and "sops". The path
+// can mean different things depending on the provider:
+//   - In "file" it's a file system path.
+//   - In "k8s_secret", it follows this format: "namespace/name/key".
+//   - In "vault", it follows this format: "path/to/secret#key", where the path
+//     is the one of the Vault API, like "secret/data/datadog" for a KV version 2
+//     engine. The client is configured by the VAULT_* environment variables.
+//   - In "aws_secrets_manager", it follows this format: "secret_id[#key]",
+//     where the optional key selects a field of a JSON secret.
+//   - In "aws_ssm", it's the name of the parameter.
+//   - In "sops", it follows this format: "/path/to/file#key/nested_key", the
+//     file being encrypted by sops with age.
+//
+// The secrets of Vault are returned with the TTL of their lease, the agent
+// refreshes them before it expires.
 //
 // 2) Without the "--with-provider-prefixes" option. The program expects a root
 // path in the arguments and input secrets are just paths relative to the root
@@ -47,6 +59,16 @@ const (
 	providerPrefixSeparator = "@"
 	filePrefix              = "file"
 	k8sSecretPrefix         = "k8s_secret"
+	vaultPrefix             = "vault"
+	awsSecretsManagerPrefix = "aws_secrets_manager"
+	awsSSMPrefix            = "aws_ssm"
+	sopsPrefix              = "sops"
+)
+
+// the clients of the remote providers, can be overridden for testing purposes
+var (
+	newVaultClient = providers.NewVaultClientFromEnv
+	newAWSClient   = providers.NewAWSClient
 )
 
 // cliParams are the command-line arguments for this subcommand
@@ -72,7 +94,7 @@ func Commands() []*cobra.Command {
 			)
 		},
 	}
-	cmd.PersistentFlags().BoolVarP(&cliParams.usePrefixes, providerPrefixesFlag, "", false, "Use prefixes to select the secrets provider (file, k8s_secret)")
+	cmd.PersistentFlags().BoolVarP(&cliParams.usePrefixes, providerPrefixesFlag, "", false, "Use prefixes to select the secrets provider (file, k8s_secret, vault, aws_secrets_manager, aws_ssm, sops)")
 
 	secretHelperCmd := &cobra.Command{
 		Use:   "secret-helper",
@@ -159,6 +181,12 @@ func readSecretsFromFile(secretsList []string, dir string) map[string]secrets.Se
 func readSecretsUsingPrefixes(secretsList []string, rootPath string, kubeSecretGetter providers.KubeSecretGetter) map[string]secrets.SecretVal {
 	res := make(map[string]secrets.SecretVal)
 
+	// the clients are only created when a secret of their provider is read
+	var vaultClient *providers.VaultClient
+	var vaultErr error
+	var awsClient *providers.AWSClient
+	var awsErr error
+
 	for _, secretID := range secretsList {
 		prefix, id, err := parseSecretWithPrefix(secretID, rootPath)
 		if err != nil {
@@ -171,6 +199,30 @@ func readSecretsUsingPrefixes(secretsList []string, rootPath string, kubeSecretG
 			res[secretID] = providers.ReadSecretFile(id)
 		case k8sSecretPrefix:
 			res[secretID] = providers.ReadKubernetesSecret(kubeSecretGetter, id)
+		case vaultPrefix:
+			if vaultClient == nil && vaultErr == nil {
+				vaultClient, vaultErr = newVaultClient()
+			}
+			if vaultErr != nil {
+				res[secretID] = secrets.SecretVal{ErrorMsg: fmt.Sprintf("failed to create the Vault client: %v", vaultErr)}
+				continue
+			}
+			res[secretID] = providers.ReadVaultSecret(vaultClient, id)
+		case awsSecretsManagerPrefix, awsSSMPrefix:
+			if awsClient == nil && awsErr == nil {
+				awsClient, awsErr = newAWSClient()
+			}
+			if awsErr != nil {
+				res[secretID] = secrets.SecretVal{ErrorMsg: fmt.Sprintf("failed to create the AWS client: %v", awsErr)}
+				continue
+			}
+			if prefix == awsSSMPrefix {
+				res[secretID] = providers.ReadAWSSSMParameter(awsClient, id)
+			} else {
+				res[secretID] = providers.ReadAWSSecretsManagerSecret(awsClient, id)
+			}
+		case sopsPrefix:
+			res[secretID] = providers.ReadSopsSecret(id)
 		default:
 			res[secretID] = secrets.SecretVal{Value: "", ErrorMsg: fmt.Sprintf("provider not supported: %s", prefix)}
 		}
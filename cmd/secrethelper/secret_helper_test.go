@@ -8,6 +8,7 @@ package secrethelper
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -18,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"github.com/DataDog/datadog-agent/cmd/secrethelper/providers"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -183,6 +185,39 @@ func TestReadSecrets(t *testing.T) {
 	}
 }
 
+func TestReadSecretsRemoteProviderClients(t *testing.T) {
+	vaultClients, awsClients := 0, 0
+	newVaultClient = func() (*providers.VaultClient, error) {
+		vaultClients++
+		return nil, errors.New("VAULT_ADDR is not set")
+	}
+	newAWSClient = func() (*providers.AWSClient, error) {
+		awsClients++
+		return nil, errors.New("no AWS region configured")
+	}
+	t.Cleanup(func() {
+		newVaultClient = providers.NewVaultClientFromEnv
+		newAWSClient = providers.NewAWSClient
+	})
+
+	in := `{"version": "1.0", "secrets": ["vault@secret/data/a#key", "vault@secret/data/b#key", "aws_ssm@/a", "aws_secrets_manager@b", "sops@missing"]}`
+	var w bytes.Buffer
+	assert.NoError(t, readSecrets(strings.NewReader(in), &w, "", true, nil))
+	assert.JSONEq(t, `
+	{
+		"vault@secret/data/a#key": {"error": "failed to create the Vault client: VAULT_ADDR is not set"},
+		"vault@secret/data/b#key": {"error": "failed to create the Vault client: VAULT_ADDR is not set"},
+		"aws_ssm@/a": {"error": "failed to create the AWS client: no AWS region configured"},
+		"aws_secrets_manager@b": {"error": "failed to create the AWS client: no AWS region configured"},
+		"sops@missing": {"error": "invalid format. Use: \"/path/to/file#key\""}
+	}
+	`, w.String())
+
+	// the clients are created once
+	assert.Equal(t, 1, vaultClients)
+	assert.Equal(t, 1, awsClients)
+}
+
 func secretAbsPath(secretName string) string {
 	testdataPath := filepath.Join("testdata", "read-secrets", secretName)
 	absPath, _ := filepath.Abs(testdataPath)
@@ -10,9 +10,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/capture"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/config"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/controlsvc"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/info"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/replay"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/run"
 	"github.com/DataDog/datadog-agent/pkg/cli/subcommands/version"
 )
@@ -48,6 +50,8 @@ func makeCommands(globalParams *subcommands.GlobalParams) *cobra.Command {
 		info.MakeCommand(globalConfGetter),
 		version.MakeCommand("trace-agent"),
 		config.MakeCommand(globalConfGetter),
+		capture.MakeCommand(globalConfGetter),
+		replay.MakeCommand(globalConfGetter),
 	}
 
 	commands = append(commands, controlsvc.Commands(globalConfGetter)...)
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package capture implements 'trace-agent capture'.
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// cliParams are the command-line arguments for this subcommand.
+type cliParams struct {
+	duration time.Duration
+}
+
+// MakeCommand returns the capture subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	cmd := &cobra.Command{
+		Use:   "capture",
+		Short: "Capture the payloads received by a running trace-agent",
+		Long: `Captures the payloads received by the running trace-agent to a file, for the given duration.
+The capture file can then be replayed offline with "trace-agent replay". Payloads are captured
+before obfuscation: capture files may hold sensitive data.`,
+		RunE: func(*cobra.Command, []string) error {
+			return fxutil.OneShot(startCapture,
+				fx.Supply(cliParams),
+				fx.Supply(config.NewAgentParams(globalParamsGetter().ConfPath, config.WithFleetPoliciesDirPath(globalParamsGetter().FleetPoliciesDirPath))),
+				fx.Supply(option.None[secrets.Component]()),
+				config.Module(),
+			)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().DurationVarP(&cliParams.duration, "duration", "d", replay.DefaultCaptureDuration, "duration of the capture")
+	return cmd
+}
+
+func startCapture(config config.Component, cliParams *cliParams) error {
+	if err := apiutil.SetAuthToken(config); err != nil {
+		return err
+	}
+	port := config.GetInt("apm_config.debug.port")
+	if port <= 0 {
+		return fmt.Errorf("invalid apm_config.debug.port -- %d", port)
+	}
+
+	c := apiutil.GetClient()
+	c.Timeout = config.GetDuration("server_timeout") * time.Second
+
+	u := fmt.Sprintf("https://127.0.0.1:%d/capture?duration=%s", port, url.QueryEscape(cliParams.duration.String()))
+	res, err := apiutil.DoPost(c, u, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("could not start the capture: %s", err)
+	}
+	var resp struct {
+		Path     string `json:"path"`
+		Duration string `json:"duration"`
+	}
+	if err := json.Unmarshal(res, &resp); err != nil {
+		return fmt.Errorf("unexpected response from the trace-agent: %s", err)
+	}
+	fmt.Printf("Capturing the payloads received by the trace-agent to %s for %s.\n", resp.Path, resp.Duration)
+	return nil
+}
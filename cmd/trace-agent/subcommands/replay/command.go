@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements 'trace-agent replay'.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	ipcfx "github.com/DataDog/datadog-agent/comp/core/ipc/fx"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	logfx "github.com/DataDog/datadog-agent/comp/core/log/fx"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/fx-noop"
+	compression "github.com/DataDog/datadog-agent/comp/trace/compression/def"
+	zstdfx "github.com/DataDog/datadog-agent/comp/trace/compression/fx-zstd"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/agent"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	tracecfg "github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// cliParams are the command-line arguments for this subcommand.
+type cliParams struct {
+	file   string
+	output string
+}
+
+// MakeCommand returns the replay subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a capture file through the trace-agent processing pipeline",
+		Long: `Replays the payloads of a capture file made with "trace-agent capture" through the processing
+pipeline of the trace-agent, using the configuration of the trace-agent. Traces and stats are flushed
+to a local stand-in intake instead of Datadog; a summary of what it received is printed, and the
+received payloads can be written as JSON lines with --output.`,
+		RunE: func(*cobra.Command, []string) error {
+			params := globalParamsGetter()
+			return fxutil.OneShot(runReplay,
+				fx.Supply(cliParams),
+				config.Module(),
+				fx.Supply(coreconfig.NewAgentParams(params.ConfPath, coreconfig.WithFleetPoliciesDirPath(params.FleetPoliciesDirPath))),
+				fx.Supply(log.ForOneShot(params.LoggerName, "off", true)),
+				fx.Supply(option.None[secrets.Component]()),
+				fx.Supply(secrets.NewEnabledParams()),
+				coreconfig.Module(),
+				secretsimpl.Module(),
+				nooptagger.Module(),
+				ipcfx.ModuleReadOnly(),
+				logfx.Module(),
+				zstdfx.Module(),
+			)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVarP(&cliParams.file, "file", "f", "", "path of the capture file to replay")
+	cmd.Flags().StringVarP(&cliParams.output, "output", "o", "", "path of a file to write the payloads received by the intake to, as JSON lines")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func runReplay(config config.Component, comp compression.Component, cliParams *cliParams) error {
+	cfg := config.Object()
+	if cfg == nil {
+		return errors.New("unable to successfully parse config")
+	}
+
+	f, err := os.Open(cliParams.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := replay.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %s", cliParams.file, err)
+	}
+
+	var out io.Writer
+	if cliParams.output != "" {
+		o, err := os.Create(cliParams.output)
+		if err != nil {
+			return err
+		}
+		defer o.Close()
+		out = o
+	}
+
+	stats, n, err := replayCapture(cfg, comp, r, out)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replayed %d payloads from %s (capture format version %d).\n", n, cliParams.file, r.Version())
+	fmt.Printf("Intake received %d trace payloads (%d chunks, %d spans) and %d stats payloads (%d buckets).\n",
+		stats.TracePayloads, stats.TraceChunks, stats.Spans, stats.StatsPayloads, stats.StatsBuckets)
+	if cliParams.output != "" {
+		fmt.Printf("Intake payloads were written to %s.\n", cliParams.output)
+	}
+	return nil
+}
+
+// replayCapture processes the payloads read from r with an agent configured with cfg, flushing
+// to a local intake. It returns what the intake received and the number of payloads replayed.
+func replayCapture(cfg *tracecfg.AgentConfig, comp compression.Component, r *replay.Reader, out io.Writer) (intakeStats, int, error) {
+	in, err := newIntake(comp, out)
+	if err != nil {
+		return intakeStats{}, 0, err
+	}
+	defer in.Close()
+	cfg.Endpoints = []*tracecfg.Endpoint{{Host: in.URL(), APIKey: "replay", NoProxy: true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := agent.NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, comp)
+	n := 0
+	err = agnt.Replay(func() (*api.Payload, error) {
+		rec, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		n++
+		return rec.Payload, nil
+	})
+	return in.Stats(), n, err
+}
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	zstd "github.com/DataDog/datadog-agent/comp/trace/compression/impl-zstd"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestReplayCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		[]*cobra.Command{MakeCommand(func() *subcommands.GlobalParams {
+			return &subcommands.GlobalParams{}
+		})},
+		[]string{"replay", "--file", "capture", "--output", "out.json"},
+		runReplay,
+		func(cliParams *cliParams) {
+			require.Equal(t, "capture", cliParams.file)
+			require.Equal(t, "out.json", cliParams.output)
+		})
+}
+
+func TestReplayCapture(t *testing.T) {
+	var capture bytes.Buffer
+	w, err := replay.NewWriter(&capture)
+	require.NoError(t, err)
+	stats := info.NewReceiverStats()
+	for i := 0; i < 3; i++ {
+		tp := testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(testutil.RandomSpan()))
+		tp.Chunks[0].Priority = 2 // user keep, so that the chunk is not sampled out
+		require.NoError(t, w.Write(replay.Record{
+			Time: time.Now(),
+			Payload: &api.Payload{
+				Source:        stats.GetTagStats(info.Tags{Lang: "go"}),
+				TracerPayload: tp,
+			},
+		}))
+	}
+	require.NoError(t, w.Flush())
+
+	r, err := replay.NewReader(&capture)
+	require.NoError(t, err)
+	cfg := config.New()
+	cfg.Hostname = "replay-host"
+	var out bytes.Buffer
+	got, n, err := replayCapture(cfg, zstd.NewComponent(), r, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, got.TraceChunks)
+	assert.Equal(t, 3, got.Spans)
+	assert.NotZero(t, got.TracePayloads)
+	assert.NotZero(t, got.StatsPayloads)
+	assert.Equal(t, got.TracePayloads+got.StatsPayloads, strings.Count(out.String(), "\n"))
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tinylib/msgp/msgp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	compression "github.com/DataDog/datadog-agent/comp/trace/compression/def"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// intakeStats holds counts of the payloads received by an intake.
+type intakeStats struct {
+	TracePayloads int
+	TraceChunks   int
+	Spans         int
+	StatsPayloads int
+	StatsBuckets  int
+}
+
+// intake is a local stand-in for the Datadog intake, receiving the trace and stats payloads
+// flushed by a replaying agent. It decodes the payloads, counts them and optionally writes
+// them as JSON lines to out.
+type intake struct {
+	srv  *http.Server
+	ln   net.Listener
+	comp compression.Component
+
+	mu    sync.Mutex // guards the fields below
+	out   io.Writer
+	stats intakeStats
+}
+
+// newIntake starts an intake listening on a random local port. Trace payloads compressed with
+// comp are decoded.
+func newIntake(comp compression.Component, out io.Writer) (*intake, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	in := &intake{ln: ln, comp: comp, out: out}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0.2/traces", in.handleTraces)
+	mux.HandleFunc("/api/v0.2/stats", in.handleStats)
+	in.srv = &http.Server{Handler: mux}
+	go in.srv.Serve(ln) //nolint:errcheck
+	return in, nil
+}
+
+// URL returns the URL of the intake.
+func (in *intake) URL() string {
+	return "http://" + in.ln.Addr().String()
+}
+
+// Stats returns the counts of the payloads received so far.
+func (in *intake) Stats() intakeStats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// Close stops the intake.
+func (in *intake) Close() error {
+	return in.srv.Close()
+}
+
+func (in *intake) handleTraces(w http.ResponseWriter, req *http.Request) {
+	var r io.Reader = req.Body
+	if enc := req.Header.Get("Content-Encoding"); enc != "" {
+		if !strings.EqualFold(enc, in.comp.Encoding()) {
+			http.Error(w, fmt.Sprintf("unsupported content encoding %q", enc), http.StatusUnsupportedMediaType)
+			return
+		}
+		rc, err := in.comp.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer rc.Close()
+		r = rc
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var ap pb.AgentPayload
+	if err := proto.Unmarshal(body, &ap); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.stats.TracePayloads++
+	for _, tp := range ap.TracerPayloads {
+		in.stats.TraceChunks += len(tp.Chunks)
+		for _, c := range tp.Chunks {
+			in.stats.Spans += len(c.Spans)
+		}
+	}
+	if err := in.writeLocked("traces", &ap); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (in *intake) handleStats(w http.ResponseWriter, req *http.Request) {
+	var r io.Reader = req.Body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var sp pb.StatsPayload
+	if err := msgp.Decode(r, &sp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.stats.StatsPayloads++
+	for _, s := range sp.Stats {
+		in.stats.StatsBuckets += len(s.Stats)
+	}
+	if err := in.writeLocked("stats", &sp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeLocked writes m as a JSON line to the intake's output, if any.
+func (in *intake) writeLocked(endpoint string, m proto.Message) error {
+	if in.out == nil {
+		return nil
+	}
+	payload, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(struct {
+		Endpoint string          `json:"endpoint"`
+		Payload  json.RawMessage `json:"payload"`
+	}{endpoint, payload})
+	if err != nil {
+		return err
+	}
+	_, err = in.out.Write(append(line, '\n'))
+	return err
+}
@@ -8,8 +8,10 @@ package providers
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -39,6 +41,44 @@ type configFormat struct {
 	CheckTagCardinality     string   `yaml:"check_tag_cardinality"`     // Use to set the tag cardinality override for the check
 }
 
+// configFormatKeys are the top-level keys of the check configuration files
+var configFormatKeys = func() map[string]interface{} {
+	keys := map[string]interface{}{}
+	t := reflect.TypeOf(configFormat{})
+	for i := 0; i < t.NumField(); i++ {
+		// like yaml.v2, the fields without tag use their lowercased name
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" {
+			key = strings.ToLower(t.Field(i).Name)
+		}
+		keys[key] = nil
+	}
+	return keys
+}()
+
+// FindUnknownKeys returns the top-level keys of a check configuration file which are ignored by the agent, sorted.
+// The keys of the instances depend on the check and are not validated.
+func FindUnknownKeys(yamlFile []byte) ([]string, error) {
+	var rawConfig map[string]interface{}
+	if err := yaml.Unmarshal(yamlFile, &rawConfig); err != nil {
+		return nil, err
+	}
+
+	var unknownKeys []string
+	for key := range rawConfig {
+		if _, found := configFormatKeys[key]; !found {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	slices.Sort(unknownKeys)
+	return unknownKeys, nil
+}
+
+// CheckConfigKeys returns the top-level keys of the check configuration files
+func CheckConfigKeys() map[string]interface{} {
+	return maps.Clone(configFormatKeys)
+}
+
 type configPkg struct {
 	confs    []integration.Config
 	defaults []integration.Config
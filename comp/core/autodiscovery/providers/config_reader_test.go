@@ -168,3 +168,30 @@ instances:
 	require.Equal(t, 0, len(configs))
 	require.Equal(t, 0, len(errors))
 }
+
+func TestFindUnknownKeys(t *testing.T) {
+	unknownKeys, err := FindUnknownKeys([]byte(`
+init_config:
+instance:
+  - host: localhost
+instances:
+  - host: localhost
+    unknown_instance_key: true
+logs:
+  - type: file
+ad_identifier:
+  - redis
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ad_identifier", "instance"}, unknownKeys)
+
+	unknownKeys, err = FindUnknownKeys([]byte("instances: [{}]\ncheck_tag_cardinality: low\ncluster_check: true\n"))
+	require.NoError(t, err)
+	assert.Empty(t, unknownKeys)
+
+	_, err = FindUnknownKeys([]byte("- not a map"))
+	assert.Error(t, err)
+
+	assert.Contains(t, CheckConfigKeys(), "instances")
+	assert.Contains(t, CheckConfigKeys(), "jmx_metrics")
+}
@@ -147,6 +147,12 @@ func (r *secretResolver) fetchSecret(secretsHandle []string) (map[string]string,
 			return nil, fmt.Errorf("resolved secret for '%s' is empty", sec)
 		}
 		res[sec] = v.Value
+
+		if v.TTL > 0 {
+			r.secretTTLs[sec] = time.Duration(v.TTL) * time.Second
+		} else {
+			delete(r.secretTTLs, sec)
+		}
 	}
 	return res, nil
 }
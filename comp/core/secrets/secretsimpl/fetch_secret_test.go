@@ -302,3 +302,23 @@ func TestFetchSecretRemoveTrailingLineBreak(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, map[string]string{"handle1": "some data"}, resp)
 }
+
+func TestFetchSecretTTL(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	resolver.commandHookFunc = func(string) ([]byte, error) {
+		return []byte("{\"handle1\":{\"value\":\"value1\",\"ttl\":3600},\"handle2\":{\"value\":\"value2\"}}"), nil
+	}
+	res, err := resolver.fetchSecret([]string{"handle1", "handle2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"handle1": "value1", "handle2": "value2"}, res)
+	assert.Equal(t, map[string]time.Duration{"handle1": time.Hour}, resolver.secretTTLs)
+
+	// the TTL is forgotten when the secret is fetched without one
+	resolver.commandHookFunc = func(string) ([]byte, error) {
+		return []byte("{\"handle1\":{\"value\":\"value1\"}}"), nil
+	}
+	_, err = resolver.fetchSecret([]string{"handle1"})
+	require.NoError(t, err)
+	assert.Empty(t, resolver.secretTTLs)
+}
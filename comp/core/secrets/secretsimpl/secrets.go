@@ -43,6 +43,9 @@ import (
 
 const auditFileBasename = "secret-audit-file.json"
 
+// minLeaseRefreshInterval bounds how often the secrets with a short TTL are refreshed
+const minLeaseRefreshInterval = 10 * time.Second
+
 var newClock = clock.New
 
 type provides struct {
@@ -100,6 +103,9 @@ type secretResolver struct {
 	refreshIntervalScatter bool
 	scatterDuration        time.Duration
 	ticker                 *clock.Ticker
+	tickerInterval         time.Duration
+	// TTL of the secrets returned with one by the backend, they are refreshed before it expires
+	secretTTLs map[string]time.Duration
 	// filename to write audit records to
 	auditFilename    string
 	auditFileMaxSize int
@@ -124,6 +130,7 @@ func newEnabledSecretResolver(telemetry telemetry.Component) *secretResolver {
 	return &secretResolver{
 		cache:                   make(map[string]string),
 		origin:                  make(handleToContext),
+		secretTTLs:              make(map[string]time.Duration),
 		enabled:                 true,
 		tlmSecretBackendElapsed: telemetry.NewGauge("secret_backend", "elapsed_ms", []string{"command", "exit_code"}, "Elapsed time of secret backend invocation"),
 		tlmSecretUnmarshalError: telemetry.NewCounter("secret_backend", "unmarshal_errors_count", []string{}, "Count of errors when unmarshalling the output of the secret binary"),
@@ -250,36 +257,82 @@ func isEnc(str string) (bool, string) {
 	return false, ""
 }
 
+// refreshIntervalLocked returns the interval between two refreshes: secret_refresh_interval,
+// shortened so that the refreshed secrets with a TTL are fetched again after two thirds of it.
+// It returns 0 when the secrets are never refreshed. The caller must hold the lock.
+func (r *secretResolver) refreshIntervalLocked() time.Duration {
+	interval := r.refreshInterval
+	for handle, ttl := range r.secretTTLs {
+		if !r.matchesAllowlist(handle) {
+			continue
+		}
+		leaseInterval := max(ttl*2/3, minLeaseRefreshInterval)
+		if interval == 0 || leaseInterval < interval {
+			interval = leaseInterval
+		}
+	}
+	return interval
+}
+
+// startRefreshRoutine starts refreshing the secrets, or shortens the interval of the running
+// refresh routine when a secret with a shorter TTL was resolved. The caller must hold the lock.
 func (r *secretResolver) startRefreshRoutine() {
-	if r.ticker != nil || r.refreshInterval == 0 {
+	interval := r.refreshIntervalLocked()
+	if interval == 0 {
+		return
+	}
+	if r.ticker != nil {
+		if interval < r.tickerInterval {
+			log.Infof("secrets will be refreshed every %s to renew them before their TTL expires", interval)
+			r.tickerInterval = interval
+			r.ticker.Reset(interval)
+		}
 		return
 	}
 
 	if r.refreshIntervalScatter {
-		r.scatterDuration = time.Duration(rand.Int63n(int64(r.refreshInterval)))
+		r.scatterDuration = time.Duration(rand.Int63n(int64(interval)))
 		log.Infof("first secret refresh will happen in %s", r.scatterDuration)
 	} else {
-		r.scatterDuration = r.refreshInterval
+		r.scatterDuration = interval
 	}
+	r.tickerInterval = r.scatterDuration
 	r.ticker = r.clk.Ticker(r.scatterDuration)
 
 	go func() {
-		<-r.ticker.C
-		if _, err := r.Refresh(); err != nil {
-			log.Infof("Error with refreshing secrets: %s", err)
-		}
-		// we want to reset the refresh interval to the refreshInterval after the first refresh in case a scattered first refresh interval was configured
-		r.ticker.Reset(r.refreshInterval)
-
 		for {
 			<-r.ticker.C
 			if _, err := r.Refresh(); err != nil {
 				log.Infof("Error with refreshing secrets: %s", err)
 			}
+			if !r.resetRefreshTicker() {
+				return
+			}
 		}
 	}()
 }
 
+// resetRefreshTicker resets the refresh interval after a refresh, in case a scattered first
+// refresh interval was configured or the TTL of the secrets changed. It stops the refresh routine
+// and returns false when the secrets aren't refreshed anymore.
+func (r *secretResolver) resetRefreshTicker() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	interval := r.refreshIntervalLocked()
+	if interval == 0 {
+		r.ticker.Stop()
+		r.ticker = nil
+		r.tickerInterval = 0
+		return false
+	}
+	if interval != r.tickerInterval {
+		r.tickerInterval = interval
+		r.ticker.Reset(interval)
+	}
+	return true
+}
+
 // SubscribeToChanges adds this callback to the list that get notified when secrets are resolved or refreshed
 func (r *secretResolver) SubscribeToChanges(cb secrets.SecretChangeCallback) {
 	r.lock.Lock()
@@ -385,6 +438,11 @@ func (r *secretResolver) Resolve(data []byte, origin string) ([]byte, error) {
 
 		// for Resolving secrets, always send notifications
 		r.processSecretResponse(secretResponse, false)
+
+		// the secrets with a TTL may require refreshing them sooner, or at all
+		if len(r.subscriptions) > 0 {
+			r.startRefreshRoutine()
+		}
 	}
 
 	finalConfig, err := yaml.Marshal(config)
@@ -698,5 +756,12 @@ func (r *secretResolver) GetDebugInfo(w io.Writer) {
 	if r.refreshIntervalScatter {
 		fmt.Fprintf(w, "'secret_refresh interval' is enabled: the first refresh will happen %s after startup and then every %s\n", r.scatterDuration, r.refreshInterval)
 	}
+	// the TTLs are updated by the refreshes
+	r.lock.Lock()
+	ttlCount, ttlRefreshInterval := len(r.secretTTLs), r.refreshIntervalLocked()
+	r.lock.Unlock()
+	if ttlCount > 0 {
+		fmt.Fprintf(w, "%d secrets were resolved with a TTL: the secrets are refreshed every %s\n", ttlCount, ttlRefreshInterval)
+	}
 
 }
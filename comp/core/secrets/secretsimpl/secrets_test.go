@@ -6,7 +6,9 @@
 package secretsimpl
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"slices"
@@ -923,6 +925,102 @@ func TestStartRefreshRoutineWithScatter(t *testing.T) {
 	}
 }
 
+func TestRefreshRoutineWithTTL(t *testing.T) {
+	newClock = func() clock.Clock { return clock.NewMock() }
+	t.Cleanup(func() {
+		newClock = clock.New
+	})
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+
+	resolver := newEnabledSecretResolver(tel)
+	mockClock := resolver.clk.(*clock.Mock)
+	resolver.backendCommand = "some_command"
+	resolver.refreshInterval = time.Hour
+
+	responses := make(chan string, 3)
+	fetched := make(chan struct{}, 3)
+	resolver.commandHookFunc = func(string) ([]byte, error) {
+		fetched <- struct{}{}
+		return []byte(<-responses), nil
+	}
+
+	resolver.SubscribeToChanges(func(_, _ string, _ []string, _, _ any) {})
+	require.NotNil(t, resolver.ticker)
+	assert.Equal(t, time.Hour, resolver.tickerInterval)
+
+	// the secret has a lease of 60s: it's refreshed after 40s instead of an hour
+	responses <- `{"lease":{"value":"first","ttl":60}}`
+	_, err := resolver.Resolve([]byte("api_key: ENC[lease]"), "test")
+	require.NoError(t, err)
+	<-fetched
+	assert.Equal(t, 40*time.Second, resolver.tickerInterval)
+
+	// the lease is renewed without a TTL: secret_refresh_interval is used again
+	responses <- `{"lease":{"value":"second"}}`
+	mockClock.Add(40 * time.Second)
+	select {
+	case <-fetched:
+	case <-time.After(time.Second):
+		t.Fatal("the secret with a TTL wasn't refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		resolver.lock.Lock()
+		defer resolver.lock.Unlock()
+		return resolver.tickerInterval == time.Hour
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "second", resolver.cache["lease"])
+}
+
+func TestRefreshRoutineStopsWithoutTTL(t *testing.T) {
+	newClock = func() clock.Clock { return clock.NewMock() }
+	t.Cleanup(func() {
+		newClock = clock.New
+	})
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+
+	resolver := newEnabledSecretResolver(tel)
+	mockClock := resolver.clk.(*clock.Mock)
+	resolver.backendCommand = "some_command"
+
+	responses := make(chan string, 3)
+	fetched := make(chan struct{}, 3)
+	resolver.commandHookFunc = func(string) ([]byte, error) {
+		fetched <- struct{}{}
+		return []byte(<-responses), nil
+	}
+
+	// without secret_refresh_interval, the secrets are only refreshed when they have a TTL
+	resolver.SubscribeToChanges(func(_, _ string, _ []string, _, _ any) {})
+	require.Nil(t, resolver.ticker)
+
+	// the TTL isn't used for the settings which can't be refreshed
+	responses <- `{"other":{"value":"value","ttl":60}}`
+	_, err := resolver.Resolve([]byte("hostname: ENC[other]"), "test")
+	require.NoError(t, err)
+	<-fetched
+	require.Nil(t, resolver.ticker)
+
+	responses <- `{"lease":{"value":"first","ttl":1}}`
+	_, err = resolver.Resolve([]byte("api_key: ENC[lease]"), "test")
+	require.NoError(t, err)
+	<-fetched
+	require.NotNil(t, resolver.ticker)
+	assert.Equal(t, minLeaseRefreshInterval, resolver.tickerInterval)
+
+	responses <- `{"lease":{"value":"second"}}`
+	mockClock.Add(minLeaseRefreshInterval)
+	select {
+	case <-fetched:
+	case <-time.After(time.Second):
+		t.Fatal("the secret with a TTL wasn't refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		resolver.lock.Lock()
+		defer resolver.lock.Unlock()
+		return resolver.ticker == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
 // helper to read number of rows in the audit file
 func auditFileNumRows(filename string) int {
 	data, _ := os.ReadFile(filename)
@@ -1004,3 +1102,28 @@ func TestIsLikelyAPIOrAppKey(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDebugInfoDuringTTLRefresh(t *testing.T) {
+	tel := fxutil.Test[telemetry.Component](t, nooptelemetry.Module())
+	resolver := newEnabledSecretResolver(tel)
+	resolver.backendCommand = "some_command"
+
+	// the refreshes update the TTLs while the debug info is printed, like `agent secret` during a refresh
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			resolver.lock.Lock()
+			resolver.secretTTLs[fmt.Sprintf("handle%d", i)] = time.Minute
+			resolver.lock.Unlock()
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		resolver.GetDebugInfo(io.Discard)
+	}
+	<-done
+
+	var buffer bytes.Buffer
+	resolver.GetDebugInfo(&buffer)
+	assert.Contains(t, buffer.String(), "100 secrets were resolved with a TTL")
+}
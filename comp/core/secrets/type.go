@@ -9,6 +9,9 @@ package secrets
 type SecretVal struct {
 	Value    string `json:"value,omitempty"`
 	ErrorMsg string `json:"error,omitempty"`
+	// TTL is the number of seconds the value is valid for, like the lease duration of a Vault
+	// secret. The secrets with a TTL are refreshed before it expires.
+	TTL int64 `json:"ttl,omitempty"`
 }
 
 // SecretChangeCallback is the callback type used by SubscribeToChanges to send notifications
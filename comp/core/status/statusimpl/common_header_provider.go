@@ -75,6 +75,7 @@ func (h *headerProvider) data() map[string]interface{} {
 	data["time_nano"] = nowFunc().UnixNano()
 	data["config"] = populateConfig(h.config)
 	data["fips_status"] = populateFIPSStatus(h.config)
+	data["config_issues"] = populateConfigIssues(h.config)
 	pythonVersion := h.params.PythonVersionGetFunc()
 	data["python_version"] = strings.Split(pythonVersion, " ")[0]
 	return data
@@ -116,6 +117,15 @@ func populateConfig(config config.Component) map[string]string {
 	return conf
 }
 
+// populateConfigIssues returns the problems found in the settings configured by the user
+func populateConfigIssues(config config.Component) []string {
+	issues := []string{}
+	for _, issue := range pkgconfigsetup.LintConfig(config) {
+		issues = append(issues, issue.String())
+	}
+	return issues
+}
+
 func populateFIPSStatus(config config.Component) string {
 	fipsStatus := fips.Status()
 	if fipsStatus == "not available" && config.GetString("fips.enabled") == "true" {
@@ -106,6 +106,34 @@ func TestCommonHeaderProviderText(t *testing.T) {
 	assert.Equal(t, expectedResult, output)
 }
 
+func TestCommonHeaderProviderConfigIssues(t *testing.T) {
+	config := config.NewMockFromYAML(t, "log_leve: debug\nlogs_enabled: maybe\n")
+
+	provider := newCommonHeaderProvider(agentParams, config)
+
+	stats := map[string]interface{}{}
+	require.NoError(t, provider.JSON(false, stats))
+	issues := []string{
+		"log_leve: unknown setting, it is ignored, did you mean `log_level`?",
+		"logs_enabled: the value `maybe` from the configuration file is not of type boolean",
+	}
+	assert.Equal(t, issues, stats["config_issues"])
+
+	buffer := new(bytes.Buffer)
+	require.NoError(t, provider.Text(false, buffer))
+	output := strings.ReplaceAll(buffer.String(), "\r\n", "\n")
+	assert.Contains(t, output, fmt.Sprintf(`
+  Configuration issues
+  ====================
+    - %s
+    - %s
+`, issues[0], issues[1]))
+
+	buffer.Reset()
+	require.NoError(t, provider.HTML(false, buffer))
+	assert.Contains(t, buffer.String(), "Configuration issues")
+}
+
 func TestCommonHeaderProviderTime(t *testing.T) {
 	// test that the time is updated on each call
 	counter := 0
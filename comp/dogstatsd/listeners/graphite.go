@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	graphiteExpvars     = expvar.NewMap("dogstatsd-graphite")
+	graphiteConnections = expvar.Int{}
+	graphiteMetrics     = expvar.Int{}
+	graphiteParseErrors = expvar.Int{}
+)
+
+func init() {
+	graphiteExpvars.Set("Connections", &graphiteConnections)
+	graphiteExpvars.Set("Metrics", &graphiteMetrics)
+	graphiteExpvars.Set("ParseErrors", &graphiteParseErrors)
+}
+
+// Protocols of the Graphite listener
+const (
+	GraphitePlaintext = "plaintext"
+	GraphitePickle    = "pickle"
+)
+
+const (
+	// graphiteMaxLineSize is the maximum size of a line of the plaintext protocol.
+	graphiteMaxLineSize = 64 * 1024
+	// graphiteMaxPickleSize is the maximum size of a message of the pickle protocol, the same as
+	// the one of carbon.
+	graphiteMaxPickleSize = 1024 * 1024
+	// graphiteConnCloseDelay is the time given to the clients to stop sending data when the
+	// listener is stopped.
+	graphiteConnCloseDelay = 1 * time.Second
+)
+
+// GraphiteListener implements the StatsdListener interface for the Graphite protocols over TCP:
+// the plaintext protocol, a `<path> <value> <timestamp>` line per datapoint, or the pickle
+// protocol of carbon-relay. The datapoints are converted to timestamped gauges in DogStatsD
+// messages, and sent back in packets, so that they go through the mapper, and are parsed and
+// enriched the same way as the messages of the other listeners.
+// The tags of the paths of Graphite 1.1, e.g. `disk.used;host=web-1;mount=/`, are supported.
+type GraphiteListener struct {
+	protocol        string
+	listener        net.Listener
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	connTracker     *ConnectionTracker
+	telemetryStore  *TelemetryStore
+	listenWg        sync.WaitGroup
+	connWg          sync.WaitGroup
+}
+
+// NewGraphiteListener returns an idle Graphite listener of the protocol, listening on the port
+// of `dogstatsd_graphite_port` for the plaintext protocol, or `dogstatsd_graphite_pickle_port` for
+// the pickle protocol.
+func NewGraphiteListener(protocol string, packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*GraphiteListener, error) {
+	var port string
+	switch protocol {
+	case GraphitePlaintext:
+		port = cfg.GetString("dogstatsd_graphite_port")
+	case GraphitePickle:
+		port = cfg.GetString("dogstatsd_graphite_pickle_port")
+	default:
+		return nil, fmt.Errorf("unknown Graphite protocol %q", protocol)
+	}
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	var url string
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	ln, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "graphite", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.Graphite)
+
+	l := &GraphiteListener{
+		protocol:        protocol,
+		listener:        ln,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		connTracker:     NewConnectionTracker("graphite-"+protocol, graphiteConnCloseDelay),
+		telemetryStore:  telemetryStore,
+	}
+	log.Debugf("dogstatsd-graphite: %s listener %s successfully initialized", protocol, ln.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *GraphiteListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *GraphiteListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *GraphiteListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-graphite: starting to listen to the %s protocol on %s", l.protocol, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("dogstatsd-graphite: error accepting connection: %v", err)
+			}
+			return
+		}
+		l.connWg.Add(1)
+		go func() {
+			defer l.connWg.Done()
+			l.connTracker.Track(conn)
+			graphiteConnections.Add(1)
+			l.telemetryStore.tlmGraphiteConnections.Inc(l.protocol)
+			if err := l.handleConnection(conn); err != nil && !errors.Is(err, net.ErrClosed) {
+				log.Debugf("dogstatsd-graphite: closing connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			graphiteConnections.Add(-1)
+			l.telemetryStore.tlmGraphiteConnections.Dec(l.protocol)
+			l.connTracker.Close(conn)
+		}()
+	}
+}
+
+// handleConnection reads the datapoints of a connection until it's closed by the client, or an
+// invalid message of the pickle protocol is received.
+func (l *GraphiteListener) handleConnection(conn net.Conn) error {
+	if l.protocol == GraphitePickle {
+		return l.readPickle(conn)
+	}
+	return l.readPlaintext(conn)
+}
+
+func (l *GraphiteListener) readPlaintext(conn net.Conn) error {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), graphiteMaxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		metric, err := parseGraphiteLine(line)
+		if err != nil {
+			l.recordParseError()
+			log.Debugf("dogstatsd-graphite: invalid line %q: %v", line, err)
+			continue
+		}
+		l.addMetrics([]graphiteMetric{metric})
+	}
+	return scanner.Err()
+}
+
+func (l *GraphiteListener) readPickle(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > graphiteMaxPickleSize {
+			l.recordParseError()
+			return fmt.Errorf("pickle message of %d bytes exceeds the maximum of %d bytes", size, graphiteMaxPickleSize)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+		metrics, invalid, err := unpickleGraphiteMetrics(payload)
+		if err != nil {
+			// the client can't be resynchronized with the next message
+			l.recordParseError()
+			return fmt.Errorf("invalid pickle message: %w", err)
+		}
+		for i := 0; i < invalid; i++ {
+			l.recordParseError()
+		}
+		l.addMetrics(metrics)
+	}
+}
+
+func (l *GraphiteListener) recordParseError() {
+	graphiteParseErrors.Add(1)
+	l.telemetryStore.tlmGraphiteMetrics.Inc(l.protocol, "error")
+}
+
+// addMetrics sends the DogStatsD messages of the datapoints to the packet assembler.
+func (l *GraphiteListener) addMetrics(metrics []graphiteMetric) {
+	for _, metric := range metrics {
+		l.packetAssembler.AddMessage(metric.message())
+	}
+	graphiteMetrics.Add(int64(len(metrics)))
+	l.telemetryStore.tlmGraphiteMetrics.Add(float64(len(metrics)), l.protocol, "ok")
+}
+
+// Stop closes the listener and the connections of the clients
+func (l *GraphiteListener) Stop() {
+	_ = l.listener.Close()
+	l.listenWg.Wait()
+	l.connTracker.Stop()
+	l.connWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// graphiteMetric is a datapoint of the Graphite protocols.
+type graphiteMetric struct {
+	name      string
+	tags      []string
+	value     float64
+	timestamp int64
+}
+
+// message returns the DogStatsD message of the datapoint, a gauge with its timestamp.
+func (m graphiteMetric) message() []byte {
+	return formatMessage(m.name, m.value, "g", m.tags, m.timestamp)
+}
+
+// parseGraphiteLine parses a line of the plaintext protocol: `<path> <value> <timestamp>`.
+func parseGraphiteLine(line string) (graphiteMetric, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return graphiteMetric{}, fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return graphiteMetric{}, fmt.Errorf("invalid value: %w", err)
+	}
+	timestamp, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return graphiteMetric{}, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	return newGraphiteMetric(fields[0], value, timestamp)
+}
+
+// newGraphiteMetric returns the datapoint of a path. The NaN and infinite values are rejected,
+// a negative timestamp means the current time.
+func newGraphiteMetric(path string, value float64, timestamp float64) (graphiteMetric, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return graphiteMetric{}, fmt.Errorf("unsupported value %v", value)
+	}
+	if math.IsNaN(timestamp) || math.IsInf(timestamp, 0) {
+		return graphiteMetric{}, fmt.Errorf("invalid timestamp %v", timestamp)
+	}
+	name, tags, err := parseGraphitePath(path)
+	if err != nil {
+		return graphiteMetric{}, err
+	}
+	metric := graphiteMetric{name: name, tags: tags, value: value}
+	if timestamp > 0 {
+		metric.timestamp = int64(timestamp)
+	}
+	return metric, nil
+}
+
+// parseGraphitePath returns the name and the tags of a path, e.g. `disk.used;host=web-1;mount=/`.
+func parseGraphitePath(path string) (string, []string, error) {
+	name, rawTags, hasTags := strings.Cut(path, ";")
+	if name == "" {
+		return "", nil, fmt.Errorf("empty metric path")
+	}
+	name = sanitizeMetricName(name)
+	if !hasTags {
+		return name, nil, nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(rawTags, ";") {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || key == "" || value == "" {
+			return "", nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		tags = append(tags, sanitizeTag(key+":"+value))
+	}
+	return name, tags, nil
+}
@@ -0,0 +1,547 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Opcodes of the pickle protocols 0 to 4 supported by the unpickler: the ones needed to decode
+// lists and tuples of strings and numbers. The opcodes building arbitrary objects, e.g. GLOBAL
+// and REDUCE, aren't supported.
+const (
+	pickleMark           = '('
+	pickleStop           = '.'
+	picklePop            = '0'
+	picklePopMark        = '1'
+	pickleDup            = '2'
+	pickleFloat          = 'F'
+	pickleInt            = 'I'
+	pickleBinInt         = 'J'
+	pickleBinInt1        = 'K'
+	pickleBinInt2        = 'M'
+	pickleLong           = 'L'
+	pickleNone           = 'N'
+	pickleString         = 'S'
+	pickleBinString      = 'T'
+	pickleShortBinString = 'U'
+	pickleUnicode        = 'V'
+	pickleBinUnicode     = 'X'
+	pickleAppend         = 'a'
+	pickleAppends        = 'e'
+	pickleList           = 'l'
+	pickleEmptyList      = ']'
+	pickleTuple          = 't'
+	pickleEmptyTuple     = ')'
+	picklePut            = 'p'
+	pickleBinPut         = 'q'
+	pickleLongBinPut     = 'r'
+	pickleGet            = 'g'
+	pickleBinGet         = 'h'
+	pickleLongBinGet     = 'j'
+	pickleBinFloat       = 'G'
+	pickleBinBytes       = 'B'
+	pickleShortBinBytes  = 'C'
+	pickleProto          = 0x80
+	pickleTuple1         = 0x85
+	pickleTuple2         = 0x86
+	pickleTuple3         = 0x87
+	pickleNewTrue        = 0x88
+	pickleNewFalse       = 0x89
+	pickleLong1          = 0x8a
+	pickleLong4          = 0x8b
+	pickleShortBinUni    = 0x8c
+	pickleBinUnicode8    = 0x8d
+	pickleBinBytes8      = 0x8e
+	pickleMemoize        = 0x94
+	pickleFrame          = 0x95
+)
+
+// pickleMarkObject is pushed on the stack by the MARK opcode.
+type pickleMarkObject struct{}
+
+// pickleListObject is a list, it's a pointer as the lists are modified after being memoized.
+type pickleListObject struct {
+	items []interface{}
+}
+
+// unpickler decodes the pickled values sent by carbon-relay.
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []interface{}
+	memo  map[int]interface{}
+}
+
+// unpickleGraphiteMetrics decodes a message of the pickle protocol of Graphite: a list of
+// `(path, (timestamp, value))` tuples. The invalid datapoints are skipped, their number is returned
+// with the valid ones.
+func unpickleGraphiteMetrics(data []byte) ([]graphiteMetric, int, error) {
+	value, err := unpickle(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	list, ok := value.(*pickleListObject)
+	if !ok {
+		return nil, 0, fmt.Errorf("expected a list, got %T", value)
+	}
+	metrics := make([]graphiteMetric, 0, len(list.items))
+	invalid := 0
+	for _, item := range list.items {
+		metric, err := pickledGraphiteMetric(item)
+		if err != nil {
+			invalid++
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, invalid, nil
+}
+
+func pickledGraphiteMetric(item interface{}) (graphiteMetric, error) {
+	datapoint, ok := pickleSequence(item)
+	if !ok || len(datapoint) != 2 {
+		return graphiteMetric{}, errors.New("expected a (path, (timestamp, value)) tuple")
+	}
+	path, ok := datapoint[0].(string)
+	if !ok {
+		return graphiteMetric{}, errors.New("expected a string path")
+	}
+	point, ok := pickleSequence(datapoint[1])
+	if !ok || len(point) != 2 {
+		return graphiteMetric{}, errors.New("expected a (timestamp, value) tuple")
+	}
+	timestamp, err := pickleNumber(point[0])
+	if err != nil {
+		return graphiteMetric{}, err
+	}
+	value, err := pickleNumber(point[1])
+	if err != nil {
+		return graphiteMetric{}, err
+	}
+	return newGraphiteMetric(path, value, timestamp)
+}
+
+func pickleSequence(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case *pickleListObject:
+		return v.items, true
+	}
+	return nil, false
+}
+
+// pickleNumber returns the value of a number, which can also be sent as a string.
+func pickleNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %T", value)
+}
+
+// unpickle decodes a pickled value.
+func unpickle(data []byte) (interface{}, error) {
+	u := &unpickler{data: data, memo: make(map[int]interface{})}
+	return u.load()
+}
+
+func (u *unpickler) load() (interface{}, error) {
+	for {
+		if u.pos >= len(u.data) {
+			return nil, errors.New("unexpected end of data")
+		}
+		op := u.data[u.pos]
+		u.pos++
+		var err error
+		switch op {
+		case pickleStop:
+			if len(u.stack) != 1 {
+				return nil, errors.New("invalid stack at the end of data")
+			}
+			return u.stack[0], nil
+		case pickleProto:
+			_, err = u.read(1)
+		case pickleFrame:
+			_, err = u.read(8)
+		case pickleMark:
+			u.push(pickleMarkObject{})
+		case picklePop:
+			_, err = u.pop()
+		case picklePopMark:
+			_, err = u.popMark()
+		case pickleDup:
+			var top interface{}
+			if top, err = u.top(); err == nil {
+				u.push(top)
+			}
+		case pickleNone:
+			u.push(nil)
+		case pickleNewTrue:
+			u.push(true)
+		case pickleNewFalse:
+			u.push(false)
+		case pickleInt:
+			err = u.loadInt()
+		case pickleLong:
+			err = u.loadLong()
+		case pickleBinInt:
+			var b []byte
+			if b, err = u.read(4); err == nil {
+				u.push(int64(int32(binary.LittleEndian.Uint32(b))))
+			}
+		case pickleBinInt1:
+			var b []byte
+			if b, err = u.read(1); err == nil {
+				u.push(int64(b[0]))
+			}
+		case pickleBinInt2:
+			var b []byte
+			if b, err = u.read(2); err == nil {
+				u.push(int64(binary.LittleEndian.Uint16(b)))
+			}
+		case pickleLong1, pickleLong4:
+			err = u.loadBinLong(op)
+		case pickleFloat:
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var f float64
+				if f, err = strconv.ParseFloat(line, 64); err == nil {
+					u.push(f)
+				}
+			}
+		case pickleBinFloat:
+			var b []byte
+			if b, err = u.read(8); err == nil {
+				u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+			}
+		case pickleString:
+			err = u.loadString()
+		case pickleUnicode:
+			var line string
+			if line, err = u.readLine(); err == nil {
+				u.push(line)
+			}
+		case pickleShortBinString, pickleShortBinBytes, pickleShortBinUni:
+			err = u.loadBinString(1)
+		case pickleBinString, pickleBinBytes, pickleBinUnicode:
+			err = u.loadBinString(4)
+		case pickleBinUnicode8, pickleBinBytes8:
+			err = u.loadBinString(8)
+		case pickleEmptyList:
+			u.push(&pickleListObject{})
+		case pickleList:
+			var items []interface{}
+			if items, err = u.popMark(); err == nil {
+				u.push(&pickleListObject{items: items})
+			}
+		case pickleAppend:
+			var item interface{}
+			if item, err = u.pop(); err == nil {
+				err = u.appendToList(item)
+			}
+		case pickleAppends:
+			var items []interface{}
+			if items, err = u.popMark(); err == nil {
+				err = u.appendToList(items...)
+			}
+		case pickleEmptyTuple:
+			u.push([]interface{}{})
+		case pickleTuple:
+			var items []interface{}
+			if items, err = u.popMark(); err == nil {
+				u.push(items)
+			}
+		case pickleTuple1, pickleTuple2, pickleTuple3:
+			err = u.loadTupleN(int(op-pickleTuple1) + 1)
+		case picklePut, pickleBinPut, pickleLongBinPut, pickleMemoize:
+			err = u.put(op)
+		case pickleGet, pickleBinGet, pickleLongBinGet:
+			err = u.get(op)
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode 0x%02x", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n < 0 || n > len(u.data)-u.pos {
+		return nil, errors.New("unexpected end of data")
+	}
+	b := u.data[u.pos : u.pos+n]
+	u.pos += n
+	return b, nil
+}
+
+func (u *unpickler) readLine() (string, error) {
+	i := bytes.IndexByte(u.data[u.pos:], '\n')
+	if i < 0 {
+		return "", errors.New("unexpected end of data")
+	}
+	line := string(u.data[u.pos : u.pos+i])
+	u.pos += i + 1
+	return line, nil
+}
+
+func (u *unpickler) push(value interface{}) {
+	u.stack = append(u.stack, value)
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, errors.New("empty stack")
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	value, err := u.top()
+	if err != nil {
+		return nil, err
+	}
+	u.stack = u.stack[:len(u.stack)-1]
+	return value, nil
+}
+
+// popMark pops the values up to the last mark.
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(pickleMarkObject); ok {
+			items := append([]interface{}(nil), u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("mark not found")
+}
+
+func (u *unpickler) appendToList(items ...interface{}) error {
+	top, err := u.top()
+	if err != nil {
+		return err
+	}
+	list, ok := top.(*pickleListObject)
+	if !ok {
+		return fmt.Errorf("can't append to %T", top)
+	}
+	list.items = append(list.items, items...)
+	return nil
+}
+
+func (u *unpickler) loadTupleN(n int) error {
+	if len(u.stack) < n {
+		return errors.New("empty stack")
+	}
+	items := append([]interface{}(nil), u.stack[len(u.stack)-n:]...)
+	u.stack = u.stack[:len(u.stack)-n]
+	u.push(items)
+	return nil
+}
+
+func (u *unpickler) loadInt() error {
+	line, err := u.readLine()
+	if err != nil {
+		return err
+	}
+	switch line {
+	case "00":
+		u.push(false)
+		return nil
+	case "01":
+		u.push(true)
+		return nil
+	}
+	i, err := strconv.ParseInt(line, 10, 64)
+	if err != nil {
+		return err
+	}
+	u.push(i)
+	return nil
+}
+
+func (u *unpickler) loadLong() error {
+	line, err := u.readLine()
+	if err != nil {
+		return err
+	}
+	i, ok := new(big.Int).SetString(strings.TrimSuffix(line, "L"), 10)
+	if !ok {
+		return fmt.Errorf("invalid long %q", line)
+	}
+	u.pushBigInt(i)
+	return nil
+}
+
+// loadBinLong loads a little-endian two's complement integer.
+func (u *unpickler) loadBinLong(op byte) error {
+	var n int
+	if op == pickleLong1 {
+		b, err := u.read(1)
+		if err != nil {
+			return err
+		}
+		n = int(b[0])
+	} else {
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		n = int(int32(binary.LittleEndian.Uint32(b)))
+	}
+	b, err := u.read(n)
+	if err != nil {
+		return err
+	}
+	bigEndian := make([]byte, len(b))
+	for i := range b {
+		bigEndian[len(b)-1-i] = b[i]
+	}
+	i := new(big.Int).SetBytes(bigEndian)
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	u.pushBigInt(i)
+	return nil
+}
+
+func (u *unpickler) pushBigInt(i *big.Int) {
+	if i.IsInt64() {
+		u.push(i.Int64())
+	} else {
+		u.push(i)
+	}
+}
+
+// loadString loads a quoted string of the protocol 0.
+func (u *unpickler) loadString() error {
+	line, err := u.readLine()
+	if err != nil {
+		return err
+	}
+	if len(line) < 2 || line[0] != line[len(line)-1] || (line[0] != '\'' && line[0] != '"') {
+		return fmt.Errorf("invalid string %q", line)
+	}
+	inner := line[1 : len(line)-1]
+	if line[0] == '\'' {
+		inner = strings.ReplaceAll(strings.ReplaceAll(inner, `\'`, `'`), `"`, `\"`)
+	}
+	s, err := strconv.Unquote(`"` + inner + `"`)
+	if err != nil {
+		return fmt.Errorf("invalid string %q: %w", line, err)
+	}
+	u.push(s)
+	return nil
+}
+
+// loadBinString loads a string or bytes prefixed by their little-endian length of size bytes.
+func (u *unpickler) loadBinString(size int) error {
+	b, err := u.read(size)
+	if err != nil {
+		return err
+	}
+	var n uint64
+	switch size {
+	case 1:
+		n = uint64(b[0])
+	case 4:
+		n = uint64(binary.LittleEndian.Uint32(b))
+	default:
+		n = binary.LittleEndian.Uint64(b)
+	}
+	if n > uint64(len(u.data)-u.pos) {
+		return errors.New("unexpected end of data")
+	}
+	s, err := u.read(int(n))
+	if err != nil {
+		return err
+	}
+	u.push(string(s))
+	return nil
+}
+
+func (u *unpickler) put(op byte) error {
+	var index int
+	switch op {
+	case pickleMemoize:
+		index = len(u.memo)
+	case picklePut:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		if index, err = strconv.Atoi(line); err != nil {
+			return err
+		}
+	case pickleBinPut:
+		b, err := u.read(1)
+		if err != nil {
+			return err
+		}
+		index = int(b[0])
+	default:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		index = int(binary.LittleEndian.Uint32(b))
+	}
+	top, err := u.top()
+	if err != nil {
+		return err
+	}
+	u.memo[index] = top
+	return nil
+}
+
+func (u *unpickler) get(op byte) error {
+	var index int
+	switch op {
+	case pickleGet:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		if index, err = strconv.Atoi(line); err != nil {
+			return err
+		}
+	case pickleBinGet:
+		b, err := u.read(1)
+		if err != nil {
+			return err
+		}
+		index = int(b[0])
+	default:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		index = int(binary.LittleEndian.Uint32(b))
+	}
+	value, ok := u.memo[index]
+	if !ok {
+		return fmt.Errorf("memo key %d not found", index)
+	}
+	u.push(value)
+	return nil
+}
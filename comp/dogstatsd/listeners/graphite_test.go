@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+// pickled datapoints, as produced by `pickle.dumps(datapoints, protocol)` with
+// datapoints = [("a.b.c", (1700000000, 3.45)), ("disk.used;host=web-1", (1700000001.0, 7)), ("bad", ("x", 1))]
+var pickledDatapoints = map[string]string{
+	"protocol 0": "(lp0\n(Va.b.c\np1\n(I1700000000\nF3.45\ntp2\ntp3\na(Vdisk.used;host=web-1\np4\n(F1700000001.0\nI7\ntp5\ntp6\na(Vbad\np7\n(Vx\np8\nI1\ntp9\ntp10\na.",
+	"protocol 2": "\x80\x02]q\x00(X\x05\x00\x00\x00a.b.cq\x01J\x00\xf1SeG@\x0b\x99\x99\x99\x99\x99\x9a\x86q\x02\x86q\x03X\x14\x00\x00\x00disk.used;host=web-1q\x04GA\xd9T\xfc@@\x00\x00K\x07\x86q\x05\x86q\x06X\x03\x00\x00\x00badq\x07X\x01\x00\x00\x00xq\x08K\x01\x86q\t\x86q\ne.",
+	"protocol 4": "\x80\x04\x95U\x00\x00\x00\x00\x00\x00\x00]\x94(\x8c\x05a.b.c\x94J\x00\xf1SeG@\x0b\x99\x99\x99\x99\x99\x9a\x86\x94\x86\x94\x8c\x14disk.used;host=web-1\x94GA\xd9T\xfc@@\x00\x00K\x07\x86\x94\x86\x94\x8c\x03bad\x94\x8c\x01x\x94K\x01\x86\x94\x86\x94e.",
+}
+
+func newTestGraphiteListener(t *testing.T, protocol string) (*GraphiteListener, chan packets.Packets) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_graphite_port":        RandomPortName,
+		"dogstatsd_graphite_pickle_port": RandomPortName,
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	packetsChannel := make(chan packets.Packets, 10)
+	l, err := NewGraphiteListener(protocol, packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	t.Cleanup(l.Stop)
+	return l, packetsChannel
+}
+
+func readGraphitePacketContents(t *testing.T, packetsChannel chan packets.Packets) string {
+	select {
+	case ps := <-packetsChannel:
+		require.Len(t, ps, 1)
+		assert.Equal(t, packets.Graphite, ps[0].Source)
+		return string(ps[0].Contents)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "no packet received")
+		return ""
+	}
+}
+
+func TestParseGraphiteLine(t *testing.T) {
+	metric, err := parseGraphiteLine("servers.web-1.cpu.user 12.5 1700000000")
+	require.NoError(t, err)
+	assert.Equal(t, "servers.web-1.cpu.user:12.5|g|T1700000000", string(metric.message()))
+
+	metric, err = parseGraphiteLine("disk.used;host=web-1;mount=/var 7 1700000000.9")
+	require.NoError(t, err)
+	assert.Equal(t, "disk.used:7|g|#host:web-1,mount:/var|T1700000000", string(metric.message()))
+
+	// a negative timestamp means now, the metric is sent without timestamp
+	metric, err = parseGraphiteLine("foo:bar|baz 1 -1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo_bar_baz:1|g", string(metric.message()))
+
+	for _, line := range []string{
+		"a.b.c 1",
+		"a.b.c one 1700000000",
+		"a.b.c 1 now",
+		"a.b.c NaN 1700000000",
+		"a.b.c;host 1 1700000000",
+		";host=web-1 1 1700000000",
+	} {
+		_, err := parseGraphiteLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestUnpickleGraphiteMetrics(t *testing.T) {
+	for name, data := range pickledDatapoints {
+		t.Run(name, func(t *testing.T) {
+			metrics, invalid, err := unpickleGraphiteMetrics([]byte(data))
+			require.NoError(t, err)
+			assert.Equal(t, 1, invalid)
+			require.Len(t, metrics, 2)
+			assert.Equal(t, "a.b.c:3.45|g|T1700000000", string(metrics[0].message()))
+			assert.Equal(t, "disk.used:7|g|#host:web-1|T1700000001", string(metrics[1].message()))
+		})
+	}
+}
+
+func TestUnpickleSanitizesPaths(t *testing.T) {
+	// pickle.dumps([("x:1|c\nother:1|g,a#b;env=prod\nx:1|c", (1700000000, 1))], 2): the separators of
+	// the path can't inject other DogStatsD messages
+	data := "\x80\x02]q\x00X\"\x00\x00\x00x:1|c\nother:1|g,a#b;env=prod\nx:1|cq\x01J\x00\xf1SeK\x01\x86q\x02\x86q\x03a."
+	metrics, invalid, err := unpickleGraphiteMetrics([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, 0, invalid)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "x_1_c_other_1_g_a_b:1|g|#env:prod_x:1_c|T1700000000", string(metrics[0].message()))
+}
+
+func TestUnpickleErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"not a list":     "\x80\x02K\x01.",
+		"global":         "cos\nsystem\n(S'echo'\ntR.",
+		"truncated":      "\x80\x02]q\x00(X\x05\x00\x00\x00a.b",
+		"missing stop":   "\x80\x02]q\x00",
+		"missing mark":   "\x80\x02]e.",
+		"unknown memo":   "\x80\x02h\x05.",
+		"huge string":    "\x80\x02X\xff\xff\xff\xff.",
+		"invalid string": "S'foo\n.",
+	} {
+		_, _, err := unpickleGraphiteMetrics([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGraphiteListenerPlaintext(t *testing.T) {
+	l, packetsChannel := newTestGraphiteListener(t, GraphitePlaintext)
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("servers.web-1.cpu.user 12.5 1700000000\ninvalid\r\ndisk.used;host=web-1 7 1700000000\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	assert.Equal(t, "servers.web-1.cpu.user:12.5|g|T1700000000\ndisk.used:7|g|#host:web-1|T1700000000", readGraphitePacketContents(t, packetsChannel))
+}
+
+func TestGraphiteListenerPickle(t *testing.T) {
+	l, packetsChannel := newTestGraphiteListener(t, GraphitePickle)
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	payload := []byte(pickledDatapoints["protocol 2"])
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	_, err = conn.Write(append(header, payload...))
+	require.NoError(t, err)
+
+	assert.Equal(t, "a.b.c:3.45|g|T1700000000\ndisk.used:7|g|#host:web-1|T1700000001", readGraphitePacketContents(t, packetsChannel))
+
+	// the connection is closed on an oversized message
+	binary.BigEndian.PutUint32(header, graphiteMaxPickleSize+1)
+	_, err = conn.Write(header)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,285 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	httpExpvars       = expvar.NewMap("dogstatsd-http")
+	httpRequestErrors = expvar.Int{}
+	httpRequests      = expvar.Int{}
+	httpBytes         = expvar.Int{}
+)
+
+func init() {
+	httpExpvars.Set("RequestErrors", &httpRequestErrors)
+	httpExpvars.Set("Requests", &httpRequests)
+	httpExpvars.Set("Bytes", &httpBytes)
+}
+
+// Paths of the endpoints of the HTTP listener
+const (
+	// HTTPStatsdPath accepts DogStatsD messages, one per line.
+	HTTPStatsdPath = "/v1/statsd"
+	// HTTPSeriesPath accepts metrics as a JSON series payload.
+	HTTPSeriesPath = "/v1/series"
+	// HTTPOpenMetricsPath accepts metrics in the Prometheus text exposition format.
+	HTTPOpenMetricsPath = "/v1/openmetrics"
+)
+
+// Headers used for origin detection, the same as the ones of the trace-agent.
+const (
+	containerIDHeader  = "Datadog-Container-ID"
+	entityIDHeader     = "Datadog-Entity-ID"
+	externalDataHeader = "Datadog-External-Env"
+)
+
+// httpReadHeaderTimeout is the maximum time to read the headers of a request.
+const httpReadHeaderTimeout = 10 * time.Second
+
+// HTTPListener implements the StatsdListener interface for HTTP. It accepts batches of DogStatsD
+// messages, JSON series and Prometheus text exposition, which are all converted to DogStatsD
+// messages and sent back in packets, so that they're parsed and enriched the same way as the
+// messages of the other listeners.
+// Origin detection relies on the Datadog-Entity-ID, Datadog-Container-ID and
+// Datadog-External-Env headers, which are added to the messages of the request.
+type HTTPListener struct {
+	listener        net.Listener
+	server          *http.Server
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	maxRequestSize  int64
+	counters        *counterCache
+	telemetryStore  *TelemetryStore
+	listenWg        sync.WaitGroup
+}
+
+// NewHTTPListener returns an idle HTTP Statsd listener
+func NewHTTPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*HTTPListener, error) {
+	var url string
+	port := cfg.GetString("dogstatsd_http_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	ln, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "http", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.HTTP)
+
+	l := &HTTPListener{
+		listener:        ln,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		maxRequestSize:  int64(cfg.GetInt("dogstatsd_http_max_request_size")),
+		counters:        newCounterCache(),
+		telemetryStore:  telemetryStore,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(HTTPStatsdPath, l.handler(HTTPStatsdPath, parseStatsdLines))
+	mux.HandleFunc(HTTPSeriesPath, l.handler(HTTPSeriesPath, parseSeries))
+	mux.HandleFunc(HTTPOpenMetricsPath, l.handler(HTTPOpenMetricsPath, l.counters.parseOpenMetrics))
+	l.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+	}
+	log.Debugf("dogstatsd-http: %s successfully initialized", ln.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *HTTPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *HTTPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		log.Infof("dogstatsd-http: starting to listen on %s", l.listener.Addr())
+		if err := l.server.Serve(l.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-http: error serving requests: %v", err)
+		}
+	}()
+}
+
+// Stop closes the HTTP server and stops listening
+func (l *HTTPListener) Stop() {
+	l.server.Close()
+	l.listenWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
+
+// messagesParser returns the DogStatsD messages of the body of a request. client is the key of the
+// client of the request, used to tell apart the counters of different clients.
+type messagesParser func(body []byte, client string) ([][]byte, error)
+
+func (l *HTTPListener) handler(endpoint string, parse messagesParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t1 := time.Now()
+		httpRequests.Add(1)
+		status, err := l.handle(r, parse)
+		if err != nil {
+			httpRequestErrors.Add(1)
+			l.telemetryStore.tlmHTTPRequests.Inc(endpoint, "error")
+			log.Debugf("dogstatsd-http: invalid request to %s: %v", endpoint, err)
+			http.Error(w, err.Error(), status)
+		} else {
+			l.telemetryStore.tlmHTTPRequests.Inc(endpoint, "ok")
+			w.WriteHeader(status)
+		}
+		l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "http", "http", "http")
+	}
+}
+
+// handle sends the messages of the request to the packet assembler, and returns the status code
+// of the response.
+func (l *HTTPListener) handle(r *http.Request, parse messagesParser) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)
+	}
+	body, err := l.readBody(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+		return http.StatusBadRequest, err
+	}
+	httpBytes.Add(int64(len(body)))
+	l.telemetryStore.tlmHTTPRequestsBytes.Add(float64(len(body)), r.URL.Path)
+
+	originFields := originFieldsFromHeaders(r.Header)
+	messages, err := parse(body, clientKey(r, originFields))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for _, message := range messages {
+		if len(originFields) > 0 {
+			message = appendOriginFields(message, originFields)
+		}
+		l.packetAssembler.AddMessage(message)
+	}
+	return http.StatusAccepted, nil
+}
+
+// readBody reads the body of the request, decompressing it if needed. The request size limit
+// applies to the decompressed body.
+func (l *HTTPListener) readBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = http.MaxBytesReader(nil, r.Body, l.maxRequestSize)
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = io.LimitReader(gz, l.maxRequestSize+1)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > l.maxRequestSize {
+		return nil, &http.MaxBytesError{Limit: l.maxRequestSize}
+	}
+	return body, nil
+}
+
+// originFieldsFromHeaders returns the DogStatsD fields holding the origin detection data of the
+// request headers, e.g. "|c:ci-1234|e:it-false", or nil if there's none.
+func originFieldsFromHeaders(header http.Header) []byte {
+	var fields []byte
+	localData := header.Get(entityIDHeader)
+	if localData == "" {
+		if containerID := header.Get(containerIDHeader); containerID != "" {
+			localData = "ci-" + containerID
+		}
+	}
+	if localData != "" {
+		fields = append(fields, "|c:"+localData...)
+	}
+	if externalData := header.Get(externalDataHeader); externalData != "" {
+		fields = append(fields, "|e:"+externalData...)
+	}
+	return fields
+}
+
+// clientKey returns the key of the client of the request: its origin detection data if it has
+// some, its IP address otherwise.
+func clientKey(r *http.Request, originFields []byte) string {
+	if len(originFields) > 0 {
+		return string(originFields)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// appendOriginFields appends the origin fields to the message, unless it already has origin
+// detection data.
+func appendOriginFields(message []byte, fields []byte) []byte {
+	if bytes.Contains(message, []byte("|c:")) || bytes.Contains(message, []byte("|e:")) {
+		return message
+	}
+	return append(message[:len(message):len(message)], fields...)
+}
+
+// parseStatsdLines returns the non-empty lines of body.
+func parseStatsdLines(body []byte, _ string) ([][]byte, error) {
+	var messages [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) > 0 {
+			messages = append(messages, line)
+		}
+	}
+	return messages, nil
+}
+
+// formatValue formats a metric value for a DogStatsD message.
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// sanitizeTag replaces the characters which can't appear in the tags of a DogStatsD message.
+var sanitizeTag = strings.NewReplacer(",", "_", "|", "_", "\n", "_").Replace
@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+// seriesTypes maps the types of the JSON series to the DogStatsD metric types.
+var seriesTypes = map[string]string{
+	"":             "g",
+	"gauge":        "g",
+	"count":        "c",
+	"histogram":    "h",
+	"distribution": "d",
+	"timing":       "ms",
+	"set":          "s",
+}
+
+// seriesPayload is the JSON series format accepted by the HTTP listener, e.g.
+//
+//	{"series": [{"metric": "app.requests", "type": "count", "points": [[1700000000, 3]], "tags": ["env:prod"]}]}
+//
+// The timestamps of the points are optional, they're only used for gauges and counts.
+type seriesPayload struct {
+	Series []struct {
+		Metric string
+		Type   string
+		Points [][2]float64
+		Tags   []string
+		Host   string
+	}
+}
+
+// parseSeries returns the DogStatsD messages of the points of a JSON series payload.
+func parseSeries(body []byte, _ string) ([][]byte, error) {
+	var payload seriesPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+	var messages [][]byte
+	for _, serie := range payload.Series {
+		if serie.Metric == "" {
+			return nil, fmt.Errorf("invalid series payload: missing metric name")
+		}
+		name := sanitizeMetricName(serie.Metric)
+		metricType, ok := seriesTypes[serie.Type]
+		if !ok {
+			return nil, fmt.Errorf("invalid series payload: unsupported type %q for metric %s", serie.Type, serie.Metric)
+		}
+		tags := make([]string, 0, len(serie.Tags)+1)
+		for _, tag := range serie.Tags {
+			tags = append(tags, sanitizeTag(tag))
+		}
+		if serie.Host != "" {
+			tags = append(tags, "host:"+sanitizeTag(serie.Host))
+		}
+		for _, point := range serie.Points {
+			timestamp := int64(point[0])
+			if metricType != "g" && metricType != "c" {
+				timestamp = 0
+			}
+			messages = append(messages, formatMessage(name, point[1], metricType, tags, timestamp))
+		}
+	}
+	return messages, nil
+}
+
+// formatMessage returns a DogStatsD metric message, with a timestamp if it's not 0.
+func formatMessage(name string, value float64, metricType string, tags []string, timestamp int64) []byte {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(formatValue(value))
+	b.WriteByte('|')
+	b.WriteString(metricType)
+	if len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(tags, ","))
+	}
+	if timestamp > 0 {
+		fmt.Fprintf(&b, "|T%d", timestamp)
+	}
+	return []byte(b.String())
+}
+
+// counterExpiration is the duration after which the value of a counter which hasn't been received
+// again is forgotten.
+const counterExpiration = time.Hour
+
+type counterValue struct {
+	value    float64
+	lastSeen time.Time
+}
+
+// counterCache keeps the last value of the cumulative Prometheus counters, histograms and
+// summaries received by the HTTP listener, to send their increase as DogStatsD counts.
+type counterCache struct {
+	mu        sync.Mutex
+	values    map[string]counterValue
+	lastPurge time.Time
+	nowFunc   func() time.Time
+}
+
+func newCounterCache() *counterCache {
+	return &counterCache{
+		values:    make(map[string]counterValue),
+		lastPurge: time.Now(),
+		nowFunc:   time.Now,
+	}
+}
+
+// parseOpenMetrics returns the DogStatsD messages of the samples of a Prometheus text exposition.
+// Gauges, untyped metrics and the quantiles of summaries are sent as gauges. Counters, and the
+// buckets, sums and counts of histograms and summaries, are cumulative: their increase since the
+// previous request from the same client is sent as a count, nothing is sent the first time they
+// are received.
+func (c *counterCache) parseOpenMetrics(body []byte, client string) ([][]byte, error) {
+	families, err := prometheus.ParseMetrics(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openmetrics payload: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.nowFunc()
+	var messages [][]byte
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			value := float64(sample.Value)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			name := sanitizeMetricName(string(sample.Metric[model.MetricNameLabel]))
+			tags := labelsToTags(sample.Metric)
+			_, isQuantile := sample.Metric[model.QuantileLabel]
+			if family.Type == "GAUGE" || family.Type == "UNTYPED" || isQuantile {
+				messages = append(messages, formatMessage(name, value, "g", tags, 0))
+				continue
+			}
+			if increase, ok := c.increase(client+"|"+name+"|"+strings.Join(tags, ","), value, now); ok {
+				messages = append(messages, formatMessage(name, increase, "c", tags, 0))
+			}
+		}
+	}
+	c.purge(now)
+	return messages, nil
+}
+
+// increase records the value of a counter and returns its increase since its previous value, if
+// it had one. A counter lower than its previous value has been reset, its increase is its value.
+func (c *counterCache) increase(key string, value float64, now time.Time) (float64, bool) {
+	previous, ok := c.values[key]
+	c.values[key] = counterValue{value: value, lastSeen: now}
+	if !ok {
+		return 0, false
+	}
+	if value < previous.value {
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// purge forgets the counters which haven't been received for a while.
+func (c *counterCache) purge(now time.Time) {
+	if now.Sub(c.lastPurge) < counterExpiration {
+		return
+	}
+	for key, counter := range c.values {
+		if now.Sub(counter.lastSeen) >= counterExpiration {
+			delete(c.values, key)
+		}
+	}
+	c.lastPurge = now
+}
+
+// labelsToTags returns the labels of a Prometheus sample as sorted tags.
+func labelsToTags(metric model.Metric) []string {
+	tags := make([]string, 0, len(metric))
+	for name, value := range metric {
+		if name == model.MetricNameLabel {
+			continue
+		}
+		tags = append(tags, sanitizeTag(string(name)+":"+string(value)))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// sanitizeMetricName replaces the characters which can't appear in the metric names of DogStatsD
+// messages, such as the colons of Prometheus metric names, which separate the name from the value.
+var sanitizeMetricName = strings.NewReplacer(":", "_", "|", "_", "#", "_", ",", "_", "\n", "_").Replace
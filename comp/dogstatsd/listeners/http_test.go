@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestHTTPListener(t *testing.T, overrides map[string]interface{}) (*HTTPListener, chan packets.Packets) {
+	overrides["dogstatsd_http_port"] = RandomPortName
+	deps := fulfillDepsWithConfig(t, overrides)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	packetsChannel := make(chan packets.Packets, 10)
+	l, err := NewHTTPListener(packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	t.Cleanup(l.Stop)
+	return l, packetsChannel
+}
+
+func postToListener(t *testing.T, l *HTTPListener, path string, body []byte, header http.Header) int {
+	req, err := http.NewRequest(http.MethodPost, "http://"+l.LocalAddr()+path, bytes.NewReader(body))
+	require.NoError(t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func readPacketContents(t *testing.T, packetsChannel chan packets.Packets) string {
+	select {
+	case ps := <-packetsChannel:
+		require.Len(t, ps, 1)
+		assert.Equal(t, packets.HTTP, ps[0].Source)
+		return string(ps[0].Contents)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "no packet received")
+		return ""
+	}
+}
+
+func TestHTTPListenerStatsdLines(t *testing.T) {
+	l, packetsChannel := newTestHTTPListener(t, map[string]interface{}{})
+
+	header := http.Header{}
+	header.Set(entityIDHeader, "ci-1234")
+	header.Set(externalDataHeader, "it-false")
+	status := postToListener(t, l, HTTPStatsdPath, []byte("daemon:666|g\r\n\n_sc|agent.up|0|c:in-1\n"), header)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "daemon:666|g|c:ci-1234|e:it-false\n_sc|agent.up|0|c:in-1", readPacketContents(t, packetsChannel))
+
+	// gzip compressed request, with the legacy container ID header
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("daemon:1|c"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	header = http.Header{}
+	header.Set("Content-Encoding", "gzip")
+	header.Set(containerIDHeader, "1234")
+	status = postToListener(t, l, HTTPStatsdPath, buf.Bytes(), header)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "daemon:1|c|c:ci-1234", readPacketContents(t, packetsChannel))
+}
+
+func TestHTTPListenerInvalidRequests(t *testing.T) {
+	l, _ := newTestHTTPListener(t, map[string]interface{}{"dogstatsd_http_max_request_size": 16})
+
+	resp, err := http.Get("http://" + l.LocalAddr() + HTTPStatsdPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postToListener(t, l, HTTPStatsdPath, []byte(strings.Repeat("a:1|c\n", 10)), nil))
+	assert.Equal(t, http.StatusBadRequest, postToListener(t, l, HTTPSeriesPath, []byte("{"), nil))
+	header := http.Header{}
+	header.Set("Content-Encoding", "br")
+	assert.Equal(t, http.StatusBadRequest, postToListener(t, l, HTTPStatsdPath, []byte("a:1|c"), header))
+}
+
+func TestParseSeries(t *testing.T) {
+	messages, err := parseSeries([]byte(`{"series": [
+		{"metric": "app.requests", "type": "count", "points": [[1700000000, 3], [0, 1.5]], "tags": ["env:prod", "a,b"], "host": "web-1"},
+		{"metric": "app.latency", "type": "distribution", "points": [[1700000000, 0.25]]},
+		{"metric": "app.queue", "points": [[0, 12]]}
+	]}`), "")
+	require.NoError(t, err)
+	var lines []string
+	for _, message := range messages {
+		lines = append(lines, string(message))
+	}
+	assert.Equal(t, []string{
+		"app.requests:3|c|#env:prod,a_b,host:web-1|T1700000000",
+		"app.requests:1.5|c|#env:prod,a_b,host:web-1",
+		"app.latency:0.25|d",
+		"app.queue:12|g",
+	}, lines)
+
+	// the metric names can't inject other DogStatsD messages
+	messages, err = parseSeries([]byte(`{"series": [{"metric": "x:1|c\nother:1|g#a,b", "points": [[0, 1]]}]}`), "")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "x_1_c_other_1_g_a_b:1|g", string(messages[0]))
+
+	_, err = parseSeries([]byte(`{"series": [{"metric": "app.requests", "type": "rate", "points": [[0, 1]]}]}`), "")
+	assert.Error(t, err)
+	_, err = parseSeries([]byte(`{"series": [{"points": [[0, 1]]}]}`), "")
+	assert.Error(t, err)
+}
+
+func TestParseOpenMetrics(t *testing.T) {
+	c := newCounterCache()
+	now := time.Now()
+	c.nowFunc = func() time.Time { return now }
+
+	payload := func(requests, latencyCount int) []byte {
+		return []byte(`# TYPE queue_size gauge
+queue_size{queue="default"} 12
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET"} ` + strconv.Itoa(requests) + `
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.25
+rpc_duration_seconds_sum 10
+rpc_duration_seconds_count ` + strconv.Itoa(latencyCount) + `
+`)
+	}
+	toLines := func(messages [][]byte) []string {
+		var lines []string
+		for _, message := range messages {
+			lines = append(lines, string(message))
+		}
+		return lines
+	}
+
+	// the cumulative metrics are only recorded the first time
+	messages, err := c.parseOpenMetrics(payload(10, 4), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"queue_size:12|g|#queue:default",
+		"rpc_duration_seconds:0.25|g|#quantile:0.5",
+	}, toLines(messages))
+
+	messages, err = c.parseOpenMetrics(payload(15, 6), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"queue_size:12|g|#queue:default",
+		"http_requests_total:5|c|#code:200,method:GET",
+		"rpc_duration_seconds:0.25|g|#quantile:0.5",
+		"rpc_duration_seconds_sum:0|c",
+		"rpc_duration_seconds_count:2|c",
+	}, toLines(messages))
+
+	// the counter has been reset
+	messages, err = c.parseOpenMetrics(payload(3, 6), "")
+	require.NoError(t, err)
+	assert.Contains(t, toLines(messages), "http_requests_total:3|c|#code:200,method:GET")
+
+	// the counters of another origin are tracked separately
+	messages, err = c.parseOpenMetrics(payload(20, 6), "|c:ci-1234")
+	require.NoError(t, err)
+	assert.NotContains(t, toLines(messages), "http_requests_total:20|c|#code:200,method:GET")
+
+	// the counters not received for a while are forgotten
+	now = now.Add(2 * counterExpiration)
+	_, err = c.parseOpenMetrics([]byte("queue_size 1\n"), "")
+	require.NoError(t, err)
+	assert.Empty(t, c.values)
+
+	_, err = c.parseOpenMetrics([]byte("not a metric {"), "")
+	assert.Error(t, err)
+
+	// the colons of the metric names are replaced, and the other separators are invalid
+	messages, err = c.parseOpenMetrics([]byte("job:requests:rate5m 1\n"), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job_requests_rate5m:1|g"}, toLines(messages))
+	_, err = c.parseOpenMetrics([]byte("x|c 1\n"), "")
+	assert.Error(t, err)
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, HTTPOpenMetricsPath, nil)
+	r.RemoteAddr = "192.0.2.1:41234"
+	assert.Equal(t, "|c:ci-1234", clientKey(r, []byte("|c:ci-1234")))
+	// the requests without origin detection data are told apart by the address of their client
+	assert.Equal(t, "ip:192.0.2.1", clientKey(r, nil))
+	r.RemoteAddr = "192.0.2.1:41235"
+	assert.Equal(t, "ip:192.0.2.1", clientKey(r, nil))
+	r.RemoteAddr = "192.0.2.2:41234"
+	assert.Equal(t, "ip:192.0.2.2", clientKey(r, nil))
+}
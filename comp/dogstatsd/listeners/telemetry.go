@@ -23,6 +23,12 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// HTTP
+	tlmHTTPRequests      telemetry.Counter
+	tlmHTTPRequestsBytes telemetry.Counter
+	// Graphite
+	tlmGraphiteMetrics     telemetry.Counter
+	tlmGraphiteConnections telemetry.Gauge
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +52,14 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmHTTPRequests: telemetrycomp.NewCounter("dogstatsd", "http_requests",
+			[]string{"endpoint", "state"}, "Dogstatsd HTTP requests count"),
+		tlmHTTPRequestsBytes: telemetrycomp.NewCounter("dogstatsd", "http_requests_bytes",
+			[]string{"endpoint"}, "Dogstatsd HTTP requests bytes count"),
+		tlmGraphiteMetrics: telemetrycomp.NewCounter("dogstatsd", "graphite_metrics",
+			[]string{"protocol", "state"}, "Dogstatsd Graphite datapoints count"),
+		tlmGraphiteConnections: telemetrycomp.NewGauge("dogstatsd", "graphite_connections",
+			[]string{"protocol"}, "Dogstatsd Graphite connections count"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
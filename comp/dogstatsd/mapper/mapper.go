@@ -9,9 +9,13 @@
 package mapper
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
 )
 
 var (
@@ -36,6 +40,7 @@ type MappingProfileConfig struct {
 	Name     string                `mapstructure:"name" json:"name" yaml:"name"`
 	Prefix   string                `mapstructure:"prefix" json:"prefix" yaml:"prefix"`
 	Mappings []MetricMappingConfig `mapstructure:"mappings" json:"mappings" yaml:"mappings"`
+	Rollups  []RollupConfig        `mapstructure:"rollups" json:"rollups" yaml:"rollups"`
 }
 
 // MetricMapping represent one mapping rule
@@ -46,10 +51,18 @@ type MetricMappingConfig struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
 }
 
+// RollupConfig represent one rollup rule
+type RollupConfig struct {
+	Match     string   `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType string   `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	DropTags  []string `mapstructure:"drop_tags" json:"drop_tags" yaml:"drop_tags"`
+}
+
 // MetricMapper contains mappings and cache instance
 type MetricMapper struct {
-	Profiles []MappingProfile
-	cache    *mapperCache
+	Profiles    []MappingProfile
+	cache       *mapperCache
+	rollupCache *rollupCache
 }
 
 // MappingProfile represent a group of mappings
@@ -57,6 +70,7 @@ type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+	Rollups  []*Rollup
 }
 
 // MetricMapping represent one mapping rule
@@ -89,12 +103,9 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			Mappings: make([]*MetricMapping, 0, len(configProfile.Mappings)),
 		}
 		for i, currentMapping := range configProfile.Mappings {
-			matchType := currentMapping.MatchType
-			if matchType == "" {
-				matchType = matchTypeWildcard
-			}
-			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
+			matchType, err := getMatchType(currentMapping.MatchType)
+			if err != nil {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: %v", profile.Name, i, err)
 			}
 			if currentMapping.Name == "" {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
@@ -108,13 +119,35 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			}
 			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
 		}
+		rollups, err := newRollups(profile.Name, configProfile.Rollups)
+		if err != nil {
+			return nil, err
+		}
+		profile.Rollups = rollups
 		profiles = append(profiles, profile)
 	}
 	cache, err := newMapperCache(cacheSize)
 	if err != nil {
 		return nil, err
 	}
-	return &MetricMapper{Profiles: profiles, cache: cache}, nil
+	mapper := &MetricMapper{Profiles: profiles, cache: cache}
+	if mapper.HasRollups() {
+		mapper.rollupCache, err = newRollupCache(cacheSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mapper, nil
+}
+
+func getMatchType(matchType string) (string, error) {
+	if matchType == "" {
+		return matchTypeWildcard, nil
+	}
+	if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+		return "", errors.New("invalid match type, must be `wildcard` or `regex`")
+	}
+	return matchType, nil
 }
 
 func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
@@ -141,6 +174,10 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
 			continue
 		}
+		if len(profile.Mappings) == 0 {
+			// the profile only has rollups
+			continue
+		}
 		result, cached := m.cache.get(metricName)
 		if cached {
 			if result.matched {
@@ -177,3 +214,15 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 	}
 	return nil
 }
+
+// GetProfiles returns the mapping profiles of the `dogstatsd_mapper_profiles` setting
+func GetProfiles(cfg model.Reader) ([]MappingProfileConfig, error) {
+	var profiles []MappingProfileConfig
+	if cfg.IsSet("dogstatsd_mapper_profiles") {
+		err := structure.UnmarshalKey(cfg, "dogstatsd_mapper_profiles", &profiles)
+		if err != nil {
+			return []MappingProfileConfig{}, fmt.Errorf("Could not parse dogstatsd_mapper_profiles: %v", err)
+		}
+	}
+	return profiles, nil
+}
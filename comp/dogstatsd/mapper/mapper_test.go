@@ -515,6 +515,120 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestRollups(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: rollups_only
+    prefix: 'http.request.'
+    rollups:
+      - match: "http.request.duration"
+        drop_tags: ["route"]
+  - name: http
+    prefix: 'http.'
+    mappings:
+      - match: "http.request.*.duration"
+        name: "http.request.duration"
+        tags:
+          route: "$1"
+    rollups:
+      - match: "http.request.*"
+        drop_tags: ["pod_name", "container_id"]
+      - match: 'http\.(request|response)\.count'
+        match_type: regex
+        drop_tags: ["pod_name", "user_id"]
+`)
+	require.NoError(t, err)
+	assert.True(t, mapper.HasRollups())
+
+	// the rollups of all the matching profiles apply
+	assert.Equal(t, []string{"pod_name", "container_id", "user_id"}, mapper.DropTags("http.request.count"))
+	assert.Equal(t, []string{"route", "pod_name", "container_id"}, mapper.DropTags("http.request.duration"))
+	assert.Equal(t, []string{"pod_name", "user_id"}, mapper.DropTags("http.response.count"))
+	assert.Empty(t, mapper.DropTags("http.response.duration"))
+	assert.Empty(t, mapper.DropTags("other.request.count"))
+
+	// the profiles without mappings don't prevent the next profiles from mapping the metrics
+	mapResult := mapper.Map("http.request.home.duration")
+	require.NotNil(t, mapResult)
+	assert.Equal(t, "http.request.duration", mapResult.Name)
+
+	mapper, err = getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+`)
+	require.NoError(t, err)
+	assert.False(t, mapper.HasRollups())
+	assert.Nil(t, mapper.DropTags("test.job.duration.foo"))
+}
+
+func TestRollupErrors(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "Missing drop_tags",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rollups:
+      - match: "test.*"
+`,
+			expectedError: "profile: test, rollup num 0: drop_tags is required",
+		},
+		{
+			name: "Missing match",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rollups:
+      - drop_tags: ["pod_name"]
+`,
+			expectedError: "profile: test, rollup num 0: match is required",
+		},
+		{
+			name: "Invalid match type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rollups:
+      - match: "test.*"
+        match_type: invalid
+        drop_tags: ["pod_name"]
+`,
+			expectedError: "invalid match type",
+		},
+		{
+			name: "Invalid wildcard",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rollups:
+      - match: "test.**"
+        drop_tags: ["pod_name"]
+`,
+			expectedError: "should not contain consecutive `*`",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := getMapper(t, scenario.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), scenario.expectedError)
+		})
+	}
+}
+
 func getMapper(t *testing.T, configString string) (*MetricMapper, error) {
 	var profiles []MappingProfileConfig
 
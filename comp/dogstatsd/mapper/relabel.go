@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Relabeling is inspired by the relabel_configs of Prometheus
+
+package mapper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+)
+
+// RelabelConfigsSetting is the setting of the relabel rules
+const RelabelConfigsSetting = "dogstatsd_metric_relabel_configs"
+
+// NameTag is the name of the pseudo tag holding the name of the metric, in the source tags and the
+// target tag of the relabel rules.
+const NameTag = "__name__"
+
+// Relabel actions
+const (
+	RelabelReplace = "replace"
+	RelabelKeep    = "keep"
+	RelabelDrop    = "drop"
+	RelabelTagMap  = "tagmap"
+	RelabelTagDrop = "tagdrop"
+	RelabelTagKeep = "tagkeep"
+)
+
+const (
+	defaultRelabelSeparator   = ";"
+	defaultRelabelRegex       = "(.*)"
+	defaultRelabelReplacement = "$1"
+)
+
+// RelabelConfig represent one relabel rule
+type RelabelConfig struct {
+	SourceTags  []string `mapstructure:"source_tags" json:"source_tags" yaml:"source_tags"`
+	Separator   string   `mapstructure:"separator" json:"separator" yaml:"separator"`
+	Regex       string   `mapstructure:"regex" json:"regex" yaml:"regex"`
+	Action      string   `mapstructure:"action" json:"action" yaml:"action"`
+	TargetTag   string   `mapstructure:"target_tag" json:"target_tag" yaml:"target_tag"`
+	Replacement string   `mapstructure:"replacement" json:"replacement" yaml:"replacement"`
+}
+
+type relabelRule struct {
+	sourceTags  []string
+	separator   string
+	regex       *regexp.Regexp
+	action      string
+	targetTag   string
+	replacement string
+}
+
+// Relabeler applies relabel rules to the metrics, in order
+type Relabeler struct {
+	rules []relabelRule
+}
+
+// GetRelabelConfigs returns the relabel rules of the `dogstatsd_metric_relabel_configs` setting
+func GetRelabelConfigs(cfg model.Reader) ([]RelabelConfig, error) {
+	var configs []RelabelConfig
+	if cfg.IsSet(RelabelConfigsSetting) {
+		err := structure.UnmarshalKey(cfg, RelabelConfigsSetting, &configs)
+		if err != nil {
+			return []RelabelConfig{}, fmt.Errorf("Could not parse %s: %v", RelabelConfigsSetting, err)
+		}
+	}
+	return configs, nil
+}
+
+// NewRelabeler creates, validates, prepares a new Relabeler
+func NewRelabeler(configs []RelabelConfig) (*Relabeler, error) {
+	rules := make([]relabelRule, 0, len(configs))
+	for i, config := range configs {
+		rule := relabelRule{
+			sourceTags:  config.SourceTags,
+			separator:   config.Separator,
+			action:      config.Action,
+			targetTag:   config.TargetTag,
+			replacement: config.Replacement,
+		}
+		if rule.separator == "" {
+			rule.separator = defaultRelabelSeparator
+		}
+		if rule.action == "" {
+			rule.action = RelabelReplace
+		}
+		if rule.replacement == "" {
+			rule.replacement = defaultRelabelReplacement
+		}
+		regex := config.Regex
+		if regex == "" {
+			regex = defaultRelabelRegex
+		}
+		var err error
+		rule.regex, err = regexp.Compile("^(?:" + regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule num %d: invalid regex `%s`: %v", i, regex, err)
+		}
+		switch rule.action {
+		case RelabelReplace:
+			if rule.targetTag == "" {
+				return nil, fmt.Errorf("relabel rule num %d: target_tag is required by the `%s` action", i, rule.action)
+			}
+			if len(rule.sourceTags) == 0 {
+				return nil, fmt.Errorf("relabel rule num %d: source_tags is required by the `%s` action", i, rule.action)
+			}
+		case RelabelKeep, RelabelDrop:
+			if len(rule.sourceTags) == 0 {
+				return nil, fmt.Errorf("relabel rule num %d: source_tags is required by the `%s` action", i, rule.action)
+			}
+		case RelabelTagMap, RelabelTagDrop, RelabelTagKeep:
+		default:
+			return nil, fmt.Errorf("relabel rule num %d: invalid action `%s`, must be one of `%s`", i, rule.action,
+				strings.Join([]string{RelabelReplace, RelabelKeep, RelabelDrop, RelabelTagMap, RelabelTagDrop, RelabelTagKeep}, "`, `"))
+		}
+		rules = append(rules, rule)
+	}
+	return &Relabeler{rules: rules}, nil
+}
+
+// Relabel applies the rules to the name and the tags of a metric, and returns its new name and
+// tags, or false if the metric is dropped. The tags can be modified in place.
+//
+// The value of a source tag is the value of its first occurrence, and the empty string if the
+// metric doesn't have it. A tag set to the empty string is removed.
+func (r *Relabeler) Relabel(name string, tags []string) (string, []string, bool) {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		switch rule.action {
+		case RelabelReplace:
+			value := sourceValue(name, tags, rule.sourceTags, rule.separator)
+			matches := rule.regex.FindStringSubmatchIndex(value)
+			if matches == nil {
+				continue
+			}
+			target := string(rule.regex.ExpandString(nil, rule.targetTag, value, matches))
+			replacement := string(rule.regex.ExpandString(nil, rule.replacement, value, matches))
+			if target == NameTag {
+				if replacement != "" {
+					name = replacement
+				}
+				continue
+			}
+			tags = removeTag(tags, target)
+			if replacement != "" {
+				tags = append(tags, target+":"+replacement)
+			}
+		case RelabelKeep:
+			if !rule.regex.MatchString(sourceValue(name, tags, rule.sourceTags, rule.separator)) {
+				return name, tags, false
+			}
+		case RelabelDrop:
+			if rule.regex.MatchString(sourceValue(name, tags, rule.sourceTags, rule.separator)) {
+				return name, tags, false
+			}
+		case RelabelTagMap:
+			for _, tag := range tags {
+				tagName, tagValue, _ := strings.Cut(tag, ":")
+				matches := rule.regex.FindStringSubmatchIndex(tagName)
+				if matches == nil {
+					continue
+				}
+				mapped := string(rule.regex.ExpandString(nil, rule.replacement, tagName, matches))
+				if mapped != "" && mapped != tagName {
+					tags = append(tags, mapped+":"+tagValue)
+				}
+			}
+		case RelabelTagDrop, RelabelTagKeep:
+			keep := rule.action == RelabelTagKeep
+			n := 0
+			for _, tag := range tags {
+				tagName, _, _ := strings.Cut(tag, ":")
+				if rule.regex.MatchString(tagName) != keep {
+					continue
+				}
+				tags[n] = tag
+				n++
+			}
+			tags = tags[:n]
+		}
+	}
+	return name, tags, true
+}
+
+// sourceValue returns the values of the source tags, joined by the separator.
+func sourceValue(name string, tags []string, sourceTags []string, separator string) string {
+	if len(sourceTags) == 1 {
+		return tagValue(name, tags, sourceTags[0])
+	}
+	values := make([]string, 0, len(sourceTags))
+	for _, sourceTag := range sourceTags {
+		values = append(values, tagValue(name, tags, sourceTag))
+	}
+	return strings.Join(values, separator)
+}
+
+func tagValue(name string, tags []string, tagName string) string {
+	if tagName == NameTag {
+		return name
+	}
+	for _, tag := range tags {
+		if value, found := strings.CutPrefix(tag, tagName); found {
+			if value == "" {
+				return ""
+			}
+			if value[0] == ':' {
+				return value[1:]
+			}
+		}
+	}
+	return ""
+}
+
+// removeTag removes the tags with the name from tags, in place.
+func removeTag(tags []string, tagName string) []string {
+	n := 0
+	for _, tag := range tags {
+		if name, _, _ := strings.Cut(tag, ":"); name == tagName {
+			continue
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configComponent "github.com/DataDog/datadog-agent/comp/core/config"
+)
+
+func TestRelabel(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		config       string
+		metricName   string
+		tags         []string
+		expectedName string
+		expectedTags []string
+		dropped      bool
+	}{
+		{
+			name: "Replace a tag with captures of other tags",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [env, region]
+    regex: '(\w+);(\w+)-\d'
+    target_tag: deployment
+    replacement: '$1-$2'
+`,
+			metricName:   "app.requests",
+			tags:         []string{"env:prod", "region:us-1", "deployment:old"},
+			expectedName: "app.requests",
+			expectedTags: []string{"env:prod", "region:us-1", "deployment:prod-us"},
+		},
+		{
+			name: "Rename a metric with captures of its name",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [__name__]
+    regex: 'legacy\.(\w+)\.(\w+)'
+    target_tag: __name__
+    replacement: 'app.$2'
+  - source_tags: [__name__]
+    regex: 'legacy\.(\w+)\.\w+'
+    target_tag: service
+`,
+			metricName:   "legacy.billing.requests",
+			tags:         []string{"env:prod"},
+			expectedName: "app.requests",
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "Capture a part of the name into a tag value",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [__name__]
+    regex: 'legacy\.(\w+)\.\w+'
+    target_tag: service
+`,
+			metricName:   "legacy.billing.requests",
+			tags:         []string{"service:unknown"},
+			expectedName: "legacy.billing.requests",
+			expectedTags: []string{"service:billing"},
+		},
+		{
+			name: "Remove a tag with an empty replacement result",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [user]
+    regex: '(anonymous)?.*'
+    target_tag: user
+`,
+			metricName:   "app.requests",
+			tags:         []string{"user:bob", "env:prod"},
+			expectedName: "app.requests",
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "Drop a metric on a tag",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [env]
+    regex: 'dev|test'
+    action: drop
+`,
+			metricName: "app.requests",
+			tags:       []string{"env:test"},
+			dropped:    true,
+		},
+		{
+			name: "Keep a metric on its name and a tag",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [__name__, env]
+    separator: '|'
+    regex: 'app\..*\|prod'
+    action: keep
+`,
+			metricName:   "app.requests",
+			tags:         []string{"env:prod"},
+			expectedName: "app.requests",
+			expectedTags: []string{"env:prod"},
+		},
+		{
+			name: "Keep drops the metrics without the tag",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [env]
+    regex: '.+'
+    action: keep
+`,
+			metricName: "app.requests",
+			tags:       []string{"envoy:true"},
+			dropped:    true,
+		},
+		{
+			name: "Map, drop and keep tags",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - action: tagmap
+    regex: 'legacy_(.*)'
+  - action: tagdrop
+    regex: 'legacy_.*'
+  - action: tagkeep
+    regex: 'env|host_.*|version'
+`,
+			metricName:   "app.requests",
+			tags:         []string{"legacy_version:1.0", "legacy_env:prod", "pod_name:web-1", "host_group:a"},
+			expectedName: "app.requests",
+			expectedTags: []string{"host_group:a", "version:1.0", "env:prod"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			relabeler, err := getRelabeler(t, scenario.config)
+			require.NoError(t, err)
+
+			name, tags, keep := relabeler.Relabel(scenario.metricName, scenario.tags)
+			if scenario.dropped {
+				assert.False(t, keep)
+				return
+			}
+			assert.True(t, keep)
+			assert.Equal(t, scenario.expectedName, name)
+			assert.Equal(t, scenario.expectedTags, tags)
+		})
+	}
+}
+
+func TestRelabelErrors(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [env]
+    action: rename
+`,
+			expectedError: "relabel rule num 0: invalid action `rename`",
+		},
+		{
+			name: "Missing target tag",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - source_tags: [env]
+`,
+			expectedError: "relabel rule num 0: target_tag is required by the `replace` action",
+		},
+		{
+			name: "Missing source tags",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - target_tag: env
+  - action: drop
+`,
+			expectedError: "relabel rule num 0: source_tags is required by the `replace` action",
+		},
+		{
+			name: "Invalid regex",
+			config: `
+dogstatsd_metric_relabel_configs:
+  - action: tagdrop
+    regex: '(foo'
+`,
+			expectedError: "relabel rule num 0: invalid regex `(foo`",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_, err := getRelabeler(t, scenario.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), scenario.expectedError)
+		})
+	}
+}
+
+func getRelabeler(t *testing.T, configString string) (*Relabeler, error) {
+	cfg := configComponent.NewMockFromYAML(t, configString)
+	configs, err := GetRelabelConfigs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewRelabeler(configs)
+}
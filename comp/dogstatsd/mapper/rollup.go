@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Rollup represent one rollup rule: the tags dropped from the metrics it matches, before they're
+// aggregated.
+type Rollup struct {
+	dropTags []string
+	regex    *regexp.Regexp
+}
+
+func newRollups(profileName string, configRollups []RollupConfig) ([]*Rollup, error) {
+	rollups := make([]*Rollup, 0, len(configRollups))
+	for i, currentRollup := range configRollups {
+		matchType, err := getMatchType(currentRollup.MatchType)
+		if err != nil {
+			return nil, fmt.Errorf("profile: %s, rollup num %d: %v", profileName, i, err)
+		}
+		if currentRollup.Match == "" {
+			return nil, fmt.Errorf("profile: %s, rollup num %d: match is required", profileName, i)
+		}
+		if len(currentRollup.DropTags) == 0 {
+			return nil, fmt.Errorf("profile: %s, rollup num %d: drop_tags is required", profileName, i)
+		}
+		regex, err := buildRegex(currentRollup.Match, matchType)
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, &Rollup{dropTags: currentRollup.DropTags, regex: regex})
+	}
+	return rollups, nil
+}
+
+// HasRollups returns true if any profile has rollup rules.
+func (m *MetricMapper) HasRollups() bool {
+	for _, profile := range m.Profiles {
+		if len(profile.Rollups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DropTags returns the names of the tags to drop from the metric, which are the tags of all the
+// rollups matching it, in all the profiles matching its prefix. The rollups match the final name
+// of the metric, once it has been mapped.
+func (m *MetricMapper) DropTags(metricName string) []string {
+	if m.rollupCache == nil {
+		return nil
+	}
+	if dropTags, ok := m.rollupCache.cache.Get(metricName); ok {
+		return dropTags
+	}
+	var dropTags []string
+	for _, profile := range m.Profiles {
+		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
+			continue
+		}
+		for _, rollup := range profile.Rollups {
+			if !rollup.regex.MatchString(metricName) {
+				continue
+			}
+			for _, tag := range rollup.dropTags {
+				if !slices.Contains(dropTags, tag) {
+					dropTags = append(dropTags, tag)
+				}
+			}
+		}
+	}
+	m.rollupCache.cache.Add(metricName, dropTags)
+	return dropTags
+}
+
+type rollupCache struct {
+	cache *lru.Cache[string, []string]
+}
+
+func newRollupCache(size int) (*rollupCache, error) {
+	cache, err := lru.New[string, []string](size)
+	if err != nil {
+		return nil, err
+	}
+	return &rollupCache{cache: cache}, nil
+}
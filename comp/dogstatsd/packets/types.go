@@ -21,6 +21,10 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// HTTP listener
+	HTTP
+	// Graphite listener
+	Graphite
 )
 
 // Packet represents a statsd packet ready to process,
@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metricNameLabel = "__name__"
+	quantileLabel   = "quantile"
+)
+
+// counterExpiration is the duration after which the value of a cumulative series which hasn't
+// been received again is forgotten.
+const counterExpiration = time.Hour
+
+// cumulativeSuffixes are the suffixes of the series of the cumulative metric families, they're
+// removed from the name of a series to find its family.
+var cumulativeSuffixes = []string{"_total", "_bucket", "_count", "_sum"}
+
+// sample is a sample of a series converted to a Datadog metric, before the mapper is applied.
+type sample struct {
+	name       string
+	tags       []string
+	value      float64
+	timestamp  float64
+	cumulative bool
+}
+
+// metricTypes keeps the types of the metric families sent in the metadata of the requests. The
+// Prometheus agents send the metadata periodically, in requests of their own.
+type metricTypes struct {
+	mu    sync.RWMutex
+	types map[string]metricType
+}
+
+func newMetricTypes() *metricTypes {
+	return &metricTypes{types: make(map[string]metricType)}
+}
+
+// update records the types of the metadata of a request.
+func (m *metricTypes) update(metadata []metricMetadata) {
+	if len(metadata) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, meta := range metadata {
+		if meta.familyName != "" {
+			m.types[meta.familyName] = meta.metricType
+		}
+	}
+}
+
+// isCumulative returns true if the series is cumulative: the series of counters, and the buckets,
+// sums and counts of histograms and summaries. The series of a family without metadata are
+// cumulative if their name has the suffix of a cumulative series, e.g. "_total".
+func (m *metricTypes) isCumulative(name string, hasQuantile bool) bool {
+	metricType, ok := m.lookup(name)
+	if !ok {
+		return !hasQuantile && hasCumulativeSuffix(name)
+	}
+	switch metricType {
+	case metricTypeCounter, metricTypeHistogram:
+		return true
+	case metricTypeSummary:
+		return !hasQuantile
+	default:
+		return false
+	}
+}
+
+// lookup returns the type of the family of the series.
+func (m *metricTypes) lookup(name string) (metricType, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if metricType, ok := m.types[name]; ok {
+		return metricType, true
+	}
+	for _, suffix := range cumulativeSuffixes {
+		if family, found := strings.CutSuffix(name, suffix); found {
+			if metricType, ok := m.types[family]; ok {
+				return metricType, true
+			}
+		}
+	}
+	return metricTypeUnknown, false
+}
+
+func hasCumulativeSuffix(name string) bool {
+	for _, suffix := range cumulativeSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type counterValue struct {
+	value     float64
+	timestamp int64
+	lastSeen  time.Time
+}
+
+// counterCache keeps the last value of the cumulative series, to send their increase as counts.
+type counterCache struct {
+	mu        sync.Mutex
+	values    map[string]counterValue
+	lastPurge time.Time
+	nowFunc   func() time.Time
+}
+
+func newCounterCache() *counterCache {
+	return &counterCache{
+		values:    make(map[string]counterValue),
+		lastPurge: time.Now(),
+		nowFunc:   time.Now,
+	}
+}
+
+// increase records the value of a cumulative series at the timestamp, in milliseconds, and returns
+// its increase since its previous value, if it had one. A value lower than the previous one means
+// the series has been reset, its increase is its value. The values which aren't newer than the
+// previous one, e.g. when a request is retried, are ignored.
+func (c *counterCache) increase(key string, value float64, timestamp int64, now time.Time) (float64, bool) {
+	previous, ok := c.values[key]
+	if ok && timestamp <= previous.timestamp {
+		return 0, false
+	}
+	c.values[key] = counterValue{value: value, timestamp: timestamp, lastSeen: now}
+	if !ok {
+		return 0, false
+	}
+	if value < previous.value {
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// purge forgets the series which haven't been received for a while.
+func (c *counterCache) purge(now time.Time) {
+	if now.Sub(c.lastPurge) < counterExpiration {
+		return
+	}
+	for key, counter := range c.values {
+		if now.Sub(counter.lastSeen) >= counterExpiration {
+			delete(c.values, key)
+		}
+	}
+	c.lastPurge = now
+}
+
+// convert returns the samples of the series of a request. The samples of the cumulative series are
+// their increase since their previous sample, nothing is sent for the first sample of a series.
+// Stale markers, and the other NaN and infinite values, are dropped, as well as native histograms.
+func convert(series []timeSeries, types *metricTypes, counters *counterCache) []sample {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+	now := counters.nowFunc()
+
+	var samples []sample
+	for _, ts := range series {
+		name, tags, hasQuantile := labelsToTags(ts.labels)
+		if name == "" {
+			continue
+		}
+		cumulative := types.isCumulative(name, hasQuantile)
+		key := name + "|" + strings.Join(tags, ",")
+		for _, s := range ts.samples {
+			if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+				continue
+			}
+			value := s.value
+			if cumulative {
+				increase, ok := counters.increase(key, s.value, s.timestamp, now)
+				if !ok {
+					continue
+				}
+				value = increase
+			}
+			samples = append(samples, sample{
+				name:       name,
+				tags:       tags,
+				value:      value,
+				timestamp:  float64(s.timestamp) / 1000,
+				cumulative: cumulative,
+			})
+		}
+	}
+	counters.purge(now)
+	return samples
+}
+
+// labelsToTags returns the name of the series, its other labels as sorted tags, and whether it
+// has a quantile label.
+func labelsToTags(labels []label) (string, []string, bool) {
+	var name string
+	var hasQuantile bool
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		switch l.name {
+		case metricNameLabel:
+			name = l.value
+			continue
+		case quantileLabel:
+			hasQuantile = true
+		}
+		tags = append(tags, l.name+":"+l.value)
+	}
+	sort.Strings(tags)
+	return name, tags, hasQuantile
+}
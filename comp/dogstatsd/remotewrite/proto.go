@@ -0,0 +1,197 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"fmt"
+
+	"github.com/richardartoul/molecule"
+	"github.com/richardartoul/molecule/src/codec"
+)
+
+// The messages of the version 1 of the remote-write protocol are decoded by hand, only the fields
+// used by the receiver are read. The field numbers are the ones of prometheus/prompb.
+const (
+	writeRequestTimeseriesField = 1
+	writeRequestMetadataField   = 3
+
+	timeSeriesLabelsField  = 1
+	timeSeriesSamplesField = 2
+
+	labelNameField  = 1
+	labelValueField = 2
+
+	sampleValueField     = 1
+	sampleTimestampField = 2
+
+	metadataTypeField       = 1
+	metadataFamilyNameField = 2
+)
+
+// metricType is the type of a metric family in the metadata of a request.
+type metricType int32
+
+const (
+	metricTypeUnknown metricType = iota
+	metricTypeCounter
+	metricTypeGauge
+	metricTypeHistogram
+	metricTypeGaugeHistogram
+	metricTypeSummary
+)
+
+type writeRequest struct {
+	timeseries []timeSeries
+	metadata   []metricMetadata
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []promSample
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// promSample is a sample of a series, its timestamp is in milliseconds.
+type promSample struct {
+	value     float64
+	timestamp int64
+}
+
+type metricMetadata struct {
+	metricType metricType
+	familyName string
+}
+
+// unmarshalWriteRequest decodes a WriteRequest message. The exemplars and native histograms of the
+// series, as well as the unknown fields, are skipped.
+func unmarshalWriteRequest(b []byte) (*writeRequest, error) {
+	var request writeRequest
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		switch field {
+		case writeRequestTimeseriesField:
+			if err := expectWireType(field, value, codec.WireBytes); err != nil {
+				return false, err
+			}
+			ts, err := unmarshalTimeSeries(value.Bytes)
+			if err != nil {
+				return false, err
+			}
+			request.timeseries = append(request.timeseries, ts)
+		case writeRequestMetadataField:
+			if err := expectWireType(field, value, codec.WireBytes); err != nil {
+				return false, err
+			}
+			metadata, err := unmarshalMetricMetadata(value.Bytes)
+			if err != nil {
+				return false, err
+			}
+			request.metadata = append(request.metadata, metadata)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func unmarshalTimeSeries(b []byte) (timeSeries, error) {
+	var ts timeSeries
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		switch field {
+		case timeSeriesLabelsField:
+			if err := expectWireType(field, value, codec.WireBytes); err != nil {
+				return false, err
+			}
+			l, err := unmarshalLabel(value.Bytes)
+			if err != nil {
+				return false, err
+			}
+			ts.labels = append(ts.labels, l)
+		case timeSeriesSamplesField:
+			if err := expectWireType(field, value, codec.WireBytes); err != nil {
+				return false, err
+			}
+			s, err := unmarshalSample(value.Bytes)
+			if err != nil {
+				return false, err
+			}
+			ts.samples = append(ts.samples, s)
+		}
+		return true, nil
+	})
+	return ts, err
+}
+
+func unmarshalLabel(b []byte) (label, error) {
+	var l label
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		var err error
+		switch field {
+		case labelNameField:
+			if err = expectWireType(field, value, codec.WireBytes); err == nil {
+				l.name, err = value.AsStringSafe()
+			}
+		case labelValueField:
+			if err = expectWireType(field, value, codec.WireBytes); err == nil {
+				l.value, err = value.AsStringSafe()
+			}
+		}
+		return err == nil, err
+	})
+	return l, err
+}
+
+func unmarshalSample(b []byte) (promSample, error) {
+	var s promSample
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		var err error
+		switch field {
+		case sampleValueField:
+			if err = expectWireType(field, value, codec.WireFixed64); err == nil {
+				s.value, err = value.AsDouble()
+			}
+		case sampleTimestampField:
+			if err = expectWireType(field, value, codec.WireVarint); err == nil {
+				s.timestamp, err = value.AsInt64()
+			}
+		}
+		return err == nil, err
+	})
+	return s, err
+}
+
+func unmarshalMetricMetadata(b []byte) (metricMetadata, error) {
+	var m metricMetadata
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		var err error
+		switch field {
+		case metadataTypeField:
+			if err = expectWireType(field, value, codec.WireVarint); err == nil {
+				var t int32
+				t, err = value.AsInt32()
+				m.metricType = metricType(t)
+			}
+		case metadataFamilyNameField:
+			if err = expectWireType(field, value, codec.WireBytes); err == nil {
+				m.familyName, err = value.AsStringSafe()
+			}
+		}
+		return err == nil, err
+	})
+	return m, err
+}
+
+func expectWireType(field int32, value molecule.Value, wireType codec.WireType) error {
+	if value.WireType != wireType {
+		return fmt.Errorf("field %d: unexpected wire type %d", field, value.WireType)
+	}
+	return nil
+}
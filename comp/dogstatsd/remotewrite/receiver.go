@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite implements a receiver of the Prometheus remote-write protocol, which sends
+// the received samples to the no-aggregation pipeline of the demultiplexer.
+package remotewrite
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	remoteWriteExpvars = expvar.NewMap("dogstatsd-remote-write")
+	requestErrors      = expvar.Int{}
+	requests           = expvar.Int{}
+	samplesReceived    = expvar.Int{}
+)
+
+func init() {
+	remoteWriteExpvars.Set("RequestErrors", &requestErrors)
+	remoteWriteExpvars.Set("Requests", &requests)
+	remoteWriteExpvars.Set("Samples", &samplesReceived)
+}
+
+// WritePath is the path of the remote-write endpoint.
+const WritePath = "/api/v1/write"
+
+// remoteWriteV2Proto is the protobuf message of the version 2 of the protocol, which isn't supported.
+const remoteWriteV2Proto = "io.prometheus.write.v2.Request"
+
+// readHeaderTimeout is the maximum time to read the headers of a request.
+const readHeaderTimeout = 10 * time.Second
+
+// SampleSender is the part of the demultiplexer used by the receiver.
+type SampleSender interface {
+	SendSamplesWithoutAggregation(samples metrics.MetricSampleBatch)
+	GetMetricSamplePool() *metrics.MetricSamplePool
+}
+
+// Receiver accepts the requests of the Prometheus remote-write protocol (version 1, snappy and
+// protobuf encoded). The samples keep their timestamp and are sent to the no-aggregation pipeline:
+// the samples of the gauges as gauges, and the increase of the cumulative series (counters, and
+// the buckets, sums and counts of histograms and summaries) as counts. The type of the series comes
+// from the metadata sent by the Prometheus agents. The names of the metrics go through the
+// DogStatsD mapper, if any.
+type Receiver struct {
+	listener       net.Listener
+	server         *http.Server
+	sender         SampleSender
+	mapper         *mapper.MetricMapper
+	hostname       string
+	maxRequestSize int
+	types          *metricTypes
+	counters       *counterCache
+	listenWg       sync.WaitGroup
+}
+
+// NewReceiver returns an idle remote-write receiver. metricMapper may be nil.
+func NewReceiver(cfg model.Reader, sender SampleSender, metricMapper *mapper.MetricMapper, hostname string) (*Receiver, error) {
+	var url string
+	port := cfg.GetString("dogstatsd_remote_write_port")
+	if port == listeners.RandomPortName {
+		port = "0"
+	}
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	ln, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	r := &Receiver{
+		listener:       ln,
+		sender:         sender,
+		mapper:         metricMapper,
+		hostname:       hostname,
+		maxRequestSize: cfg.GetInt("dogstatsd_remote_write_max_request_size"),
+		types:          newMetricTypes(),
+		counters:       newCounterCache(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WritePath, r.handleWrite)
+	r.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	log.Debugf("dogstatsd-remote-write: %s successfully initialized", ln.Addr())
+	return r, nil
+}
+
+// LocalAddr returns the local network address of the receiver.
+func (r *Receiver) LocalAddr() string {
+	return r.listener.Addr().String()
+}
+
+// Listen starts serving the requests in its own goroutine.
+func (r *Receiver) Listen() {
+	r.listenWg.Add(1)
+	go func() {
+		defer r.listenWg.Done()
+		log.Infof("dogstatsd-remote-write: starting to listen on %s", r.listener.Addr())
+		if err := r.server.Serve(r.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-remote-write: error serving requests: %v", err)
+		}
+	}()
+}
+
+// Stop closes the HTTP server and stops listening
+func (r *Receiver) Stop() {
+	r.server.Close()
+	r.listenWg.Wait()
+}
+
+func (r *Receiver) handleWrite(w http.ResponseWriter, req *http.Request) {
+	requests.Add(1)
+	status, err := r.handle(req)
+	if err != nil {
+		requestErrors.Add(1)
+		log.Debugf("dogstatsd-remote-write: invalid request: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// handle sends the samples of the request to the demultiplexer, and returns the status code of
+// the response. The Prometheus agents retry the requests failing with a 5xx status, but not the
+// ones failing with a 4xx status.
+func (r *Receiver) handle(req *http.Request) (int, error) {
+	if req.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method)
+	}
+	if strings.Contains(req.Header.Get("Content-Type"), remoteWriteV2Proto) {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported remote-write message %s", remoteWriteV2Proto)
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	writeRequest, err := r.readWriteRequest(req)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+		return http.StatusBadRequest, err
+	}
+
+	r.types.update(writeRequest.metadata)
+	samples := convert(writeRequest.timeseries, r.types, r.counters)
+	samplesReceived.Add(int64(len(samples)))
+	r.send(samples)
+	return http.StatusNoContent, nil
+}
+
+// readWriteRequest decodes the body of the request. The request size limit applies to the
+// decompressed body. The s2 decoder reads the snappy block format.
+func (r *Receiver) readWriteRequest(req *http.Request) (*writeRequest, error) {
+	compressed, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, int64(r.maxRequestSize)))
+	if err != nil {
+		return nil, err
+	}
+	size, err := s2.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+	if size > r.maxRequestSize {
+		return nil, &http.MaxBytesError{Limit: int64(r.maxRequestSize)}
+	}
+	body, err := s2.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+	writeRequest, err := unmarshalWriteRequest(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf payload: %w", err)
+	}
+	return writeRequest, nil
+}
+
+// send maps the names of the samples and sends them to the no-aggregation pipeline, in batches
+// from the pool of the demultiplexer.
+func (r *Receiver) send(samples []sample) {
+	if len(samples) == 0 {
+		return
+	}
+	pool := r.sender.GetMetricSamplePool()
+	batch := pool.GetBatch()
+	n := 0
+	for _, s := range samples {
+		name, tags := s.name, s.tags
+		if r.mapper != nil {
+			if mapResult := r.mapper.Map(name); mapResult != nil {
+				name = mapResult.Name
+				tags = append(tags[:len(tags):len(tags)], mapResult.Tags...)
+			}
+		}
+		mtype := metrics.GaugeType
+		if s.cumulative {
+			mtype = metrics.CounterType
+		}
+		batch[n] = metrics.MetricSample{
+			Name:       name,
+			Value:      s.value,
+			Mtype:      mtype,
+			Tags:       tags,
+			Host:       r.hostname,
+			SampleRate: 1,
+			Timestamp:  s.timestamp,
+			Source:     metrics.MetricSourceDogstatsd,
+		}
+		n++
+		if n == len(batch) {
+			r.sender.SendSamplesWithoutAggregation(batch)
+			batch = pool.GetBatch()
+			n = 0
+		}
+	}
+	if n > 0 {
+		r.sender.SendSamplesWithoutAggregation(batch[:n])
+	} else {
+		pool.PutBatch(batch)
+	}
+}
@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/richardartoul/molecule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type fakeSender struct {
+	sync.Mutex
+	pool    *metrics.MetricSamplePool
+	samples []metrics.MetricSample
+	batches int
+}
+
+func (f *fakeSender) SendSamplesWithoutAggregation(samples metrics.MetricSampleBatch) {
+	f.Lock()
+	defer f.Unlock()
+	f.samples = append(f.samples, samples...)
+	f.batches++
+}
+
+func (f *fakeSender) GetMetricSamplePool() *metrics.MetricSamplePool {
+	return f.pool
+}
+
+func (f *fakeSender) reset() []metrics.MetricSample {
+	f.Lock()
+	defer f.Unlock()
+	samples := f.samples
+	f.samples = nil
+	f.batches = 0
+	return samples
+}
+
+func newTestReceiver(t *testing.T, metricMapper *mapper.MetricMapper) (*Receiver, *fakeSender) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("dogstatsd_remote_write_port", "__random__")
+	cfg.SetWithoutSource("dogstatsd_remote_write_max_request_size", 1024)
+	sender := &fakeSender{pool: metrics.NewMetricSamplePool(2, false)}
+	r, err := NewReceiver(cfg, sender, metricMapper, "myhost")
+	require.NoError(t, err)
+	r.Listen()
+	t.Cleanup(r.Stop)
+	return r, sender
+}
+
+// marshal encodes the request like the Prometheus agents.
+func (w *writeRequest) marshal(t *testing.T) []byte {
+	var buf bytes.Buffer
+	ps := molecule.NewProtoStream(&buf)
+	for _, ts := range w.timeseries {
+		err := ps.Embedded(writeRequestTimeseriesField, func(ps *molecule.ProtoStream) error {
+			for _, l := range ts.labels {
+				err := ps.Embedded(timeSeriesLabelsField, func(ps *molecule.ProtoStream) error {
+					if err := ps.String(labelNameField, l.name); err != nil {
+						return err
+					}
+					return ps.String(labelValueField, l.value)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			for _, s := range ts.samples {
+				err := ps.Embedded(timeSeriesSamplesField, func(ps *molecule.ProtoStream) error {
+					if err := ps.Double(sampleValueField, s.value); err != nil {
+						return err
+					}
+					return ps.Int64(sampleTimestampField, s.timestamp)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	for _, m := range w.metadata {
+		err := ps.Embedded(writeRequestMetadataField, func(ps *molecule.ProtoStream) error {
+			if err := ps.Int32(metadataTypeField, int32(m.metricType)); err != nil {
+				return err
+			}
+			return ps.String(metadataFamilyNameField, m.familyName)
+		})
+		require.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func postWriteRequest(t *testing.T, r *Receiver, writeRequest *writeRequest, header http.Header) int {
+	return post(t, r, s2.EncodeSnappy(nil, writeRequest.marshal(t)), header)
+}
+
+func post(t *testing.T, r *Receiver, body []byte, header http.Header) int {
+	req, err := http.NewRequest(http.MethodPost, "http://"+r.LocalAddr()+WritePath, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func series(name string, value float64, timestampMs int64, labels ...string) timeSeries {
+	ts := timeSeries{
+		labels:  []label{{name: "__name__", value: name}},
+		samples: []promSample{{value: value, timestamp: timestampMs}},
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.labels = append(ts.labels, label{name: labels[i], value: labels[i+1]})
+	}
+	return ts
+}
+
+func sortSamples(samples []metrics.MetricSample) {
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Name != samples[j].Name {
+			return samples[i].Name < samples[j].Name
+		}
+		return samples[i].Timestamp < samples[j].Timestamp
+	})
+}
+
+func TestReceiverGaugesAndCounters(t *testing.T) {
+	r, sender := newTestReceiver(t, nil)
+
+	metadata := []metricMetadata{
+		{metricType: metricTypeGauge, familyName: "temperature"},
+		{metricType: metricTypeCounter, familyName: "requests"},
+		{metricType: metricTypeGauge, familyName: "queue_sum"},
+	}
+	status := postWriteRequest(t, r, &writeRequest{metadata: metadata}, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, sender.reset())
+
+	status = postWriteRequest(t, r, &writeRequest{timeseries: []timeSeries{
+		series("temperature", 21.5, 1700000000000, "room", "kitchen"),
+		series("requests_total", 10, 1700000000000, "code", "200"),
+		series("queue_sum", 3, 1700000000000),
+	}}, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	samples := sender.reset()
+	sortSamples(samples)
+	require.Len(t, samples, 2)
+	assert.Equal(t, metrics.MetricSample{
+		Name:       "queue_sum",
+		Value:      3,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{},
+		Host:       "myhost",
+		SampleRate: 1,
+		Timestamp:  1700000000,
+		Source:     metrics.MetricSourceDogstatsd,
+	}, samples[0])
+	assert.Equal(t, "temperature", samples[1].Name)
+	assert.Equal(t, metrics.GaugeType, samples[1].Mtype)
+	assert.Equal(t, []string{"room:kitchen"}, samples[1].Tags)
+
+	// the increase of the counter is sent as a count, the retried samples are ignored
+	status = postWriteRequest(t, r, &writeRequest{timeseries: []timeSeries{
+		series("requests_total", 10, 1700000000000, "code", "200"),
+		series("requests_total", 14, 1700000015000, "code", "200"),
+	}}, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	samples = sender.reset()
+	require.Len(t, samples, 1)
+	assert.Equal(t, "requests_total", samples[0].Name)
+	assert.Equal(t, metrics.CounterType, samples[0].Mtype)
+	assert.Equal(t, 4.0, samples[0].Value)
+	assert.Equal(t, 1700000015.0, samples[0].Timestamp)
+	assert.Equal(t, []string{"code:200"}, samples[0].Tags)
+
+	// a reset counter sends its value, stale markers are dropped
+	status = postWriteRequest(t, r, &writeRequest{timeseries: []timeSeries{
+		series("requests_total", 2, 1700000030000, "code", "200"),
+		series("temperature", math.NaN(), 1700000030000, "room", "kitchen"),
+	}}, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	samples = sender.reset()
+	require.Len(t, samples, 1)
+	assert.Equal(t, 2.0, samples[0].Value)
+}
+
+func TestReceiverTypesWithoutMetadata(t *testing.T) {
+	types := newMetricTypes()
+	assert.True(t, types.isCumulative("requests_total", false))
+	assert.True(t, types.isCumulative("latency_bucket", false))
+	assert.False(t, types.isCumulative("latency", true))
+	assert.False(t, types.isCumulative("temperature", false))
+
+	types.update([]metricMetadata{
+		{metricType: metricTypeSummary, familyName: "rpc_duration"},
+		{metricType: metricTypeHistogram, familyName: "latency"},
+		{metricType: metricTypeGaugeHistogram, familyName: "queue_size"},
+	})
+	assert.False(t, types.isCumulative("rpc_duration", true))
+	assert.True(t, types.isCumulative("rpc_duration_count", false))
+	assert.True(t, types.isCumulative("latency_bucket", false))
+	assert.False(t, types.isCumulative("queue_size_bucket", false))
+}
+
+func TestReceiverMapper(t *testing.T) {
+	metricMapper, err := mapper.NewMetricMapper([]mapper.MappingProfileConfig{{
+		Name:   "prometheus",
+		Prefix: "node_",
+		Mappings: []mapper.MetricMappingConfig{{
+			Match:     `node_cpu_(\w+)`,
+			MatchType: "regex",
+			Name:      "node.cpu",
+			Tags:      map[string]string{"mode": "$1"},
+		}},
+	}}, 100)
+	require.NoError(t, err)
+	r, sender := newTestReceiver(t, metricMapper)
+
+	status := postWriteRequest(t, r, &writeRequest{timeseries: []timeSeries{
+		series("node_cpu_idle", 0.5, 1700000000000, "cpu", "0"),
+		series("node_load1", 1, 1700000000000),
+		series("other", 2, 1700000000000),
+	}}, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	samples := sender.reset()
+	sortSamples(samples)
+	require.Len(t, samples, 3)
+	assert.Equal(t, "node.cpu", samples[0].Name)
+	assert.ElementsMatch(t, []string{"cpu:0", "mode:idle"}, samples[0].Tags)
+	assert.Equal(t, "node_load1", samples[1].Name)
+	assert.Equal(t, "other", samples[2].Name)
+}
+
+func TestReceiverInvalidRequests(t *testing.T) {
+	r, sender := newTestReceiver(t, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+r.LocalAddr()+WritePath, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	assert.Equal(t, http.StatusBadRequest, post(t, r, []byte("not snappy"), nil))
+	assert.Equal(t, http.StatusBadRequest, post(t, r, s2.EncodeSnappy(nil, []byte("not protobuf")), nil))
+
+	header := http.Header{}
+	header.Set("Content-Encoding", "gzip")
+	assert.Equal(t, http.StatusUnsupportedMediaType, post(t, r, nil, header))
+	header = http.Header{}
+	header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+	assert.Equal(t, http.StatusUnsupportedMediaType, post(t, r, nil, header))
+
+	// the limit applies to the decompressed body
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, r, s2.EncodeSnappy(nil, make([]byte, 2048)), nil))
+
+	assert.Empty(t, sender.reset())
+}
+
+func TestUnmarshalWriteRequestSkipsUnknownFields(t *testing.T) {
+	var buf bytes.Buffer
+	ps := molecule.NewProtoStream(&buf)
+	require.NoError(t, ps.Embedded(writeRequestTimeseriesField, func(ps *molecule.ProtoStream) error {
+		if err := ps.Embedded(timeSeriesLabelsField, func(ps *molecule.ProtoStream) error {
+			return ps.String(labelValueField, "requests_total")
+		}); err != nil {
+			return err
+		}
+		// the exemplars and native histograms of the series
+		if err := ps.Embedded(3, func(ps *molecule.ProtoStream) error { return ps.Double(2, 1) }); err != nil {
+			return err
+		}
+		return ps.Embedded(4, func(ps *molecule.ProtoStream) error { return ps.Uint64(1, 5) })
+	}))
+	require.NoError(t, ps.String(4, "unknown"))
+
+	request, err := unmarshalWriteRequest(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []timeSeries{{labels: []label{{value: "requests_total"}}}}, request.timeseries)
+
+	// a field with the wrong wire type is an error
+	buf.Reset()
+	require.NoError(t, ps.Uint64(writeRequestTimeseriesField, 1))
+	_, err = unmarshalWriteRequest(buf.Bytes())
+	assert.Error(t, err)
+}
@@ -0,0 +1,389 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+// CaptureFilter selects the messages of a capture to analyze.
+type CaptureFilter struct {
+	// MetricName matches the names of the metrics to keep, the events and service checks are
+	// dropped when it's set. All the messages are kept when nil.
+	MetricName *regexp.Regexp
+	// Pids are the origin PIDs of the packets to keep. All the packets are kept when empty.
+	Pids []int32
+}
+
+// keepPid returns whether the packets sent by a PID are kept.
+func (f CaptureFilter) keepPid(pid int32) bool {
+	return len(f.Pids) == 0 || slices.Contains(f.Pids, pid)
+}
+
+// CaptureAnalysis summarizes the messages of a capture kept by a CaptureFilter.
+type CaptureAnalysis struct {
+	// Packets is the number of packets with at least one message kept.
+	Packets int
+	// Messages is the number of messages kept.
+	Messages int
+	// Events and ServiceChecks are the number of events and service checks kept.
+	Events        int
+	ServiceChecks int
+	// InvalidMessages is the number of messages which couldn't be parsed.
+	InvalidMessages int
+	// First and Last are the times of the first and last packets kept.
+	First time.Time
+	Last  time.Time
+	// Metrics are the metrics kept by name.
+	Metrics map[string]*MetricAnalysis
+	// Origins are the origins of the packets kept by PID.
+	Origins map[int32]*OriginAnalysis
+
+	tagValues map[string]map[string]struct{}
+}
+
+// MetricAnalysis summarizes the messages of a metric.
+type MetricAnalysis struct {
+	Name string
+	// Packets is the number of packets containing the metric.
+	Packets int
+	// Messages is the number of messages of the metric.
+	Messages int
+
+	contexts  map[string]struct{}
+	tagValues map[string]map[string]struct{}
+}
+
+// Contexts returns the number of distinct tag sets of the metric.
+func (m *MetricAnalysis) Contexts() int {
+	return len(m.contexts)
+}
+
+// TagCardinality returns the number of distinct values of each tag of the metric.
+func (m *MetricAnalysis) TagCardinality() []TagCardinality {
+	return tagCardinality(m.tagValues)
+}
+
+// OriginAnalysis summarizes the packets sent by a PID.
+type OriginAnalysis struct {
+	Pid int32
+	// ContainerID is the container of the PID found in the tagger state of the capture, if any.
+	ContainerID string
+	Packets     int
+	Messages    int
+
+	metrics map[string]struct{}
+}
+
+// Metrics returns the number of distinct metrics sent by the PID.
+func (o *OriginAnalysis) Metrics() int {
+	return len(o.metrics)
+}
+
+// TagCardinality is the number of distinct values of a tag.
+type TagCardinality struct {
+	Tag    string
+	Values int
+}
+
+func tagCardinality(tagValues map[string]map[string]struct{}) []TagCardinality {
+	cardinality := make([]TagCardinality, 0, len(tagValues))
+	for tag, values := range tagValues {
+		cardinality = append(cardinality, TagCardinality{Tag: tag, Values: len(values)})
+	}
+	sort.Slice(cardinality, func(i, j int) bool {
+		if cardinality[i].Values != cardinality[j].Values {
+			return cardinality[i].Values > cardinality[j].Values
+		}
+		return cardinality[i].Tag < cardinality[j].Tag
+	})
+	return cardinality
+}
+
+// TopMetrics returns the n metrics found in the most packets, all of them when n is 0.
+func (a *CaptureAnalysis) TopMetrics(n int) []*MetricAnalysis {
+	metrics := make([]*MetricAnalysis, 0, len(a.Metrics))
+	for _, metric := range a.Metrics {
+		metrics = append(metrics, metric)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Packets != metrics[j].Packets {
+			return metrics[i].Packets > metrics[j].Packets
+		}
+		return metrics[i].Name < metrics[j].Name
+	})
+	if n > 0 && n < len(metrics) {
+		metrics = metrics[:n]
+	}
+	return metrics
+}
+
+// TopOrigins returns the n origins which sent the most packets, all of them when n is 0.
+func (a *CaptureAnalysis) TopOrigins(n int) []*OriginAnalysis {
+	origins := make([]*OriginAnalysis, 0, len(a.Origins))
+	for _, origin := range a.Origins {
+		origins = append(origins, origin)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		if origins[i].Packets != origins[j].Packets {
+			return origins[i].Packets > origins[j].Packets
+		}
+		return origins[i].Pid < origins[j].Pid
+	})
+	if n > 0 && n < len(origins) {
+		origins = origins[:n]
+	}
+	return origins
+}
+
+// TopTags returns the n tags with the most distinct values across all the metrics, all of them
+// when n is 0.
+func (a *CaptureAnalysis) TopTags(n int) []TagCardinality {
+	cardinality := tagCardinality(a.tagValues)
+	if n > 0 && n < len(cardinality) {
+		cardinality = cardinality[:n]
+	}
+	return cardinality
+}
+
+// Analyze reads the whole capture from its start, and returns the summary of the messages kept
+// by the filter. When out isn't nil, a capture with only the messages kept, and the tagger state
+// of their PIDs, is written to it; it can be replayed like the original one.
+// Analyze must not be called during a replay.
+func (tc *TrafficCaptureReader) Analyze(filter CaptureFilter, out io.Writer) (*CaptureAnalysis, error) {
+	var pidMap map[int32]string
+	var entities map[string]*pb.Entity
+	if tc.Version >= minStateVersion {
+		var err error
+		if pidMap, entities, err = tc.ReadState(); err != nil {
+			return nil, fmt.Errorf("unable to read the tagger state: %w", err)
+		}
+	}
+
+	tsResolution := time.Nanosecond
+	if tc.Version < minNanoVersion {
+		tsResolution = time.Second
+	}
+
+	if out != nil {
+		if err := WriteHeader(out); err != nil {
+			return nil, err
+		}
+	}
+
+	analysis := &CaptureAnalysis{
+		Metrics:   make(map[string]*MetricAnalysis),
+		Origins:   make(map[int32]*OriginAnalysis),
+		tagValues: make(map[string]map[string]struct{}),
+	}
+
+	tc.Seek(0)
+	for {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !filter.keepPid(msg.Pid) {
+			continue
+		}
+
+		payload := analysis.addPacket(msg, filter, pidMap, tsResolution)
+		if payload == nil || out == nil {
+			continue
+		}
+
+		// the timestamps are written in nanoseconds, as expected by the current file version
+		filtered := &pb.UnixDogstatsdMsg{
+			Timestamp:     int64(time.Duration(msg.Timestamp) * tsResolution),
+			PayloadSize:   int32(len(payload)),
+			Payload:       payload,
+			Pid:           msg.Pid,
+			AncillarySize: msg.AncillarySize,
+			Ancillary:     msg.Ancillary,
+		}
+		if err := writeMessage(out, filtered); err != nil {
+			return nil, err
+		}
+	}
+
+	if out != nil {
+		if _, err := writeState(out, filterState(analysis.Origins, pidMap, entities)); err != nil {
+			return nil, err
+		}
+	}
+
+	return analysis, nil
+}
+
+// addPacket adds the messages of a packet kept by the filter to the analysis, and returns them,
+// or nil if none is kept.
+func (a *CaptureAnalysis) addPacket(msg *pb.UnixDogstatsdMsg, filter CaptureFilter, pidMap map[int32]string, tsResolution time.Duration) []byte {
+	contents := msg.Payload
+	if int(msg.PayloadSize) <= len(contents) {
+		contents = contents[:msg.PayloadSize]
+	}
+
+	var kept [][]byte
+	seen := make(map[string]struct{})
+	for _, message := range bytes.Split(contents, []byte("\n")) {
+		message = bytes.TrimSuffix(message, []byte("\r"))
+		if len(message) == 0 {
+			continue
+		}
+
+		switch {
+		case bytes.HasPrefix(message, []byte("_e{")):
+			if filter.MetricName != nil {
+				continue
+			}
+			a.Events++
+		case bytes.HasPrefix(message, []byte("_sc|")):
+			if filter.MetricName != nil {
+				continue
+			}
+			a.ServiceChecks++
+		default:
+			name, tags, ok := parseMetricMessage(message)
+			if !ok {
+				if filter.MetricName != nil {
+					continue
+				}
+				a.InvalidMessages++
+				break
+			}
+			if filter.MetricName != nil && !filter.MetricName.MatchString(name) {
+				continue
+			}
+			a.addMetric(name, tags, seen)
+		}
+		kept = append(kept, message)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	a.Packets++
+	a.Messages += len(kept)
+	ts := time.Unix(0, int64(time.Duration(msg.Timestamp)*tsResolution))
+	if a.First.IsZero() || ts.Before(a.First) {
+		a.First = ts
+	}
+	if ts.After(a.Last) {
+		a.Last = ts
+	}
+
+	origin, ok := a.Origins[msg.Pid]
+	if !ok {
+		origin = &OriginAnalysis{Pid: msg.Pid, metrics: make(map[string]struct{})}
+		if entityID, found := pidMap[msg.Pid]; found {
+			if _, id, err := types.ExtractPrefixAndID(entityID); err == nil {
+				origin.ContainerID = id
+			}
+		}
+		a.Origins[msg.Pid] = origin
+	}
+	origin.Packets++
+	origin.Messages += len(kept)
+	for name := range seen {
+		origin.metrics[name] = struct{}{}
+	}
+
+	return bytes.Join(kept, []byte("\n"))
+}
+
+// addMetric adds a message of a metric to the analysis, seen holds the metrics already found in
+// the packet.
+func (a *CaptureAnalysis) addMetric(name string, tags []string, seen map[string]struct{}) {
+	metric, ok := a.Metrics[name]
+	if !ok {
+		metric = &MetricAnalysis{
+			Name:      name,
+			contexts:  make(map[string]struct{}),
+			tagValues: make(map[string]map[string]struct{}),
+		}
+		a.Metrics[name] = metric
+	}
+	metric.Messages++
+	if _, ok := seen[name]; !ok {
+		seen[name] = struct{}{}
+		metric.Packets++
+	}
+
+	sort.Strings(tags)
+	metric.contexts[strings.Join(tags, ",")] = struct{}{}
+	for _, tag := range tags {
+		key, value, _ := strings.Cut(tag, ":")
+		addTagValue(metric.tagValues, key, value)
+		addTagValue(a.tagValues, key, value)
+	}
+}
+
+func addTagValue(tagValues map[string]map[string]struct{}, key, value string) {
+	values, ok := tagValues[key]
+	if !ok {
+		values = make(map[string]struct{})
+		tagValues[key] = values
+	}
+	values[value] = struct{}{}
+}
+
+// parseMetricMessage returns the name and the tags of a metric message:
+// `<name>:<value>|<type>[|@<sample rate>][|#<tags>]...`
+func parseMetricMessage(message []byte) (string, []string, bool) {
+	name, rest, ok := bytes.Cut(message, []byte(":"))
+	if !ok || len(name) == 0 {
+		return "", nil, false
+	}
+	fields := bytes.Split(rest, []byte("|"))
+	if len(fields) < 2 {
+		return "", nil, false
+	}
+	var tags []string
+	for _, field := range fields[2:] {
+		if len(field) > 1 && field[0] == '#' {
+			for _, tag := range bytes.Split(field[1:], []byte(",")) {
+				if len(tag) > 0 {
+					tags = append(tags, string(tag))
+				}
+			}
+		}
+	}
+	return string(name), tags, true
+}
+
+// filterState returns the tagger state of the origins.
+func filterState(origins map[int32]*OriginAnalysis, pidMap map[int32]string, entities map[string]*pb.Entity) *pb.TaggerState {
+	state := &pb.TaggerState{
+		State:  make(map[string]*pb.Entity),
+		PidMap: make(map[int32]string),
+	}
+	for pid, origin := range origins {
+		entityID, ok := pidMap[pid]
+		if !ok {
+			continue
+		}
+		state.PidMap[pid] = entityID
+		// older captures key the entities by their full ID
+		for _, key := range []string{origin.ContainerID, entityID} {
+			if entity, ok := entities[key]; ok {
+				state.State[key] = entity
+			}
+		}
+	}
+	return state
+}
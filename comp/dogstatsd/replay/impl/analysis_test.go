@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const testContainerID = "c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22"
+
+func TestAnalyze(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog.zstd", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	analysis, err := tc.Analyze(CaptureFilter{}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 21, analysis.Packets)
+	assert.Equal(t, 21, analysis.Messages)
+	assert.Equal(t, time.Unix(1621285674, 0), analysis.First)
+	assert.Equal(t, time.Unix(1621285687, 0), analysis.Last)
+
+	metrics := analysis.TopMetrics(10)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "jaime.uds.test", metrics[0].Name)
+	assert.Equal(t, 21, metrics[0].Packets)
+	assert.Equal(t, 1, metrics[0].Contexts())
+	assert.Equal(t, []TagCardinality{{Tag: "shell", Values: 1}}, metrics[0].TagCardinality())
+
+	origins := analysis.TopOrigins(0)
+	require.Len(t, origins, 21)
+	assert.Equal(t, int32(2809), origins[0].Pid)
+	assert.Empty(t, origins[0].ContainerID)
+	assert.Equal(t, testContainerID, analysis.Origins[2815].ContainerID)
+	assert.Equal(t, 1, analysis.Origins[2815].Metrics())
+
+	// the reader can be analyzed again
+	analysis, err = tc.Analyze(CaptureFilter{Pids: []int32{2815, 2818, 2809}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, analysis.Packets)
+	assert.Len(t, analysis.Origins, 3)
+
+	analysis, err = tc.Analyze(CaptureFilter{MetricName: regexp.MustCompile(`^tcp\.`)}, nil)
+	require.NoError(t, err)
+	assert.Zero(t, analysis.Packets)
+	assert.Empty(t, analysis.Metrics)
+}
+
+func TestAnalyzePackets(t *testing.T) {
+	analysis := &CaptureAnalysis{
+		Metrics:   make(map[string]*MetricAnalysis),
+		Origins:   make(map[int32]*OriginAnalysis),
+		tagValues: make(map[string]map[string]struct{}),
+	}
+	packets := []string{
+		"http.requests:1|c|#route:/a,pod:web-1\nhttp.requests:1|c|#pod:web-1,route:/a\nhttp.latency:12|d|@0.5|#route:/a",
+		"http.requests:1|c|#route:/b,pod:web-2\n_e{5,4}:title|text\n_sc|agent.up|0\ninvalid\n",
+		"queue.size:3|g",
+	}
+	filter := CaptureFilter{}
+	for i, p := range packets {
+		payload := analysis.addPacket(&pb.UnixDogstatsdMsg{Timestamp: int64(i), Pid: 10, Payload: []byte(p), PayloadSize: int32(len(p))}, filter, nil, time.Second)
+		assert.Equal(t, []byte(p)[:len(payload)], payload)
+	}
+
+	assert.Equal(t, 3, analysis.Packets)
+	assert.Equal(t, 8, analysis.Messages)
+	assert.Equal(t, 1, analysis.Events)
+	assert.Equal(t, 1, analysis.ServiceChecks)
+	assert.Equal(t, 1, analysis.InvalidMessages)
+
+	metrics := analysis.TopMetrics(2)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "http.requests", metrics[0].Name)
+	assert.Equal(t, 2, metrics[0].Packets)
+	assert.Equal(t, 3, metrics[0].Messages)
+	assert.Equal(t, 2, metrics[0].Contexts())
+	assert.Equal(t, []TagCardinality{{Tag: "pod", Values: 2}, {Tag: "route", Values: 2}}, metrics[0].TagCardinality())
+	assert.Equal(t, "http.latency", metrics[1].Name)
+	assert.Equal(t, []TagCardinality{{Tag: "pod", Values: 2}, {Tag: "route", Values: 2}}, analysis.TopTags(0))
+	assert.Equal(t, 3, analysis.Origins[10].Metrics())
+
+	// only the messages of the matching metrics are kept
+	filter = CaptureFilter{MetricName: regexp.MustCompile(`^http\.`)}
+	payload := analysis.addPacket(&pb.UnixDogstatsdMsg{Pid: 10, Payload: []byte(packets[1]), PayloadSize: int32(len(packets[1]))}, filter, nil, time.Second)
+	assert.Equal(t, "http.requests:1|c|#route:/b,pod:web-2", string(payload))
+	assert.Nil(t, analysis.addPacket(&pb.UnixDogstatsdMsg{Pid: 10, Payload: []byte(packets[2]), PayloadSize: int32(len(packets[2]))}, filter, nil, time.Second))
+}
+
+func TestAnalyzeSubCapture(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	var buf bytes.Buffer
+	expected, err := tc.Analyze(CaptureFilter{Pids: []int32{2809, 2815}, MetricName: regexp.MustCompile(`uds`)}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, expected.Packets)
+
+	path := filepath.Join(t.TempDir(), "sub-capture.dog")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+
+	sub, err := NewTrafficCaptureReader(path, 1, false)
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, int(datadogFileVersion), sub.Version)
+
+	pidMap, entities, err := sub.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{2815: "container_id://" + testContainerID}, pidMap)
+	assert.Contains(t, entities, "container_id://"+testContainerID)
+
+	sub.Seek(0)
+	var msgs []*pb.UnixDogstatsdMsg
+	for {
+		msg, err := sub.ReadNext()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	require.Len(t, msgs, 2)
+	assert.Equal(t, int32(2809), msgs[0].Pid)
+	assert.Equal(t, int64(1621285674)*int64(time.Second), msgs[0].Timestamp)
+	assert.Equal(t, "jaime.uds.test:8|g|#shell:test", string(msgs[0].Payload))
+
+	analysis, err := sub.Analyze(CaptureFilter{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Packets, analysis.Packets)
+	assert.Equal(t, expected.First, analysis.First)
+	assert.Equal(t, expected.Last, analysis.Last)
+}
@@ -348,24 +348,29 @@ func (tc *TrafficCaptureWriter) writeState() (int, error) {
 
 	log.Debugf("Going to write STATE: %#v", pbState)
 
+	return writeState(tc.writer, pbState)
+}
+
+// writeState writes the tagger state to the end of a capture.
+func writeState(w io.Writer, pbState *pb.TaggerState) (int, error) {
 	s, err := proto.Marshal(pbState)
 	if err != nil {
 		return 0, err
 	}
 
 	// Record State Separator
-	if n, err := tc.writer.Write([]byte{0, 0, 0, 0}); err != nil {
+	if n, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
 		return n, err
 	}
 
 	// Record State
-	n, err := tc.writer.Write(s)
+	n, err := w.Write(s)
 
 	// Record size
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(s)))
 
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
@@ -385,27 +390,37 @@ func (tc *TrafficCaptureWriter) writeNext(msg *replay.CaptureBuffer) error {
 		Ancillary:     msg.Pb.Ancillary,
 	}
 
-	buff, err := proto.Marshal(&pb)
+	return writeMessage(tc.writer, &pb)
+}
+
+// writeMessage serializes a message to a protobuf format and writes it to a capture.
+func writeMessage(w io.Writer, msg *pb.UnixDogstatsdMsg) error {
+	buff, err := proto.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
-	_, err = tc.Write(buff)
+	_, err = writeRecord(w, buff)
 	return err
 }
 
 // Write writes the byte slice argument to file.
 func (tc *TrafficCaptureWriter) Write(p []byte) (int, error) {
+	return writeRecord(tc.writer, p)
+}
+
+// writeRecord writes the byte slice argument prefixed by its size.
+func writeRecord(w io.Writer, p []byte) (int, error) {
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(p)))
 
 	// Record size
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
 	// Record
-	n, err := tc.writer.Write(p)
+	n, err := w.Write(p)
 
 	return n + 4, err
 }
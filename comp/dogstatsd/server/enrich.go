@@ -6,11 +6,14 @@
 package server
 
 import (
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/constants"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	metricsevent "github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
@@ -34,6 +37,12 @@ type enrichConfig struct {
 	defaultHostname           string
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
+	// relabeler holds the relabel rules, which can be reloaded at runtime.
+	relabeler *atomic.Pointer[mapper.Relabeler]
+	// rollups is the mapper of the rollup rules, it's nil if there's none.
+	rollups *mapper.MetricMapper
+	// histogramOverrides converts histograms to distributions, it's nil if none is converted.
+	histogramOverrides *metrics.HistogramOverrides
 }
 
 // extractTagsMetadata returns tags (client tags + host tag) and information needed to query tagger (origins, cardinality).
@@ -121,6 +130,20 @@ func isExcluded(metricName, namespace string, excludedNamespaces []string) bool
 	return false
 }
 
+// dropTags removes the tags with one of the names from tags, in place.
+func dropTags(tags []string, names []string) []string {
+	n := 0
+	for _, tag := range tags {
+		name, _, _ := strings.Cut(tag, ":")
+		if slices.Contains(names, name) {
+			continue
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
+
 func tsToFloatForSamples(ts time.Time) float64 {
 	if ts.IsZero() { // avoid a conversion
 		// for on-time samples, we don't want to write any value in there
@@ -137,15 +160,36 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 		metricName = conf.metricPrefix + metricName
 	}
 
+	if conf.relabeler != nil {
+		if relabeler := conf.relabeler.Load(); relabeler != nil {
+			var keep bool
+			if metricName, tags, keep = relabeler.Relabel(metricName, tags); !keep {
+				return dest
+			}
+		}
+	}
+
 	if conf.metricBlocklist.test(metricName) {
 		return []metrics.MetricSample{}
 	}
 
+	// the rollups drop the tags before the samples are sharded, for the contexts they merge to be
+	// aggregated by the same time sampler. The samples with a timestamp aren't aggregated, they
+	// keep their tags.
+	if conf.rollups != nil && ddSample.ts.IsZero() {
+		if names := conf.rollups.DropTags(metricName); len(names) > 0 {
+			tags = dropTags(tags, names)
+		}
+	}
+
 	if conf.serverlessMode { // we don't want to set the host while running in serverless mode
 		hostnameFromTags = ""
 	}
 
 	mtype := enrichMetricType(ddSample.metricType)
+	if mtype == metrics.HistogramType && conf.histogramOverrides.IsDistribution(metricName) {
+		mtype = metrics.DistributionType
+	}
 
 	// if 'ddSample.values' contains values we're enriching a multi-value
 	// dogstatsd message and will create a MetricSample per value. If not
@@ -14,6 +14,8 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
 	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
@@ -1049,6 +1051,66 @@ func TestMetricBlocklistShouldNotBlock(t *testing.T) {
 	assert.Equal(t, 1, len(samples))
 }
 
+func TestRollupShouldDropTags(t *testing.T) {
+	rollups, err := mapper.NewMetricMapper([]mapper.MappingProfileConfig{{
+		Name:    "http",
+		Prefix:  "ns.http.",
+		Rollups: []mapper.RollupConfig{{Match: "ns.http.request.*", DropTags: []string{"pod_name", "container_id"}}},
+	}}, 100)
+	require.NoError(t, err)
+	conf := enrichConfig{
+		metricPrefix:    "ns.",
+		defaultHostname: "default",
+		rollups:         rollups,
+	}
+
+	// the rollups match the name with the namespace
+	parsed, err := parseAndEnrichSingleMetricMessage(t, []byte("http.request.count:1|c|#pod_name:web-1,code:200,container_id:abc"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code:200"}, parsed.Tags)
+
+	parsed, err = parseAndEnrichSingleMetricMessage(t, []byte("http.response.count:1|c|#pod_name:web-1,code:200"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod_name:web-1", "code:200"}, parsed.Tags)
+
+	// the samples with a timestamp keep their tags
+	parsed, err = parseAndEnrichSingleMetricMessage(t, []byte("http.request.count:1|c|#pod_name:web-1,code:200|T1700000000"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod_name:web-1", "code:200"}, parsed.Tags)
+}
+
+func TestHistogramToDistribution(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "ns.app.latency.*", "distribution": true},
+		{"match": "ns.app.*", "percentiles": []string{"0.99"}},
+	})
+	histogramOverrides, err := metrics.NewHistogramOverrides(cfg)
+	require.NoError(t, err)
+	conf := enrichConfig{
+		metricPrefix:       "ns.",
+		defaultHostname:    "default",
+		histogramOverrides: histogramOverrides,
+	}
+
+	// the overrides match the name with the namespace
+	parsed, err := parseAndEnrichSingleMetricMessage(t, []byte("app.latency.db:12|h"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.DistributionType, parsed.Mtype)
+
+	parsed, err = parseAndEnrichSingleMetricMessage(t, []byte("app.size:12|h"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.HistogramType, parsed.Mtype)
+
+	// the timings are histograms too, the other types aren't converted
+	parsed, err = parseAndEnrichSingleMetricMessage(t, []byte("app.latency.db:12|ms"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.DistributionType, parsed.Mtype)
+	parsed, err = parseAndEnrichSingleMetricMessage(t, []byte("app.latency.db:12|g"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.GaugeType, parsed.Mtype)
+}
+
 func TestConvertEntityOriginDetectionNoTags(t *testing.T) {
 	conf := enrichConfig{
 		defaultHostname: "default-hostname",
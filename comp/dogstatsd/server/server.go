@@ -13,6 +13,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/fx"
@@ -26,11 +27,11 @@ import (
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/pidmap"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/remotewrite"
 	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
-	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/config/utils"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
@@ -84,8 +85,9 @@ type dependencies struct {
 type provides struct {
 	fx.Out
 
-	Comp          Component
-	StatsEndpoint api.AgentEndpointProvider
+	Comp                  Component
+	StatsEndpoint         api.AgentEndpointProvider
+	ContextLimitsEndpoint api.AgentEndpointProvider
 }
 
 // When the internal telemetry is enabled, used to tag the origin
@@ -132,6 +134,7 @@ type server struct {
 	tCapture                replay.Component
 	pidMap                  pidmap.Component
 	mapper                  *mapper.MetricMapper
+	relabeler               atomic.Pointer[mapper.Relabeler]
 	eolTerminationUDP       bool
 	eolTerminationUDS       bool
 	eolTerminationNamedPipe bool
@@ -153,6 +156,9 @@ type server struct {
 	ServerlessMode bool
 	udpLocalAddr   string
 
+	// remoteWrite receives the Prometheus remote-write requests, it's nil when disabled.
+	remoteWrite *remotewrite.Receiver
+
 	// originTelemetry is true if we want to report telemetry per origin.
 	originTelemetry bool
 
@@ -193,8 +199,9 @@ func newServer(deps dependencies) provides {
 	}
 
 	return provides{
-		Comp:          s,
-		StatsEndpoint: api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		Comp:                  s,
+		StatsEndpoint:         api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		ContextLimitsEndpoint: api.NewAgentEndpointProvider(s.writeContextLimits, "/dogstatsd-context-limits", "GET"),
 	}
 }
 
@@ -308,6 +315,14 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		stringInternerTelemetry: newSiTelemetry(utils.IsTelemetryEnabled(cfg), telemetrycomp),
 	}
 
+	s.enrichConfig.relabeler = &s.relabeler
+
+	if histogramOverrides, err := metrics.NewHistogramOverrides(cfg); err != nil {
+		log.Errorf("Dogstatsd: the histograms won't be converted to distributions: %s", err)
+	} else if histogramOverrides.HasDistributions() {
+		s.enrichConfig.histogramOverrides = histogramOverrides
+	}
+
 	buckets := getBuckets(cfg, log, "telemetry.dogstatsd.aggregator_channel_latency_buckets")
 	if buckets == nil {
 		buckets = defaultChannelBuckets
@@ -397,6 +412,26 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_http_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_http_port") > 0 {
+		httpListener, err := listeners.NewHTTPListener(packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init HTTP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, httpListener)
+		}
+	}
+
+	for protocol, setting := range map[string]string{listeners.GraphitePlaintext: "dogstatsd_graphite_port", listeners.GraphitePickle: "dogstatsd_graphite_pickle_port"} {
+		if s.config.GetString(setting) == listeners.RandomPortName || s.config.GetInt(setting) > 0 {
+			graphiteListener, err := listeners.NewGraphiteListener(protocol, packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+			if err != nil {
+				s.log.Errorf("Can't init Graphite %s listener: %s", protocol, err.Error())
+			} else {
+				tmpListeners = append(tmpListeners, graphiteListener)
+			}
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -448,7 +483,7 @@ func (s *server) start(context.Context) error {
 
 	cacheSize := s.config.GetInt("dogstatsd_mapper_cache_size")
 
-	mappings, err := getDogstatsdMappingProfiles(s.config)
+	mappings, err := mapper.GetProfiles(s.config)
 	if err != nil {
 		s.log.Warn(err)
 	} else if len(mappings) != 0 {
@@ -457,6 +492,32 @@ func (s *server) start(context.Context) error {
 			s.log.Warnf("Could not create metric mapper: %v", err)
 		} else {
 			s.mapper = mapperInstance
+			if mapperInstance.HasRollups() {
+				s.enrichConfig.rollups = mapperInstance
+			}
+		}
+	}
+
+	// relabel the metrics, the rules are reloaded when the setting is updated at runtime
+	// ----------------------
+
+	s.loadRelabelConfigs()
+	s.config.OnUpdate(func(setting string, _, _ any) {
+		if setting == mapper.RelabelConfigsSetting {
+			s.loadRelabelConfigs()
+		}
+	})
+
+	// receive the Prometheus remote-write requests
+	// ----------------------
+
+	if s.config.GetString("dogstatsd_remote_write_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_remote_write_port") > 0 {
+		receiver, err := remotewrite.NewReceiver(s.config, s.demultiplexer, s.mapper, s.enrichConfig.defaultHostname)
+		if err != nil {
+			s.log.Errorf("Can't init remote-write receiver: %s", err.Error())
+		} else {
+			s.remoteWrite = receiver
+			receiver.Listen()
 		}
 	}
 
@@ -476,6 +537,10 @@ func (s *server) stop(context.Context) error {
 	for _, l := range s.listeners {
 		l.Stop()
 	}
+	if s.remoteWrite != nil {
+		s.remoteWrite.Stop()
+		s.remoteWrite = nil
+	}
 	if s.Statistics != nil {
 		s.Statistics.Stop()
 	}
@@ -497,6 +562,27 @@ func (s *server) SetExtraTags(tags []string) {
 	s.extraTags = tags
 }
 
+// loadRelabelConfigs replaces the relabel rules by the ones of the configuration. The current rules
+// are kept if the new ones are invalid.
+func (s *server) loadRelabelConfigs() {
+	configs, err := mapper.GetRelabelConfigs(s.config)
+	if err != nil {
+		s.log.Warn(err)
+		return
+	}
+	if len(configs) == 0 {
+		s.relabeler.Store(nil)
+		return
+	}
+	relabeler, err := mapper.NewRelabeler(configs)
+	if err != nil {
+		s.log.Warnf("Could not create metric relabeler: %v", err)
+		return
+	}
+	s.log.Infof("Dogstatsd: %d metric relabel rules loaded", len(configs))
+	s.relabeler.Store(relabeler)
+}
+
 func (s *server) handleMessages() {
 	if s.Statistics != nil {
 		go s.Statistics.Process()
@@ -825,14 +911,3 @@ func getBuckets(cfg model.Reader, logger log.Component, option string) []float64
 	}
 	return buckets
 }
-
-func getDogstatsdMappingProfiles(cfg model.Reader) ([]mapper.MappingProfileConfig, error) {
-	var mappings []mapper.MappingProfileConfig
-	if cfg.IsSet("dogstatsd_mapper_profiles") {
-		err := structure.UnmarshalKey(cfg, "dogstatsd_mapper_profiles", &mappings)
-		if err != nil {
-			return []mapper.MappingProfileConfig{}, fmt.Errorf("Could not parse dogstatsd_mapper_profiles: %v", err)
-		}
-	}
-	return mappings, nil
-}
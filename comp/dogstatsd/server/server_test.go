@@ -80,6 +80,48 @@ func TestNoMappingsConfig(t *testing.T) {
 	assert.Len(t, samples, 1)
 }
 
+func TestRelabelConfigsReload(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_port"] = listeners.RandomPortName
+	deps := fulfillDepsWithConfigOverride(t, cfg)
+	s := deps.Server.(*server)
+	cw := deps.Config.(model.Writer)
+
+	requireStart(t, s)
+	assert.Nil(t, s.relabeler.Load())
+
+	parser := newParser(deps.Config, s.sharedFloat64List, 1, deps.WMeta, s.stringInternerTelemetry)
+	samples, err := s.parseMetricMessage(nil, parser, []byte("legacy.billing.requests:1|c|#env:test"), "", 0, "", false)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+
+	// the rules are reloaded when the setting is updated
+	cw.Set(mapper.RelabelConfigsSetting, []interface{}{
+		map[string]interface{}{"source_tags": []interface{}{"env"}, "regex": "test", "action": "drop"},
+		map[string]interface{}{"source_tags": []interface{}{"__name__"}, "regex": `legacy\.(\w+)\.(\w+)`, "target_tag": "__name__", "replacement": "app.$2"},
+		map[string]interface{}{"source_tags": []interface{}{"__name__"}, "regex": `app\..*`, "target_tag": "service", "replacement": "billing"},
+	}, model.SourceAgentRuntime)
+	require.NotNil(t, s.relabeler.Load())
+
+	samples, err = s.parseMetricMessage(nil, parser, []byte("legacy.billing.requests:1|c|#env:test"), "", 0, "", false)
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	samples, err = s.parseMetricMessage(nil, parser, []byte("legacy.billing.requests:1|c|#env:prod"), "", 0, "", false)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "app.requests", samples[0].Name)
+	assert.Equal(t, []string{"env:prod", "service:billing"}, samples[0].Tags)
+
+	// invalid rules don't replace the current ones
+	relabeler := s.relabeler.Load()
+	cw.Set(mapper.RelabelConfigsSetting, []interface{}{map[string]interface{}{"action": "invalid"}}, model.SourceAgentRuntime)
+	assert.Same(t, relabeler, s.relabeler.Load())
+
+	cw.Set(mapper.RelabelConfigsSetting, []interface{}{}, model.SourceAgentRuntime)
+	assert.Nil(t, s.relabeler.Load())
+}
+
 func TestNewServerExtraTags(t *testing.T) {
 	cfg := make(map[string]interface{})
 
@@ -231,7 +273,7 @@ dogstatsd_mapper_profiles:
 `
 	testConfig := configmock.NewFromYAML(t, datadogYaml)
 
-	profiles, err := getDogstatsdMappingProfiles(testConfig)
+	profiles, err := mapper.GetProfiles(testConfig)
 	require.NoError(t, err)
 
 	expectedProfiles := []mapper.MappingProfileConfig{
@@ -273,7 +315,7 @@ dogstatsd_mapper_profiles:
 `
 	testConfig := configmock.NewFromYAML(t, datadogYaml)
 
-	profiles, err := getDogstatsdMappingProfiles(testConfig)
+	profiles, err := mapper.GetProfiles(testConfig)
 
 	var expectedProfiles []mapper.MappingProfileConfig
 
@@ -288,7 +330,7 @@ dogstatsd_mapper_profiles:
 `
 	testConfig := configmock.NewFromYAML(t, datadogYaml)
 
-	profiles, err := getDogstatsdMappingProfiles(testConfig)
+	profiles, err := mapper.GetProfiles(testConfig)
 
 	expectedErrorMsg := "Could not parse dogstatsd_mapper_profiles"
 	assert.NotNil(t, err)
@@ -316,6 +358,6 @@ func TestDogstatsdMappingProfilesEnv(t *testing.T) {
 		}},
 	}
 	cfg := configmock.New(t)
-	mappings, _ := getDogstatsdMappingProfiles(cfg)
+	mappings, _ := mapper.GetProfiles(cfg)
 	assert.Equal(t, expected, mappings)
 }
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
@@ -54,3 +55,14 @@ func (s *server) writeStats(w http.ResponseWriter, _ *http.Request) {
 
 	w.Write(jsonStats)
 }
+
+// writeContextLimits writes the metric names and origins which exceeded their context budget.
+func (s *server) writeContextLimits(w http.ResponseWriter, _ *http.Request) {
+	body, err := json.Marshal(aggregator.GetDogstatsdContextLimitsStats())
+	if err != nil {
+		httputils.SetJSONError(w, s.log.Errorf("Error getting marshalled Dogstatsd context limits: %s", err), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
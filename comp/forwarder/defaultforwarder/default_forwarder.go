@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/archive"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
 	pkgresolver "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
@@ -106,6 +107,7 @@ type Options struct {
 	DomainResolvers                map[string]pkgresolver.DomainResolver
 	ConnectionResetInterval        time.Duration
 	CompletionHandler              transaction.HTTPCompletionHandler
+	RoutingRules                   *pkgresolver.RoutingRules
 }
 
 // SetFeature sets forwarder features in a feature set
@@ -244,6 +246,7 @@ type DefaultForwarder struct {
 	m                sync.Mutex // To control Start/Stop races
 
 	completionHandler transaction.HTTPCompletionHandler
+	routingRules      *pkgresolver.RoutingRules
 
 	agentName                       string
 	queueDurationCapacity           *retry.QueueDurationCapacity
@@ -269,6 +272,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 			validationInterval:    options.APIKeyValidationInterval,
 		},
 		completionHandler: options.CompletionHandler,
+		routingRules:      options.RoutingRules,
 		agentName:         agentName,
 		localForwarder:    nil,
 	}
@@ -306,6 +310,19 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
 	}
 
+	archiveMaxSize := config.GetInt64("forwarder_archive_max_size_in_bytes")
+	var archivePath string
+
+	// The archive of the transactions sent is a core-only feature.
+	if archiveMaxSize > 0 {
+		if agentName != "" {
+			archivePath = getArchivePath(config, agentName)
+			log.Infof("Archiving the transactions sent in %s", archivePath)
+		} else {
+			log.Infof("The archive of the transactions sent is disabled because the feature is unavailable for this process.")
+		}
+	}
+
 	flushToDiskMemRatio := config.GetFloat64("forwarder_flush_to_disk_mem_ratio")
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
@@ -324,6 +341,8 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 			}
 
 		}
+		// The archive folder is named after the configured domain, it doesn't change when the agent is upgraded.
+		archiveFolder := archive.DomainFolder(archivePath, domain)
 		domain, _ := utils.AddAgentVersionToDomain(domain, "app")
 		resolver.SetBaseDomain(domain)
 
@@ -366,6 +385,12 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				options.ConnectionResetInterval,
 				domainForwarderSort,
 				pointCountTelemetry)
+			if archivePath != "" && !isLocal {
+				fwd.archive, err = archive.NewArchive(log, resolver, archiveFolder, archiveMaxSize)
+				if err != nil {
+					log.Errorf("Archive of the transactions sent disabled. Cannot create the archive of the domain '%v': %v", domain, err)
+				}
+			}
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
@@ -394,6 +419,15 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	return f
 }
 
+// getArchivePath returns the folder of the archive of the transactions sent by an agent.
+func getArchivePath(config config.Component, agentName string) string {
+	archivePath := config.GetString("forwarder_archive_path")
+	if archivePath == "" {
+		archivePath = path.Join(config.GetString("run_path"), "transactions_archive")
+	}
+	return path.Join(archivePath, agentName)
+}
+
 func getAgentName(options *Options) string {
 	if HasFeature(options.EnabledFeatures, CoreFeatures) {
 		return "core"
@@ -524,6 +558,9 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				}
 			} else {
 				for _, apiKey := range dr.GetAPIKeys() {
+					if !f.routingRules.Allows(payload.RoutingRule, endpoint, kind, domain, apiKey) {
+						continue
+					}
 					t := transaction.NewHTTPTransaction()
 					t.Domain = drDomain
 					t.Endpoint = endpoint
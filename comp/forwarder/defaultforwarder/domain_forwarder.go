@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/archive"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -51,6 +52,7 @@ type domainForwarder struct {
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
 	pointCountTelemetry       *retry.PointCountTelemetry
+	archive                   *archive.Archive
 }
 
 func newDomainForwarder(
@@ -218,6 +220,7 @@ func (f *domainForwarder) Start() error {
 
 	for i := 0; i < f.numberOfWorkers; i++ {
 		w := NewWorker(f.config, f.log, f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList, f.pointCountTelemetry, f.Client)
+		w.archive = f.archive
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
@@ -299,6 +302,9 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 		w.Stop(purgeHighPrio)
 	}
 	f.workers = []*Worker{}
+	if f.archive != nil {
+		f.archive.Close()
+	}
 	close(f.highPrio)
 	close(f.lowPrio)
 	close(f.requeuedTransaction)
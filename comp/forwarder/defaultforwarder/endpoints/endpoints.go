@@ -55,3 +55,28 @@ var (
 	// OrchestratorManifestEndpoint is a v2 endpoint used to send orchestrator manifests
 	OrchestratorManifestEndpoint = transaction.Endpoint{Route: "/api/v2/orchmanif", Name: "orchmanifest"}
 )
+
+// All is the list of the endpoints above
+var All = []transaction.Endpoint{
+	V1SeriesEndpoint,
+	V1CheckRunsEndpoint,
+	V1IntakeEndpoint,
+	V1SketchSeriesEndpoint,
+	V1ValidateEndpoint,
+	V1MetadataEndpoint,
+	SeriesEndpoint,
+	EventsEndpoint,
+	ServiceChecksEndpoint,
+	SketchSeriesEndpoint,
+	HostMetadataEndpoint,
+	ProcessesEndpoint,
+	ProcessDiscoveryEndpoint,
+	ProcessLifecycleEndpoint,
+	RtProcessesEndpoint,
+	ContainerEndpoint,
+	RtContainerEndpoint,
+	ConnectionsEndpoint,
+	LegacyOrchestratorEndpoint,
+	OrchestratorEndpoint,
+	OrchestratorManifestEndpoint,
+}
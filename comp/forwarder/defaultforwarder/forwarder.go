@@ -78,6 +78,15 @@ func createOptions(params Params, config config.Component, log log.Component) (*
 	}
 	options.SetEnabledFeatures(params.features)
 
+	options.RoutingRules, err = resolver.LoadRoutingRules(config)
+	if err == nil {
+		err = options.RoutingRules.ValidateDestinations(options.DomainResolvers)
+	}
+	if err != nil {
+		log.Error("Misconfiguration of the forwarder routing rules: ", err)
+		return nil, fmt.Errorf("Misconfiguration of the forwarder routing rules: %s", err)
+	}
+
 	log.Infof("starting forwarder with %d endpoints", len(options.DomainResolvers))
 	for _, resolver := range options.DomainResolvers {
 		scrubbedKeys := []string{}
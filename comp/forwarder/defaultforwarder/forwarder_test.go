@@ -247,6 +247,60 @@ func TestCreateHTTPTransactionsWithMultipleDomains(t *testing.T) {
 	assert.Equal(t, txBar[0].Headers.Get("DD-Api-Key"), "api-key-3")
 }
 
+func TestCreateHTTPTransactionsWithRoutingRules(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("dd_url", testDomain)
+	mockConfig.SetWithoutSource("api_key", "api-key-1")
+	log := logmock.New(t)
+	r, err := resolver.NewSingleDomainResolvers(keysWithMultipleDomains)
+	require.NoError(t, err)
+	options := NewOptionsWithResolvers(mockConfig, log, r)
+	options.RoutingRules, err = resolver.NewRoutingRules(mockConfig, []resolver.RoutingRuleConfig{
+		{Name: "bar", Metrics: []string{"bar\\..*"}, Destinations: []resolver.RoutingDestinationConfig{{Domain: "datadog.bar"}}},
+		{Name: "primary", Kinds: []string{"events"}, Destinations: []resolver.RoutingDestinationConfig{{Domain: resolver.PrimaryDomain}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, options.RoutingRules.ValidateDestinations(options.DomainResolvers))
+	forwarder := NewDefaultForwarder(mockConfig, log, options)
+
+	payload := transaction.NewBytesPayloadWithoutMetaData([]byte("A payload"))
+	barPayload := transaction.NewBytesPayloadWithoutMetaData([]byte("A bar payload"))
+	barPayload.RoutingRule = "bar"
+
+	destinations := func(transactions []*transaction.HTTPTransaction) []string {
+		var destinations []string
+		for _, t := range transactions {
+			destinations = append(destinations, t.Domain+" "+t.Headers.Get("DD-Api-Key"))
+		}
+		return destinations
+	}
+
+	transactions := forwarder.createHTTPTransactions(endpoints.SeriesEndpoint, transaction.BytesPayloads{payload, barPayload}, transaction.Series, nil)
+	assert.ElementsMatch(t, []string{
+		testVersionDomain + " api-key-1",
+		testVersionDomain + " api-key-2",
+		"datadog.bar api-key-3",
+		"datadog.bar api-key-3",
+	}, destinations(transactions))
+
+	transactions = forwarder.createHTTPTransactions(endpoints.V1IntakeEndpoint, transaction.BytesPayloads{payload}, transaction.Events, nil)
+	assert.Equal(t, []string{testVersionDomain + " api-key-1"}, destinations(transactions))
+
+	transactions = forwarder.createHTTPTransactions(endpoints.V1IntakeEndpoint, transaction.BytesPayloads{payload}, transaction.CheckRuns, nil)
+	assert.Len(t, transactions, 3)
+}
+
+func TestCreateOptionsWithInvalidRoutingRules(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("dd_url", testDomain)
+	mockConfig.SetWithoutSource("api_key", "api-key-1")
+	mockConfig.SetWithoutSource(resolver.RoutingRulesSetting, []map[string]interface{}{
+		{"name": "sandbox", "destinations": []map[string]interface{}{{"domain": "https://sandbox.example.com"}}},
+	})
+	_, err := createOptions(NewParams(), mockConfig, logmock.New(t))
+	assert.ErrorContains(t, err, "routing rule sandbox")
+}
+
 func TestCreateHTTPTransactionsWithDifferentResolvers(t *testing.T) {
 	resolvers, err := resolver.NewSingleDomainResolvers(keysWithMultipleDomains)
 	require.NoError(t, err)
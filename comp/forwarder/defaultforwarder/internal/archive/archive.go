@@ -0,0 +1,284 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package archive stores the transactions successfully sent by the forwarder
+// to a size-bounded local directory, to audit and replay them.
+package archive
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+)
+
+const archiveExtension = ".archive"
+
+// The files are sorted by name: the creation time is written with a fixed width.
+const archiveFileFormat = "2006_01_02__15_04_05.000000000_"
+
+// Each domain archive is split in archiveFilesCount files at most, the oldest
+// file is removed when the archive is full.
+const archiveFilesCount = 10
+
+// The size of the record header holding the size of a serialized transaction.
+const recordHeaderSize = 4
+
+// Archive appends the transactions sent to a domain to the files of a folder.
+// Each record of a file is a transaction serialized like the ones of the retry
+// queue (the API keys are replaced by placeholders), prefixed by its size.
+type Archive struct {
+	log            log.Component
+	serializer     *retry.HTTPTransactionsSerializer
+	storagePath    string
+	maxSizeInBytes int64
+	maxFileSize    int64
+
+	m                  sync.Mutex
+	file               *os.File
+	fileSize           int64
+	filenames          []string
+	currentSizeInBytes int64
+}
+
+// DomainFolder returns the folder of the archive of a domain. The domain is the
+// configured one, without the agent version, to keep the same folder across
+// upgrades.
+func DomainFolder(storagePath string, domain string) string {
+	folder := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://"))
+	return path.Join(storagePath, folder)
+}
+
+// NewArchive creates the archive of a domain in storagePath. The files already
+// in storagePath are kept and count towards maxSizeInBytes.
+func NewArchive(log log.Component, resolver resolver.DomainResolver, storagePath string, maxSizeInBytes int64) (*Archive, error) {
+	if maxSizeInBytes <= 0 {
+		return nil, fmt.Errorf("invalid maximum size for the archive: %d", maxSizeInBytes)
+	}
+	if err := os.MkdirAll(storagePath, 0700); err != nil {
+		return nil, err
+	}
+
+	a := &Archive{
+		log:            log,
+		serializer:     retry.NewHTTPTransactionsSerializer(log, resolver),
+		storagePath:    storagePath,
+		maxSizeInBytes: maxSizeInBytes,
+		maxFileSize:    max(maxSizeInBytes/archiveFilesCount, 1),
+	}
+
+	files, err := getArchiveFiles(storagePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		a.filenames = append(a.filenames, path.Join(storagePath, file.Name()))
+		a.currentSizeInBytes += file.Size()
+	}
+	return a, nil
+}
+
+// Add appends a transaction to the archive, removing the oldest files when
+// the archive is full.
+func (a *Archive) Add(t *transaction.HTTPTransaction) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	// Reset the serializer in case a transaction was serialized
+	// but `GetBytesAndReset` was not called because of an error.
+	_, _ = a.serializer.GetBytesAndReset()
+	if err := a.serializer.Add(t); err != nil {
+		return err
+	}
+	bytes, err := a.serializer.GetBytesAndReset()
+	if err != nil {
+		return err
+	}
+	recordSize := int64(recordHeaderSize + len(bytes))
+	if recordSize > a.maxSizeInBytes {
+		return fmt.Errorf("the transaction is too big to be archived. Current:%v Maximum:%v", recordSize, a.maxSizeInBytes)
+	}
+
+	if a.file != nil && a.fileSize+recordSize > a.maxFileSize {
+		a.closeFile()
+	}
+	a.makeRoomFor(recordSize)
+	if a.file == nil {
+		if err := a.createFile(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, recordSize)
+	binary.LittleEndian.PutUint32(record, uint32(len(bytes)))
+	copy(record[recordHeaderSize:], bytes)
+	n, err := a.file.Write(record)
+	a.fileSize += int64(n)
+	a.currentSizeInBytes += int64(n)
+	if err != nil {
+		// A partial record can't be read: start a new file.
+		a.closeFile()
+		return err
+	}
+	return nil
+}
+
+// Close closes the file being written, the next transaction added is written to a new file.
+func (a *Archive) Close() {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.closeFile()
+}
+
+// GetDiskSpaceUsed returns the current disk space used.
+func (a *Archive) GetDiskSpaceUsed() int64 {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.currentSizeInBytes
+}
+
+func (a *Archive) createFile() error {
+	filename := time.Now().UTC().Format(archiveFileFormat)
+	file, err := os.CreateTemp(a.storagePath, filename+"*"+archiveExtension)
+	if err != nil {
+		return err
+	}
+	a.file = file
+	a.fileSize = 0
+	a.filenames = append(a.filenames, file.Name())
+	return nil
+}
+
+func (a *Archive) closeFile() {
+	if a.file == nil {
+		return
+	}
+	if err := a.file.Close(); err != nil {
+		a.log.Errorf("Error when closing the archive file %s: %v", a.file.Name(), err)
+	}
+	a.file = nil
+	a.fileSize = 0
+}
+
+func (a *Archive) makeRoomFor(recordSize int64) {
+	for len(a.filenames) > 0 && a.currentSizeInBytes+recordSize > a.maxSizeInBytes {
+		filename := a.filenames[0]
+		if a.file != nil && a.file.Name() == filename {
+			a.closeFile()
+		}
+		a.filenames = slices.Delete(a.filenames, 0, 1)
+
+		info, err := os.Stat(filename)
+		if err != nil {
+			a.log.Errorf("Cannot get the size of the archive file %s: %v", filename, err)
+			continue
+		}
+		if err := os.Remove(filename); err != nil {
+			a.log.Errorf("Cannot remove the archive file %s: %v", filename, err)
+			continue
+		}
+		a.log.Debugf("Maximum disk space for the archive is reached. Removed %s", filename)
+		a.currentSizeInBytes -= info.Size()
+	}
+}
+
+// Read calls fn for each archived transaction of the folder storagePath created
+// between from and to (inclusive), from the oldest file to the newest. The API
+// keys of the transactions are restored from the resolver. It returns the
+// number of transactions which can't be read.
+func Read(log log.Component, resolver resolver.DomainResolver, storagePath string, from time.Time, to time.Time, fn func(*transaction.HTTPTransaction) error) (int, error) {
+	files, err := getArchiveFiles(storagePath)
+	if err != nil {
+		return 0, err
+	}
+
+	// The creation time of the archived transactions is stored in seconds.
+	from = from.Truncate(time.Second)
+	serializer := retry.NewHTTPTransactionsSerializer(log, resolver)
+	errorCount := 0
+	for _, file := range files {
+		// The transactions of a file were all created before its last modification.
+		if file.ModTime().Before(from) {
+			continue
+		}
+		filename := path.Join(storagePath, file.Name())
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return errorCount, err
+		}
+
+		for len(content) > 0 {
+			if len(content) < recordHeaderSize {
+				log.Warnf("Truncated record at the end of the archive file %s", filename)
+				break
+			}
+			size := int(binary.LittleEndian.Uint32(content))
+			content = content[recordHeaderSize:]
+			if len(content) < size {
+				log.Warnf("Truncated record at the end of the archive file %s", filename)
+				break
+			}
+			record := content[:size]
+			content = content[size:]
+
+			transactions, count, err := serializer.Deserialize(record)
+			errorCount += count
+			if err != nil {
+				log.Errorf("Cannot deserialize a record of the archive file %s: %v", filename, err)
+				errorCount++
+				continue
+			}
+			for _, t := range transactions {
+				tr, ok := t.(*transaction.HTTPTransaction)
+				if !ok || tr.CreatedAt.Before(from) || (!to.IsZero() && tr.CreatedAt.After(to)) {
+					continue
+				}
+				if err := fn(tr); err != nil {
+					return errorCount, err
+				}
+			}
+		}
+	}
+	return errorCount, nil
+}
+
+// getArchiveFiles returns the archive files of storagePath, sorted from the oldest to the newest.
+func getArchiveFiles(storagePath string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(storagePath)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.Mode().IsRegular() && filepath.Ext(entry.Name()) == archiveExtension {
+			files = append(files, info)
+		}
+	}
+	// The names start with the creation time, unlike the modification time it is kept when the files are copied.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files, nil
+}
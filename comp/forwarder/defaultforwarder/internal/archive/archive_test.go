@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package archive
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+const domain = "https://7-55-0-app.agent.datadoghq.com"
+const apiKey = "0123456789abcdef0123456789abcdef"
+
+func newTestResolver(t *testing.T) resolver.DomainResolver {
+	r, err := resolver.NewSingleDomainResolver(domain, []utils.APIKeys{utils.NewAPIKeys("api_key", apiKey)})
+	require.NoError(t, err)
+	return r
+}
+
+func newTestTransaction(payload string, createdAt time.Time) *transaction.HTTPTransaction {
+	tr := transaction.NewHTTPTransaction()
+	tr.Domain = domain
+	tr.Endpoint = transaction.Endpoint{Route: "/api/v2/series", Name: "series_v2"}
+	tr.Headers = http.Header{"Dd-Api-Key": []string{apiKey}, "Content-Encoding": []string{"zstd"}}
+	tr.Payload = transaction.NewBytesPayloadWithoutMetaData([]byte(payload))
+	tr.CreatedAt = createdAt
+	return tr
+}
+
+func readAll(t *testing.T, r resolver.DomainResolver, storagePath string, from, to time.Time) []*transaction.HTTPTransaction {
+	var transactions []*transaction.HTTPTransaction
+	errorCount, err := Read(logmock.New(t), r, storagePath, from, to, func(tr *transaction.HTTPTransaction) error {
+		transactions = append(transactions, tr)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, errorCount)
+	return transactions
+}
+
+func TestDomainFolder(t *testing.T) {
+	assert.Equal(t, "/archive/app.datadoghq.eu", DomainFolder("/archive", "https://app.datadoghq.eu"))
+	assert.Equal(t, "/archive/localhost_8080_intake", DomainFolder("/archive", "http://localhost:8080/intake"))
+}
+
+func TestArchiveAddRead(t *testing.T) {
+	r := newTestResolver(t)
+	storagePath := filepath.Join(t.TempDir(), "domain")
+	a, err := NewArchive(logmock.New(t), r, storagePath, 1024*1024)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, a.Add(newTestTransaction("payload1", now.Add(-time.Hour))))
+	require.NoError(t, a.Add(newTestTransaction("payload2", now)))
+	a.Close()
+	require.NoError(t, a.Add(newTestTransaction("payload3", now)))
+	a.Close()
+
+	files, err := os.ReadDir(storagePath)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(storagePath, file.Name()))
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(content, []byte(apiKey)), "the API key must not be archived")
+	}
+
+	transactions := readAll(t, r, storagePath, time.Time{}, time.Time{})
+	require.Len(t, transactions, 3)
+	for i, tr := range transactions {
+		assert.Equal(t, domain, tr.Domain)
+		assert.Equal(t, "/api/v2/series", tr.Endpoint.Route)
+		assert.Equal(t, []string{apiKey}, tr.Headers["Dd-Api-Key"])
+		assert.Equal(t, []string{"zstd"}, tr.Headers["Content-Encoding"])
+		assert.Equal(t, []byte("payload"+string(rune('1'+i))), tr.Payload.GetContent())
+	}
+
+	transactions = readAll(t, r, storagePath, now.Add(-time.Minute), time.Time{})
+	require.Len(t, transactions, 2)
+	assert.Equal(t, []byte("payload2"), transactions[0].Payload.GetContent())
+
+	transactions = readAll(t, r, storagePath, time.Time{}, now.Add(-time.Minute))
+	require.Len(t, transactions, 1)
+	assert.Equal(t, []byte("payload1"), transactions[0].Payload.GetContent())
+}
+
+func TestArchiveMaxSize(t *testing.T) {
+	r := newTestResolver(t)
+	storagePath := t.TempDir()
+	payload := string(bytes.Repeat([]byte{'a'}, 100))
+
+	a, err := NewArchive(logmock.New(t), r, storagePath, 20000)
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, a.Add(newTestTransaction(payload, time.Now())))
+	}
+	assert.LessOrEqual(t, a.GetDiskSpaceUsed(), int64(20000))
+	assert.Greater(t, a.GetDiskSpaceUsed(), int64(15000))
+	files, err := os.ReadDir(storagePath)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(files), archiveFilesCount+1)
+	archived := len(readAll(t, r, storagePath, time.Time{}, time.Time{}))
+	a.Close()
+
+	// the existing files are reloaded
+	a, err = NewArchive(logmock.New(t), r, storagePath, 10000)
+	require.NoError(t, err)
+	require.NoError(t, a.Add(newTestTransaction(payload, time.Now())))
+	assert.LessOrEqual(t, a.GetDiskSpaceUsed(), int64(10000))
+	assert.Less(t, len(readAll(t, r, storagePath, time.Time{}, time.Time{})), archived)
+
+	assert.Error(t, a.Add(newTestTransaction(string(bytes.Repeat([]byte{'a'}, 10000)), time.Now())))
+}
+
+func TestArchiveTruncatedRecord(t *testing.T) {
+	r := newTestResolver(t)
+	storagePath := t.TempDir()
+	a, err := NewArchive(logmock.New(t), r, storagePath, 1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, a.Add(newTestTransaction("payload1", time.Now())))
+	require.NoError(t, a.Add(newTestTransaction("payload2", time.Now())))
+	a.Close()
+
+	files, err := os.ReadDir(storagePath)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	filename := filepath.Join(storagePath, files[0].Name())
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(filename, info.Size()-3))
+
+	transactions := readAll(t, r, storagePath, time.Time{}, time.Time{})
+	require.Len(t, transactions, 1)
+	assert.Equal(t, []byte("payload1"), transactions[0].Payload.GetContent())
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/archive"
+	pkgresolver "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+// ReplayResult contains the outcome of the replay of the archive of a domain
+type ReplayResult struct {
+	Domain string
+	// Transactions is the number of archived transactions in the time range.
+	Transactions int
+	// Bytes is the size of the payloads of these transactions.
+	Bytes int
+	// Sent is the number of transactions accepted by the backend.
+	Sent int
+	// Failed is the number of transactions which were not accepted by the backend.
+	Failed int
+	// ReadErrors is the number of transactions which can't be read from the archive.
+	ReadErrors int
+}
+
+// ReplayArchive resubmits the transactions archived by the core agent (see
+// `forwarder_archive_max_size_in_bytes`) and created between from and to, to the
+// endpoints of the configuration. The API keys of the configuration are used.
+// When dryRun is true, the transactions are only counted.
+func ReplayArchive(ctx context.Context, config config.Component, log log.Component, from time.Time, to time.Time, dryRun bool) ([]ReplayResult, error) {
+	keysPerDomain, err := utils.GetMultipleEndpoints(config)
+	if err != nil {
+		return nil, fmt.Errorf("Misconfiguration of agent endpoints: %s", err)
+	}
+	resolvers, err := pkgresolver.NewSingleDomainResolvers(keysPerDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	archivePath := getArchivePath(config, getAgentName(&Options{EnabledFeatures: CoreFeatures}))
+	client := NewHTTPClient(config, 1, log)
+
+	domains := make([]string, 0, len(resolvers))
+	for domain := range resolvers {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	var results []ReplayResult
+	for _, domain := range domains {
+		folder := archive.DomainFolder(archivePath, domain)
+		if _, err := os.Stat(folder); os.IsNotExist(err) {
+			log.Infof("No archive for the domain '%s'", domain)
+			continue
+		}
+
+		resolver := resolvers[domain]
+		versionedDomain, _ := utils.AddAgentVersionToDomain(domain, "app")
+		resolver.SetBaseDomain(versionedDomain)
+
+		result := ReplayResult{Domain: domain}
+		result.ReadErrors, err = archive.Read(log, resolver, folder, from, to, func(t *transaction.HTTPTransaction) error {
+			result.Transactions++
+			result.Bytes += t.GetPayloadSize()
+			if dryRun {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// The replayed transactions are not retried, the failed ones can be replayed again.
+			t.Retryable = false
+			if err := t.Process(ctx, config, log, client); err != nil || !t.IsAccepted() {
+				result.Failed++
+			} else {
+				result.Sent++
+			}
+			return nil
+		})
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
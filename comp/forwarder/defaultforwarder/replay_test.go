@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package defaultforwarder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	configUtils "github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+type testIntake struct {
+	sync.Mutex
+	payloads []string
+}
+
+func (i *testIntake) get() []string {
+	i.Lock()
+	defer i.Unlock()
+	return append([]string{}, i.payloads...)
+}
+
+func newTestIntake(t *testing.T) (*testIntake, *httptest.Server) {
+	intake := &testIntake{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/validate" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		assert.Equal(t, "api_key1", r.Header.Get("DD-Api-Key"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if string(body) == "rejected" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		intake.Lock()
+		intake.payloads = append(intake.payloads, string(body))
+		intake.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+	return intake, ts
+}
+
+func TestArchiveAndReplay(t *testing.T) {
+	intake, ts := newTestIntake(t)
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("dd_url", ts.URL)
+	mockConfig.SetWithoutSource("api_key", "api_key1")
+	mockConfig.SetWithoutSource("run_path", t.TempDir())
+	mockConfig.SetWithoutSource("forwarder_archive_max_size_in_bytes", 1024*1024)
+	log := logmock.New(t)
+
+	options, err := NewOptions(mockConfig, log, map[string][]configUtils.APIKeys{ts.URL: {configUtils.NewAPIKeys("api_key", "api_key1")}})
+	require.NoError(t, err)
+	options.SetEnabledFeatures([]Features{CoreFeatures})
+	f := NewDefaultForwarder(mockConfig, log, options)
+	require.NoError(t, f.Start())
+
+	start := time.Now()
+	for _, data := range []string{"payload 1", "rejected", "payload 2"} {
+		content := []byte(data)
+		require.NoError(t, f.SubmitSeries(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&content}), http.Header{}))
+	}
+	require.Eventually(t, func() bool { return len(intake.get()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	f.Stop()
+
+	results, err := ReplayArchive(context.Background(), mockConfig, log, start.Add(time.Hour), time.Time{}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, ReplayResult{Domain: ts.URL}, results[0])
+
+	results, err = ReplayArchive(context.Background(), mockConfig, log, start, time.Time{}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []ReplayResult{{Domain: ts.URL, Transactions: 2, Bytes: 18}}, results)
+	assert.Len(t, intake.get(), 2)
+
+	results, err = ReplayArchive(context.Background(), mockConfig, log, start, time.Now(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []ReplayResult{{Domain: ts.URL, Transactions: 2, Bytes: 18, Sent: 2}}, results)
+	assert.ElementsMatch(t, []string{"payload 1", "payload 2", "payload 1", "payload 2"}, intake.get())
+}
+
+func TestArchiveDisabled(t *testing.T) {
+	_, ts := newTestIntake(t)
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("run_path", t.TempDir())
+	log := logmock.New(t)
+
+	options, err := NewOptions(mockConfig, log, map[string][]configUtils.APIKeys{ts.URL: {configUtils.NewAPIKeys("api_key", "api_key1")}})
+	require.NoError(t, err)
+	options.SetEnabledFeatures([]Features{CoreFeatures})
+	f := NewDefaultForwarder(mockConfig, log, options)
+	for _, fwd := range f.domainForwarders {
+		assert.Nil(t, fwd.archive)
+	}
+}
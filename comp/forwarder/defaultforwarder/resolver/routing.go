@@ -0,0 +1,355 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package resolver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+// RoutingRulesSetting is the setting of the routing rules of the forwarder
+const RoutingRulesSetting = "forwarder_routing_rules"
+
+// PrimaryDomain selects, in the destinations of a routing rule, the main endpoint of the
+// configuration (`dd_url` or `site`) and its API key (`api_key`).
+const PrimaryDomain = "primary"
+
+// RoutingRuleConfig represents one routing rule. A rule matches the payloads sent to its endpoints
+// and of its kinds (all of them when empty) and, when it has metric patterns, the series and
+// sketches whose name matches one of them. The payloads matched by a rule are only sent to its
+// destinations.
+type RoutingRuleConfig struct {
+	Name         string                     `mapstructure:"name" json:"name" yaml:"name"`
+	Endpoints    []string                   `mapstructure:"endpoints" json:"endpoints" yaml:"endpoints"`
+	Kinds        []string                   `mapstructure:"kinds" json:"kinds" yaml:"kinds"`
+	Metrics      []string                   `mapstructure:"metrics" json:"metrics" yaml:"metrics"`
+	Destinations []RoutingDestinationConfig `mapstructure:"destinations" json:"destinations" yaml:"destinations"`
+}
+
+// RoutingDestinationConfig selects the API keys of a domain, or one of them
+type RoutingDestinationConfig struct {
+	Domain string `mapstructure:"domain" json:"domain" yaml:"domain"`
+	APIKey string `mapstructure:"api_key" json:"api_key" yaml:"api_key"`
+}
+
+type routingDestination struct {
+	primary bool
+	// domain is the domain with the agent version, like the domains of the forwarder
+	domain string
+	apiKey string
+}
+
+type routingRule struct {
+	name         string
+	endpoints    map[string]struct{}
+	kinds        map[transaction.Kind]struct{}
+	metrics      []*regexp.Regexp
+	destinations []routingDestination
+}
+
+// RoutingRules selects the domains and the API keys each payload is sent to. A nil RoutingRules
+// sends every payload to every domain and API key.
+type RoutingRules struct {
+	config model.Reader
+	// rules without metric patterns, applied in order
+	endpointRules []routingRule
+	// rules with metric patterns, applied in order by the serializer
+	metricRules []routingRule
+}
+
+// metricEndpoints are the endpoints whose payloads can be split by metric name
+var metricEndpoints = map[string]struct{}{
+	endpoints.SeriesEndpoint.Name:       {},
+	endpoints.SketchSeriesEndpoint.Name: {},
+}
+
+// routableEndpoints are the endpoints the forwarder sends payloads to. The events and the host
+// metadata are sent to the `intake` endpoint, their rules select them with their kind.
+var routableEndpoints = []transaction.Endpoint{
+	endpoints.V1SeriesEndpoint,
+	endpoints.V1CheckRunsEndpoint,
+	endpoints.V1IntakeEndpoint,
+	endpoints.V1MetadataEndpoint,
+	endpoints.SeriesEndpoint,
+	endpoints.SketchSeriesEndpoint,
+	endpoints.ProcessesEndpoint,
+	endpoints.ProcessDiscoveryEndpoint,
+	endpoints.ProcessLifecycleEndpoint,
+	endpoints.RtProcessesEndpoint,
+	endpoints.ContainerEndpoint,
+	endpoints.RtContainerEndpoint,
+	endpoints.ConnectionsEndpoint,
+	endpoints.OrchestratorEndpoint,
+	endpoints.OrchestratorManifestEndpoint,
+}
+
+// kindNames are the names of the transaction kinds in the routing rules
+var kindNames = map[string]transaction.Kind{
+	"series":         transaction.Series,
+	"sketches":       transaction.Sketches,
+	"service_checks": transaction.ServiceChecks,
+	"events":         transaction.Events,
+	"check_runs":     transaction.CheckRuns,
+	"metadata":       transaction.Metadata,
+	"process":        transaction.Process,
+}
+
+// GetRoutingRuleConfigs returns the routing rules of the `forwarder_routing_rules` setting
+func GetRoutingRuleConfigs(cfg model.Reader) ([]RoutingRuleConfig, error) {
+	var configs []RoutingRuleConfig
+	if cfg.IsSet(RoutingRulesSetting) {
+		err := structure.UnmarshalKey(cfg, RoutingRulesSetting, &configs)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse %s: %v", RoutingRulesSetting, err)
+		}
+	}
+	return configs, nil
+}
+
+// LoadRoutingRules creates and validates the routing rules of the configuration. It returns nil
+// when no rule is configured.
+func LoadRoutingRules(cfg model.Reader) (*RoutingRules, error) {
+	configs, err := GetRoutingRuleConfigs(cfg)
+	if err != nil || len(configs) == 0 {
+		return nil, err
+	}
+	return NewRoutingRules(cfg, configs)
+}
+
+// NewRoutingRules creates and validates routing rules. The destinations are checked against the
+// configured domains with ValidateDestinations.
+func NewRoutingRules(cfg model.Reader, configs []RoutingRuleConfig) (*RoutingRules, error) {
+	knownEndpoints := map[string]struct{}{}
+	for _, endpoint := range routableEndpoints {
+		knownEndpoints[endpoint.Name] = struct{}{}
+	}
+
+	r := &RoutingRules{config: cfg}
+	names := map[string]struct{}{}
+	for i, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("routing rule num %d: a name is required", i)
+		}
+		if _, found := names[config.Name]; found {
+			return nil, fmt.Errorf("routing rule %s: the name is already used by another rule", config.Name)
+		}
+		names[config.Name] = struct{}{}
+
+		rule := routingRule{name: config.Name, endpoints: map[string]struct{}{}, kinds: map[transaction.Kind]struct{}{}}
+		for _, endpoint := range config.Endpoints {
+			if _, found := knownEndpoints[endpoint]; !found {
+				return nil, fmt.Errorf("routing rule %s: unknown endpoint `%s`, or the forwarder doesn't send payloads to it", config.Name, endpoint)
+			}
+			if _, found := metricEndpoints[endpoint]; len(config.Metrics) > 0 && !found {
+				return nil, fmt.Errorf("routing rule %s: the metric patterns only apply to the `%s` and `%s` endpoints, not to `%s`",
+					config.Name, endpoints.SeriesEndpoint.Name, endpoints.SketchSeriesEndpoint.Name, endpoint)
+			}
+			rule.endpoints[endpoint] = struct{}{}
+		}
+		for _, name := range config.Kinds {
+			kind, found := kindNames[name]
+			if !found {
+				return nil, fmt.Errorf("routing rule %s: unknown kind `%s`", config.Name, name)
+			}
+			if len(config.Metrics) > 0 {
+				return nil, fmt.Errorf("routing rule %s: the kinds can't be combined with metric patterns", config.Name)
+			}
+			rule.kinds[kind] = struct{}{}
+		}
+		for _, pattern := range config.Metrics {
+			regex, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("routing rule %s: invalid metric pattern `%s`: %v", config.Name, pattern, err)
+			}
+			rule.metrics = append(rule.metrics, regex)
+		}
+
+		if len(config.Destinations) == 0 {
+			return nil, fmt.Errorf("routing rule %s: at least one destination is required", config.Name)
+		}
+		for _, destination := range config.Destinations {
+			apiKey := strings.TrimSpace(destination.APIKey)
+			switch destination.Domain {
+			case "":
+				return nil, fmt.Errorf("routing rule %s: the domain of a destination is required, use `%s` for the main endpoint", config.Name, PrimaryDomain)
+			case PrimaryDomain:
+				if apiKey != "" {
+					return nil, fmt.Errorf("routing rule %s: the API key of the `%s` destination is the `api_key` setting and can't be set", config.Name, PrimaryDomain)
+				}
+				rule.destinations = append(rule.destinations, routingDestination{primary: true})
+			default:
+				domain, err := utils.AddAgentVersionToDomain(destination.Domain, "app")
+				if err != nil {
+					return nil, fmt.Errorf("routing rule %s: invalid domain `%s`: %v", config.Name, destination.Domain, err)
+				}
+				rule.destinations = append(rule.destinations, routingDestination{domain: domain, apiKey: apiKey})
+			}
+		}
+
+		if len(rule.metrics) > 0 {
+			if len(rule.endpoints) == 0 {
+				rule.endpoints = metricEndpoints
+			}
+			r.metricRules = append(r.metricRules, rule)
+		} else {
+			r.endpointRules = append(r.endpointRules, rule)
+		}
+	}
+	return r, nil
+}
+
+// ValidateDestinations checks that the destinations of the rules are configured endpoints. The
+// resolvers are indexed by domain, without the agent version.
+func (r *RoutingRules) ValidateDestinations(resolvers map[string]DomainResolver) error {
+	if r == nil {
+		return nil
+	}
+	versionedResolvers := make(map[string]DomainResolver, len(resolvers))
+	for domain, resolver := range resolvers {
+		versionedDomain, _ := utils.AddAgentVersionToDomain(domain, "app")
+		versionedResolvers[versionedDomain] = resolver
+	}
+
+	for _, rule := range append(append([]routingRule{}, r.endpointRules...), r.metricRules...) {
+		for _, destination := range rule.destinations {
+			if destination.primary {
+				continue
+			}
+			resolver, found := versionedResolvers[destination.domain]
+			if !found {
+				return fmt.Errorf("routing rule %s: the domain %s is not a configured endpoint", rule.name, destination.domain)
+			}
+			if destination.apiKey != "" && !containsAPIKey(resolver.GetAPIKeys(), destination.apiKey) {
+				return fmt.Errorf("routing rule %s: the API key ending with %s is not configured for the domain %s",
+					rule.name, lastChars(destination.apiKey, 5), destination.domain)
+			}
+		}
+	}
+	return nil
+}
+
+// HasMetricRules returns whether some rules split the payloads of an endpoint by metric name
+func (r *RoutingRules) HasMetricRules(endpoint transaction.Endpoint) bool {
+	if r == nil {
+		return false
+	}
+	for _, rule := range r.metricRules {
+		if rule.matchesEndpoint(endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricRoutingRule returns the name of the first rule matching a metric sent to an endpoint, or an
+// empty string when no rule with metric patterns matches it.
+func (r *RoutingRules) MetricRoutingRule(endpoint transaction.Endpoint, name string) string {
+	if r == nil {
+		return ""
+	}
+	for _, rule := range r.metricRules {
+		if !rule.matchesEndpoint(endpoint) {
+			continue
+		}
+		for _, regex := range rule.metrics {
+			if regex.MatchString(name) {
+				return rule.name
+			}
+		}
+	}
+	return ""
+}
+
+// Allows returns whether a payload is sent to a domain (with the agent version) with an API key.
+// The payload is routed by its metric routing rule if it has one, or by the first rule matching
+// its endpoint and kind. The payloads matching no rule are sent everywhere. The domain of the
+// multi-region failover stands in for the main endpoint and always receives the payloads.
+func (r *RoutingRules) Allows(payloadRoutingRule string, endpoint transaction.Endpoint, kind transaction.Kind, domain string, apiKey string) bool {
+	if r == nil || r.isFailoverDomain(domain) {
+		return true
+	}
+	if payloadRoutingRule != "" {
+		for _, rule := range r.metricRules {
+			if rule.name == payloadRoutingRule {
+				return r.matchesDestination(rule, domain, apiKey)
+			}
+		}
+	}
+	for _, rule := range r.endpointRules {
+		if rule.matchesEndpoint(endpoint) && rule.matchesKind(kind) {
+			return r.matchesDestination(rule, domain, apiKey)
+		}
+	}
+	return true
+}
+
+func (r *RoutingRules) matchesDestination(rule routingRule, domain string, apiKey string) bool {
+	for _, destination := range rule.destinations {
+		if destination.primary {
+			// read at each call, the main API key can be refreshed at runtime
+			primaryDomain, _ := utils.AddAgentVersionToDomain(utils.GetInfraEndpoint(r.config), "app")
+			if domain == primaryDomain && apiKey == strings.TrimSpace(r.config.GetString("api_key")) {
+				return true
+			}
+			continue
+		}
+		if destination.domain == domain && (destination.apiKey == "" || destination.apiKey == apiKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RoutingRules) isFailoverDomain(domain string) bool {
+	if !r.config.GetBool("multi_region_failover.enabled") {
+		return false
+	}
+	siteURL, err := utils.GetMRFInfraEndpoint(r.config)
+	if err != nil {
+		return false
+	}
+	failoverDomain, _ := utils.AddAgentVersionToDomain(siteURL, "app")
+	return domain == failoverDomain
+}
+
+func (rule *routingRule) matchesKind(kind transaction.Kind) bool {
+	if len(rule.kinds) == 0 {
+		return true
+	}
+	_, found := rule.kinds[kind]
+	return found
+}
+
+func (rule *routingRule) matchesEndpoint(endpoint transaction.Endpoint) bool {
+	if len(rule.endpoints) == 0 {
+		return true
+	}
+	_, found := rule.endpoints[endpoint.Name]
+	return found
+}
+
+func containsAPIKey(apiKeys []string, apiKey string) bool {
+	for _, key := range apiKeys {
+		if key == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+func lastChars(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
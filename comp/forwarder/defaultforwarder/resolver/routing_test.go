@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package resolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/config/utils"
+)
+
+func TestLoadRoutingRules(t *testing.T) {
+	mockConfig := configmock.New(t)
+	rules, err := LoadRoutingRules(mockConfig)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+	assert.True(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.Events, "https://example.com", "key1"))
+
+	mockConfig.SetWithoutSource(RoutingRulesSetting, []map[string]interface{}{
+		{
+			"name":         "sandbox",
+			"metrics":      []string{`sandbox\..*`},
+			"destinations": []map[string]interface{}{{"domain": "https://sandbox.example.com"}},
+		},
+	})
+	rules, err = LoadRoutingRules(mockConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox", rules.MetricRoutingRule(endpoints.SeriesEndpoint, "sandbox.requests"))
+}
+
+func TestNewRoutingRulesErrors(t *testing.T) {
+	destinations := []RoutingDestinationConfig{{Domain: PrimaryDomain}}
+	for name, configs := range map[string][]RoutingRuleConfig{
+		"missing name":         {{Destinations: destinations}},
+		"duplicated name":      {{Name: "a", Destinations: destinations}, {Name: "a", Destinations: destinations}},
+		"unknown endpoint":     {{Name: "a", Endpoints: []string{"unknown"}, Destinations: destinations}},
+		"unused endpoint":      {{Name: "a", Endpoints: []string{"events_v2"}, Destinations: destinations}},
+		"metrics of intake":    {{Name: "a", Endpoints: []string{"intake"}, Metrics: []string{"a"}, Destinations: destinations}},
+		"unknown kind":         {{Name: "a", Kinds: []string{"unknown"}, Destinations: destinations}},
+		"metrics with kinds":   {{Name: "a", Kinds: []string{"series"}, Metrics: []string{"a"}, Destinations: destinations}},
+		"invalid regex":        {{Name: "a", Metrics: []string{"("}, Destinations: destinations}},
+		"missing destinations": {{Name: "a"}},
+		"missing domain":       {{Name: "a", Destinations: []RoutingDestinationConfig{{APIKey: "key1"}}}},
+		"primary with api key": {{Name: "a", Destinations: []RoutingDestinationConfig{{Domain: PrimaryDomain, APIKey: "key1"}}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRoutingRules(configmock.New(t), configs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRoutingRulesValidateDestinations(t *testing.T) {
+	resolvers, err := NewSingleDomainResolvers(map[string][]utils.APIKeys{
+		"https://app.datadoghq.com": {utils.NewAPIKeys("api_key", "key1")},
+		"https://app.datadoghq.eu":  {utils.NewAPIKeys("additional_endpoints", "key2", "key3")},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		destination RoutingDestinationConfig
+		valid       bool
+	}{
+		{RoutingDestinationConfig{Domain: PrimaryDomain}, true},
+		{RoutingDestinationConfig{Domain: "https://app.datadoghq.eu"}, true},
+		{RoutingDestinationConfig{Domain: "https://app.datadoghq.eu", APIKey: "key3"}, true},
+		{RoutingDestinationConfig{Domain: "https://app.datadoghq.eu", APIKey: "key1"}, false},
+		{RoutingDestinationConfig{Domain: "https://app.datad0ghq.eu"}, false},
+	} {
+		rules, err := NewRoutingRules(configmock.New(t), []RoutingRuleConfig{{Name: "a", Destinations: []RoutingDestinationConfig{tc.destination}}})
+		require.NoError(t, err)
+		err = rules.ValidateDestinations(resolvers)
+		if tc.valid {
+			assert.NoError(t, err, tc.destination)
+		} else {
+			assert.Error(t, err, tc.destination)
+		}
+	}
+}
+
+func TestRoutingRulesAllows(t *testing.T) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("dd_url", "https://app.datadoghq.com")
+	mockConfig.SetWithoutSource("api_key", "key1")
+
+	rules, err := NewRoutingRules(mockConfig, []RoutingRuleConfig{
+		{
+			Name:         "sandbox",
+			Metrics:      []string{`sandbox\..*`},
+			Destinations: []RoutingDestinationConfig{{Domain: "https://app.datadoghq.eu", APIKey: "key3"}},
+		},
+		{
+			Name:         "primary-only",
+			Endpoints:    []string{"intake"},
+			Kinds:        []string{"events", "metadata"},
+			Destinations: []RoutingDestinationConfig{{Domain: PrimaryDomain}},
+		},
+	})
+	require.NoError(t, err)
+
+	primary, _ := utils.AddAgentVersionToDomain("https://app.datadoghq.com", "app")
+	secondary, _ := utils.AddAgentVersionToDomain("https://app.datadoghq.eu", "app")
+
+	assert.True(t, rules.HasMetricRules(endpoints.SeriesEndpoint))
+	assert.True(t, rules.HasMetricRules(endpoints.SketchSeriesEndpoint))
+	assert.False(t, rules.HasMetricRules(endpoints.V1IntakeEndpoint))
+	assert.Equal(t, "sandbox", rules.MetricRoutingRule(endpoints.SeriesEndpoint, "sandbox.requests"))
+	assert.Equal(t, "", rules.MetricRoutingRule(endpoints.SeriesEndpoint, "prod.sandbox.requests"))
+
+	// the series and sketches go everywhere, except those of the sandbox
+	assert.True(t, rules.Allows("", endpoints.SeriesEndpoint, transaction.Series, primary, "key1"))
+	assert.True(t, rules.Allows("", endpoints.SketchSeriesEndpoint, transaction.Sketches, secondary, "key2"))
+	assert.False(t, rules.Allows("sandbox", endpoints.SeriesEndpoint, transaction.Series, primary, "key1"))
+	assert.False(t, rules.Allows("sandbox", endpoints.SeriesEndpoint, transaction.Series, secondary, "key2"))
+	assert.True(t, rules.Allows("sandbox", endpoints.SeriesEndpoint, transaction.Series, secondary, "key3"))
+
+	// the events and host metadata sent to the intake only go to the primary
+	assert.True(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.Events, primary, "key1"))
+	assert.False(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.Events, secondary, "key2"))
+	assert.False(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.Metadata, primary, "key2"))
+	assert.True(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.CheckRuns, secondary, "key2"))
+	assert.True(t, rules.Allows("", endpoints.V1MetadataEndpoint, transaction.Metadata, secondary, "key2"))
+
+	// the primary API key is read when the payloads are routed
+	mockConfig.SetWithoutSource("api_key", "key2")
+	assert.True(t, rules.Allows("", endpoints.V1IntakeEndpoint, transaction.Metadata, primary, "key2"))
+}
+
+func TestRoutingRulesAllowFailoverDomain(t *testing.T) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("dd_url", "https://app.datadoghq.com")
+	mockConfig.SetWithoutSource("api_key", "key1")
+	mockConfig.SetWithoutSource("multi_region_failover.dd_url", "https://app.datadoghq.eu")
+
+	rules, err := NewRoutingRules(mockConfig, []RoutingRuleConfig{
+		{Name: "primary-only", Destinations: []RoutingDestinationConfig{{Domain: PrimaryDomain}}},
+	})
+	require.NoError(t, err)
+
+	failover, _ := utils.AddAgentVersionToDomain("https://app.datadoghq.eu", "app")
+	assert.False(t, rules.Allows("", endpoints.SeriesEndpoint, transaction.Series, failover, "key2"))
+
+	mockConfig.SetWithoutSource("multi_region_failover.enabled", true)
+	assert.True(t, rules.Allows("", endpoints.SeriesEndpoint, transaction.Series, failover, "key2"))
+}
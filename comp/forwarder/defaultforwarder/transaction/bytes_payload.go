@@ -11,6 +11,8 @@ type BytesPayload struct {
 	content     []byte
 	pointCount  int
 	Destination Destination
+	// RoutingRule is the name of the routing rule matching the metrics of the payload, if any
+	RoutingRule string
 }
 
 // NewBytesPayload creates a new instance of BytesPayload.
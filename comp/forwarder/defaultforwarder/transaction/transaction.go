@@ -255,6 +255,9 @@ type HTTPTransaction struct {
 	Kind Kind
 
 	Destination Destination
+
+	// statusCode is the HTTP status code of the last attempt to send the transaction (0 if no response was received).
+	statusCode int
 }
 
 // TransactionsSerializer serializes Transaction instances.
@@ -348,11 +351,17 @@ func (t *HTTPTransaction) GetDestination() Destination {
 	return t.Destination
 }
 
+// IsAccepted returns whether the payload was accepted by the backend on the last attempt to send the transaction.
+func (t *HTTPTransaction) IsAccepted() bool {
+	return t.statusCode > 0 && t.statusCode < 400
+}
+
 // Process sends the Payload of the transaction to the right Endpoint and Domain.
 func (t *HTTPTransaction) Process(ctx context.Context, config config.Component, log log.Component, client *http.Client) error {
 	t.AttemptHandler(t)
 
 	statusCode, body, err := t.internalProcess(ctx, config, log, client)
+	t.statusCode = statusCode
 
 	if err == nil || !t.Retryable {
 		t.CompletionHandler(t, statusCode, body, err)
@@ -15,6 +15,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/internal/archive"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 )
 
@@ -37,6 +38,8 @@ type Worker struct {
 	stopped               chan struct{}
 	blockedList           *blockedEndpoints
 	pointSuccessfullySent PointSuccessfullySent
+	// archive stores the transactions accepted by the backend, nil when the archive is disabled.
+	archive *archive.Archive
 
 	// The maximum number of HTTP requests we can have inflight at any one time.
 	maxConcurrentRequests *semaphore.Weighted
@@ -200,6 +203,19 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 	} else {
 		w.pointSuccessfullySent.OnPointSuccessfullySent(t.GetPointCount())
 		w.blockedList.recover(target)
+		w.archiveTransaction(t)
+	}
+}
+
+func (w *Worker) archiveTransaction(t transaction.Transaction) {
+	if w.archive == nil {
+		return
+	}
+	// Only the payloads accepted by the backend are archived, not the dropped ones.
+	if tr, ok := t.(*transaction.HTTPTransaction); ok && tr.IsAccepted() {
+		if err := w.archive.Add(tr); err != nil {
+			w.log.Errorf("Error while archiving transaction: %v", err)
+		}
 	}
 }
 
@@ -35,6 +35,8 @@ import (
 	"github.com/DataDog/datadog-agent/comp/metadata/inventoryagent"
 	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
 	logscompression "github.com/DataDog/datadog-agent/comp/serializer/logscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -42,6 +44,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
@@ -59,6 +62,7 @@ import (
 const (
 	// key used to display a warning message on the agent status
 	invalidProcessingRules = "invalid_global_processing_rules"
+	invalidMetricRules     = "invalid_global_metric_rules"
 	invalidEndpoints       = "invalid_endpoints"
 	intakeTrackType        = "logs"
 
@@ -67,6 +71,9 @@ const (
 
 	// inventory setting name
 	logsTransport = "logs_transport"
+
+	// logMetricsSenderID is the ID of the sender submitting the metrics generated from logs
+	logMetricsSenderID checkid.ID = "logs_agent_metric_rules"
 )
 
 // Module defines the fx options for this component.
@@ -87,6 +94,8 @@ type dependencies struct {
 	SchedulerProviders []schedulers.Scheduler `group:"log-agent-scheduler"`
 	Tagger             tagger.Component
 	Compression        logscompression.Component
+	// SenderManager is used to submit the metrics generated from logs, if available.
+	SenderManager sender.SenderManager `optional:"true"`
 }
 
 type provides struct {
@@ -126,6 +135,8 @@ type logAgent struct {
 	schedulerProviders        []schedulers.Scheduler
 	integrationsLogs          integrations.Component
 	compression               logscompression.Component
+	senderManager             sender.SenderManager
+	logMetrics                *processor.LogMetrics
 
 	// make sure this is done only once, when we're ready
 	prepareSchedulers sync.Once
@@ -158,6 +169,7 @@ func newLogsAgent(deps dependencies) provides {
 			integrationsLogs:   integrationsLogs,
 			tagger:             deps.Tagger,
 			compression:        deps.Compression,
+			senderManager:      deps.SenderManager,
 		}
 		deps.Lc.Append(fx.Hook{
 			OnStart: logsAgent.start,
@@ -244,6 +256,13 @@ func (a *logAgent) setupAgent() error {
 		status.AddGlobalWarning(invalidProcessingRules, multiLineWarning)
 	}
 
+	// setup the metrics generated from logs
+	if err := a.setupLogMetrics(); err != nil {
+		message := fmt.Sprintf("Invalid metric rules: %v", err)
+		status.AddGlobalError(invalidMetricRules, message)
+		return errors.New(message)
+	}
+
 	if err := sds.ValidateConfigField(a.config); err != nil {
 		a.log.Error(fmt.Errorf("error while reading configuration, will block until the Agents receive an SDS configuration: %v", err))
 	}
@@ -252,6 +271,26 @@ func (a *logAgent) setupAgent() error {
 	return nil
 }
 
+// setupLogMetrics sets up the generation of metrics from logs when the aggregator is available.
+func (a *logAgent) setupLogMetrics() error {
+	metricRules, err := config.GlobalMetricRules(a.config)
+	if err != nil {
+		return err
+	}
+	if a.senderManager == nil {
+		if len(metricRules) > 0 {
+			a.log.Warn("Metric rules are ignored: no aggregator is available to submit metrics")
+		}
+		return nil
+	}
+	s, err := a.senderManager.GetSender(logMetricsSenderID)
+	if err != nil {
+		return fmt.Errorf("could not get a sender for the metrics generated from logs: %v", err)
+	}
+	a.logMetrics = processor.NewLogMetrics(metricRules, s)
+	return nil
+}
+
 // Start starts all the elements of the data pipeline
 // in the right order to prevent data loss
 func (a *logAgent) startPipeline() {
@@ -262,6 +301,7 @@ func (a *logAgent) startPipeline() {
 	starter := startstop.NewStarter(
 		a.destinationsCtx,
 		a.auditor,
+		a.logMetrics,
 		a.pipelineProvider,
 		a.diagnosticMessageReceiver,
 		a.launchers,
@@ -298,6 +338,7 @@ func (a *logAgent) stop(context.Context) error {
 		a.schedulers,
 		a.launchers,
 		a.pipelineProvider,
+		a.logMetrics,
 		a.auditor,
 		a.destinationsCtx,
 		a.diagnosticMessageReceiver,
@@ -51,6 +51,7 @@ func (a *logAgent) SetupPipeline(processingRules []*config.ProcessingRule, wmeta
 		auditor,
 		diagnosticMessageReceiver,
 		processingRules,
+		a.logMetrics,
 		a.endpoints,
 		destinationsCtx,
 		NewStatusProvider(),
@@ -892,6 +892,50 @@ func (suite *ConfigTestSuite) TestEndpointsSetDDUrlWithPrefix() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestKafkaEndpointsAndSpool() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.sender_backoff_base", 2.0)
+	suite.config.SetWithoutSource("logs_config.kafka_endpoints", `[
+	{"brokers": ["kafka-1:9092", "kafka-2:9092"], "topic": "logs", "use_ssl": true, "sasl_mechanism": "scram-sha-512", "username": "agent", "password": "secret"},
+	{"brokers": ["kafka-3:9092"], "topic": "audit", "is_reliable": false},
+	{"brokers": ["kafka-4:9092"]},
+	{"brokers": ["kafka-5:9092"], "topic": "logs", "sasl_mechanism": "GSSAPI", "username": "agent"}]`)
+	suite.config.SetWithoutSource("logs_config.spool", map[string]interface{}{"path": "/var/spool/datadog", "max_files": 3})
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+
+	// the invalid endpoints are ignored
+	suite.Len(endpoints.KafkaEndpoints, 2)
+	suite.Equal([]string{"kafka-1:9092", "kafka-2:9092"}, endpoints.KafkaEndpoints[0].Brokers)
+	suite.Equal(SASLMechanismSCRAMSHA512, endpoints.KafkaEndpoints[0].SASLMechanism)
+	suite.True(endpoints.KafkaEndpoints[0].UseSSL)
+	suite.True(endpoints.KafkaEndpoints[0].IsReliable())
+	suite.Equal(2.0, endpoints.KafkaEndpoints[0].BackoffBase)
+	suite.Equal("kafka://kafka-3:9092/audit", endpoints.KafkaEndpoints[1].Target())
+	suite.False(endpoints.KafkaEndpoints[1].IsReliable())
+
+	suite.NotNil(endpoints.Spool)
+	suite.Equal("/var/spool/datadog", endpoints.Spool.Path)
+	suite.Equal(3, endpoints.Spool.MaxFiles)
+	suite.Equal(int64(DefaultSpoolMaxFileSize), endpoints.Spool.MaxFileSize)
+
+	tcpEndpoints, err := buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Nil(err)
+	suite.Len(tcpEndpoints.KafkaEndpoints, 2)
+	suite.NotNil(tcpEndpoints.Spool)
+}
+
+func (suite *ConfigTestSuite) TestInvalidSpool() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.spool", `{"max_files": 3}`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Nil(endpoints.Spool)
+	suite.Empty(endpoints.KafkaEndpoints)
+}
+
 func Test_parseAddressWithScheme(t *testing.T) {
 	type args struct {
 		address       string
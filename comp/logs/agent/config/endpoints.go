@@ -7,6 +7,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
@@ -329,6 +330,11 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+
+	// KafkaEndpoints receive the payloads sent to the endpoints, and Spool receives the payloads
+	// which can't be sent to the reliable endpoints. They're only set for the logs pipeline.
+	KafkaEndpoints []KafkaEndpoint
+	Spool          *SpoolEndpoint
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -340,9 +346,22 @@ func (e *Endpoints) GetStatus() []string {
 	for _, endpoint := range e.GetUnReliableEndpoints() {
 		result = append(result, endpoint.GetStatus("Unreliable: ", e.UseHTTP))
 	}
+	for _, endpoint := range e.KafkaEndpoints {
+		result = append(result, fmt.Sprintf("%sSending logs to Kafka topic %s on %s", reliabilityPrefix(endpoint.IsReliable()), endpoint.Topic, strings.Join(endpoint.Brokers, ", ")))
+	}
+	if e.Spool != nil {
+		result = append(result, "Fallback: Writing logs to the spool in "+e.Spool.Path+" while the reliable endpoints are unreachable")
+	}
 	return result
 }
 
+func reliabilityPrefix(isReliable bool) string {
+	if isReliable {
+		return "Reliable: "
+	}
+	return "Unreliable: "
+}
+
 // NewEndpoints returns a new endpoints composite with default batching settings
 func NewEndpoints(main Endpoint, additionalEndpoints []Endpoint, useProto bool, useHTTP bool) *Endpoints {
 	return NewEndpointsWithBatchSettings(
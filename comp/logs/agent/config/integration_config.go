@@ -26,6 +26,7 @@ const (
 	IntegrationType   = "integration"
 	WindowsEventType  = "windows_event"
 	StringChannelType = "string_channel"
+	SyslogType        = "syslog"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -42,10 +43,15 @@ type LogsConfig struct {
 
 	IntegrationName string
 
-	Port        int    // Network
-	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network
+	Port        int    // Network, Syslog
+	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network, Syslog
 	Path        string // File, Journald
 
+	// Protocol is the transport syslog messages are received on, either tcp (the default) or udp.
+	Protocol    string `mapstructure:"protocol" json:"protocol" yaml:"protocol"`                // Syslog
+	TLSCertFile string `mapstructure:"tls_cert_file" json:"tls_cert_file" yaml:"tls_cert_file"` // TCP, Syslog
+	TLSKeyFile  string `mapstructure:"tls_key_file" json:"tls_key_file" yaml:"tls_key_file"`    // TCP, Syslog
+
 	Encoding     string           `mapstructure:"encoding" json:"encoding" yaml:"encoding"`                   // File
 	ExcludePaths StringSliceField `mapstructure:"exclude_paths" json:"exclude_paths" yaml:"exclude_paths"`    // File
 	TailingMode  string           `mapstructure:"start_position" json:"start_position" yaml:"start_position"` // File
@@ -87,6 +93,10 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            StringSliceField
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules" yaml:"log_processing_rules"`
+	// MetricRules define the metrics generated from the logs of the source.
+	MetricRules []*MetricRule `mapstructure:"log_metric_rules" json:"log_metric_rules" yaml:"log_metric_rules"`
+	// Throttling limits the volume of logs sent for the source.
+	Throttling *ThrottlingConfig `mapstructure:"log_throttling" json:"log_throttling" yaml:"log_throttling"`
 	// ProcessRawMessage is used to process the raw message instead of only the content part of the message.
 	ProcessRawMessage *bool `mapstructure:"process_raw_message" json:"process_raw_message" yaml:"process_raw_message"`
 
@@ -142,9 +152,17 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case TCPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -178,6 +196,8 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	fmt.Fprintf(&b, ws("SourceCategory: %#v,"), c.SourceCategory)
 	fmt.Fprintf(&b, ws("Tags: %#v,"), c.Tags)
 	fmt.Fprintf(&b, ws("ProcessingRules: %#v,"), c.ProcessingRules)
+	fmt.Fprintf(&b, ws("MetricRules: %#v,"), c.MetricRules)
+	fmt.Fprintf(&b, ws("Throttling: %#v,"), c.Throttling)
 	if c.ProcessRawMessage != nil {
 		fmt.Fprintf(&b, ws("ProcessRawMessage: %t,"), *c.ProcessRawMessage)
 	} else {
@@ -288,12 +308,36 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		if c.Port == 0 {
+			return fmt.Errorf("syslog source must have a port")
+		}
+		if c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType {
+			return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+		}
+		if c.Protocol == UDPType && (c.TLSCertFile != "" || c.TLSKeyFile != "") {
+			return fmt.Errorf("tls is not supported for syslog sources over udp")
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be set together")
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
 		return err
 	}
-	return CompileProcessingRules(c.ProcessingRules)
+	err = CompileProcessingRules(c.ProcessingRules)
+	if err != nil {
+		return err
+	}
+	err = CompileMetricRules(c.MetricRules)
+	if err != nil {
+		return err
+	}
+	if c.Throttling != nil {
+		return c.Throttling.Validate()
+	}
+	return nil
 }
 
 func (c *LogsConfig) validateTailingMode() error {
@@ -21,8 +21,18 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: FileType, Path: "/var/log/foo.log"},
 		{Type: TCPType, Port: 1234},
 		{Type: UDPType, Port: 5678},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: UDPType},
+		{Type: SyslogType, Port: 6514, Protocol: TCPType, TLSCertFile: "/etc/syslog.crt", TLSKeyFile: "/etc/syslog.key"},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{
+			{Name: "remap", Type: JSONRemapField, Field: "log.msg", Target: "message"},
+			{Name: "drop", Type: JSONDropFields, Fields: []string{"password", "user.token"}},
+			{Name: "status", Type: JSONFieldToStatus, Field: "level"},
+			{Name: "tag", Type: JSONFieldToTag, Field: "trace_id"},
+		}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{RateLimit: 100, SampleRates: map[string]float64{"info": 0.5}, Dedup: true, DedupWindow: "30s"}},
 	}
 
 	for _, config := range validConfigs {
@@ -37,6 +47,10 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "http"},
+		{Type: SyslogType, Port: 514, Protocol: UDPType, TLSCertFile: "/etc/syslog.crt", TLSKeyFile: "/etc/syslog.key"},
+		{Type: SyslogType, Port: 6514, TLSCertFile: "/etc/syslog.crt"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -44,6 +58,16 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONRemapField, Field: "level"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONRemapField, Target: "level"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONRemapField, Field: "log..level", Target: "level"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONDropFields}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONDropFields, Fields: []string{"a", ".b"}}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONFieldToStatus}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: JSONFieldToTag, Field: "trace_id", Target: "trace id"}}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{RateLimit: -1}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{SampleRates: map[string]float64{"info": 2}}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{Dedup: true, DedupWindow: "forever"}},
 	}
 
 	for _, config := range invalidConfigs {
@@ -52,6 +76,15 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 	}
 }
 
+func TestThrottlingConfig(t *testing.T) {
+	throttling := &ThrottlingConfig{SampleRates: map[string]float64{"INFO": 0.1}}
+	assert.NoError(t, throttling.Validate())
+	assert.Equal(t, 0.1, throttling.SampleRate("info"))
+	assert.Equal(t, 0.1, throttling.SampleRate("Info"))
+	assert.Equal(t, 1.0, throttling.SampleRate("error"))
+	assert.Equal(t, defaultDedupWindow, throttling.DedupWindowDuration)
+}
+
 func TestAutoMultilineEnabled(t *testing.T) {
 	decode := func(cfg string) *LogsConfig {
 		lc := LogsConfig{}
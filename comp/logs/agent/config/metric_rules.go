@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+)
+
+// Log metric types
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// MetricRule defines a metric generated from the logs matching it. A count is incremented by
+// one, or by the extracted value if any, for each matching log; a distribution gets a sample
+// of the extracted value for each matching log.
+type MetricRule struct {
+	// Name is the name of the generated metric.
+	Name string
+	// Type is the type of the generated metric, either count or distribution.
+	Type string
+	// Pattern is the regular expression logs must match, all logs match if it's empty.
+	Pattern string
+	// ValueGroup is the name of the capture group of Pattern holding the value of the metric.
+	ValueGroup string `mapstructure:"value_group" json:"value_group" yaml:"value_group"`
+	// ValueField is the path of the field holding the value of the metric, in logs holding a
+	// JSON object. Nested fields are separated by dots, e.g. "http.duration".
+	ValueField string `mapstructure:"value_field" json:"value_field" yaml:"value_field"`
+	// GroupBy are the names of the capture groups of Pattern added as tags to the metric.
+	GroupBy []string `mapstructure:"group_by" json:"group_by" yaml:"group_by"`
+	// Tags are added to the metric, along with the tags of the log.
+	Tags []string
+	// DropLog drops the logs matching the rule once the metric has been generated.
+	DropLog bool `mapstructure:"drop_log" json:"drop_log" yaml:"drop_log"`
+
+	Regex      *regexp.Regexp `mapstructure:"-" json:"-" yaml:"-"`
+	ValuePath  []string       `mapstructure:"-" json:"-" yaml:"-"`
+	ValueIndex int            `mapstructure:"-" json:"-" yaml:"-"`
+	// GroupByIndexes are the indexes of the GroupBy capture groups.
+	GroupByIndexes []int `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+// GlobalMetricRules returns the metric rules to apply to all logs.
+func GlobalMetricRules(coreConfig pkgconfigmodel.Reader) ([]*MetricRule, error) {
+	var rules []*MetricRule
+	var err error
+	raw := coreConfig.Get("logs_config.metric_rules")
+	if raw == nil {
+		return rules, nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &rules)
+	} else {
+		err = structure.UnmarshalKey(coreConfig, "logs_config.metric_rules", &rules, structure.ConvertEmptyStringToNil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = CompileMetricRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CompileMetricRules validates the rules and compiles their regular expressions and value
+// extractors. It returns an error if one is misconfigured.
+func CompileMetricRules(rules []*MetricRule) error {
+	for _, rule := range rules {
+		if err := compileMetricRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileMetricRule(rule *MetricRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("all metric rules must have a name")
+	}
+	switch rule.Type {
+	case MetricTypeCount, MetricTypeDistribution:
+		break
+	case "":
+		return fmt.Errorf("type must be set for metric rule `%s`", rule.Name)
+	default:
+		return fmt.Errorf("type %s is not supported for metric rule `%s`", rule.Type, rule.Name)
+	}
+
+	rule.Regex = nil
+	if rule.Pattern != "" {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s for metric rule: %s", rule.Pattern, rule.Name)
+		}
+		rule.Regex = re
+	}
+	subexpIndex := func(name string) int {
+		if rule.Regex == nil || name == "" {
+			return -1
+		}
+		return slices.Index(rule.Regex.SubexpNames(), name)
+	}
+
+	if rule.ValueGroup != "" && rule.ValueField != "" {
+		return fmt.Errorf("value_group and value_field can't be both set for metric rule: %s", rule.Name)
+	}
+	if rule.Type == MetricTypeDistribution && rule.ValueGroup == "" && rule.ValueField == "" {
+		return fmt.Errorf("no value_group or value_field provided for distribution metric rule: %s", rule.Name)
+	}
+	rule.ValueIndex = -1
+	if rule.ValueGroup != "" {
+		if rule.ValueIndex = subexpIndex(rule.ValueGroup); rule.ValueIndex < 0 {
+			return fmt.Errorf("value_group %s isn't a capture group of the pattern of metric rule: %s", rule.ValueGroup, rule.Name)
+		}
+	}
+	rule.ValuePath = nil
+	if rule.ValueField != "" {
+		if !isValidFieldPath(rule.ValueField) {
+			return fmt.Errorf("invalid value_field %q for metric rule: %s", rule.ValueField, rule.Name)
+		}
+		rule.ValuePath = strings.Split(rule.ValueField, ".")
+	}
+	rule.GroupByIndexes = nil
+	for _, name := range rule.GroupBy {
+		i := subexpIndex(name)
+		if i < 0 {
+			return fmt.Errorf("group_by %s isn't a capture group of the pattern of metric rule: %s", name, rule.Name)
+		}
+		rule.GroupByIndexes = append(rule.GroupByIndexes, i)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileMetricRules(t *testing.T) {
+	rules := []*MetricRule{
+		{Name: "errors", Type: MetricTypeCount, Pattern: "ERROR"},
+		{Name: "duration", Type: MetricTypeDistribution, Pattern: `(?P<code>\d{3}) (?P<duration>[\d.]+)`, ValueGroup: "duration", GroupBy: []string{"code"}},
+		{Name: "bytes", Type: MetricTypeCount, ValueField: "http.bytes"},
+	}
+	err := CompileMetricRules(rules)
+	assert.Nil(t, err)
+	assert.True(t, rules[0].Regex.MatchString("ERROR"))
+	assert.Equal(t, -1, rules[0].ValueIndex)
+	assert.Equal(t, 2, rules[1].ValueIndex)
+	assert.Equal(t, []int{1}, rules[1].GroupByIndexes)
+	assert.Nil(t, rules[2].Regex)
+	assert.Equal(t, []string{"http", "bytes"}, rules[2].ValuePath)
+}
+
+func TestCompileMetricRulesShouldFailWithInvalidRules(t *testing.T) {
+	invalidRules := []*MetricRule{
+		{Type: MetricTypeCount},
+		{Name: "foo"},
+		{Name: "foo", Type: "gauge"},
+		{Name: "foo", Type: MetricTypeCount, Pattern: "(?=abf)"},
+		{Name: "foo", Type: MetricTypeDistribution, Pattern: ".*"},
+		{Name: "foo", Type: MetricTypeDistribution, Pattern: "(?P<v>\\d+)", ValueGroup: "value"},
+		{Name: "foo", Type: MetricTypeDistribution, Pattern: "(?P<v>\\d+)", ValueGroup: "v", ValueField: "v"},
+		{Name: "foo", Type: MetricTypeDistribution, ValueField: "a..b"},
+		{Name: "foo", Type: MetricTypeCount, Pattern: "(?P<v>\\d+)", GroupBy: []string{"code"}},
+		{Name: "foo", Type: MetricTypeCount, GroupBy: []string{"code"}},
+	}
+
+	for _, rule := range invalidRules {
+		assert.NotNil(t, CompileMetricRules([]*MetricRule{rule}), rule)
+	}
+}
@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// SASL mechanisms supported by Kafka endpoints
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+const (
+	// DefaultSpoolMaxFileSize is the default size in bytes after which a spool file is rotated.
+	DefaultSpoolMaxFileSize = 100 * 1024 * 1024
+	// DefaultSpoolMaxFiles is the default number of spool files kept.
+	DefaultSpoolMaxFiles = 10
+)
+
+// RetrySettings holds the backoff settings used to retry the failed sends to an endpoint. They're
+// the settings of the sender, e.g. 'logs_config.sender_backoff_factor'.
+type RetrySettings struct {
+	BackoffFactor    float64 `mapstructure:"-" json:"-"`
+	BackoffBase      float64 `mapstructure:"-" json:"-"`
+	BackoffMax       float64 `mapstructure:"-" json:"-"`
+	RecoveryInterval int     `mapstructure:"-" json:"-"`
+	RecoveryReset    bool    `mapstructure:"-" json:"-"`
+}
+
+// KafkaEndpoint holds the settings of a Kafka cluster to which logs are sent, in addition to
+// the Datadog intake.
+type KafkaEndpoint struct {
+	Brokers []string `mapstructure:"brokers" json:"brokers"`
+	Topic   string   `mapstructure:"topic" json:"topic"`
+	// IsReliableSetting makes the endpoint block the pipeline when it's unavailable, like the main endpoint.
+	IsReliableSetting *bool  `mapstructure:"is_reliable" json:"is_reliable"`
+	UseSSL            bool   `mapstructure:"use_ssl" json:"use_ssl"`
+	TLSSkipVerify     bool   `mapstructure:"tls_skip_verify" json:"tls_skip_verify"`
+	SASLMechanism     string `mapstructure:"sasl_mechanism" json:"sasl_mechanism"`
+	Username          string `mapstructure:"username" json:"username"`
+	Password          string `mapstructure:"password" json:"password"`
+
+	RetrySettings `mapstructure:",squash"`
+}
+
+// IsReliable returns true if the endpoint is reliable. Kafka endpoints are reliable by default.
+func (e *KafkaEndpoint) IsReliable() bool {
+	return e.IsReliableSetting == nil || *e.IsReliableSetting
+}
+
+// Target returns the address of the endpoint.
+func (e *KafkaEndpoint) Target() string {
+	return fmt.Sprintf("kafka://%s/%s", strings.Join(e.Brokers, ","), e.Topic)
+}
+
+func (e *KafkaEndpoint) validate() error {
+	if len(e.Brokers) == 0 {
+		return fmt.Errorf("no brokers provided")
+	}
+	if e.Topic == "" {
+		return fmt.Errorf("no topic provided")
+	}
+	switch strings.ToUpper(e.SASLMechanism) {
+	case "":
+		if e.Username != "" {
+			return fmt.Errorf("username is set but no sasl_mechanism is provided")
+		}
+	case SASLMechanismPlain, SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512:
+		if e.Username == "" {
+			return fmt.Errorf("sasl_mechanism %s requires a username", e.SASLMechanism)
+		}
+		e.SASLMechanism = strings.ToUpper(e.SASLMechanism)
+	default:
+		return fmt.Errorf("sasl_mechanism %s is not supported", e.SASLMechanism)
+	}
+	return nil
+}
+
+// SpoolEndpoint holds the settings of the local spool to which logs are written while the Datadog
+// intake is unreachable, before being sent to it once it's reachable again. The spool is made of
+// rotated files, the oldest ones being deleted.
+type SpoolEndpoint struct {
+	Path string `mapstructure:"path" json:"path"`
+	// MaxFileSize is the size in bytes after which a spool file is rotated.
+	MaxFileSize int64 `mapstructure:"max_file_size" json:"max_file_size"`
+	// MaxFiles is the number of spool files kept.
+	MaxFiles int `mapstructure:"max_files" json:"max_files"`
+
+	RetrySettings `mapstructure:",squash"`
+}
+
+// Target returns the path of the spool.
+func (e *SpoolEndpoint) Target() string {
+	return "file://" + e.Path
+}
+
+func (e *SpoolEndpoint) validate() error {
+	if e.Path == "" {
+		return fmt.Errorf("no path provided")
+	}
+	if e.MaxFileSize < 0 || e.MaxFiles < 0 {
+		return fmt.Errorf("max_file_size and max_files can't be negative")
+	}
+	if e.MaxFileSize == 0 {
+		e.MaxFileSize = DefaultSpoolMaxFileSize
+	}
+	if e.MaxFiles == 0 {
+		e.MaxFiles = DefaultSpoolMaxFiles
+	}
+	return nil
+}
+
+// loadKafkaEndpoints returns the valid Kafka endpoints from '<prefix>.kafka_endpoints'.
+func loadKafkaEndpoints(l *LogsConfigKeys) []KafkaEndpoint {
+	var endpoints []KafkaEndpoint
+	configKey := l.getConfigKey("kafka_endpoints")
+	if !unmarshalOutputEndpoints(l, configKey, &endpoints) {
+		return nil
+	}
+	valid := make([]KafkaEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if err := e.validate(); err != nil {
+			log.Warnf("Ignoring invalid Kafka endpoint in %s: %v", configKey, err)
+			continue
+		}
+		e.RetrySettings = l.retrySettings()
+		valid = append(valid, e)
+	}
+	return valid
+}
+
+// loadSpoolEndpoint returns the spool configured in '<prefix>.spool', if valid.
+func loadSpoolEndpoint(l *LogsConfigKeys) *SpoolEndpoint {
+	var spool SpoolEndpoint
+	configKey := l.getConfigKey("spool")
+	if !unmarshalOutputEndpoints(l, configKey, &spool) {
+		return nil
+	}
+	if err := spool.validate(); err != nil {
+		log.Warnf("Ignoring invalid spool in %s: %v", configKey, err)
+		return nil
+	}
+	spool.RetrySettings = l.retrySettings()
+	return &spool
+}
+
+// unmarshalOutputEndpoints unmarshals the setting at configKey, which can be set as JSON, into v.
+// It returns false if the setting isn't set or can't be parsed.
+func unmarshalOutputEndpoints(l *LogsConfigKeys, configKey string, v interface{}) bool {
+	raw := l.getConfig().Get(configKey)
+	if raw == nil {
+		return false
+	}
+	var err error
+	if s, ok := raw.(string); ok {
+		if s == "" {
+			return false
+		}
+		err = json.Unmarshal([]byte(s), v)
+	} else {
+		err = structure.UnmarshalKey(l.getConfig(), configKey, v, structure.EnableSquash)
+	}
+	if err != nil {
+		log.Warnf("Could not parse %s for logs: %v", configKey, err)
+		return false
+	}
+	return true
+}
+
+// retrySettings returns the backoff settings of the sender.
+func (l *LogsConfigKeys) retrySettings() RetrySettings {
+	return RetrySettings{
+		BackoffFactor:    l.senderBackoffFactor(),
+		BackoffBase:      l.senderBackoffBase(),
+		BackoffMax:       l.senderBackoffMax(),
+		RecoveryInterval: l.senderRecoveryInterval(),
+		RecoveryReset:    l.senderRecoveryReset(),
+	}
+}
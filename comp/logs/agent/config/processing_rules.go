@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Processing rule types
@@ -16,25 +17,51 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+
+	// JSON processing rules apply to log lines holding a JSON object. Fields are referenced
+	// by their path, with nested fields separated by dots, e.g. "log.level".
+	JSONRemapField    = "json_remap_field"
+	JSONDropFields    = "json_drop_fields"
+	JSONFieldToStatus = "json_field_to_status"
+	JSONFieldToTag    = "json_field_to_tag"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
+// ProcessingRule defines an exclusion, a masking or a JSON processing rule to
 // be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder" yaml:"replace_placeholder"`
 	Pattern            string
+	// Field is the path of the field a JSON processing rule applies to.
+	Field string
+	// Fields are the paths of the fields a json_drop_fields rule removes.
+	Fields []string
+	// Target is the path a json_remap_field rule moves Field to, or the name of the tag
+	// a json_field_to_tag rule adds. It defaults to Field for json_field_to_tag rules.
+	Target string
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// FieldPaths and TargetPath are the split paths of Field, or Fields, and Target.
+	FieldPaths [][]string `mapstructure:"-" json:"-" yaml:"-"`
+	TargetPath []string   `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+// IsJSONRule returns true if the rule applies to JSON log lines.
+func (r *ProcessingRule) IsJSONRule() bool {
+	switch r.Type {
+	case JSONRemapField, JSONDropFields, JSONFieldToStatus, JSONFieldToTag:
+		return true
+	}
+	return false
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, or valid fields for JSON processing rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +71,11 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case JSONRemapField, JSONDropFields, JSONFieldToStatus, JSONFieldToTag:
+			if err := validateJSONProcessingRule(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -61,9 +93,59 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 	return nil
 }
 
-// CompileProcessingRules compiles all processing rule regular expressions.
+// validateJSONProcessingRule validates the fields of a JSON processing rule.
+func validateJSONProcessingRule(rule *ProcessingRule) error {
+	if rule.Type == JSONDropFields {
+		if len(rule.Fields) == 0 {
+			return fmt.Errorf("no fields provided for processing rule: %s", rule.Name)
+		}
+		for _, field := range rule.Fields {
+			if !isValidFieldPath(field) {
+				return fmt.Errorf("invalid field %q for processing rule: %s", field, rule.Name)
+			}
+		}
+		return nil
+	}
+	if rule.Field == "" {
+		return fmt.Errorf("no field provided for processing rule: %s", rule.Name)
+	}
+	if !isValidFieldPath(rule.Field) {
+		return fmt.Errorf("invalid field %q for processing rule: %s", rule.Field, rule.Name)
+	}
+	switch rule.Type {
+	case JSONRemapField:
+		if rule.Target == "" {
+			return fmt.Errorf("no target provided for processing rule: %s", rule.Name)
+		}
+		if !isValidFieldPath(rule.Target) {
+			return fmt.Errorf("invalid target %q for processing rule: %s", rule.Target, rule.Name)
+		}
+	case JSONFieldToTag:
+		if strings.ContainsAny(rule.Target, ":, ") {
+			return fmt.Errorf("invalid tag name %q for processing rule: %s", rule.Target, rule.Name)
+		}
+	}
+	return nil
+}
+
+// isValidFieldPath returns true if none of the dot separated elements of path is empty.
+func isValidFieldPath(path string) bool {
+	for _, elem := range strings.Split(path, ".") {
+		if elem == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileProcessingRules compiles all processing rule regular expressions, and splits the
+// field paths of JSON processing rules.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.IsJSONRule() {
+			compileJSONProcessingRule(rule)
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
@@ -83,3 +165,22 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+func compileJSONProcessingRule(rule *ProcessingRule) {
+	rule.FieldPaths = nil
+	if rule.Type == JSONDropFields {
+		for _, field := range rule.Fields {
+			rule.FieldPaths = append(rule.FieldPaths, strings.Split(field, "."))
+		}
+		return
+	}
+	rule.FieldPaths = [][]string{strings.Split(rule.Field, ".")}
+	switch rule.Type {
+	case JSONRemapField:
+		rule.TargetPath = strings.Split(rule.Target, ".")
+	case JSONFieldToTag:
+		if rule.Target == "" {
+			rule.Target = rule.Field
+		}
+	}
+}
@@ -33,3 +33,18 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestCompileJSONRules(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Type: JSONRemapField, Field: "log.level", Target: "level"},
+		{Type: JSONDropFields, Fields: []string{"password", "user.token"}},
+		{Type: JSONFieldToTag, Field: "trace_id"},
+	}
+	err := CompileProcessingRules(rules)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"log", "level"}}, rules[0].FieldPaths)
+	assert.Equal(t, []string{"level"}, rules[0].TargetPath)
+	assert.Equal(t, [][]string{{"password"}, {"user", "token"}}, rules[1].FieldPaths)
+	assert.Equal(t, "trace_id", rules[2].Target)
+	assert.Nil(t, rules[2].Regex)
+}
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultDedupWindow is the default maximum duration of a run of identical logs collapsed into one.
+const defaultDedupWindow = 10 * time.Second
+
+// ThrottlingConfig limits the volume of logs sent for a source. The logs are first sampled by
+// status, then deduplicated, and finally rate limited.
+type ThrottlingConfig struct {
+	// RateLimit is the maximum number of logs per second sent for the source, there's no limit if
+	// it's 0. The limit applies to the source as a whole, whatever the number of its inputs.
+	RateLimit float64 `mapstructure:"rate_limit" json:"rate_limit" yaml:"rate_limit"`
+	// Burst is the number of logs which can be sent at once above the rate limit, it defaults to
+	// the rate limit.
+	Burst int
+	// SampleRates are the ratios of the logs kept by status, between 0 and 1, e.g. {"info": 0.1}.
+	// The logs with a status missing from the map are all kept.
+	SampleRates map[string]float64 `mapstructure:"sample_rates" json:"sample_rates" yaml:"sample_rates"`
+	// Dedup collapses the identical consecutive logs into one log with a repeat count.
+	Dedup bool
+	// DedupWindow is the maximum duration of a run of identical logs collapsed into one, e.g.
+	// "30s". It defaults to 10s.
+	DedupWindow string `mapstructure:"dedup_window" json:"dedup_window" yaml:"dedup_window"`
+
+	// DedupWindowDuration is the parsed DedupWindow.
+	DedupWindowDuration time.Duration `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+// Validate returns an error if the throttling config is misconfigured, and parses its window.
+func (c *ThrottlingConfig) Validate() error {
+	if c.RateLimit < 0 {
+		return fmt.Errorf("invalid rate_limit %v, must be positive", c.RateLimit)
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("invalid burst %v, must be positive", c.Burst)
+	}
+	sampleRates := make(map[string]float64, len(c.SampleRates))
+	for status, rate := range c.SampleRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid sample rate %v for status %q, must be between 0 and 1", rate, status)
+		}
+		sampleRates[strings.ToLower(status)] = rate
+	}
+	c.SampleRates = sampleRates
+	c.DedupWindowDuration = defaultDedupWindow
+	if c.DedupWindow != "" {
+		window, err := time.ParseDuration(c.DedupWindow)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid dedup_window %q, must be a positive duration", c.DedupWindow)
+		}
+		c.DedupWindowDuration = window
+	}
+	return nil
+}
+
+// SampleRate returns the ratio of the logs with the given status which are kept.
+func (c *ThrottlingConfig) SampleRate(status string) float64 {
+	if rate, ok := c.SampleRates[strings.ToLower(status)]; ok {
+		return rate
+	}
+	return 1
+}
@@ -37,6 +37,14 @@ func (a *NullAuditor) GetTailingMode(_ string) string {
 	return ""
 }
 
+// GetFingerprint returns 0
+func (a *NullAuditor) GetFingerprint(_ string) uint64 {
+	return 0
+}
+
+// KeepAlive does nothing
+func (a *NullAuditor) KeepAlive(_ string) {}
+
 // Start starts the NullAuditor main loop
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -38,6 +38,8 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	// Fingerprint is the fingerprint of the file tailed at the offset, if it's known.
+	Fingerprint uint64 `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -172,6 +174,26 @@ func (a *registryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the fingerprint of the file of the last committed offset for a given
+// identifier, returns 0 if it does not exist or is unknown.
+func (a *registryAuditor) GetFingerprint(identifier string) uint64 {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return 0
+	}
+	return entry.Fingerprint
+}
+
+// KeepAlive resets the TTL of the entry of a given identifier, for the offsets which are still
+// valid but aren't updated anymore, like the final offsets of the compressed files.
+func (a *registryAuditor) KeepAlive(identifier string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.LastUpdated = time.Now().UTC()
+	}
+}
+
 // run keeps up to date the registry on different events
 func (a *registryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -195,7 +217,7 @@ func (a *registryAuditor) run() {
 			}
 			// update the registry with the new entry
 			for _, msg := range payload.MessageMetas {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from the registry
@@ -250,7 +272,7 @@ func (a *registryAuditor) cleanupRegistry() {
 }
 
 // updateRegistry updates the registry entry matching identifier with the new offset and timestamp
-func (a *registryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+func (a *registryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint uint64, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -273,6 +295,7 @@ func (a *registryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Fingerprint:        fingerprint,
 	}
 }
 
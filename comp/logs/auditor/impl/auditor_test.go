@@ -61,11 +61,11 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0, 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 0, 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
@@ -122,6 +122,20 @@ func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.Equal("43", suite.a.registry[otherpath].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorKeepsAliveRegistryEntry() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+	}
+
+	suite.a.KeepAlive(suite.source.Config.Path)
+	suite.a.KeepAlive("otherpath")
+	suite.a.cleanupRegistry()
+	suite.Equal(1, len(suite.a.registry))
+	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
+}
+
 func TestScannerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuditorTestSuite))
 }
@@ -85,6 +85,8 @@ func (a *Auditor) run() {
 type Registry struct {
 	offset      string
 	tailingMode string
+	fingerprint uint64
+	keptAlive   []string
 }
 
 // NewMockRegistry returns a new mock registry.
@@ -111,3 +113,23 @@ func (r *Registry) GetTailingMode(_ string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetFingerprint returns the fingerprint.
+func (r *Registry) GetFingerprint(_ string) uint64 {
+	return r.fingerprint
+}
+
+// SetFingerprint sets the fingerprint.
+func (r *Registry) SetFingerprint(fingerprint uint64) {
+	r.fingerprint = fingerprint
+}
+
+// KeepAlive records the identifier.
+func (r *Registry) KeepAlive(identifier string) {
+	r.keptAlive = append(r.keptAlive, identifier)
+}
+
+// KeptAlive returns the identifiers given to KeepAlive.
+func (r *Registry) KeptAlive() []string {
+	return r.keptAlive
+}
@@ -219,6 +219,7 @@ func (a *Agent) SetupPipeline(
 		auditor,
 		&diagnostic.NoopMessageReceiver{},
 		processingRules,
+		nil, // log metrics
 		a.endpoints,
 		destinationsCtx,
 		NewStatusProvider(),
@@ -70,6 +70,13 @@ func runAgentSidekicks(ag component) error {
 	// trace-agent would largely increase the number of module pulled by OTEL when using the pkg/trace go-module.
 	ag.Agent.DebugServer.AddRoute("/config", ag.config.GetConfigHandler())
 	ag.Agent.DebugServer.AddRoute("/config/set", ag.config.SetHandler())
+	// Captures of the received payloads, to be replayed with `trace-agent replay`, are started from the CLI.
+	ag.Agent.DebugServer.AddRoute("/capture", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if apiutil.Validate(w, req) != nil {
+			return
+		}
+		ag.Agent.Capture.ServeHTTP(w, req)
+	}))
 	// The below endpoint is deprecated and has been replaced with /config/set on the debug server.
 	// It will be removed in a future version.
 	api.AttachEndpoint(api.Endpoint{
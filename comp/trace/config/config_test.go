@@ -49,6 +49,7 @@ import (
 	traceconfig "github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
 )
 
 // team: agent-apm
@@ -523,6 +524,9 @@ func TestDefaultConfig(t *testing.T) {
 	assert.False(t, cfg.InstallSignature.Found)
 
 	assert.True(t, cfg.ReceiverEnabled)
+
+	assert.Equal(t, filepath.Join(pkgconfigsetup.Datadog().GetString("run_path"), "trace_capture"), cfg.CapturePath)
+	assert.Equal(t, int64(100*1024*1024), cfg.CaptureMaxBytes)
 }
 
 func TestNoAPMConfig(t *testing.T) {
@@ -1040,6 +1044,93 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_TRACE_SAMPLING_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"errors","conditions":["http.status_code >= 500"]},{"service":"web-*","tags":{"env":"staging"},"any_span":true,"sample_rate":0.1}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.TraceSamplingRule{
+			{Name: "errors", Conditions: []string{"http.status_code >= 500"}},
+			{Service: "web-*", Tags: map[string]string{"env": "staging"}, AnySpan: true, SampleRate: pointer.Ptr(0.1)},
+		}, cfg.TraceSamplingRules)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSamplingEnabled)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_DECISION_WAIT"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "30s")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, 30*time.Second, cfg.TailSamplingDecisionWait)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_MAX_BUFFER_SIZE"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "1048576")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, 1048576, cfg.TailSamplingMaxBufferSize)
+	})
+
+	env = "DD_APM_CAPTURE_PATH"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "/tmp/trace-captures")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, "/tmp/trace-captures", cfg.CapturePath)
+	})
+
+	env = "DD_APM_CAPTURE_MAX_BYTES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "1048576")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, int64(1048576), cfg.CaptureMaxBytes)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -1591,6 +1682,119 @@ func TestLoadEnv(t *testing.T) {
 		assert.True(t, cfg.Obfuscation.Memcached.KeepCommand)
 	})
 
+	env = "DD_APM_OBFUSCATION_GRAPHQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled"))
+		assert.True(t, cfg.Obfuscation.GraphQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_GRAPHQL_KEEP_ALIASES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.False(t, cfg.Obfuscation.GraphQL.Enabled)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.keep_aliases"))
+		assert.True(t, cfg.Obfuscation.GraphQL.KeepAliases)
+	})
+
+	env = "DD_APM_OBFUSCATION_CQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.enabled"))
+		assert.True(t, cfg.Obfuscation.CQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_CQL_REPLACE_DIGITS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.replace_digits"))
+		assert.True(t, cfg.Obfuscation.CQL.ReplaceDigits)
+	})
+
+	env = "DD_APM_OBFUSCATION_DYNAMODB_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "false")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.False(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.dynamodb.enabled"))
+		assert.False(t, cfg.Obfuscation.DynamoDB.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_DYNAMODB_REMOVE_EXPRESSION_ATTRIBUTE_VALUES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.dynamodb.remove_expression_attribute_values"))
+		assert.True(t, cfg.Obfuscation.DynamoDB.RemoveExpressionAttributeValues)
+	})
+
+	env = "DD_APM_OBFUSCATION_KAFKA_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "false")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.False(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.kafka.enabled"))
+		assert.False(t, cfg.Obfuscation.Kafka.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_KAFKA_KEEP_MESSAGE_KEY"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.kafka.keep_message_key"))
+		assert.True(t, cfg.Obfuscation.Kafka.KeepMessageKey)
+	})
+
 	env = "DD_APM_OBFUSCATION_MONGODB_ENABLED"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, "true")
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -312,6 +313,28 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.probabilistic_sampler.hash_seed") {
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
+	if k := "apm_config.trace_sampling_rules"; core.IsSet(k) {
+		var rules []*config.TraceSamplingRule
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be a list of rules of the form '[{\"service\": \"glob\", \"resource\": \"glob\", \"conditions\": [\"http.status_code >= 500\"], \"sample_rate\": 1}]', error: %v", k, err)
+		} else {
+			c.TraceSamplingRules = rules
+		}
+	}
+
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSamplingEnabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		if d := core.GetDuration("apm_config.tail_sampling.decision_wait"); d > 0 {
+			c.TailSamplingDecisionWait = d
+		} else {
+			log.Warnf("Invalid value for apm_config.tail_sampling.decision_wait: %v, it must be positive. Using default %v", d, c.TailSamplingDecisionWait)
+		}
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_size") {
+		c.TailSamplingMaxBufferSize = core.GetInt("apm_config.tail_sampling.max_buffer_size")
+	}
 
 	if core.IsSet("apm_config.error_tracking_standalone.enabled") {
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
@@ -353,6 +376,8 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	} else {
 		c.DecoderTimeout = 1000
 	}
+	c.ZipkinReceiverEnabled = core.GetBool("apm_config.zipkin_receiver.enabled")
+	c.JaegerReceiverEnabled = core.GetBool("apm_config.jaeger_receiver.enabled")
 
 	if k := "apm_config.replace_tags"; core.IsSet(k) {
 		rt := make([]*config.ReplaceRule, 0)
@@ -468,6 +493,14 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	c.Obfuscation.Redis.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.redis.remove_all_args")
 	c.Obfuscation.Valkey.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.enabled")
 	c.Obfuscation.Valkey.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.remove_all_args")
+	c.Obfuscation.GraphQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled")
+	c.Obfuscation.GraphQL.KeepAliases = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.keep_aliases")
+	c.Obfuscation.CQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.enabled")
+	c.Obfuscation.CQL.ReplaceDigits = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.replace_digits")
+	c.Obfuscation.DynamoDB.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.dynamodb.enabled")
+	c.Obfuscation.DynamoDB.RemoveExpressionAttributeValues = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.dynamodb.remove_expression_attribute_values")
+	c.Obfuscation.Kafka.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.kafka.enabled")
+	c.Obfuscation.Kafka.KeepMessageKey = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.kafka.keep_message_key")
 	c.Obfuscation.CreditCards.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.enabled")
 	c.Obfuscation.CreditCards.Luhn = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.luhn")
 	c.Obfuscation.CreditCards.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.credit_cards.keep_values")
@@ -655,6 +688,11 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.OpenLineageProxy.APIVersion = core.GetInt(k)
 	}
 	c.DebugServerPort = core.GetInt("apm_config.debug.port")
+	c.CapturePath = core.GetString("apm_config.capture_path")
+	if c.CapturePath == "" {
+		c.CapturePath = filepath.Join(core.GetString("run_path"), "trace_capture")
+	}
+	c.CaptureMaxBytes = core.GetInt64("apm_config.capture_max_bytes")
 	return nil
 }
 
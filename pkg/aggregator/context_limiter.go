@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Context budgets
+const (
+	contextBudgetMetric = "metric"
+	contextBudgetOrigin = "origin"
+)
+
+// Actions taken on the new contexts exceeding a budget
+const (
+	contextLimitDrop  = "drop"
+	contextLimitStrip = "strip"
+)
+
+var (
+	tlmDogstatsdContextsLimited = telemetry.NewCounter("aggregator", "dogstatsd_contexts_limited",
+		[]string{"budget", "action"}, "Count the number of new DogStatsD contexts dropped or stripped of some tags because they exceeded a context budget")
+
+	// dogstatsdContextLimiter is the context limiter of the last created demultiplexer, its
+	// offenders are exposed in the status and in the dogstatsd-stats command.
+	dogstatsdContextLimiter atomic.Pointer[contextLimiter]
+)
+
+// ContextLimitOffender holds the contexts of a metric name, or of an origin, which exceeded its
+// context budget.
+type ContextLimitOffender struct {
+	// Contexts is the current number of contexts.
+	Contexts int
+	// Dropped is the number of new contexts dropped.
+	Dropped uint64
+	// Stripped is the number of new contexts which were stripped of some tags.
+	Stripped uint64
+}
+
+// ContextLimitsStats holds the metric names and the origins which exceeded their context budget.
+type ContextLimitsStats struct {
+	Metrics map[string]ContextLimitOffender `json:",omitempty"`
+	Origins map[string]ContextLimitOffender `json:",omitempty"`
+}
+
+// GetDogstatsdContextLimitsStats returns the metric names and the origins which exceeded their
+// DogStatsD context budget, see `dogstatsd_context_limits`.
+func GetDogstatsdContextLimitsStats() ContextLimitsStats {
+	limiter := dogstatsdContextLimiter.Load()
+	if limiter == nil {
+		return ContextLimitsStats{}
+	}
+	return limiter.stats()
+}
+
+func expContextLimits() interface{} {
+	return GetDogstatsdContextLimitsStats()
+}
+
+type originOffender struct {
+	origin string
+	ContextLimitOffender
+}
+
+// contextLimiter enforces the context budgets of the DogStatsD time samplers: the maximum number of
+// contexts of a metric name, and of an origin, the origin of a context being its origin detection
+// tags. The contexts without origin detection tags have no origin budget.
+//
+// Once a budget is exceeded, the configured tags are stripped from the new contexts, which are
+// dropped if they have none of these tags. The limiter is shared by the time samplers, as the
+// contexts of a metric are spread over all of them, it's only involved when a context is created or
+// removed.
+type contextLimiter struct {
+	metricLimit int
+	originLimit int
+	stripTags   map[string]struct{}
+
+	mu              sync.Mutex
+	byMetric        map[string]int
+	byOrigin        map[ckey.TagsKey]int
+	metricOffenders map[string]*ContextLimitOffender
+	originOffenders map[ckey.TagsKey]*originOffender
+}
+
+// newContextLimiter returns the context limiter configured by `dogstatsd_context_limits`, or nil
+// if there's no budget.
+func newContextLimiter(cfg model.Reader) *contextLimiter {
+	metricLimit := cfg.GetInt("dogstatsd_context_limits.metric_limit")
+	originLimit := cfg.GetInt("dogstatsd_context_limits.origin_limit")
+	if metricLimit <= 0 && originLimit <= 0 {
+		return nil
+	}
+	stripTags := make(map[string]struct{})
+	for _, tag := range cfg.GetStringSlice("dogstatsd_context_limits.strip_tags") {
+		stripTags[tag] = struct{}{}
+	}
+	log.Infof("DogStatsD context budgets: %d contexts per metric, %d contexts per origin", metricLimit, originLimit)
+	return &contextLimiter{
+		metricLimit:     metricLimit,
+		originLimit:     originLimit,
+		stripTags:       stripTags,
+		byMetric:        make(map[string]int),
+		byOrigin:        make(map[ckey.TagsKey]int),
+		metricOffenders: make(map[string]*ContextLimitOffender),
+		originOffenders: make(map[ckey.TagsKey]*originOffender),
+	}
+}
+
+// allow returns true and counts the new context if it fits in its budgets, or returns the budget
+// it exceeds.
+func (l *contextLimiter) allow(name string, originKey ckey.TagsKey) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.metricLimit > 0 && l.byMetric[name] >= l.metricLimit {
+		return contextBudgetMetric, false
+	}
+	if l.originLimit > 0 && originKey != 0 && l.byOrigin[originKey] >= l.originLimit {
+		return contextBudgetOrigin, false
+	}
+	l.trackLocked(name, originKey)
+	return "", true
+}
+
+// track counts a new context, whatever its budgets.
+func (l *contextLimiter) track(name string, originKey ckey.TagsKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.trackLocked(name, originKey)
+}
+
+func (l *contextLimiter) trackLocked(name string, originKey ckey.TagsKey) {
+	l.byMetric[name]++
+	if originKey != 0 {
+		l.byOrigin[originKey]++
+	}
+}
+
+// release forgets a removed context. The offenders without contexts left are forgotten too.
+func (l *contextLimiter) release(name string, originKey ckey.TagsKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byMetric[name] <= 1 {
+		delete(l.byMetric, name)
+		delete(l.metricOffenders, name)
+	} else {
+		l.byMetric[name]--
+	}
+	if originKey == 0 {
+		return
+	}
+	if l.byOrigin[originKey] <= 1 {
+		delete(l.byOrigin, originKey)
+		delete(l.originOffenders, originKey)
+	} else {
+		l.byOrigin[originKey]--
+	}
+}
+
+// originKey returns the key of the origin detection tags of the context, the same as the one
+// generated when the context was created.
+func originKey(context *Context) ckey.TagsKey {
+	return ckey.TagsKey(tagset.NewHashingTagsAccumulatorWithTags(context.taggerTags.Tags()).Hash())
+}
+
+// strip removes the configured tags from the buffer and returns true if it had any of them.
+func (l *contextLimiter) strip(buffer *tagset.HashingTagsAccumulator) bool {
+	if len(l.stripTags) == 0 {
+		return false
+	}
+	return removeTags(buffer, func(name string) bool {
+		_, ok := l.stripTags[name]
+		return ok
+	})
+}
+
+// recordLimited records that a new context of the metric and origin exceeded the budget.
+// originTags are the origin detection tags of the context.
+func (l *contextLimiter) recordLimited(name string, originKey ckey.TagsKey, originTags []string, budget, action string) {
+	tlmDogstatsdContextsLimited.Inc(budget, action)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var offender *ContextLimitOffender
+	if budget == contextBudgetMetric {
+		offender = l.metricOffenders[name]
+		if offender == nil {
+			offender = &ContextLimitOffender{}
+			l.metricOffenders[name] = offender
+			log.Warnf("DogStatsD metric %q exceeded its budget of %d contexts, action on its new contexts: %s", name, l.metricLimit, action)
+		}
+	} else {
+		origin := l.originOffenders[originKey]
+		if origin == nil {
+			sorted := append([]string(nil), originTags...)
+			sort.Strings(sorted)
+			origin = &originOffender{origin: strings.Join(sorted, ",")}
+			l.originOffenders[originKey] = origin
+			log.Warnf("DogStatsD origin %q exceeded its budget of %d contexts, action on its new contexts: %s", origin.origin, l.originLimit, action)
+		}
+		offender = &origin.ContextLimitOffender
+	}
+	if action == contextLimitDrop {
+		offender.Dropped++
+	} else {
+		offender.Stripped++
+	}
+}
+
+func (l *contextLimiter) stats() ContextLimitsStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := ContextLimitsStats{}
+	if len(l.metricOffenders) > 0 {
+		stats.Metrics = make(map[string]ContextLimitOffender, len(l.metricOffenders))
+		for name, offender := range l.metricOffenders {
+			o := *offender
+			o.Contexts = l.byMetric[name]
+			stats.Metrics[name] = o
+		}
+	}
+	if len(l.originOffenders) > 0 {
+		stats.Origins = make(map[string]ContextLimitOffender, len(l.originOffenders))
+		for key, offender := range l.originOffenders {
+			o := offender.ContextLimitOffender
+			o.Contexts = l.byOrigin[key]
+			stats.Origins[offender.origin] = o
+		}
+	}
+	return stats
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func newTestContextLimiter(t *testing.T, metricLimit, originLimit int, stripTags []string) *contextLimiter {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("dogstatsd_context_limits.metric_limit", metricLimit)
+	cfg.SetWithoutSource("dogstatsd_context_limits.origin_limit", originLimit)
+	cfg.SetWithoutSource("dogstatsd_context_limits.strip_tags", stripTags)
+	limiter := newContextLimiter(cfg)
+	require.NotNil(t, limiter)
+	return limiter
+}
+
+func TestNewContextLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newContextLimiter(configmock.New(t)))
+}
+
+func testContextLimiterMetricBudget(t *testing.T, store *tags.Store) {
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	resolver.setLimiter(newTestContextLimiter(t, 2, 0, nil))
+
+	key1 := resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:1"}}, 4)
+	key2 := resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:2"}}, 6)
+	assert.False(t, key1.IsZero())
+	assert.False(t, key2.IsZero())
+
+	// the budget of foo is exceeded, its known contexts are still tracked
+	assert.True(t, resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:3"}}, 6).IsZero())
+	assert.Equal(t, key1, resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:1"}}, 6))
+	assert.False(t, resolver.trackContext(&mockSample{"bar", nil, []string{"user_id:3"}}, 6).IsZero())
+	assert.Equal(t, 3, resolver.length())
+
+	assert.Equal(t, ContextLimitsStats{
+		Metrics: map[string]ContextLimitOffender{"foo": {Contexts: 2, Dropped: 1}},
+	}, resolver.resolver.limiter.stats())
+
+	// the expired contexts free their budget
+	resolver.expireContexts(9)
+	assert.Equal(t, 0, resolver.length())
+	assert.Equal(t, ContextLimitsStats{}, resolver.resolver.limiter.stats())
+	assert.False(t, resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:3"}}, 10).IsZero())
+}
+
+func TestContextLimiterMetricBudget(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterMetricBudget)
+}
+
+func testContextLimiterStripTags(t *testing.T, store *tags.Store) {
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	resolver.setLimiter(newTestContextLimiter(t, 1, 0, []string{"user_id"}))
+
+	resolver.trackContext(&mockSample{"foo", nil, []string{"env:prod", "user_id:1"}}, 0)
+
+	// the new contexts are stripped of user_id once the budget is exceeded
+	key2 := resolver.trackContext(&mockSample{"foo", nil, []string{"env:prod", "user_id:2"}}, 0)
+	key3 := resolver.trackContext(&mockSample{"foo", nil, []string{"user_id:3", "env:prod"}}, 0)
+	require.False(t, key2.IsZero())
+	assert.Equal(t, key2, key3)
+	context, ok := resolver.get(key2)
+	require.True(t, ok)
+	assertContext(t, context, "foo", []string{"env:prod"}, "noop")
+
+	// the contexts without tags to strip are dropped
+	assert.True(t, resolver.trackContext(&mockSample{"foo", nil, []string{"env:dev"}}, 0).IsZero())
+
+	assert.Equal(t, ContextLimitsStats{
+		Metrics: map[string]ContextLimitOffender{"foo": {Contexts: 2, Dropped: 1, Stripped: 2}},
+	}, resolver.resolver.limiter.stats())
+}
+
+func TestContextLimiterStripTags(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterStripTags)
+}
+
+func testContextLimiterOriginBudget(t *testing.T, store *tags.Store) {
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	resolver.setLimiter(newTestContextLimiter(t, 0, 1, nil))
+
+	origin := []string{"kube_namespace:default", "container_name:app"}
+	assert.False(t, resolver.trackContext(&mockSample{"foo", origin, nil}, 0).IsZero())
+	assert.True(t, resolver.trackContext(&mockSample{"bar", origin, nil}, 0).IsZero())
+	assert.False(t, resolver.trackContext(&mockSample{"bar", []string{"container_name:other"}, nil}, 0).IsZero())
+
+	// the contexts without origin have no origin budget
+	assert.False(t, resolver.trackContext(&mockSample{"foo", nil, nil}, 0).IsZero())
+	assert.False(t, resolver.trackContext(&mockSample{"bar", nil, nil}, 0).IsZero())
+
+	assert.Equal(t, ContextLimitsStats{
+		Origins: map[string]ContextLimitOffender{"container_name:app,kube_namespace:default": {Contexts: 1, Dropped: 1}},
+	}, resolver.resolver.limiter.stats())
+
+	// the expired contexts free the budget of their origin
+	resolver.expireContexts(3)
+	assert.Empty(t, resolver.resolver.limiter.byOrigin)
+	assert.Equal(t, ContextLimitsStats{}, resolver.resolver.limiter.stats())
+}
+
+func TestContextLimiterOriginBudget(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterOriginBudget)
+}
+
+func TestContextLimiterSharedBySamplers(t *testing.T) {
+	limiter := newTestContextLimiter(t, 1, 0, nil)
+	tagger := nooptagger.NewComponent()
+	sampler1 := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(true, "test"), tagger, "")
+	sampler2 := NewTimeSampler(TimeSamplerID(1), 10, tags.NewStore(true, "test"), tagger, "")
+	sampler1.setContextLimiter(limiter)
+	sampler2.setContextLimiter(limiter)
+
+	sample1 := &mockSample{"foo", nil, []string{"user_id:1"}}
+	sample2 := &mockSample{"foo", nil, []string{"user_id:2"}}
+	assert.False(t, sampler1.contextResolver.trackContext(sample1, 0).IsZero())
+	assert.True(t, sampler2.contextResolver.trackContext(sample2, 0).IsZero())
+	assert.Equal(t, 0, sampler2.contextResolver.length())
+}
@@ -10,6 +10,7 @@ import (
 	"unsafe"
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -76,6 +77,10 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	// limiter enforces the context budgets, it's nil if there's none.
+	limiter *contextLimiter
+	// rollups is the mapper of the rollup rules, it's nil if there's none.
+	rollups *mapper.MetricMapper
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -99,15 +104,30 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns a zero contextKey if the context is dropped because it exceeds a context budget.
 func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
 
+	if cr.rollups != nil {
+		cr.rollup(metricSampleContext.GetName())
+	}
+
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
+	entry, ok := cr.contextsByKey[contextKey]
+	if !ok && cr.limiter != nil {
+		var allowed bool
+		contextKey, taggerKey, metricKey, allowed = cr.limitContext(metricSampleContext, contextKey, taggerKey, metricKey)
+		if !allowed {
+			return ckey.ContextKey(0)
+		}
+		entry, ok = cr.contextsByKey[contextKey]
+	}
+
+	if !ok {
 		mtype := metricSampleContext.GetMetricType()
 		context := &Context{
 			Name:       metricSampleContext.GetName(),
@@ -138,6 +158,28 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	return contextKey
 }
 
+// limitContext applies the context budgets to a new context. Once a budget is exceeded, the
+// configured tags are stripped from the context, which is dropped if it has none of them. It
+// returns the keys of the context to track, and false if it's dropped.
+func (cr *contextResolver) limitContext(metricSampleContext metrics.MetricSampleContext, contextKey ckey.ContextKey, taggerKey, metricKey ckey.TagsKey) (ckey.ContextKey, ckey.TagsKey, ckey.TagsKey, bool) {
+	name := metricSampleContext.GetName()
+	budget, ok := cr.limiter.allow(name, taggerKey)
+	if ok {
+		return contextKey, taggerKey, metricKey, true
+	}
+	if !cr.limiter.strip(cr.metricBuffer) {
+		cr.limiter.recordLimited(name, taggerKey, cr.taggerBuffer.Get(), budget, contextLimitDrop)
+		return contextKey, taggerKey, metricKey, false
+	}
+	cr.limiter.recordLimited(name, taggerKey, cr.taggerBuffer.Get(), budget, contextLimitStrip)
+	contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+	if _, found := cr.contextsByKey[contextKey]; !found {
+		// the stripped contexts are only bounded by the cardinality of their remaining tags
+		cr.limiter.track(name, taggerKey)
+	}
+	return contextKey, taggerKey, metricKey, true
+}
+
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
 	ctx, found := cr.contextsByKey[key]
 	return ctx.context, found
@@ -155,6 +197,9 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
+		if cr.limiter != nil {
+			cr.limiter.release(context.Name, originKey(context))
+		}
 		context.release()
 	}
 }
@@ -178,6 +223,9 @@ func (cr *contextResolver) updateMetrics(countsByMTypeGauge telemetry.Gauge, byt
 
 func (cr *contextResolver) release() {
 	for _, c := range cr.contextsByKey {
+		if cr.limiter != nil {
+			cr.limiter.release(c.context.Name, originKey(c.context))
+		}
 		c.context.release()
 	}
 }
@@ -236,6 +284,16 @@ func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.Met
 	return contextKey
 }
 
+// setLimiter makes the resolver enforce the context budgets of the limiter.
+func (cr *timestampContextResolver) setLimiter(limiter *contextLimiter) {
+	cr.resolver.limiter = limiter
+}
+
+// setRollups makes the resolver drop the origin detection tags of the rollup rules.
+func (cr *timestampContextResolver) setRollups(rollups *mapper.MetricMapper) {
+	cr.resolver.rollups = rollups
+}
+
 func (cr *timestampContextResolver) length() int {
 	return cr.resolver.length()
 }
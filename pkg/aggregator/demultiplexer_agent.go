@@ -170,12 +170,21 @@ func initAgentDemultiplexer(log log.Component,
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	contextLimiter := newContextLimiter(pkgconfigsetup.Datadog())
+	dogstatsdContextLimiter.Store(contextLimiter)
+	rollups := newDogstatsdRollups(pkgconfigsetup.Datadog())
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
 		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		if contextLimiter != nil {
+			statsdSampler.setContextLimiter(contextLimiter)
+		}
+		if rollups != nil {
+			statsdSampler.setRollups(rollups)
+		}
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// newDogstatsdRollups returns the mapper of the rollup rules of `dogstatsd_mapper_profiles`, or nil
+// if there's none. The DogStatsD server drops the tags sent by the clients, the time samplers drop
+// the origin detection tags.
+func newDogstatsdRollups(cfg model.Reader) *mapper.MetricMapper {
+	profiles, err := mapper.GetProfiles(cfg)
+	if err != nil || len(profiles) == 0 {
+		// the DogStatsD server logs the invalid profiles
+		return nil
+	}
+	rollups, err := mapper.NewMetricMapper(profiles, cfg.GetInt("dogstatsd_mapper_cache_size"))
+	if err != nil || !rollups.HasRollups() {
+		return nil
+	}
+	log.Info("DogStatsD rollup rules enabled")
+	return rollups
+}
+
+// removeTags removes the tags whose name matches from the buffer and returns true if it had any.
+func removeTags(buffer *tagset.HashingTagsAccumulator, match func(name string) bool) bool {
+	tags, hashes := buffer.Get(), buffer.Hashes()
+	n := 0
+	for i, tag := range tags {
+		name, _, _ := strings.Cut(tag, ":")
+		if match(name) {
+			continue
+		}
+		tags[n] = tag
+		hashes[n] = hashes[i]
+		n++
+	}
+	if n == len(tags) {
+		return false
+	}
+	buffer.Truncate(n)
+	return true
+}
+
+// rollup removes the tags dropped by the rollup rules of the metric from the origin detection tags.
+func (cr *contextResolver) rollup(name string) {
+	names := cr.rollups.DropTags(name)
+	if len(names) == 0 {
+		return
+	}
+	removeTags(cr.taggerBuffer, func(name string) bool {
+		return slices.Contains(names, name)
+	})
+}
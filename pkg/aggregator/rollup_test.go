@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	taggertypes "github.com/DataDog/datadog-agent/pkg/tagger/types"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// podTagger adds the tags of the pod named after the container ID of the origin.
+type podTagger struct {
+	tagger.Component
+}
+
+func (podTagger) EnrichTags(tb tagset.TagsAccumulator, originInfo taggertypes.OriginInfo) {
+	tb.Append("kube_namespace:default", "pod_name:"+originInfo.ContainerIDFromSocket)
+}
+
+func newTestRollups(t *testing.T) *mapper.MetricMapper {
+	rollups, err := mapper.NewMetricMapper([]mapper.MappingProfileConfig{{
+		Name:    "http",
+		Prefix:  "http.",
+		Rollups: []mapper.RollupConfig{{Match: "http.request.*", DropTags: []string{"pod_name", "container_id"}}},
+	}}, 100)
+	require.NoError(t, err)
+	return rollups
+}
+
+func TestNewDogstatsdRollups(t *testing.T) {
+	cfg := configmock.New(t)
+	assert.Nil(t, newDogstatsdRollups(cfg))
+
+	cfg.SetWithoutSource("dogstatsd_mapper_profiles", []map[string]interface{}{{
+		"name":     "http",
+		"prefix":   "http.",
+		"mappings": []map[string]interface{}{{"match": "http.*.duration", "name": "http.duration"}},
+	}})
+	assert.Nil(t, newDogstatsdRollups(cfg))
+
+	cfg.SetWithoutSource("dogstatsd_mapper_profiles", []map[string]interface{}{{
+		"name":    "http",
+		"prefix":  "http.",
+		"rollups": []map[string]interface{}{{"match": "http.request.*", "drop_tags": []string{"pod_name"}}},
+	}})
+	rollups := newDogstatsdRollups(cfg)
+	require.NotNil(t, rollups)
+	assert.Equal(t, []string{"pod_name"}, rollups.DropTags("http.request.count"))
+}
+
+func testRollupOriginTags(t *testing.T, store *tags.Store) {
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	resolver.setRollups(newTestRollups(t))
+
+	key1 := resolver.trackContext(&mockSample{"http.request.count", []string{"kube_namespace:default", "pod_name:web-1"}, []string{"code:200"}}, 0)
+	key2 := resolver.trackContext(&mockSample{"http.request.count", []string{"pod_name:web-2", "kube_namespace:default"}, []string{"code:200"}}, 0)
+	assert.Equal(t, key1, key2)
+	context, ok := resolver.get(key1)
+	require.True(t, ok)
+	assertContext(t, context, "http.request.count", []string{"kube_namespace:default", "code:200"}, "noop")
+
+	// the other metrics keep their tags
+	key3 := resolver.trackContext(&mockSample{"http.response.count", []string{"pod_name:web-1"}, nil}, 0)
+	key4 := resolver.trackContext(&mockSample{"http.response.count", []string{"pod_name:web-2"}, nil}, 0)
+	assert.NotEqual(t, key3, key4)
+	assert.Equal(t, 3, resolver.length())
+}
+
+func TestRollupOriginTags(t *testing.T) {
+	testWithTagsStore(t, testRollupOriginTags)
+}
+
+func TestRollupAggregation(t *testing.T) {
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(true, "test"), podTagger{nooptagger.NewComponent()}, "host")
+	sampler.setRollups(newTestRollups(t))
+
+	for i, pod := range []string{"web-1", "web-2", "web-1"} {
+		origin := taggertypes.OriginInfo{ContainerIDFromSocket: pod}
+		sampler.sample(&metrics.MetricSample{Name: "http.request.count", Value: float64(i + 1), Mtype: metrics.CounterType, SampleRate: 1, OriginInfo: origin}, 12340)
+		sampler.sample(&metrics.MetricSample{Name: "http.request.inflight", Value: float64(10 * (i + 1)), Mtype: metrics.GaugeType, SampleRate: 1, OriginInfo: origin}, 12341)
+	}
+
+	series, _ := flushSerie(sampler, 12350)
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	require.Len(t, series, 2)
+
+	// the counts of the pods are summed
+	assert.Equal(t, "http.request.count", series[0].Name)
+	assert.Equal(t, []string{"kube_namespace:default"}, series[0].Tags.UnsafeToReadOnlySliceString())
+	require.Len(t, series[0].Points, 1)
+	assert.Equal(t, 0.6, series[0].Points[0].Value)
+
+	// the last value of the gauges is kept
+	assert.Equal(t, "http.request.inflight", series[1].Name)
+	assert.Equal(t, []string{"kube_namespace:default"}, series[1].Tags.UnsafeToReadOnlySliceString())
+	require.Len(t, series[1].Points, 1)
+	assert.Equal(t, 30.0, series[1].Points[0].Value)
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
@@ -69,6 +70,18 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger
 	return s
 }
 
+// setContextLimiter makes the sampler enforce the context budgets of the limiter, which can be
+// shared with other samplers.
+func (s *TimeSampler) setContextLimiter(limiter *contextLimiter) {
+	s.contextResolver.setLimiter(limiter)
+}
+
+// setRollups makes the sampler drop the origin detection tags of the rollup rules, the DogStatsD
+// server drops the other tags.
+func (s *TimeSampler) setRollups(rollups *mapper.MetricMapper) {
+	s.contextResolver.setRollups(rollups)
+}
+
 func (s *TimeSampler) calculateBucketStart(timestamp float64) int64 {
 	return int64(timestamp) - int64(timestamp)%s.interval
 }
@@ -85,6 +98,10 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 
 	// Keep track of the context
 	contextKey := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	if contextKey.IsZero() {
+		// the context exceeds a context budget
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
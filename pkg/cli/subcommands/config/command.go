@@ -31,6 +31,9 @@ type cliParams struct {
 	// source enables detailed information about each source and its value
 	source bool
 
+	// jsonOutput prints the issues found by `config lint` as JSON
+	jsonOutput bool
+
 	// args are the positional command line args
 	args []string
 }
@@ -101,6 +104,23 @@ func MakeCommand(globalParamsGetter func() GlobalParams) *cobra.Command {
 	cmd.AddCommand(getCmd)
 	getCmd.Flags().BoolVarP(&cliParams.source, "source", "s", false, "print every source and its value")
 
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check the configuration file, the environment variables and the check configuration files for unknown settings, invalid values and deprecated settings",
+		Long:  ``,
+		RunE:  oneShotRunE(lintConfig),
+	}
+	cmd.AddCommand(lintCmd)
+	lintCmd.Flags().BoolVarP(&cliParams.jsonOutput, "json", "j", false, "print out the issues as JSON")
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the type, the default value and the deprecation of the settings as JSON",
+		Long:  ``,
+		RunE:  oneShotRunE(printSchema),
+	}
+	cmd.AddCommand(schemaCmd)
+
 	otelCmd := &cobra.Command{
 		Use:   "otel-agent",
 		Short: "Otel-agent, prints out the read-only runtime configs of otel-agent if otel-agent is present and converter is enabled",
@@ -83,3 +83,38 @@ func TestConfigGetCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestConfigLintCommand(t *testing.T) {
+	commands := []*cobra.Command{
+		MakeCommand(func() GlobalParams {
+			return GlobalParams{}
+		}),
+	}
+
+	fxutil.TestOneShotSubcommand(t,
+		commands,
+		[]string{"config", "lint", "--json"},
+		lintConfig,
+		func(cliParams *cliParams, _ core.BundleParams, secretParams secrets.Params) {
+			require.Equal(t, []string{}, cliParams.args)
+			require.Equal(t, true, cliParams.jsonOutput)
+			require.Equal(t, false, secretParams.Enabled)
+		})
+}
+
+func TestConfigSchemaCommand(t *testing.T) {
+	commands := []*cobra.Command{
+		MakeCommand(func() GlobalParams {
+			return GlobalParams{}
+		}),
+	}
+
+	fxutil.TestOneShotSubcommand(t,
+		commands,
+		[]string{"config", "schema"},
+		printSchema,
+		func(cliParams *cliParams, _ core.BundleParams, secretParams secrets.Params) {
+			require.Equal(t, []string{}, cliParams.args)
+			require.Equal(t, false, secretParams.Enabled)
+		})
+}
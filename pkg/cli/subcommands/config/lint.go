@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+)
+
+// lintInvalidFile is the issue of the check configuration files which can't be parsed
+const lintInvalidFile pkgconfigsetup.LintIssueKind = "invalid_file"
+
+// lintResult is the output of `config lint`
+type lintResult struct {
+	ConfigFile string                     `json:"config_file"`
+	Issues     []pkgconfigsetup.LintIssue `json:"issues"`
+	// CheckFiles are the issues of the check configuration files, by path relative to the conf.d directory
+	CheckFiles map[string][]pkgconfigsetup.LintIssue `json:"check_files"`
+}
+
+func lintConfig(_ log.Component, config config.Component, cliParams *cliParams) error {
+	return runLint(config, cliParams.jsonOutput, os.Stdout)
+}
+
+func runLint(config config.Component, jsonOutput bool, w io.Writer) error {
+	confdPath := config.GetString("confd_path")
+	result := lintResult{
+		ConfigFile: config.ConfigFileUsed(),
+		Issues:     pkgconfigsetup.LintConfig(config),
+		CheckFiles: lintCheckFiles(confdPath),
+	}
+	count := len(result.Issues)
+	for _, issues := range result.CheckFiles {
+		count += len(issues)
+	}
+
+	if jsonOutput {
+		if result.Issues == nil {
+			result.Issues = []pkgconfigsetup.LintIssue{}
+		}
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+	} else {
+		configFile := result.ConfigFile
+		if configFile == "" {
+			configFile = "no config file"
+		}
+		fmt.Fprintf(w, "=== Configuration (%s) ===\n", configFile)
+		if len(result.Issues) == 0 {
+			fmt.Fprintln(w, "No issue found")
+		}
+		for _, issue := range result.Issues {
+			fmt.Fprintf(w, "  %s\n", issue)
+		}
+
+		fmt.Fprintf(w, "\n=== Check configurations (%s) ===\n", confdPath)
+		if len(result.CheckFiles) == 0 {
+			fmt.Fprintln(w, "No issue found")
+		}
+		files := make([]string, 0, len(result.CheckFiles))
+		for file := range result.CheckFiles {
+			files = append(files, file)
+		}
+		slices.Sort(files)
+		for _, file := range files {
+			fmt.Fprintf(w, "  %s\n", file)
+			for _, issue := range result.CheckFiles[file] {
+				fmt.Fprintf(w, "    %s\n", issue)
+			}
+		}
+	}
+
+	if count > 0 {
+		return fmt.Errorf("found %d configuration issues", count)
+	}
+	return nil
+}
+
+// lintCheckFiles checks the top-level keys of the check configuration files of the conf.d directory, with the layout
+// read by the file provider: `<check>.yaml` and `<check>.d/*.yaml`.
+func lintCheckFiles(confdPath string) map[string][]pkgconfigsetup.LintIssue {
+	result := map[string][]pkgconfigsetup.LintIssue{}
+	if confdPath == "" {
+		return result
+	}
+
+	var files []string
+	entries, _ := os.ReadDir(confdPath)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+			continue
+		}
+		dirEntries, _ := os.ReadDir(filepath.Join(confdPath, entry.Name()))
+		for _, dirEntry := range dirEntries {
+			if !dirEntry.IsDir() {
+				files = append(files, filepath.Join(entry.Name(), dirEntry.Name()))
+			}
+		}
+	}
+
+	knownKeys := providers.CheckConfigKeys()
+	for _, file := range files {
+		ext := filepath.Ext(strings.TrimSuffix(file, ".default"))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		var issues []pkgconfigsetup.LintIssue
+		data, err := os.ReadFile(filepath.Join(confdPath, file))
+		var unknownKeys []string
+		if err == nil {
+			unknownKeys, err = providers.FindUnknownKeys(data)
+		}
+		if err != nil {
+			issues = append(issues, pkgconfigsetup.LintIssue{Kind: lintInvalidFile, Message: err.Error()})
+		}
+		for _, key := range unknownKeys {
+			message := "unknown key, it is ignored"
+			if suggestion := pkgconfigsetup.SuggestKey(key, knownKeys); suggestion != "" {
+				message += fmt.Sprintf(", did you mean `%s`?", suggestion)
+			}
+			issues = append(issues, pkgconfigsetup.LintIssue{Kind: pkgconfigsetup.LintUnknownKey, Key: key, Message: message})
+		}
+		if len(issues) > 0 {
+			result[file] = issues
+		}
+	}
+	return result
+}
+
+func printSchema(_ log.Component, config config.Component, _ *cliParams) error {
+	out, err := json.MarshalIndent(pkgconfigsetup.GetSchema(config), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+)
+
+func writeCheckFiles(t *testing.T, files map[string]string) string {
+	confd := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(confd, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return confd
+}
+
+func TestRunLint(t *testing.T) {
+	confd := writeCheckFiles(t, map[string]string{
+		"redisdb.d/conf.yaml":      "init_config:\ninstance:\n  - host: localhost\n",
+		"redisdb.d/auto_conf.yaml": "ad_identifiers: [redis]\ninstances: [{}]\n",
+		"disk.d/conf.yaml.default": "instances: [{}]\nlog:\n  - type: file\n",
+		"http_check.yaml":          "instances: [{]\n",
+		"notes.txt":                "not a check configuration",
+	})
+	cfg := config.NewMockFromYAML(t, "log_leve: debug\nlogs_enabled: maybe\nconfd_path: "+confd+"\n")
+
+	var out bytes.Buffer
+	err := runLint(cfg, false, &out)
+	assert.EqualError(t, err, "found 5 configuration issues")
+	assert.Contains(t, out.String(), "  log_leve: unknown setting, it is ignored, did you mean `log_level`?\n")
+	assert.Contains(t, out.String(), "  logs_enabled: the value `maybe` from the configuration file is not of type boolean\n")
+	assert.Contains(t, out.String(), "  "+filepath.Join("redisdb.d", "conf.yaml")+"\n    instance: unknown key, it is ignored, did you mean `instances`?\n")
+	assert.Contains(t, out.String(), "  "+filepath.Join("disk.d", "conf.yaml.default")+"\n    log: unknown key, it is ignored, did you mean `logs`?\n")
+	assert.Contains(t, out.String(), "  http_check.yaml\n    yaml: ")
+	assert.NotContains(t, out.String(), "auto_conf.yaml")
+	assert.NotContains(t, out.String(), "notes.txt")
+
+	out.Reset()
+	err = runLint(cfg, true, &out)
+	assert.Error(t, err)
+	var result lintResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Len(t, result.Issues, 2)
+	assert.Equal(t, []pkgconfigsetup.LintIssue{
+		{Kind: pkgconfigsetup.LintUnknownKey, Key: "instance", Message: "unknown key, it is ignored, did you mean `instances`?"},
+	}, result.CheckFiles[filepath.Join("redisdb.d", "conf.yaml")])
+	assert.Equal(t, lintInvalidFile, result.CheckFiles["http_check.yaml"][0].Kind)
+}
+
+func TestRunLintNoIssue(t *testing.T) {
+	confd := writeCheckFiles(t, map[string]string{
+		"redisdb.d/conf.yaml": "init_config:\ninstances:\n  - host: localhost\n",
+	})
+	cfg := config.NewMockFromYAML(t, "log_level: debug\nconfd_path: "+confd+"\n")
+
+	var out bytes.Buffer
+	require.NoError(t, runLint(cfg, true, &out))
+	var result lintResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Empty(t, result.Issues)
+	assert.Empty(t, result.CheckFiles)
+}
+
+func TestSchemaIsJSON(t *testing.T) {
+	_, err := json.Marshal(pkgconfigsetup.GetSchema(config.NewMock(t)))
+	assert.NoError(t, err)
+}
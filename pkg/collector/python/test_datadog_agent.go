@@ -162,6 +162,22 @@ func testObfuscaterConfig(t *testing.T) {
 			Enabled:     true,
 			KeepCommand: false,
 		},
+		GraphQL: obfuscate.GraphQLConfig{
+			Enabled:     false,
+			KeepAliases: false,
+		},
+		CQL: obfuscate.CQLConfig{
+			Enabled:       false,
+			ReplaceDigits: false,
+		},
+		DynamoDB: obfuscate.DynamoDBConfig{
+			Enabled:                         true,
+			RemoveExpressionAttributeValues: false,
+		},
+		Kafka: obfuscate.KafkaConfig{
+			Enabled:        true,
+			KeepMessageKey: false,
+		},
 		CreditCard: obfuscate.CreditCardsConfig{
 			Enabled:    true,
 			Luhn:       false,
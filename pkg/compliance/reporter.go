@@ -50,6 +50,7 @@ func NewLogReporter(hostname string, sourceName, sourceType string, endpoints *c
 		auditor,
 		&diagnostic.NoopMessageReceiver{},
 		nil, // processingRules
+		nil, // logMetrics
 		endpoints,
 		dstcontext,
 		&common.NoopStatusProvider{},
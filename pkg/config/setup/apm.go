@@ -45,6 +45,14 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("apm_config.obfuscation.valkey.remove_all_args", false, "DD_APM_OBFUSCATION_VALKEY_REMOVE_ALL_ARGS")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.memcached.enabled", true, "DD_APM_OBFUSCATION_MEMCACHED_ENABLED")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.memcached.keep_command", false, "DD_APM_OBFUSCATION_MEMCACHED_KEEP_COMMAND")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.graphql.enabled", false, "DD_APM_OBFUSCATION_GRAPHQL_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.graphql.keep_aliases", false, "DD_APM_OBFUSCATION_GRAPHQL_KEEP_ALIASES")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.cql.enabled", false, "DD_APM_OBFUSCATION_CQL_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.cql.replace_digits", false, "DD_APM_OBFUSCATION_CQL_REPLACE_DIGITS")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.dynamodb.enabled", true, "DD_APM_OBFUSCATION_DYNAMODB_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.dynamodb.remove_expression_attribute_values", false, "DD_APM_OBFUSCATION_DYNAMODB_REMOVE_EXPRESSION_ATTRIBUTE_VALUES")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.kafka.enabled", true, "DD_APM_OBFUSCATION_KAFKA_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.kafka.keep_message_key", false, "DD_APM_OBFUSCATION_KAFKA_KEEP_MESSAGE_KEY")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.cache.enabled", true, "DD_APM_OBFUSCATION_CACHE_ENABLED")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.cache.max_size", 5000000, "DD_APM_OBFUSCATION_CACHE_MAX_SIZE")
 	config.SetKnown("apm_config.filter_tags.require")
@@ -136,7 +144,20 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.probabilistic_sampler.enabled", "DD_APM_PROBABILISTIC_SAMPLER_ENABLED")
 	config.BindEnv("apm_config.probabilistic_sampler.sampling_percentage", "DD_APM_PROBABILISTIC_SAMPLER_SAMPLING_PERCENTAGE")
 	config.BindEnv("apm_config.probabilistic_sampler.hash_seed", "DD_APM_PROBABILISTIC_SAMPLER_HASH_SEED")
+	config.BindEnv("apm_config.trace_sampling_rules", "DD_APM_TRACE_SAMPLING_RULES")
+	config.ParseEnvAsSlice("apm_config.trace_sampling_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"apm_config.trace_sampling_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_buffer_size", "DD_APM_TAIL_SAMPLING_MAX_BUFFER_SIZE")
 	config.BindEnvAndSetDefault("apm_config.error_tracking_standalone.enabled", false, "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.zipkin_receiver.enabled", false, "DD_APM_ZIPKIN_RECEIVER_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.jaeger_receiver.enabled", false, "DD_APM_JAEGER_RECEIVER_ENABLED")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -188,6 +209,10 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("apm_config.obfuscation.credit_cards.keep_values", []string{}, "DD_APM_OBFUSCATION_CREDIT_CARDS_KEEP_VALUES")
 	config.BindEnvAndSetDefault("apm_config.sql_obfuscation_mode", "", "DD_APM_SQL_OBFUSCATION_MODE")
 	config.BindEnvAndSetDefault("apm_config.debug.port", 5012, "DD_APM_DEBUG_PORT")
+	// Location to store trace payload captures by default
+	config.BindEnvAndSetDefault("apm_config.capture_path", "", "DD_APM_CAPTURE_PATH")
+	// Maximum size of a trace payload capture file, 100MB by default
+	config.BindEnvAndSetDefault("apm_config.capture_max_bytes", 100*1024*1024, "DD_APM_CAPTURE_MAX_BYTES")
 	config.BindEnv("apm_config.features", "DD_APM_FEATURES")
 	config.ParseEnvAsStringSlice("apm_config.features", func(s string) []string {
 		// Either commas or spaces can be used as separators.
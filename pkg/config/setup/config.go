@@ -1359,6 +1359,12 @@ func serializer(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("enable_payloads.service_checks", true)
 	config.BindEnvAndSetDefault("enable_payloads.sketches", true)
 	config.BindEnvAndSetDefault("enable_payloads.json_to_v1_intake", true)
+
+	// Serializer: export the series and sketches as OTLP metrics, in addition to the Datadog intake
+	config.BindEnvAndSetDefault("serializer_otlp_exporter.enabled", false)
+	config.BindEnvAndSetDefault("serializer_otlp_exporter.endpoint", "http://localhost:4318/v1/metrics")
+	config.BindEnvAndSetDefault("serializer_otlp_exporter.headers", map[string]string{})
+	config.BindEnvAndSetDefault("serializer_otlp_exporter.timeout", 10) // in seconds
 }
 
 func aggregator(config pkgconfigmodel.Setup) {
@@ -1408,6 +1414,20 @@ func forwarder(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
 
+	// Forwarder archive of the transactions sent
+	config.BindEnvAndSetDefault("forwarder_archive_path", "")
+	config.BindEnvAndSetDefault("forwarder_archive_max_size_in_bytes", 0) // 0 means disabled. Maximum size per domain.
+
+	// Forwarder routing rules, validated when the forwarder starts
+	config.BindEnv("forwarder_routing_rules")
+	config.ParseEnvAsSlice("forwarder_routing_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"forwarder_routing_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
@@ -1420,6 +1440,16 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	config.BindEnvAndSetDefault("dogstatsd_http_port", 0)  // Notice: 0 means HTTP listener disabled
+	// The maximum size of the decompressed body of a request to the HTTP listener.
+	config.BindEnvAndSetDefault("dogstatsd_http_max_request_size", 4*1024*1024)
+	// The port of the Prometheus remote-write receiver, 0 means disabled.
+	config.BindEnvAndSetDefault("dogstatsd_remote_write_port", 0)
+	// The maximum size of the decompressed body of a request to the remote-write receiver.
+	config.BindEnvAndSetDefault("dogstatsd_remote_write_max_request_size", 16*1024*1024)
+	// The ports of the Graphite plaintext and pickle listeners, 0 means disabled.
+	config.BindEnvAndSetDefault("dogstatsd_graphite_port", 0)
+	config.BindEnvAndSetDefault("dogstatsd_graphite_pickle_port", 0)
 	// Experimental and not officially supported for now.
 	// Options are: udp, uds, named_pipe
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
@@ -1476,6 +1506,11 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	// Force the amount of dogstatsd workers (mainly used for benchmarks or some very specific use-case)
 	config.BindEnvAndSetDefault("dogstatsd_workers_count", 0)
 
+	// Per-metric-name and per-origin DogStatsD context budgets, 0 means no budget.
+	config.BindEnvAndSetDefault("dogstatsd_context_limits.metric_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limits.origin_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limits.strip_tags", []string{})
+
 	// To enable the following feature, GODEBUG must contain `madvdontneed=1`
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.enabled", false)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.low_soft_limit", 0.7)
@@ -1497,6 +1532,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 		}
 		return mappings
 	})
+	// reloaded by the DogStatsD server when updated at runtime
+	config.BindEnv("dogstatsd_metric_relabel_configs")
+	config.ParseEnvAsSlice("dogstatsd_metric_relabel_configs", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_metric_relabel_configs" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
@@ -1509,6 +1553,14 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnv("histogram_overrides")
+	config.ParseEnvAsSlice("histogram_overrides", func(in string) []interface{} {
+		var overrides []interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 }
 
 func logsagent(config pkgconfigmodel.Setup) {
@@ -1548,6 +1600,11 @@ func logsagent(config pkgconfigmodel.Setup) {
 	}
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules")
+	// add global metric rules generating metrics from all logs
+	config.BindEnv("logs_config.metric_rules")
+	// send logs to Kafka clusters and to a local spool, in addition to the intake
+	config.BindEnv("logs_config.kafka_endpoints")
+	config.BindEnv("logs_config.spool")
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// Tail a container's logs by querying the kubelet's API
@@ -1669,6 +1726,14 @@ func logsagent(config pkgconfigmodel.Setup) {
 	// more disk I/O at the wildcard log paths
 	config.BindEnvAndSetDefault("logs_config.file_wildcard_selection_mode", "by_name")
 
+	// Identify the tailed files by a checksum of their first bytes, stored in the registry with
+	// their offsets, to detect the rotations which keep the path or the inode of the files.
+	config.BindEnvAndSetDefault("logs_config.fingerprint_enabled", false)
+	// Number of bytes used to compute the fingerprint of the files
+	config.BindEnvAndSetDefault("logs_config.fingerprint_size", 1024)
+	// Decompress the gzip and zstd files tailed, e.g. rotated files, and read them once
+	config.BindEnvAndSetDefault("logs_config.read_compressed_files", false)
+
 	// Max size in MB an integration logs file can use
 	config.BindEnvAndSetDefault("logs_config.integrations_logs_files_max_size", 10)
 	// Max disk usage in MB all integrations logs files are allowed to use in total
@@ -1883,28 +1948,49 @@ func Merge(configPaths []string, config pkgconfigmodel.Config) error {
 	return nil
 }
 
-func findUnknownKeys(config pkgconfigmodel.Config) []string {
+// findUnknownKeys returns the loaded keys which are not known. A key is known when it, or one of its parents, is a known
+// key. The parents which only group other known keys, like `logs_config`, don't make their other children known.
+func findUnknownKeys(config pkgconfigmodel.Reader) []string {
 	var unknownKeys []string
 	knownKeys := config.GetKnownKeysLowercased()
+	sections := map[string]struct{}{}
+	for knownKey := range knownKeys {
+		for i := strings.IndexByte(knownKey, '.'); i >= 0; i = nextDot(knownKey, i) {
+			sections[knownKey[:i]] = struct{}{}
+		}
+	}
+
 	loadedKeys := config.AllKeysLowercased()
 	for _, loadedKey := range loadedKeys {
-		if _, found := knownKeys[loadedKey]; !found {
-			nestedValue := false
-			// If a value is within a known key it is considered known.
-			for knownKey := range knownKeys {
-				if strings.HasPrefix(loadedKey, knownKey+".") {
-					nestedValue = true
-					break
-				}
-			}
-			if !nestedValue {
-				unknownKeys = append(unknownKeys, loadedKey)
+		if _, found := knownKeys[loadedKey]; found {
+			continue
+		}
+		nestedValue := false
+		// If a value is within a known key it is considered known.
+		for i := strings.IndexByte(loadedKey, '.'); i >= 0; i = nextDot(loadedKey, i) {
+			_, known := knownKeys[loadedKey[:i]]
+			_, section := sections[loadedKey[:i]]
+			if known && !section {
+				nestedValue = true
+				break
 			}
 		}
+		if !nestedValue {
+			unknownKeys = append(unknownKeys, loadedKey)
+		}
 	}
 	return unknownKeys
 }
 
+// nextDot returns the index of the first dot of key after index i, or -1
+func nextDot(key string, i int) int {
+	next := strings.IndexByte(key[i+1:], '.')
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
 func findUnexpectedUnicode(config pkgconfigmodel.Config) []string {
 	messages := make([]string, 0)
 	checkAndRecordString := func(str string, prefix string) {
@@ -2130,8 +2216,8 @@ func LoadCustom(config pkgconfigmodel.Config, additionalKnownEnvVars []string) e
 		return err
 	}
 
-	for _, key := range findUnknownKeys(config) {
-		log.Warnf("Unknown key in config file: %v", key)
+	for _, issue := range LintConfig(config) {
+		log.Warnf("Invalid configuration setting %v", issue)
 	}
 
 	for _, v := range findUnknownEnvVars(config, os.Environ(), additionalKnownEnvVars) {
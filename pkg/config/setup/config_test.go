@@ -1496,6 +1496,14 @@ func TestAPMObfuscationDefaultValue(t *testing.T) {
 	assert.False(t, conf.GetBool("apm_config.obfuscation.redis.remove_all_args"))
 	assert.True(t, conf.GetBool("apm_config.obfuscation.memcached.enabled"))
 	assert.False(t, conf.GetBool("apm_config.obfuscation.memcached.keep_command"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.graphql.enabled"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.graphql.keep_aliases"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.cql.enabled"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.cql.replace_digits"))
+	assert.True(t, conf.GetBool("apm_config.obfuscation.dynamodb.enabled"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.dynamodb.remove_expression_attribute_values"))
+	assert.True(t, conf.GetBool("apm_config.obfuscation.kafka.enabled"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.kafka.keep_message_key"))
 	assert.True(t, conf.GetBool("apm_config.obfuscation.credit_cards.enabled"))
 	assert.False(t, conf.GetBool("apm_config.obfuscation.credit_cards.luhn"))
 	assert.Len(t, conf.GetStringSlice("apm_config.obfuscation.credit_cards.keep_values"), 0)
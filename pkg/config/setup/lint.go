@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package setup
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// LintIssueKind is the kind of a problem found in a configuration
+type LintIssueKind string
+
+const (
+	// LintUnknownKey is a setting which is not known by the agent, it is ignored
+	LintUnknownKey LintIssueKind = "unknown_key"
+	// LintTypeMismatch is a setting whose value doesn't have the type of its default value
+	LintTypeMismatch LintIssueKind = "type_mismatch"
+	// LintDeprecated is a deprecated setting
+	LintDeprecated LintIssueKind = "deprecated"
+)
+
+// lintSources are the sources of the settings configured by the user, with their description. The other sources are
+// set by the agent.
+var lintSources = map[pkgconfigmodel.Source]string{
+	pkgconfigmodel.SourceFile:          "configuration file",
+	pkgconfigmodel.SourceEnvVar:        "environment variables",
+	pkgconfigmodel.SourceFleetPolicies: "fleet policies",
+}
+
+// LintIssue is a problem found in a configuration
+type LintIssue struct {
+	Kind    LintIssueKind `json:"kind"`
+	Key     string        `json:"key"`
+	Message string        `json:"message"`
+}
+
+// String returns the key and the message of the issue
+func (i LintIssue) String() string {
+	if i.Key == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Key, i.Message)
+}
+
+// LintConfig checks the settings configured by the user against the schema of a configuration: it reports the
+// unknown settings, the values which don't match the type of the setting and the deprecated settings. The issues are
+// sorted by key.
+func LintConfig(config pkgconfigmodel.Reader) []LintIssue {
+	var issues []LintIssue
+
+	unknownKeys := findUnknownKeys(config)
+	if len(unknownKeys) > 0 {
+		knownKeys := config.GetKnownKeysLowercased()
+		for _, key := range unknownKeys {
+			message := "unknown setting, it is ignored"
+			if suggestion := SuggestKey(key, knownKeys); suggestion != "" {
+				message += fmt.Sprintf(", did you mean `%s`?", suggestion)
+			}
+			issues = append(issues, LintIssue{Kind: LintUnknownKey, Key: key, Message: message})
+		}
+	}
+
+	for _, setting := range GetSchema(config) {
+		if setting.Type == SettingTypeAny && !setting.Deprecated() {
+			continue
+		}
+		for _, value := range config.GetAllSources(setting.Key) {
+			source, found := lintSources[value.Source]
+			if value.Value == nil || !found {
+				continue
+			}
+			if !matchesSettingType(value.Value, setting.Type) {
+				issues = append(issues, LintIssue{
+					Kind:    LintTypeMismatch,
+					Key:     setting.Key,
+					Message: fmt.Sprintf("the value `%v` from the %s is not of type %s", value.Value, source, setting.Type),
+				})
+			}
+			if setting.Deprecated() {
+				issues = append(issues, LintIssue{
+					Kind:    LintDeprecated,
+					Key:     setting.Key,
+					Message: fmt.Sprintf("deprecated setting set in the %s, use `%s` instead", source, setting.ReplacedBy),
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(issues, func(a, b LintIssue) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return issues
+}
+
+// matchesSettingType returns whether a value can be read as a setting of a type. The strings are accepted when they
+// can be converted, like the values of environment variables.
+func matchesSettingType(value interface{}, settingType string) bool {
+	if s, ok := value.(string); ok {
+		s = strings.TrimSpace(s)
+		switch settingType {
+		case SettingTypeBoolean:
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		case SettingTypeInteger:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				_, err = strconv.ParseInt(s, 0, 64)
+				return err == nil
+			}
+			return f == float64(int64(f))
+		case SettingTypeNumber:
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		case SettingTypeDuration:
+			if _, err := time.ParseDuration(s); err == nil {
+				return true
+			}
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		default:
+			// the lists are split on spaces and the maps parsed as JSON
+			return true
+		}
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	switch kind {
+	case reflect.Bool:
+		return settingType == SettingTypeBoolean || settingType == SettingTypeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return settingType == SettingTypeInteger || settingType == SettingTypeNumber ||
+			settingType == SettingTypeDuration || settingType == SettingTypeString
+	case reflect.Float32, reflect.Float64:
+		if settingType == SettingTypeInteger {
+			f := reflect.ValueOf(value).Float()
+			return f == float64(int64(f))
+		}
+		return settingType == SettingTypeNumber || settingType == SettingTypeDuration || settingType == SettingTypeString
+	case reflect.Slice, reflect.Array:
+		return settingType == SettingTypeList
+	case reflect.Map:
+		return settingType == SettingTypeMap
+	default:
+		return true
+	}
+}
+
+// SuggestKey returns the known key closest to an unknown key, or the only known key with the same last segment when
+// no key is close enough. It returns an empty string when there is no good suggestion.
+func SuggestKey(key string, knownKeys map[string]interface{}) string {
+	maxDistance := max(2, len(key)/6)
+	suggestion := ""
+	suggestionDistance := maxDistance + 1
+	for knownKey := range knownKeys {
+		d := editDistance(key, knownKey, suggestionDistance+1)
+		if d < suggestionDistance || (d == suggestionDistance && d <= maxDistance && knownKey < suggestion) {
+			suggestion, suggestionDistance = knownKey, d
+		}
+	}
+	if suggestion != "" {
+		return suggestion
+	}
+
+	// the setting may be at the wrong level, like `batch_wait` instead of `logs_config.batch_wait`
+	lastSegment := key[strings.LastIndex(key, ".")+1:]
+	for knownKey := range knownKeys {
+		if knownKey == lastSegment || strings.HasSuffix(knownKey, "."+lastSegment) {
+			if suggestion != "" {
+				return ""
+			}
+			suggestion = knownKey
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions of adjacent characters
+// between two strings. It returns early with a value of at least limit when the distance is at least limit.
+func editDistance(a, b string, limit int) int {
+	if diff := len(a) - len(b); diff >= limit || -diff >= limit {
+		return limit
+	}
+
+	// rows i-2, i-1 and i of the matrix of the distances between the prefixes of a and b
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+	return prev[len(b)]
+}
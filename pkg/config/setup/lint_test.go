@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSchema(t *testing.T) {
+	conf := newTestConf(t)
+	schema := GetSchema(conf)
+
+	settings := map[string]SettingSchema{}
+	for _, setting := range schema {
+		settings[setting.Key] = setting
+	}
+	assert.Len(t, settings, len(conf.GetKnownKeysLowercased()))
+
+	assert.Equal(t, SettingSchema{Key: "logs_enabled", Type: SettingTypeBoolean, Default: false}, settings["logs_enabled"])
+	assert.Equal(t, SettingSchema{Key: "forwarder_timeout", Type: SettingTypeInteger, Default: 20}, settings["forwarder_timeout"])
+	assert.Equal(t, SettingTypeString, settings["log_level"].Type)
+	assert.Equal(t, SettingTypeList, settings["cloud_provider_metadata"].Type)
+	assert.Equal(t, SettingTypeMap, settings["additional_endpoints"].Type)
+	assert.Equal(t, SettingTypeAny, settings["forwarder_retry_queue_payloads_max_size"].Type)
+	assert.Equal(t, "logs_enabled", settings["log_enabled"].ReplacedBy)
+	assert.True(t, settings["log_enabled"].Deprecated())
+	assert.False(t, settings["logs_enabled"].Deprecated())
+}
+
+func TestDeprecatedSettingsAreKnown(t *testing.T) {
+	conf := newTestConf(t)
+	for key, replacement := range deprecatedSettings {
+		assert.True(t, conf.IsKnown(key), key)
+		assert.True(t, conf.IsKnown(replacement), replacement)
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	yaml := `
+log_leve: debug
+kubelet_api_client_read_timeout: 10s
+zzz_unrelated: true
+logs_enabled: "yes please"
+forwarder_timeout: 1.5
+log_enabled: true
+additional_endpoints:
+  - https://app.datadoghq.com
+logs_config:
+  use_compresion: false
+  batch_wait: "10"
+tags: "env:prod"
+`
+	conf := confFromYAML(t, yaml)
+
+	assert.Equal(t, []LintIssue{
+		{Kind: LintTypeMismatch, Key: "additional_endpoints", Message: "the value `[https://app.datadoghq.com]` from the configuration file is not of type map"},
+		{Kind: LintTypeMismatch, Key: "forwarder_timeout", Message: "the value `1.5` from the configuration file is not of type integer"},
+		{Kind: LintUnknownKey, Key: "kubelet_api_client_read_timeout", Message: "unknown setting, it is ignored, did you mean `logs_config.kubelet_api_client_read_timeout`?"},
+		{Kind: LintDeprecated, Key: "log_enabled", Message: "deprecated setting set in the configuration file, use `logs_enabled` instead"},
+		{Kind: LintUnknownKey, Key: "log_leve", Message: "unknown setting, it is ignored, did you mean `log_level`?"},
+		{Kind: LintUnknownKey, Key: "logs_config.use_compresion", Message: "unknown setting, it is ignored, did you mean `logs_config.use_compression`?"},
+		{Kind: LintTypeMismatch, Key: "logs_enabled", Message: "the value `yes please` from the configuration file is not of type boolean"},
+		{Kind: LintUnknownKey, Key: "zzz_unrelated", Message: "unknown setting, it is ignored"},
+	}, LintConfig(conf))
+}
+
+func TestLintConfigEnvVars(t *testing.T) {
+	t.Setenv("DD_LOGS_ENABLED", "maybe")
+	t.Setenv("DD_FORWARDER_TIMEOUT", "30")
+	t.Setenv("DD_LOG_ENABLED", "true")
+	conf := newTestConf(t)
+
+	assert.Equal(t, []LintIssue{
+		{Kind: LintDeprecated, Key: "log_enabled", Message: "deprecated setting set in the environment variables, use `logs_enabled` instead"},
+		{Kind: LintTypeMismatch, Key: "logs_enabled", Message: "the value `maybe` from the environment variables is not of type boolean"},
+	}, LintConfig(conf))
+}
+
+func TestLintConfigValid(t *testing.T) {
+	yaml := `
+api_key: abcdef
+log_level: info
+logs_enabled: "true"
+forwarder_timeout: "30"
+tags:
+  - env:prod
+additional_endpoints:
+  https://app.datadoghq.com:
+    - key
+logs_config:
+  batch_wait: 5
+`
+	assert.Empty(t, LintConfig(confFromYAML(t, yaml)))
+}
+
+func TestMatchesSettingType(t *testing.T) {
+	for _, tc := range []struct {
+		value       interface{}
+		settingType string
+		matches     bool
+	}{
+		{true, SettingTypeBoolean, true},
+		{"false", SettingTypeBoolean, true},
+		{"nope", SettingTypeBoolean, false},
+		{1, SettingTypeBoolean, false},
+		{12, SettingTypeInteger, true},
+		{12.0, SettingTypeInteger, true},
+		{12.5, SettingTypeInteger, false},
+		{"0x10", SettingTypeInteger, true},
+		{"ten", SettingTypeInteger, false},
+		{12, SettingTypeNumber, true},
+		{"0.5", SettingTypeNumber, true},
+		{"10s", SettingTypeDuration, true},
+		{30, SettingTypeDuration, true},
+		{"soon", SettingTypeDuration, false},
+		{time.Second, SettingTypeDuration, true},
+		{12, SettingTypeString, true},
+		{[]interface{}{"a"}, SettingTypeString, false},
+		{"a b", SettingTypeList, true},
+		{[]string{"a"}, SettingTypeList, true},
+		{map[string]interface{}{}, SettingTypeList, false},
+		{`{"a": "b"}`, SettingTypeMap, true},
+		{map[interface{}]interface{}{}, SettingTypeMap, true},
+		{true, SettingTypeMap, false},
+	} {
+		assert.Equal(t, tc.matches, matchesSettingType(tc.value, tc.settingType), "%#v as %s", tc.value, tc.settingType)
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	knownKeys := map[string]interface{}{
+		"api_key":                nil,
+		"apm_config.enabled":     nil,
+		"logs_config.batch_wait": nil,
+		"logs_config.enabled":    nil,
+		"process_config.enabled": nil,
+	}
+	assert.Equal(t, "api_key", SuggestKey("apikey", knownKeys))
+	assert.Equal(t, "apm_config.enabled", SuggestKey("apm_config.enabeld", knownKeys))
+	assert.Equal(t, "logs_config.batch_wait", SuggestKey("batch_wait", knownKeys))
+	// several keys end with `enabled`
+	assert.Equal(t, "", SuggestKey("enabled", knownKeys))
+	assert.Equal(t, "", SuggestKey("something_else", knownKeys))
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"enabled", "enabeld", 1},
+		{"log_level", "log_leve", 1},
+	} {
+		require.Equal(t, tc.distance, editDistance(tc.a, tc.b, 10), "%s %s", tc.a, tc.b)
+		require.Equal(t, tc.distance, editDistance(tc.b, tc.a, 10), "%s %s", tc.b, tc.a)
+	}
+	assert.Equal(t, 2, editDistance("kitten", "sitting", 2))
+}
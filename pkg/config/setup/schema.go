@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package setup
+
+import (
+	"reflect"
+	"slices"
+	"time"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// The types of the settings, inferred from their default value
+const (
+	SettingTypeBoolean  = "boolean"
+	SettingTypeInteger  = "integer"
+	SettingTypeNumber   = "number"
+	SettingTypeString   = "string"
+	SettingTypeDuration = "duration"
+	SettingTypeList     = "list"
+	SettingTypeMap      = "map"
+	// SettingTypeAny is the type of the settings without default value, their values are not checked
+	SettingTypeAny = "any"
+)
+
+// deprecatedSettings are the settings replaced by another setting, they are still read by the agent
+var deprecatedSettings = map[string]string{
+	"apm_config.max_traces_per_second":                 "apm_config.target_traces_per_second",
+	"compliance_config.xccdf.enabled":                  "compliance_config.host_benchmarks.enabled",
+	"flare_stripped_keys":                              "scrubber.additional_keys",
+	"forwarder_retry_queue_max_size":                   "forwarder_retry_queue_payloads_max_size",
+	"ipc_address":                                      "cmd_host",
+	"log_enabled":                                      "logs_enabled",
+	"logs_config.use_http":                             "logs_config.force_use_http",
+	"logs_config.use_tcp":                              "logs_config.force_use_tcp",
+	"process_config.orchestrator_additional_endpoints": "orchestrator_explorer.orchestrator_additional_endpoints",
+	"process_config.orchestrator_dd_url":               "orchestrator_explorer.orchestrator_dd_url",
+	"tracemalloc_blacklist":                            "tracemalloc_exclude",
+	"tracemalloc_whitelist":                            "tracemalloc_include",
+}
+
+// SettingSchema describes a setting known by the agent
+type SettingSchema struct {
+	Key     string      `json:"key"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default,omitempty"`
+	// ReplacedBy is the setting to use instead of a deprecated setting
+	ReplacedBy string `json:"replaced_by,omitempty"`
+}
+
+// Deprecated returns whether the setting is deprecated
+func (s SettingSchema) Deprecated() bool {
+	return s.ReplacedBy != ""
+}
+
+// GetSchema returns the schema of the settings known by a configuration, sorted by key. The type of each setting is
+// inferred from its default value.
+func GetSchema(config pkgconfigmodel.Reader) []SettingSchema {
+	knownKeys := config.GetKnownKeysLowercased()
+	keys := make([]string, 0, len(knownKeys))
+	for key := range knownKeys {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	schema := make([]SettingSchema, 0, len(keys))
+	for _, key := range keys {
+		defaultValue := getSourceValue(config, key, pkgconfigmodel.SourceDefault)
+		schema = append(schema, SettingSchema{
+			Key:        key,
+			Type:       settingType(defaultValue),
+			Default:    defaultValue,
+			ReplacedBy: deprecatedSettings[key],
+		})
+	}
+	return schema
+}
+
+func getSourceValue(config pkgconfigmodel.Reader, key string, source pkgconfigmodel.Source) interface{} {
+	for _, value := range config.GetAllSources(key) {
+		if value.Source == source {
+			return value.Value
+		}
+	}
+	return nil
+}
+
+func settingType(value interface{}) string {
+	if value == nil {
+		return SettingTypeAny
+	}
+	if _, ok := value.(time.Duration); ok {
+		return SettingTypeDuration
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Bool:
+		return SettingTypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return SettingTypeInteger
+	case reflect.Float32, reflect.Float64:
+		return SettingTypeNumber
+	case reflect.String:
+		return SettingTypeString
+	case reflect.Slice, reflect.Array:
+		return SettingTypeList
+	case reflect.Map:
+		return SettingTypeMap
+	default:
+		return SettingTypeAny
+	}
+}
@@ -33,6 +33,8 @@ const registryAPIVersion = 2
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFingerprint(identifier string) uint64
+	KeepAlive(identifier string)
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +44,8 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	// Fingerprint is the fingerprint of the file tailed at the offset, if it's known.
+	Fingerprint uint64 `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -150,6 +154,26 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the fingerprint of the file of the last committed offset for a given
+// identifier, returns 0 if it does not exist or is unknown.
+func (a *RegistryAuditor) GetFingerprint(identifier string) uint64 {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return 0
+	}
+	return entry.Fingerprint
+}
+
+// KeepAlive resets the TTL of the entry of a given identifier, for the offsets which are still
+// valid but aren't updated anymore, like the final offsets of the compressed files.
+func (a *RegistryAuditor) KeepAlive(identifier string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.LastUpdated = time.Now().UTC()
+	}
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -172,7 +196,7 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.MessageMetas {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
@@ -226,7 +250,7 @@ func (a *RegistryAuditor) cleanupRegistry() {
 }
 
 // updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint uint64, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -249,6 +273,7 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Fingerprint:        fingerprint,
 	}
 }
 
@@ -36,6 +36,12 @@ func (a *NullAuditor) GetOffset(_ string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(_ string) string { return "" }
 
+// GetFingerprint returns 0.
+func (a *NullAuditor) GetFingerprint(_ string) uint64 { return 0 }
+
+// KeepAlive does nothing.
+func (a *NullAuditor) KeepAlive(_ string) {}
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -26,3 +26,10 @@ type Destination interface {
 	// signaled when the retry state changes. isRetrying can be nil if you don't need to handle retries.
 	Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{})
 }
+
+// Replayer is implemented by the fallback destinations keeping the payloads they receive, so that
+// they're sent to the reliable destinations once these are available again.
+type Replayer interface {
+	// NextReplay removes the oldest kept payload and returns it, it returns nil if there is none.
+	NextReplay() *message.Payload
+}
@@ -9,6 +9,9 @@ package client
 type Destinations struct {
 	Reliable   []Destination
 	Unreliable []Destination
+	// Fallback receives the payloads while all the reliable destinations are retrying, it's nil
+	// when there is none. Its payloads don't update the auditor.
+	Fallback Destination
 }
 
 // NewDestinations returns a new destinations composite.
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kafka provides a destination sending the logs payloads to a Kafka topic.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	clientID = "datadog-agent"
+	// deliveryTimeout is the time after which a payload which couldn't be delivered fails, to be
+	// retried with a backoff.
+	deliveryTimeout = 30 * time.Second
+	// contentEncodingHeader is the record header holding the encoding of compressed payloads.
+	contentEncodingHeader = "Content-Encoding"
+	// sourceTag is the source tag of the telemetry, Kafka destinations are only used for logs.
+	sourceTag = "logs"
+)
+
+// Destination sends each payload as a record to a Kafka topic. The payloads are the batches built
+// by the sender, encoded and compressed the same way they are for the main endpoint.
+type Destination struct {
+	endpoint config.KafkaEndpoint
+	client   *kgo.Client
+	retrier  *client.Retrier
+	destMeta *client.DestinationMetadata
+}
+
+// NewDestination returns a new Destination. It returns an error if the Kafka client can't be
+// created from the endpoint settings.
+func NewDestination(endpoint config.KafkaEndpoint, destinationsContext *client.DestinationsContext, shouldRetry bool, destMeta *client.DestinationMetadata) (*Destination, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(endpoint.Brokers...),
+		kgo.DefaultProduceTopic(endpoint.Topic),
+		kgo.ClientID(clientID),
+		kgo.RecordDeliveryTimeout(deliveryTimeout),
+	}
+	if endpoint.UseSSL {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{
+			InsecureSkipVerify: endpoint.TLSSkipVerify, //nolint:gosec // user-configured
+			MinVersion:         tls.VersionTLS12,
+		}))
+	}
+	switch endpoint.SASLMechanism {
+	case config.SASLMechanismPlain:
+		opts = append(opts, kgo.SASL(plain.Auth{User: endpoint.Username, Pass: endpoint.Password}.AsMechanism()))
+	case config.SASLMechanismSCRAMSHA256:
+		opts = append(opts, kgo.SASL(scram.Auth{User: endpoint.Username, Pass: endpoint.Password}.AsSha256Mechanism()))
+	case config.SASLMechanismSCRAMSHA512:
+		opts = append(opts, kgo.SASL(scram.Auth{User: endpoint.Username, Pass: endpoint.Password}.AsSha512Mechanism()))
+	}
+	kafkaClient, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kafka client for %s: %w", endpoint.Target(), err)
+	}
+	return &Destination{
+		endpoint: endpoint,
+		client:   kafkaClient,
+		retrier:  client.NewRetrier(endpoint.Target(), endpoint.RetrySettings, destinationsContext, shouldRetry),
+		destMeta: destMeta,
+	}, nil
+}
+
+// IsMRF returns false, Kafka destinations aren't used for Multi-Region Failover.
+func (d *Destination) IsMRF() bool {
+	return false
+}
+
+// Target is the address of the destination.
+func (d *Destination) Target() string {
+	return d.endpoint.Target()
+}
+
+// Metadata returns the metadata of the destination
+func (d *Destination) Metadata() *client.DestinationMetadata {
+	return d.destMeta
+}
+
+// Start starts sending the payloads of the input to Kafka, one at a time.
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go func() {
+		for payload := range input {
+			d.sendAndRetry(payload, output, isRetrying)
+		}
+		d.client.Close()
+		d.retrier.SetRetrying(false, isRetrying)
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+func (d *Destination) sendAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	err := d.retrier.Send(func(ctx context.Context) error {
+		return d.send(ctx, payload)
+	}, isRetrying)
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err == nil {
+		metrics.LogsSent.Add(payload.Count())
+		metrics.TlmLogsSent.Add(float64(payload.Count()))
+		metrics.BytesSent.Add(int64(payload.UnencodedSize))
+		metrics.TlmBytesSent.Add(float64(payload.UnencodedSize), sourceTag)
+		metrics.EncodedBytesSent.Add(int64(len(payload.Encoded)))
+		metrics.TlmEncodedBytesSent.Add(float64(len(payload.Encoded)), sourceTag)
+	}
+	output <- payload
+}
+
+func (d *Destination) send(ctx context.Context, payload *message.Payload) error {
+	record := &kgo.Record{Value: payload.Encoded}
+	if payload.Encoding != "" {
+		record.Headers = []kgo.RecordHeader{{Key: contentEncodingHeader, Value: []byte(payload.Encoding)}}
+	}
+	err := d.client.ProduceSync(ctx, record).FirstErr()
+	if err == nil {
+		return nil
+	}
+	var kafkaErr *kerr.Error
+	if errors.As(err, &kafkaErr) && !kafkaErr.Retriable {
+		// e.g. the payload is larger than the maximum size of a record
+		log.Warnf("Dropping payload of %d logs rejected by %s: %v", payload.Count(), d.endpoint.Target(), err)
+		return err
+	}
+	return client.NewRetryableError(err)
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestNewDestination(t *testing.T) {
+	for _, mechanism := range []string{"", config.SASLMechanismPlain, config.SASLMechanismSCRAMSHA256, config.SASLMechanismSCRAMSHA512} {
+		endpoint := config.KafkaEndpoint{
+			Brokers:       []string{"localhost:9092", "localhost:9093"},
+			Topic:         "logs",
+			UseSSL:        true,
+			SASLMechanism: mechanism,
+			Username:      "user",
+			Password:      "pass",
+		}
+		destination, err := NewDestination(endpoint, client.NewDestinationsContext(), true, client.NewNoopDestinationMetadata())
+		require.NoError(t, err, mechanism)
+		assert.False(t, destination.IsMRF())
+		assert.Equal(t, "kafka://localhost:9092,localhost:9093/logs", destination.Target())
+		destination.client.Close()
+	}
+}
+
+func TestDestinationStopsWhenCancelled(t *testing.T) {
+	destinationsCtx := client.NewDestinationsContext()
+	endpoint := config.KafkaEndpoint{Brokers: []string{"localhost:0"}, Topic: "logs"}
+	destination, err := NewDestination(endpoint, destinationsCtx, true, client.NewNoopDestinationMetadata())
+	require.NoError(t, err)
+
+	// the destinations context isn't started, the payloads are never sent
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+	stop := destination.Start(input, output, nil)
+	input <- &message.Payload{Encoded: []byte("hello")}
+	close(input)
+	<-stop
+	assert.Len(t, output, 0)
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Retrier retries the failed sends of a destination with an exponential backoff, the way the HTTP
+// destination does, and reports the retry state changes of the destination. It must be used by a
+// single goroutine.
+type Retrier struct {
+	target              string
+	backoff             backoff.Policy
+	destinationsContext *DestinationsContext
+	shouldRetry         bool
+	nbErrors            int
+	retrying            bool
+}
+
+// NewRetrier returns a new Retrier. Sends are only retried if shouldRetry is true, i.e. if the
+// destination is reliable.
+func NewRetrier(target string, settings config.RetrySettings, destinationsContext *DestinationsContext, shouldRetry bool) *Retrier {
+	return &Retrier{
+		target: target,
+		backoff: backoff.NewExpBackoffPolicy(
+			settings.BackoffFactor,
+			settings.BackoffBase,
+			settings.BackoffMax,
+			settings.RecoveryInterval,
+			settings.RecoveryReset,
+		),
+		destinationsContext: destinationsContext,
+		shouldRetry:         shouldRetry,
+	}
+}
+
+// Send calls send until it succeeds, fails with an error which isn't a RetryableError, or the
+// destinations context is cancelled, in which case it returns context.Canceled. Failed sends
+// aren't retried if the destination isn't reliable. It returns the error of the last send.
+func (r *Retrier) Send(send func(ctx context.Context) error, isRetrying chan bool) error {
+	for {
+		if backoffDuration := r.backoff.GetBackoffDuration(r.nbErrors); backoffDuration > 0 {
+			log.Warnf("%s: sleeping for %s before retrying due to %d errors", r.target, backoffDuration, r.nbErrors)
+			r.waitForBackoff(backoffDuration)
+			metrics.RetryTimeSpent.Add(int64(backoffDuration))
+			metrics.RetryCount.Add(1)
+			metrics.TlmRetryCount.Add(1)
+		}
+
+		ctx := r.destinationsContext.Context()
+		if ctx == nil || ctx.Err() != nil {
+			r.SetRetrying(false, isRetrying)
+			return context.Canceled
+		}
+
+		err := send(ctx)
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			log.Warnf("Could not send payload to %s: %v", r.target, err)
+		}
+		if err != nil && ctx.Err() != nil {
+			// the send was interrupted by the shutdown of the destinations
+			r.SetRetrying(false, isRetrying)
+			return context.Canceled
+		}
+		if _, ok := err.(*RetryableError); ok && r.shouldRetry {
+			r.nbErrors = r.backoff.IncError(r.nbErrors)
+			r.SetRetrying(true, isRetrying)
+			continue
+		}
+		r.nbErrors = r.backoff.DecError(r.nbErrors)
+		r.SetRetrying(false, isRetrying)
+		return err
+	}
+}
+
+// SetRetrying updates the retry state of the destination, reporting its changes to isRetrying if
+// it's not nil.
+func (r *Retrier) SetRetrying(retrying bool, isRetrying chan bool) {
+	if retrying != r.retrying && isRetrying != nil {
+		isRetrying <- retrying
+	}
+	r.retrying = retrying
+}
+
+func (r *Retrier) waitForBackoff(d time.Duration) {
+	ctx := r.destinationsContext.Context()
+	if ctx == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	<-ctx.Done()
+}
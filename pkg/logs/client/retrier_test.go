@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+)
+
+var testRetrySettings = config.RetrySettings{
+	BackoffFactor:    2,
+	BackoffBase:      0.001,
+	BackoffMax:       0.01,
+	RecoveryInterval: 2,
+}
+
+func TestRetrierRetriesRetryableErrors(t *testing.T) {
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	retrier := NewRetrier("test", testRetrySettings, destinationsCtx, true)
+	isRetrying := make(chan bool, 10)
+	attempts := 0
+	err := retrier.Send(func(_ context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return NewRetryableError(errors.New("unavailable"))
+		}
+		return nil
+	}, isRetrying)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, true, <-isRetrying)
+	assert.Equal(t, false, <-isRetrying)
+	assert.Len(t, isRetrying, 0)
+}
+
+func TestRetrierDoesNotRetry(t *testing.T) {
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	// errors which aren't retryable
+	retrier := NewRetrier("test", testRetrySettings, destinationsCtx, true)
+	attempts := 0
+	err := retrier.Send(func(_ context.Context) error {
+		attempts++
+		return errors.New("rejected")
+	}, nil)
+	assert.EqualError(t, err, "rejected")
+	assert.Equal(t, 1, attempts)
+
+	// unreliable destinations
+	retrier = NewRetrier("test", testRetrySettings, destinationsCtx, false)
+	attempts = 0
+	err = retrier.Send(func(_ context.Context) error {
+		attempts++
+		return NewRetryableError(errors.New("unavailable"))
+	}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetrierStopsWhenCancelled(t *testing.T) {
+	destinationsCtx := NewDestinationsContext()
+	destinationsCtx.Start()
+
+	retrier := NewRetrier("test", testRetrySettings, destinationsCtx, true)
+	err := retrier.Send(func(_ context.Context) error {
+		destinationsCtx.Stop()
+		return NewRetryableError(errors.New("unavailable"))
+	}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
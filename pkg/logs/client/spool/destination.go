@@ -0,0 +1,312 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package spool provides a fallback destination writing the logs payloads to rotated local files
+// while the intake is unreachable, and reading them back once it's reachable again.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	filePrefix = "logs-"
+	fileSuffix = ".spool"
+
+	// maxRecordSize is the maximum size of a record accepted when reading a spool file.
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+// Each payload is written to a spool file as a record, prefixed by its size as a little endian
+// uint32. A record holds:
+//
+//	unencodedSize  uint32, little endian, the UnencodedSize of the payload
+//	encodingLen    uint8, the length of encoding
+//	encoding       the Encoding of the payload
+//	encoded        the Encoded bytes of the payload, up to the end of the record
+//
+// The metadata of the messages of a payload aren't written: the payloads read from the spool
+// don't update the auditor.
+const recordHeaderSize = 4 + 1
+
+// Destination writes the payloads to a spool. It's used as the fallback destination of the
+// workers of a sender, which all share the same Writer.
+type Destination struct {
+	writer   *Writer
+	retrier  *client.Retrier
+	destMeta *client.DestinationMetadata
+}
+
+// NewDestination returns a new Destination writing to the spool of writer.
+func NewDestination(writer *Writer, destinationsContext *client.DestinationsContext, destMeta *client.DestinationMetadata) *Destination {
+	return &Destination{
+		writer:   writer,
+		retrier:  client.NewRetrier(writer.endpoint.Target(), writer.endpoint.RetrySettings, destinationsContext, true),
+		destMeta: destMeta,
+	}
+}
+
+// IsMRF returns false, spools aren't used for Multi-Region Failover.
+func (d *Destination) IsMRF() bool {
+	return false
+}
+
+// Target is the address of the destination.
+func (d *Destination) Target() string {
+	return d.writer.endpoint.Target()
+}
+
+// Metadata returns the metadata of the destination
+func (d *Destination) Metadata() *client.DestinationMetadata {
+	return d.destMeta
+}
+
+// Start starts writing the payloads of the input to the spool, one at a time.
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go func() {
+		for payload := range input {
+			d.writeAndRetry(payload, output, isRetrying)
+		}
+		d.writer.Close()
+		d.retrier.SetRetrying(false, isRetrying)
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+func (d *Destination) writeAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	err := d.retrier.Send(func(_ context.Context) error {
+		return d.writer.Write(payload)
+	}, isRetrying)
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	output <- payload
+}
+
+// NextReplay removes the oldest payload from the spool and returns it, it returns nil if the
+// spool is empty.
+func (d *Destination) NextReplay() *message.Payload {
+	return d.writer.Read()
+}
+
+// Writer writes payloads to files in the spool directory, and reads them back. A file is rotated
+// when it reaches the maximum file size, and the oldest files are deleted to keep at most the
+// maximum number of files. It's safe for concurrent use.
+type Writer struct {
+	endpoint config.SpoolEndpoint
+	// nowFunc returns the current time, it's used to name the spool files.
+	nowFunc func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	fileSize int64
+	// readFile is the spool file being read, it's deleted once all its payloads have been read.
+	readFile   *os.File
+	readBuffer *bufio.Reader
+}
+
+// NewWriter returns a new Writer. The spool directory is created on the first write.
+func NewWriter(endpoint config.SpoolEndpoint) *Writer {
+	return &Writer{
+		endpoint: endpoint,
+		nowFunc:  time.Now,
+	}
+}
+
+// Write appends the payload to the current spool file, rotating it first if the payload doesn't
+// fit. Write errors are retryable, the file being reopened on the next attempt.
+func (w *Writer) Write(payload *message.Payload) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := make([]byte, 0, 4+recordHeaderSize+len(payload.Encoding)+len(payload.Encoded))
+	data = binary.LittleEndian.AppendUint32(data, uint32(recordHeaderSize+len(payload.Encoding)+len(payload.Encoded)))
+	data = binary.LittleEndian.AppendUint32(data, uint32(payload.UnencodedSize))
+	data = append(data, uint8(len(payload.Encoding)))
+	data = append(data, payload.Encoding...)
+	data = append(data, payload.Encoded...)
+
+	if w.file != nil && w.fileSize > 0 && w.fileSize+int64(len(data)) > w.endpoint.MaxFileSize {
+		w.closeFile()
+	}
+	if w.file == nil {
+		if err := w.openFile(); err != nil {
+			return client.NewRetryableError(err)
+		}
+	}
+
+	n, err := w.file.Write(data)
+	w.fileSize += int64(n)
+	if err != nil {
+		// the partial record is dropped when reading the file
+		w.closeFile()
+		return client.NewRetryableError(err)
+	}
+	return nil
+}
+
+// Read removes the oldest payload from the spool and returns it, it returns nil if the spool is
+// empty. The spool files are deleted once all their payloads have been read.
+func (w *Writer) Read() *message.Payload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		if w.readFile == nil && !w.openReadFile() {
+			return nil
+		}
+		payload, err := readRecord(w.readBuffer)
+		if err == nil {
+			return payload
+		}
+		if !errors.Is(err, io.EOF) {
+			log.Warnf("Could not read spool file %s, dropping its remaining payloads: %v", w.readFile.Name(), err)
+		}
+		w.closeReadFile()
+	}
+}
+
+// openReadFile opens the oldest spool file for reading. The current spool file is closed first if
+// it's the oldest one, so that it isn't written to anymore. It returns false if there is no file.
+func (w *Writer) openReadFile() bool {
+	files := w.files()
+	if len(files) == 0 {
+		return false
+	}
+	if w.file != nil && w.file.Name() == files[0] {
+		w.closeFile()
+	}
+	file, err := os.Open(files[0])
+	if err != nil {
+		log.Warnf("Could not open spool file %s: %v", files[0], err)
+		return false
+	}
+	w.readFile = file
+	w.readBuffer = bufio.NewReader(file)
+	return true
+}
+
+// closeReadFile closes the spool file being read and deletes it.
+func (w *Writer) closeReadFile() {
+	name := w.readFile.Name()
+	if err := w.readFile.Close(); err != nil {
+		log.Warnf("Could not close spool file %s: %v", name, err)
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Could not delete spool file %s: %v", name, err)
+	}
+	w.readFile, w.readBuffer = nil, nil
+}
+
+// readRecord reads the next payload of a spool file. It returns io.EOF at the end of the file and
+// io.ErrUnexpectedEOF if the last record is truncated.
+func readRecord(r *bufio.Reader) (*message.Payload, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < recordHeaderSize || n > maxRecordSize {
+		return nil, fmt.Errorf("invalid record size %d", n)
+	}
+	record := make([]byte, n)
+	if _, err := io.ReadFull(r, record); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	unencodedSize := binary.LittleEndian.Uint32(record)
+	encodingLen := int(record[4])
+	if recordHeaderSize+encodingLen > len(record) {
+		return nil, fmt.Errorf("invalid encoding length %d", encodingLen)
+	}
+	return &message.Payload{
+		Encoding:      string(record[recordHeaderSize : recordHeaderSize+encodingLen]),
+		Encoded:       record[recordHeaderSize+encodingLen:],
+		UnencodedSize: int(unencodedSize),
+	}, nil
+}
+
+// Close closes the current spool file, the next write opens a new one.
+func (w *Writer) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeFile()
+}
+
+// openFile creates a new spool file and deletes the oldest ones.
+func (w *Writer) openFile() error {
+	if err := os.MkdirAll(w.endpoint.Path, 0755); err != nil {
+		return fmt.Errorf("could not create spool directory: %w", err)
+	}
+	name := filePrefix + strconv.FormatInt(w.nowFunc().UnixNano(), 10) + fileSuffix
+	file, err := os.OpenFile(filepath.Join(w.endpoint.Path, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("could not create spool file: %w", err)
+	}
+	w.file = file
+	w.fileSize = 0
+	if info, err := file.Stat(); err == nil {
+		w.fileSize = info.Size()
+	}
+	w.prune()
+	return nil
+}
+
+func (w *Writer) closeFile() {
+	if w.file == nil {
+		return
+	}
+	if err := w.file.Close(); err != nil {
+		log.Warnf("Could not close spool file %s: %v", w.file.Name(), err)
+	}
+	w.file = nil
+}
+
+// files returns the spool files, from the oldest to the newest.
+func (w *Writer) files() []string {
+	files, err := filepath.Glob(filepath.Join(w.endpoint.Path, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		return nil
+	}
+	// the files are named after their creation time
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.Base(files[i]) < filepath.Base(files[j])
+	})
+	return files
+}
+
+// prune deletes the oldest spool files, keeping the maximum number of files, the current one
+// included.
+func (w *Writer) prune() {
+	files := w.files()
+	if len(files) <= w.endpoint.MaxFiles {
+		return
+	}
+	for _, file := range files[:len(files)-w.endpoint.MaxFiles] {
+		if err := os.Remove(file); err != nil {
+			log.Warnf("Could not delete spool file %s: %v", file, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestWriter(path string, maxFileSize int64, maxFiles int) *Writer {
+	writer := NewWriter(config.SpoolEndpoint{Path: path, MaxFileSize: maxFileSize, MaxFiles: maxFiles})
+	now := time.Unix(1700000000, 0)
+	writer.nowFunc = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return writer
+}
+
+func spoolFiles(t *testing.T, path string) []string {
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestWriterRotatesAndPrunesFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool")
+	// a record of 4 bytes of identity-encoded data takes 21 bytes
+	writer := newTestWriter(path, 50, 2)
+
+	for _, content := range []string{"aaaa", "bbbb", "cccc", "dddd"} {
+		require.NoError(t, writer.Write(&message.Payload{Encoded: []byte(content), Encoding: "identity"}))
+	}
+	writer.Close()
+
+	// two payloads fit in a file
+	assert.Equal(t, []string{"logs-1700000001000000000.spool", "logs-1700000002000000000.spool"}, spoolFiles(t, path))
+
+	for _, content := range []string{"eeee", "ffff"} {
+		require.NoError(t, writer.Write(&message.Payload{Encoded: []byte(content), Encoding: "identity"}))
+	}
+	writer.Close()
+	// the oldest file is deleted
+	assert.Equal(t, []string{"logs-1700000002000000000.spool", "logs-1700000003000000000.spool"}, spoolFiles(t, path))
+}
+
+func TestWriterReadsPayloadsBack(t *testing.T) {
+	path := t.TempDir()
+	writer := newTestWriter(path, 50, 3)
+	assert.Nil(t, writer.Read())
+
+	payloads := []*message.Payload{
+		{Encoded: []byte("aaaa"), Encoding: "identity", UnencodedSize: 4},
+		{Encoded: []byte("gz1"), Encoding: "gzip", UnencodedSize: 10},
+		{Encoded: []byte("bbbb"), Encoding: "identity", UnencodedSize: 4},
+	}
+	for _, payload := range payloads {
+		require.NoError(t, writer.Write(payload))
+	}
+
+	// the payloads are read in order, the current file included
+	for _, payload := range payloads {
+		assert.Equal(t, payload, writer.Read())
+	}
+	// the first file is deleted once read
+	assert.Equal(t, []string{"logs-1700000002000000000.spool"}, spoolFiles(t, path))
+
+	// the current file was closed to be read, the next write opens a new one
+	require.NoError(t, writer.Write(&message.Payload{Encoded: []byte("cccc"), Encoding: "identity", UnencodedSize: 4}))
+	assert.Equal(t, []byte("cccc"), writer.Read().Encoded)
+	assert.Nil(t, writer.Read())
+	assert.Empty(t, spoolFiles(t, path))
+}
+
+func TestWriterDropsTruncatedRecords(t *testing.T) {
+	path := t.TempDir()
+	writer := newTestWriter(path, 100, 2)
+	require.NoError(t, writer.Write(&message.Payload{Encoded: []byte("aaaa"), Encoding: "identity"}))
+	writer.Close()
+
+	file := filepath.Join(path, "logs-1700000001000000000.spool")
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, append(content, content[:len(content)-1]...), 0640))
+
+	assert.Equal(t, []byte("aaaa"), writer.Read().Encoded)
+	assert.Nil(t, writer.Read())
+	assert.Empty(t, spoolFiles(t, path))
+}
+
+func TestWriterErrorsAreRetryable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	writer := newTestWriter(path, 100, 2)
+
+	err := writer.Write(&message.Payload{Encoded: []byte("a")})
+	assert.IsType(t, &client.RetryableError{}, err)
+}
+
+func TestDestination(t *testing.T) {
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	path := t.TempDir()
+	destination := NewDestination(newTestWriter(path, 100, 2), destinationsCtx, client.NewNoopDestinationMetadata())
+	assert.False(t, destination.IsMRF())
+	assert.Equal(t, "file://"+path, destination.Target())
+
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+	stop := destination.Start(input, output, nil)
+
+	payload := &message.Payload{Encoded: []byte("hello"), Encoding: "identity", UnencodedSize: 5}
+	input <- payload
+	assert.Equal(t, payload, <-output)
+	close(input)
+	<-stop
+
+	assert.Equal(t, []string{"logs-1700000001000000000.spool"}, spoolFiles(t, path))
+	assert.Equal(t, payload, destination.NextReplay())
+	assert.Nil(t, destination.NextReplay())
+}
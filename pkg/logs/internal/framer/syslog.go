@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxOctetCountDigits is the maximum number of digits of the length of an
+// octet-counted syslog frame.
+const maxOctetCountDigits = 9
+
+// syslogMatcher implements FrameMatcher for syslog messages sent over a stream
+// (RFC 6587).  Each frame is either octet-counted, i.e. prefixed with the length
+// of the message and a space, or terminated by a newline.  As a syslog message
+// starts with '<', a frame is considered octet-counted when it starts with
+// digits followed by a space and '<'.
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Octet-counted messages longer than this value are truncated.
+	contentLenLimit int
+
+	// discard is the number of bytes left to discard from the truncated
+	// octet-counted message.
+	discard int
+}
+
+func (s *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if s.discard > 0 {
+		n := min(s.discard, len(buf))
+		s.discard -= n
+		return buf[:0], n
+	}
+
+	if msgLen, headerLen, ok := parseOctetCount(buf); ok {
+		if headerLen+msgLen > s.contentLenLimit {
+			// keep the first contentLenLimit bytes of the frame and discard the rest
+			if len(buf) < s.contentLenLimit {
+				return nil, 0
+			}
+			s.discard = headerLen + msgLen - s.contentLenLimit
+			return buf[headerLen:s.contentLenLimit], s.contentLenLimit
+		}
+		if len(buf) < headerLen+msgLen {
+			return nil, 0
+		}
+		return buf[headerLen : headerLen+msgLen], headerLen + msgLen
+	}
+
+	nl := bytes.IndexByte(buf[seen:], '\n')
+	if nl == -1 {
+		return nil, 0
+	}
+	eol := nl + seen
+	if eol > s.contentLenLimit {
+		return buf[:s.contentLenLimit], s.contentLenLimit
+	}
+	return buf[:eol], eol + 1
+}
+
+// parseOctetCount returns the message length and the header length of an
+// octet-counted frame, or false if buf doesn't start with an octet-counting header.
+func parseOctetCount(buf []byte) (int, int, bool) {
+	i := 0
+	for i < len(buf) && i < maxOctetCountDigits && buf[i] >= '0' && buf[i] <= '9' {
+		i++
+	}
+	if i == 0 || buf[0] == '0' || i+1 >= len(buf) || buf[i] != ' ' || buf[i+1] != '<' {
+		return 0, 0, false
+	}
+	msgLen, err := strconv.Atoi(string(buf[:i]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return msgLen, i + 1, true
+}
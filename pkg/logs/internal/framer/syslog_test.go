@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func syslogFrames(limit int, chunks ...string) ([]string, []int) {
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(msg *message.Message, rawDataLen int) {
+		gotContent = append(gotContent, string(msg.GetContent()))
+		gotLens = append(gotLens, rawDataLen)
+	}
+	fr := NewFramer(outputFn, Syslog, limit)
+	for _, chunk := range chunks {
+		fr.Process(message.NewMessage([]byte(chunk), nil, "", 0))
+	}
+	return gotContent, gotLens
+}
+
+func TestSyslogNewlineFraming(t *testing.T) {
+	content, lens := syslogFrames(contentLenLimit, "<13>1 - - - - - - hello\n<13>Oct 11 22:14:15 host app: world\n1234 not octet counted\n")
+	assert.Equal(t, []string{"<13>1 - - - - - - hello", "<13>Oct 11 22:14:15 host app: world", "1234 not octet counted"}, content)
+	assert.Equal(t, []int{24, 36, 23}, lens)
+}
+
+func TestSyslogOctetCountingFraming(t *testing.T) {
+	input := "21 <13>1 - - - - - - a\nb18 <13>1 - - - - - - 23 <13>1 - - - - - - hello"
+	content, lens := syslogFrames(contentLenLimit, input)
+	assert.Equal(t, []string{"<13>1 - - - - - - a\nb", "<13>1 - - - - - - ", "<13>1 - - - - - - hello"}, content)
+	assert.Equal(t, []int{24, 21, 26}, lens)
+
+	// the frames are the same when the input is received byte by byte
+	chunks := make([]string, 0, len(input))
+	for i := range input {
+		chunks = append(chunks, input[i:i+1])
+	}
+	content, lens = syslogFrames(contentLenLimit, chunks...)
+	assert.Equal(t, []string{"<13>1 - - - - - - a\nb", "<13>1 - - - - - - ", "<13>1 - - - - - - hello"}, content)
+	assert.Equal(t, []int{24, 21, 26}, lens)
+}
+
+func TestSyslogMixedFraming(t *testing.T) {
+	content, _ := syslogFrames(contentLenLimit, "<13>1 - - - - - - a\n19 <13>1 - - - - - - b<13>1 - - - - - - c\n")
+	assert.Equal(t, []string{"<13>1 - - - - - - a", "<13>1 - - - - - - b", "<13>1 - - - - - - c"}, content)
+}
+
+func TestSyslogOctetCountingTruncation(t *testing.T) {
+	msg := "<13>1 - - - - - - " + strings.Repeat("a", 100)
+	content, lens := syslogFrames(50, "118 "+msg+"21 <13>1 - - - - - - end")
+	assert.Equal(t, []string{msg[:46], "", "<13>1 - - - - - - end"}, content)
+	assert.Equal(t, []int{50, 72, 24}, lens)
+}
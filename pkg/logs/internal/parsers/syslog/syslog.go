@@ -0,0 +1,331 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog parses the headers of syslog messages, in the RFC 5424 format
+// or in the BSD format described by RFC 3164.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// nilValue is the value of the empty fields of RFC 5424 messages.
+	nilValue = "-"
+	// maxPriority is the highest valid priority, for the facility local7 and the severity debug.
+	maxPriority = 191
+	// maxTagLen is the maximum length of the tag of RFC 3164 messages.
+	maxTagLen = 48
+	// rfc3164TimestampLayout is the layout of the timestamp of RFC 3164 messages.
+	rfc3164TimestampLayout = time.Stamp
+)
+
+var (
+	errNoPriority    = errors.New("missing or invalid priority")
+	errInvalidHeader = errors.New("invalid RFC 5424 header")
+	errInvalidSD     = errors.New("invalid RFC 5424 structured data")
+	utf8BOM          = []byte{0xef, 0xbb, 0xbf}
+)
+
+// facilities are the names of the syslog facilities.
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severityStatuses maps syslog severities to statuses.
+var severityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// SDParam is a parameter of a structured data element.
+type SDParam struct {
+	Name  string
+	Value string
+}
+
+// SDElement is a structured data element of an RFC 5424 message.
+type SDElement struct {
+	ID     string
+	Params []SDParam
+}
+
+// Message is a parsed syslog message. The fields missing from the message are empty.
+type Message struct {
+	Facility  int
+	Severity  int
+	Timestamp string
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	// StructuredData is only found in RFC 5424 messages.
+	StructuredData []SDElement
+	// Msg is the free-form message, following the header.
+	Msg []byte
+}
+
+// Parse parses a syslog message. Messages in the RFC 5424 format are parsed strictly, while
+// parsing the BSD format is best-effort: the header fields which can't be found are left empty
+// and the remainder is considered to be the message. It returns an error if the message doesn't
+// start with a valid priority, or if its RFC 5424 header is invalid.
+func Parse(content []byte) (*Message, error) {
+	priority, rest, ok := parsePriority(content)
+	if !ok {
+		return nil, errNoPriority
+	}
+	m := &Message{
+		Facility: priority / 8,
+		Severity: priority % 8,
+	}
+	if version, after, ok := nextField(rest); ok && isVersion(version) {
+		if err := m.parseRFC5424(after); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	m.parseRFC3164(rest)
+	return m, nil
+}
+
+// Status returns the status of the message, given by its severity.
+func (m *Message) Status() string {
+	return severityStatuses[m.Severity]
+}
+
+// Tags returns the tags describing the message: its facility, process ID, message ID and
+// structured data parameters. The app name isn't part of the tags, it's the service of the
+// message.
+func (m *Message) Tags() []string {
+	tags := []string{"syslog_facility:" + facilities[m.Facility]}
+	if m.ProcID != "" {
+		tags = append(tags, "syslog_procid:"+m.ProcID)
+	}
+	if m.MsgID != "" {
+		tags = append(tags, "syslog_msgid:"+m.MsgID)
+	}
+	for _, element := range m.StructuredData {
+		for _, param := range element.Params {
+			tags = append(tags, element.ID+"."+param.Name+":"+param.Value)
+		}
+	}
+	return tags
+}
+
+// parsePriority parses the PRI part of a message, e.g. "<34>", and returns the remainder.
+func parsePriority(content []byte) (int, []byte, bool) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(content[:min(len(content), 5)], '>')
+	if end < 2 {
+		return 0, nil, false
+	}
+	digits := content[1:end]
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, nil, false
+	}
+	priority := 0
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, nil, false
+		}
+		priority = priority*10 + int(c-'0')
+	}
+	if priority > maxPriority {
+		return 0, nil, false
+	}
+	return priority, content[end+1:], true
+}
+
+// isVersion returns true if field is the version of an RFC 5424 message: a non-zero number
+// of at most three digits.
+func isVersion(field []byte) bool {
+	if len(field) == 0 || len(field) > 3 || field[0] == '0' {
+		return false
+	}
+	for _, c := range field {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// nextField returns the field at the start of content, up to the next space, and the
+// remainder after the space. It returns false if the field is empty.
+func nextField(content []byte) ([]byte, []byte, bool) {
+	end := bytes.IndexByte(content, ' ')
+	if end == -1 {
+		return content, nil, len(content) > 0
+	}
+	return content[:end], content[end+1:], end > 0
+}
+
+// parseRFC5424 parses the header of an RFC 5424 message following the version:
+// TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func (m *Message) parseRFC5424(content []byte) error {
+	fields := []*string{&m.Timestamp, &m.Hostname, &m.AppName, &m.ProcID, &m.MsgID}
+	for _, field := range fields {
+		value, rest, ok := nextField(content)
+		if !ok || rest == nil {
+			return errInvalidHeader
+		}
+		if string(value) != nilValue {
+			*field = string(value)
+		}
+		content = rest
+	}
+
+	rest, err := m.parseStructuredData(content)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return errInvalidSD
+		}
+		rest = bytes.TrimPrefix(rest[1:], utf8BOM)
+	}
+	m.Msg = rest
+	return nil
+}
+
+// parseStructuredData parses the structured data of an RFC 5424 message, either the nil
+// value or a sequence of elements such as `[id name="value"]`, and returns the remainder.
+func (m *Message) parseStructuredData(content []byte) ([]byte, error) {
+	if len(content) > 0 && content[0] == '-' {
+		return content[1:], nil
+	}
+	if len(content) == 0 || content[0] != '[' {
+		return nil, errInvalidSD
+	}
+	for len(content) > 0 && content[0] == '[' {
+		content = content[1:]
+		end := bytes.IndexAny(content, " ]")
+		if end <= 0 {
+			return nil, errInvalidSD
+		}
+		element := SDElement{ID: string(content[:end])}
+		content = content[end:]
+		for len(content) > 0 && content[0] == ' ' {
+			param, rest, err := parseSDParam(content[1:])
+			if err != nil {
+				return nil, err
+			}
+			element.Params = append(element.Params, param)
+			content = rest
+		}
+		if len(content) == 0 || content[0] != ']' {
+			return nil, errInvalidSD
+		}
+		content = content[1:]
+		m.StructuredData = append(m.StructuredData, element)
+	}
+	return content, nil
+}
+
+// parseSDParam parses a structured data parameter, `name="value"` where the characters '"',
+// '\' and ']' of the value are escaped with a backslash, and returns the remainder.
+func parseSDParam(content []byte) (SDParam, []byte, error) {
+	eq := bytes.IndexByte(content, '=')
+	if eq <= 0 || eq+1 >= len(content) || content[eq+1] != '"' {
+		return SDParam{}, nil, errInvalidSD
+	}
+	param := SDParam{Name: string(content[:eq])}
+	var value []byte
+	for i := eq + 2; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\' && i+1 < len(content) && (content[i+1] == '"' || content[i+1] == '\\' || content[i+1] == ']'):
+			value = append(value, content[i+1])
+			i++
+		case c == '"':
+			param.Value = string(value)
+			return param, content[i+1:], nil
+		default:
+			value = append(value, c)
+		}
+	}
+	return SDParam{}, nil, errInvalidSD
+}
+
+// parseRFC3164 parses the header of a BSD syslog message following the priority:
+// TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+// The timestamp is either in the "Mmm dd hh:mm:ss" format or in the RFC 3339 format. The
+// hostname is only looked for after a timestamp.
+func (m *Message) parseRFC3164(content []byte) {
+	hasTimestamp := false
+	if len(content) >= len(rfc3164TimestampLayout) {
+		if _, err := time.Parse(rfc3164TimestampLayout, string(content[:len(rfc3164TimestampLayout)])); err == nil {
+			m.Timestamp = string(content[:len(rfc3164TimestampLayout)])
+			content = bytes.TrimPrefix(content[len(rfc3164TimestampLayout):], []byte(" "))
+			hasTimestamp = true
+		}
+	}
+	if !hasTimestamp {
+		if field, rest, ok := nextField(content); ok {
+			if _, err := time.Parse(time.RFC3339Nano, string(field)); err == nil {
+				m.Timestamp = string(field)
+				content = rest
+				hasTimestamp = true
+			}
+		}
+	}
+	if hasTimestamp {
+		// the hostname can't be told apart from the tag if it's followed by a colon
+		if field, rest, ok := nextField(content); ok && rest != nil && !bytes.ContainsAny(field, ":[") {
+			m.Hostname = string(field)
+			content = rest
+		}
+	}
+	m.Msg = m.parseTag(content)
+}
+
+// parseTag parses the tag of a BSD syslog message, i.e. the app name optionally followed by
+// the process ID in brackets, and a colon. It returns the remainder, or content if it doesn't
+// start with a tag.
+func (m *Message) parseTag(content []byte) []byte {
+	end := 0
+	for end < len(content) && end < maxTagLen && isTagChar(content[end]) {
+		end++
+	}
+	if end == 0 || end == len(content) {
+		return content
+	}
+	appName, procID := content[:end], []byte(nil)
+	rest := content[end:]
+	if rest[0] == '[' {
+		closing := bytes.IndexByte(rest, ']')
+		if closing == -1 {
+			return content
+		}
+		procID = rest[1:closing]
+		rest = rest[closing+1:]
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return content
+	}
+	m.AppName = string(appName)
+	m.ProcID = string(procID)
+	return bytes.TrimPrefix(rest[1:], []byte(" "))
+}
+
+// isTagChar returns true if c can be part of the tag of a BSD syslog message.
+func isTagChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '/'
+}
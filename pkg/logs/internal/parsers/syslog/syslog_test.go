@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication\]"][origin ip="192.0.2.1"] ` + "\xef\xbb\xbf" + `An application event log entry`))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{
+		Facility:  20,
+		Severity:  5,
+		Timestamp: "2003-10-11T22:14:15.003Z",
+		Hostname:  "mymachine.example.com",
+		AppName:   "evntslog",
+		ProcID:    "1234",
+		MsgID:     "ID47",
+		StructuredData: []SDElement{
+			{ID: "exampleSDID@32473", Params: []SDParam{{Name: "iut", Value: "3"}, {Name: "eventSource", Value: `App"lication]`}}},
+			{ID: "origin", Params: []SDParam{{Name: "ip", Value: "192.0.2.1"}}},
+		},
+		Msg: []byte("An application event log entry"),
+	}, m)
+	assert.Equal(t, message.StatusNotice, m.Status())
+	assert.Equal(t, []string{
+		"syslog_facility:local4",
+		"syslog_procid:1234",
+		"syslog_msgid:ID47",
+		"exampleSDID@32473.iut:3",
+		`exampleSDID@32473.eventSource:App"lication]`,
+		"origin.ip:192.0.2.1",
+	}, m.Tags())
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	m, err := Parse([]byte("<34>1 - - - - - -"))
+	require.NoError(t, err)
+	assert.Equal(t, &Message{Facility: 4, Severity: 2, Msg: []byte{}}, m)
+	assert.Equal(t, message.StatusCritical, m.Status())
+	assert.Equal(t, []string{"syslog_facility:auth"}, m.Tags())
+
+	m, err = Parse([]byte("<34>1 - host app - - - su failed"))
+	require.NoError(t, err)
+	assert.Equal(t, "host", m.Hostname)
+	assert.Equal(t, "app", m.AppName)
+	assert.Equal(t, []byte("su failed"), m.Msg)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		expected *Message
+	}{
+		{
+			name:     "full header",
+			input:    "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8",
+			expected: &Message{Facility: 4, Severity: 2, Timestamp: "Oct 11 22:14:15", Hostname: "mymachine", AppName: "su", ProcID: "123", Msg: []byte("'su root' failed for lonvick on /dev/pts/8")},
+		},
+		{
+			name:     "padded day and no pid",
+			input:    "<13>Feb  5 17:32:18 10.0.0.99 myapp: Use the BFG!",
+			expected: &Message{Facility: 1, Severity: 5, Timestamp: "Feb  5 17:32:18", Hostname: "10.0.0.99", AppName: "myapp", Msg: []byte("Use the BFG!")},
+		},
+		{
+			name:     "RFC 3339 timestamp",
+			input:    "<30>2024-01-02T03:04:05.678+01:00 host systemd[1]: Started Session 1.",
+			expected: &Message{Facility: 3, Severity: 6, Timestamp: "2024-01-02T03:04:05.678+01:00", Hostname: "host", AppName: "systemd", ProcID: "1", Msg: []byte("Started Session 1.")},
+		},
+		{
+			name:     "no hostname",
+			input:    "<13>Oct 11 22:14:15 myapp: hello",
+			expected: &Message{Facility: 1, Severity: 5, Timestamp: "Oct 11 22:14:15", AppName: "myapp", Msg: []byte("hello")},
+		},
+		{
+			name:     "no timestamp",
+			input:    "<13>myapp[42]: hello",
+			expected: &Message{Facility: 1, Severity: 5, AppName: "myapp", ProcID: "42", Msg: []byte("hello")},
+		},
+		{
+			name:     "no tag",
+			input:    "<13>just a message: with a colon",
+			expected: &Message{Facility: 1, Severity: 5, Msg: []byte("just a message: with a colon")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Parse([]byte(tc.input))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"hello",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<013>1 - - - - - -",
+		"<1a>1 - - - - - -",
+		"<13>1 - - - -",
+		"<13>1 - - - - - [id",
+		"<13>1 - - - - - [id name=value]",
+		`<13>1 - - - - - [id name="value]`,
+		"<13>1 - - - - - -msg",
+	} {
+		_, err := Parse([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
@@ -42,6 +42,20 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFingerprint implements auditor.Registry#GetFingerprint.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) GetFingerprint(identifier string) uint64 {
+	panic("unused")
+}
+
+// KeepAlive implements auditor.Registry#KeepAlive.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) KeepAlive(identifier string) {
+	panic("unused")
+}
+
 func TestWhichTailer(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
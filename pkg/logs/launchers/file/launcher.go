@@ -7,6 +7,8 @@
 package file
 
 import (
+	"io"
+	"os"
 	"regexp"
 	"slices"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	flareController "github.com/DataDog/datadog-agent/comp/logs/agent/flare"
 	auditor "github.com/DataDog/datadog-agent/comp/logs/auditor/def"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
 	fileprovider "github.com/DataDog/datadog-agent/pkg/logs/launchers/file/provider"
@@ -33,6 +36,16 @@ import (
 // DefaultSleepDuration represents the amount of time the tailer waits before reading new data when no data is received
 const DefaultSleepDuration = 1 * time.Second
 
+// rotatedFileRetention is the time during which the offset of a rotated file is kept, for the file
+// to be tailed from this offset if it's found under another path.
+const rotatedFileRetention = time.Hour
+
+// rotatedFile is a file which was tailed until its tailer was stopped after a rotation.
+type rotatedFile struct {
+	offset     int64
+	expiration time.Time
+}
+
 // Launcher checks all files provided by fileProvider and create new tailers
 // or update the old ones if needed
 type Launcher struct {
@@ -55,6 +68,17 @@ type Launcher struct {
 	scanPeriod             time.Duration
 	flarecontroller        *flareController.FlareController
 	tagger                 tagger.Component
+	// fingerprintSize is the number of bytes used to compute the fingerprint of the files, 0 if
+	// fingerprinting is disabled, see `logs_config.fingerprint_enabled`.
+	fingerprintSize int
+	// readCompressedFiles is true if the compressed files are decompressed,
+	// see `logs_config.read_compressed_files`.
+	readCompressedFiles bool
+	// rotatedFiles are the files which were rotated, by fingerprint.
+	rotatedFiles map[uint64]rotatedFile
+	// fullyReadFiles are the compressed files which were fully read, by fingerprint. They're not
+	// tailed again while they're found by the scans.
+	fullyReadFiles map[uint64]struct{}
 }
 
 // NewLauncher returns a new launcher.
@@ -71,6 +95,15 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		wildcardStrategy = fileprovider.WildcardUseFileName
 	}
 
+	fingerprintSize := 0
+	if pkgconfigsetup.Datadog().GetBool("logs_config.fingerprint_enabled") {
+		fingerprintSize = pkgconfigsetup.Datadog().GetInt("logs_config.fingerprint_size")
+		if fingerprintSize <= 0 {
+			log.Warnf("Invalid logs_config.fingerprint_size %d, using %d instead", fingerprintSize, tailer.DefaultFingerprintSize)
+			fingerprintSize = tailer.DefaultFingerprintSize
+		}
+	}
+
 	return &Launcher{
 		tailingLimit:           tailingLimit,
 		fileProvider:           fileprovider.NewFileProvider(tailingLimit, wildcardStrategy),
@@ -83,6 +116,10 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		scanPeriod:             scanPeriod,
 		flarecontroller:        flarecontroller,
 		tagger:                 tagger,
+		fingerprintSize:        fingerprintSize,
+		readCompressedFiles:    pkgconfigsetup.Datadog().GetBool("logs_config.read_compressed_files"),
+		rotatedFiles:           make(map[uint64]rotatedFile),
+		fullyReadFiles:         make(map[uint64]struct{}),
 	}
 }
 
@@ -172,6 +209,9 @@ func (s *Launcher) scan() {
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsFullyRead() {
+				s.fullyReadFiles[tailer.Fingerprint()] = struct{}{}
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -213,12 +253,26 @@ func (s *Launcher) scan() {
 	tailersLen := s.tailers.Count()
 	log.Debugf("After stopping tailers, there are %d tailers running.\n", tailersLen)
 
+	fullyReadFiles := make(map[uint64]struct{})
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
 		if !isTailed && tailersLen < s.tailingLimit {
+			// the fingerprint is computed once per scan for the files which aren't tailed
+			fingerprint, compression := s.fileIdentity(file)
+			if _, isFullyRead := s.fullyReadFiles[fingerprint]; isFullyRead && compression != "" {
+				// the final offset of the file is kept in the auditor as long as the file exists
+				s.registry.KeepAlive(tailer.FingerprintIdentifier(fingerprint))
+				fullyReadFiles[fingerprint] = struct{}{}
+				continue
+			}
+			if s.isTailedUnderAnotherPath(fingerprint) {
+				// the file has been renamed by a rotation and is still tailed under its former
+				// path, it will be tailed from where this tailer stopped
+				continue
+			}
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
-			succeeded := s.startNewTailer(file, config.Beginning)
+			succeeded := s.startNewTailer(file, config.Beginning, fingerprint, compression)
 			if !succeeded {
 				// the setup failed, let's try to tail this file in the next scan
 				continue
@@ -228,6 +282,8 @@ func (s *Launcher) scan() {
 			continue
 		}
 	}
+	// forget the fully read files which were removed
+	s.fullyReadFiles = fullyReadFiles
 	log.Debugf("After starting new tailers, there are %d tailers running. Limit is %d.\n", tailersLen, s.tailingLimit)
 
 	// Check how many file handles the Agent process has open and log a warning if the process is coming close to the OS file limit
@@ -237,17 +293,76 @@ func (s *Launcher) scan() {
 	}
 }
 
-// cleanUpRotatedTailers removes any rotated tailers that have stopped from the list
+// cleanUpRotatedTailers removes any rotated tailers that have stopped from the list, keeping track
+// of the offsets of their files if they're fingerprinted
 func (s *Launcher) cleanUpRotatedTailers() {
+	now := time.Now()
+	for fingerprint, file := range s.rotatedFiles {
+		if now.After(file.expiration) {
+			delete(s.rotatedFiles, fingerprint)
+		}
+	}
 	pendingTailers := []*tailer.Tailer{}
 	for _, tailer := range s.rotatedTailers {
 		if !tailer.IsFinished() {
 			pendingTailers = append(pendingTailers, tailer)
+		} else if fingerprint := tailer.Fingerprint(); fingerprint != 0 {
+			s.rotatedFiles[fingerprint] = rotatedFile{
+				offset:     tailer.DecodedOffset(),
+				expiration: now.Add(rotatedFileRetention),
+			}
 		}
 	}
 	s.rotatedTailers = pendingTailers
 }
 
+// isTailedUnderAnotherPath returns true if a tailer, possibly rotated, tails a file with the
+// fingerprint of a file.
+func (s *Launcher) isTailedUnderAnotherPath(fingerprint uint64) bool {
+	if fingerprint == 0 {
+		return false
+	}
+	for _, t := range append(s.tailers.All(), s.rotatedTailers...) {
+		if t.Fingerprint() == fingerprint && !t.IsFinished() {
+			return true
+		}
+	}
+	return false
+}
+
+// fileIdentity returns the fingerprint of file, 0 if it's unknown or if fingerprinting is
+// disabled, and its compression if compressed files are read. Compressed files are always
+// fingerprinted.
+func (s *Launcher) fileIdentity(file *tailer.File) (uint64, string) {
+	compression := ""
+	if s.readCompressedFiles {
+		compression = tailer.CompressionFromPath(file.Path)
+	}
+	size := s.fingerprintSize
+	if size == 0 && compression != "" {
+		size = tailer.DefaultFingerprintSize
+	}
+	if size == 0 {
+		return 0, compression
+	}
+	fingerprint, err := tailer.ComputeFingerprint(file.Path, size, compression != "")
+	if err != nil {
+		log.Debugf("Could not compute the fingerprint of %s: %v", file.Path, err)
+		return 0, compression
+	}
+	return fingerprint, compression
+}
+
+// isCompressedFileComplete returns true if the compressed file can be read: it's fingerprinted, and
+// it hasn't been modified during the last scan period, so that it isn't being written.
+func (s *Launcher) isCompressedFileComplete(file *tailer.File, fingerprint uint64) bool {
+	if fingerprint == 0 {
+		return false
+	}
+	fi, err := os.Stat(file.Path)
+	return err == nil && time.Since(fi.ModTime()) >= s.scanPeriod
+}
+
 // addSource keeps track of the new source and launch new tailers for this source.
 func (s *Launcher) addSource(source *sources.LogSource) {
 	s.activeSources = append(s.activeSources, source)
@@ -300,28 +415,45 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			source.Config.TailingMode = mode.String()
 		}
 
-		s.startNewTailer(file, mode)
+		fingerprint, compression := s.fileIdentity(file)
+		s.startNewTailer(file, mode, fingerprint, compression)
 	}
 }
 
 // startNewTailer creates a new tailer, making it tail from the last committed offset, the beginning or the end of the file,
-// returns true if the operation succeeded, false otherwise.
-func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool {
+// returns true if the operation succeeded, false otherwise. The fingerprint and the compression of the file are given
+// by fileIdentity.
+func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode, fingerprint uint64, compression string) bool {
 	if file == nil {
 		log.Debug("startNewTailer called with a nil file")
 		return false
 	}
 
+	if compression != "" && !s.isCompressedFileComplete(file, fingerprint) {
+		log.Debugf("Compressed file %s can't be read yet", file.Path)
+		return false
+	}
+
 	channel, monitor := s.pipelineProvider.NextPipelineChanWithMonitor()
-	tailer := s.createTailer(file, channel, monitor)
+	tailer := s.createTailer(file, channel, monitor, fingerprint, compression)
 
 	var offset int64
 	var whence int
-	mode := s.handleTailingModeChange(tailer.Identifier(), m)
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	identifier := tailer.Identifier()
+	mode := s.handleTailingModeChange(identifier, m)
+	if compression == "" && fingerprint != 0 {
+		identifier, mode = s.handleFingerprintChange(identifier, fingerprint, mode)
+	}
+	offset, whence, err := Position(s.registry, identifier, mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
+	if rotated, ok := s.rotatedFiles[fingerprint]; ok && fingerprint != 0 {
+		// the file was tailed under another path until it was renamed by a rotation
+		log.Infof("File %s was tailed under another path until offset %d", file.Path, rotated.offset)
+		offset, whence = rotated.offset, io.SeekStart
+		delete(s.rotatedFiles, fingerprint)
+	}
 
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 	err = tailer.Start(offset, whence)
@@ -356,6 +488,30 @@ func (s *Launcher) handleTailingModeChange(tailerID string, currentTailingMode c
 	return currentTailingMode
 }
 
+// handleFingerprintChange determines the identifier and the tailing mode of a file from its fingerprint
+// and the one recorded in the registry. If another file was tailed at this path, its offset is
+// ignored: the tailing mode is forced. If no file was tailed at this path, the file may have been
+// tailed under another path until it was renamed by a rotation, in which case its offset is
+// recorded under its fingerprint.
+func (s *Launcher) handleFingerprintChange(identifier string, fingerprint uint64, mode config.TailingMode) (string, config.TailingMode) {
+	if previous := s.registry.GetFingerprint(identifier); previous != 0 && previous != fingerprint {
+		log.Infof("File %v has been replaced since its offset was recorded, ignoring it", identifier)
+		switch mode {
+		case config.Beginning:
+			return identifier, config.ForceBeginning
+		case config.End:
+			return identifier, config.ForceEnd
+		}
+		return identifier, mode
+	}
+	if s.registry.GetOffset(identifier) == "" {
+		if fingerprintIdentifier := tailer.FingerprintIdentifier(fingerprint); s.registry.GetOffset(fingerprintIdentifier) != "" {
+			return fingerprintIdentifier, mode
+		}
+	}
+	return identifier, mode
+}
+
 // stopTailer stops the tailer
 func (s *Launcher) stopTailer(tailer *tailer.Tailer) {
 	go tailer.Stop()
@@ -384,7 +540,7 @@ func (s *Launcher) restartTailerAfterFileRotation(oldTailer *tailer.Tailer, file
 }
 
 // createTailer returns a new initialized tailer
-func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message, pipelineMonitor metrics.PipelineMonitor) *tailer.Tailer {
+func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message, pipelineMonitor metrics.PipelineMonitor, fingerprint uint64, compression string) *tailer.Tailer {
 	tailerInfo := status.NewInfoRegistry()
 
 	tailerOptions := &tailer.TailerOptions{
@@ -395,6 +551,9 @@ func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Mess
 		Info:            tailerInfo,
 		TagAdder:        s.tagger,
 		PipelineMonitor: pipelineMonitor,
+		FingerprintSize: s.fingerprintSize,
+		Fingerprint:     fingerprint,
+		Compression:     compression,
 	}
 
 	return tailer.NewTailer(tailerOptions)
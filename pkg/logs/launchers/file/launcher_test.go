@@ -8,6 +8,8 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -758,3 +760,82 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
+
+func TestLauncherIgnoresOffsetOfReplacedFile(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerfxmock.SetupFakeTagger(t)
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("hello world\nhello again\n"), 0644))
+
+	fc := flareController.NewFlareController()
+	launcher := NewLauncher(2, 20*time.Millisecond, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.fingerprintSize = 16
+	registry := auditorMock.NewMockRegistry()
+	registry.SetOffset("100")
+	registry.SetTailingMode("beginning")
+	registry.SetFingerprint(1)
+	launcher.registry = registry
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+
+	// the offset was recorded for another file, which had another fingerprint
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailingMode: "beginning"})
+	launcher.addSource(source)
+
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.NotZero(t, msg.Origin.Fingerprint)
+}
+
+func TestLauncherReadsCompressedFiles(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerfxmock.SetupFakeTagger(t)
+
+	path := fmt.Sprintf("%s/test.log.1.gz", testDir)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte("hello world\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	fc := flareController.NewFlareController()
+	launcher := NewLauncher(2, 20*time.Millisecond, false, time.Second, "by_name", fc, fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditorMock.NewMockRegistry()
+	launcher.readCompressedFiles = true
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+
+	// the file may still be written
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.gz", testDir), TailingMode: "beginning"})
+	launcher.addSource(source)
+	assert.Equal(t, 0, launcher.tailers.Count())
+
+	past := time.Now().Add(-time.Minute)
+	assert.Nil(t, os.Chtimes(path, past, past))
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	fingerprint, err := tailer.ComputeFingerprint(path, tailer.DefaultFingerprintSize, true)
+	assert.Nil(t, err)
+	assert.Equal(t, tailer.FingerprintIdentifier(fingerprint), msg.Origin.Identifier)
+
+	// the tailer stops once the file is fully read, and the file isn't tailed again
+	fileTailer, _ := launcher.tailers.Get(getScanKey(path, source))
+	assert.Eventually(t, fileTailer.IsFinished, time.Second, 10*time.Millisecond)
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.fullyReadFiles, fingerprint)
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	// the final offset is kept alive by each scan
+	identifier := tailer.FingerprintIdentifier(fingerprint)
+	assert.Equal(t, []string{identifier, identifier}, launcher.registry.(*auditorMock.Registry).KeptAlive())
+
+	assert.Nil(t, os.Remove(path))
+	launcher.scan()
+	assert.Empty(t, launcher.fullyReadFiles)
+}
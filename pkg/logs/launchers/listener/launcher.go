@@ -21,6 +21,7 @@ type Launcher struct {
 	frameSize        int
 	tcpSources       chan *sources.LogSource
 	udpSources       chan *sources.LogSource
+	syslogSources    chan *sources.LogSource
 	listeners        []startstop.StartStoppable
 	stop             chan struct{}
 }
@@ -40,6 +41,7 @@ func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvid
 	l.pipelineProvider = pipelineProvider
 	l.tcpSources = sourceProvider.GetAddedForType(config.TCPType)
 	l.udpSources = sourceProvider.GetAddedForType(config.UDPType)
+	l.syslogSources = sourceProvider.GetAddedForType(config.SyslogType)
 	go l.run()
 }
 
@@ -55,6 +57,15 @@ func (l *Launcher) run() {
 			listener := NewUDPListener(l.pipelineProvider, source, l.frameSize)
 			listener.Start()
 			l.listeners = append(l.listeners, listener)
+		case source := <-l.syslogSources:
+			var listener startstop.StartStoppable
+			if source.Config.Protocol == config.UDPType {
+				listener = NewUDPListener(l.pipelineProvider, source, l.frameSize)
+			} else {
+				listener = NewTCPListener(l.pipelineProvider, source, l.frameSize)
+			}
+			listener.Start()
+			l.listeners = append(l.listeners, listener)
 		case <-l.stop:
 			return
 		}
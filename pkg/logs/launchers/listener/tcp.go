@@ -6,6 +6,7 @@
 package listener
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"slices"
@@ -120,8 +121,24 @@ func (l *TCPListener) run() {
 }
 
 // startListener starts a new listener, returns an error if it failed.
+// Connections are secured with TLS when a certificate is configured.
 func (l *TCPListener) startListener() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
+	address := fmt.Sprintf(":%d", l.source.Config.Port)
+	var listener net.Listener
+	var err error
+	if l.source.Config.TLSCertFile != "" {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(l.source.Config.TLSCertFile, l.source.Config.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("can't load TLS certificate: %w", err)
+		}
+		listener, err = tls.Listen("tcp", address, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
 	if err != nil {
 		return err
 	}
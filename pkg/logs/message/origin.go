@@ -16,9 +16,11 @@ type Origin struct {
 	Identifier string
 	LogSource  *sources.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// Fingerprint is the fingerprint of the tailed file, 0 if it's unknown.
+	Fingerprint uint64
+	service     string
+	source      string
+	tags        []string
 }
 
 // NewOrigin returns a new Origin
@@ -26,6 +26,12 @@ var (
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
 
+	// LogsThrottled is the total number of logs dropped by the throttling of their source.
+	LogsThrottled = expvar.Int{}
+	// TlmLogsThrottled is the total number of logs dropped by the throttling of their source, by reason.
+	TlmLogsThrottled = telemetry.NewCounter("logs", "throttled",
+		[]string{"reason"}, "Total number of logs dropped by the throttling of their source")
+
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
 	// TlmLogsSent is the total number of sent logs.
@@ -103,6 +109,7 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsThrottled", &LogsThrottled)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
@@ -35,7 +35,7 @@ func NewProcessorOnlyProvider(diagnosticMessageReceiver diagnostic.MessageReceiv
 	inputChan := make(chan *message.Message, chanSize)
 	pipelineID := 0
 	pipelineMonitor := metrics.NewTelemetryPipelineMonitor(strconv.Itoa(pipelineID))
-	processor := processor.New(cfg, inputChan, outputChan, processingRules,
+	processor := processor.New(cfg, inputChan, outputChan, processingRules, nil,
 		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
 
 	p := &processorOnlyProvider{
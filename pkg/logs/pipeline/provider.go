@@ -21,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	httpsender "github.com/DataDog/datadog-agent/pkg/logs/sender/http"
@@ -63,6 +64,7 @@ type provider struct {
 	numberOfPipelines         int
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	processingRules           []*config.ProcessingRule
+	logMetrics                *processor.LogMetrics
 	endpoints                 *config.Endpoints
 	sender                    sender.PipelineComponent
 
@@ -81,6 +83,7 @@ func NewProvider(
 	sink sender.Sink,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	processingRules []*config.ProcessingRule,
+	logMetrics *processor.LogMetrics,
 	endpoints *config.Endpoints,
 	destinationsContext *client.DestinationsContext,
 	status statusinterface.Status,
@@ -103,6 +106,7 @@ func NewProvider(
 		numberOfPipelines,
 		diagnosticMessageReceiver,
 		processingRules,
+		logMetrics,
 		endpoints,
 		hostname,
 		cfg,
@@ -209,6 +213,7 @@ func newProvider(
 	numberOfPipelines int,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	processingRules []*config.ProcessingRule,
+	logMetrics *processor.LogMetrics,
 	endpoints *config.Endpoints,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
@@ -220,6 +225,7 @@ func newProvider(
 		numberOfPipelines:         numberOfPipelines,
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
 		processingRules:           processingRules,
+		logMetrics:                logMetrics,
 		endpoints:                 endpoints,
 		sender:                    senderImpl,
 		pipelines:                 []*Pipeline{},
@@ -238,6 +244,7 @@ func (p *provider) Start() {
 	for i := 0; i < p.numberOfPipelines; i++ {
 		pipeline := NewPipeline(
 			p.processingRules,
+			p.logMetrics,
 			p.endpoints,
 			p.sender,
 			p.diagnosticMessageReceiver,
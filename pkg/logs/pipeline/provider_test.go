@@ -224,6 +224,7 @@ func TestProviderConfigurations(t *testing.T) {
 				&sender.NoopSink{},
 				diagnosticMessageReceiver,
 				nil, // processing rules
+				nil, // log metrics
 				endpoints,
 				destinationsContext,
 				status,
@@ -292,6 +293,7 @@ func TestPipelineChannelDistribution(t *testing.T) {
 				&sender.NoopSink{},
 				diagnosticMessageReceiver,
 				nil, // processing rules
+				nil, // log metrics
 				endpoints,
 				destinationsContext,
 				status,
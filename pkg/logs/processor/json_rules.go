@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// jsonContent is the content of a log line holding a JSON object, decoded so that JSON
+// processing rules can be applied to it. The content is decoded on the first JSON rule
+// applied and encoded back once all the consecutive JSON rules have been applied.
+type jsonContent struct {
+	obj map[string]interface{}
+	// decoded is true once decoding has been attempted, obj is nil if it failed.
+	decoded bool
+	// modified is true if obj has been modified since it was decoded.
+	modified bool
+}
+
+// apply applies the JSON processing rule to the content and message, decoding the content
+// first if needed. Content which doesn't hold a JSON object is left untouched.
+func (c *jsonContent) apply(rule *config.ProcessingRule, content []byte, msg *message.Message) {
+	if !c.decoded {
+		c.obj = decodeJSONObject(content)
+		c.decoded = true
+	}
+	if c.obj == nil {
+		return
+	}
+	switch rule.Type {
+	case config.JSONRemapField:
+		v, ok := getJSONField(c.obj, rule.FieldPaths[0])
+		if !ok {
+			return
+		}
+		deleteJSONField(c.obj, rule.FieldPaths[0])
+		if !setJSONField(c.obj, rule.TargetPath, v) {
+			// the target can't be set, e.g. one of its parents isn't an object
+			setJSONField(c.obj, rule.FieldPaths[0], v)
+			return
+		}
+		c.modified = true
+	case config.JSONDropFields:
+		for _, path := range rule.FieldPaths {
+			if deleteJSONField(c.obj, path) {
+				c.modified = true
+			}
+		}
+	case config.JSONFieldToStatus:
+		v, ok := getJSONField(c.obj, rule.FieldPaths[0])
+		if !ok {
+			return
+		}
+		if status := toStatus(v); status != "" {
+			msg.Status = status
+		}
+	case config.JSONFieldToTag:
+		v, ok := getJSONField(c.obj, rule.FieldPaths[0])
+		if !ok {
+			return
+		}
+		if value, ok := toTagValue(v); ok {
+			msg.ProcessingTags = append(msg.ProcessingTags, rule.Target+":"+value)
+		}
+	}
+}
+
+// flush returns the content encoded back if it has been modified, and resets c so that the
+// content is decoded again by the next JSON rule applied.
+func (c *jsonContent) flush(content []byte) []byte {
+	defer func() { *c = jsonContent{} }()
+	if !c.modified {
+		return content
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(c.obj); err != nil {
+		return content
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// decodeJSONObject returns the JSON object held by content, or nil if content doesn't hold
+// exactly one JSON object. Numbers are kept as json.Number so that they're encoded back
+// without loss of precision.
+func decodeJSONObject(content []byte) map[string]interface{} {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil
+	}
+	return obj
+}
+
+func getJSONField(obj map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = child
+	}
+	v, ok := obj[path[len(path)-1]]
+	return v, ok
+}
+
+func deleteJSONField(obj map[string]interface{}, path []string) bool {
+	for _, key := range path[:len(path)-1] {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		obj = child
+	}
+	if _, ok := obj[path[len(path)-1]]; !ok {
+		return false
+	}
+	delete(obj, path[len(path)-1])
+	return true
+}
+
+// setJSONField sets the field at path, creating the missing parent objects. It returns false
+// if one of the parents exists and isn't an object.
+func setJSONField(obj map[string]interface{}, path []string, v interface{}) bool {
+	for _, key := range path[:len(path)-1] {
+		child, exists := obj[key]
+		if !exists {
+			child = make(map[string]interface{})
+			obj[key] = child
+		}
+		childObj, ok := child.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		obj = childObj
+	}
+	obj[path[len(path)-1]] = v
+	return true
+}
+
+// statusAliases maps the common names of log levels to statuses.
+var statusAliases = map[string]string{
+	"emerg":       message.StatusEmergency,
+	"fatal":       message.StatusCritical,
+	"crit":        message.StatusCritical,
+	"err":         message.StatusError,
+	"warning":     message.StatusWarning,
+	"information": message.StatusInfo,
+	"trace":       message.StatusDebug,
+}
+
+// syslogSeverityStatuses maps syslog severities to statuses.
+var syslogSeverityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// toStatus returns the status denoted by a log level, which is either a level name or a
+// syslog severity. It returns an empty string if v isn't a known level.
+func toStatus(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		s := strings.ToLower(strings.TrimSpace(v))
+		if alias, ok := statusAliases[s]; ok {
+			return alias
+		}
+		for _, status := range syslogSeverityStatuses {
+			if s == status {
+				return status
+			}
+		}
+	case json.Number:
+		if sev, err := v.Int64(); err == nil && sev >= 0 && sev < int64(len(syslogSeverityStatuses)) {
+			return syslogSeverityStatuses[sev]
+		}
+	}
+	return ""
+}
+
+// toTagValue returns the tag value of a scalar field.
+func toTagValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, v != ""
+	case json.Number, bool:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// metricsCommitInterval is the interval at which the metrics generated from logs are committed.
+const metricsCommitInterval = 15 * time.Second
+
+// MetricSender is the subset of the aggregator's sender.Sender used to submit the metrics
+// generated from logs.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// LogMetrics generates metrics from the logs matching the global metric rules and the metric
+// rules of their source. It is shared by the processors of all the pipelines.
+type LogMetrics struct {
+	rules  []*config.MetricRule
+	sender MetricSender
+
+	// pending is true when metrics have been sent since the last commit.
+	pending atomic.Bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewLogMetrics returns a new LogMetrics applying the global rules and submitting the metrics
+// with sender.
+func NewLogMetrics(rules []*config.MetricRule, sender MetricSender) *LogMetrics {
+	return &LogMetrics{
+		rules:  rules,
+		sender: sender,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start starts committing the generated metrics periodically. It does nothing if m is nil.
+func (m *LogMetrics) Start() {
+	if m == nil {
+		return
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(metricsCommitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.commit()
+			case <-m.stop:
+				m.commit()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops LogMetrics, committing the metrics generated so far. It does nothing if m is nil.
+func (m *LogMetrics) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+func (m *LogMetrics) commit() {
+	if m.pending.CompareAndSwap(true, false) {
+		m.sender.Commit()
+	}
+}
+
+// generate submits the metrics of the rules matching msg, and returns true if msg must be
+// dropped. It does nothing if m is nil.
+func (m *LogMetrics) generate(msg *message.Message) bool {
+	if m == nil {
+		return false
+	}
+	sourceRules := msg.Origin.LogSource.Config.MetricRules
+	if len(m.rules) == 0 && len(sourceRules) == 0 {
+		return false
+	}
+
+	content := msg.GetContent()
+	var (
+		tags    []string
+		obj     map[string]interface{}
+		decoded bool
+		drop    bool
+	)
+	for _, rules := range [][]*config.MetricRule{m.rules, sourceRules} {
+		for _, rule := range rules {
+			var match []int
+			if rule.Regex != nil {
+				if match = rule.Regex.FindSubmatchIndex(content); match == nil {
+					continue
+				}
+			}
+
+			value := 1.0
+			switch {
+			case rule.ValueIndex >= 0:
+				v, err := strconv.ParseFloat(string(submatch(content, match, rule.ValueIndex)), 64)
+				if err != nil {
+					continue
+				}
+				value = v
+			case rule.ValuePath != nil:
+				if !decoded {
+					obj = decodeJSONObject(content)
+					decoded = true
+				}
+				if obj == nil {
+					continue
+				}
+				v, ok := getJSONField(obj, rule.ValuePath)
+				if !ok {
+					continue
+				}
+				if value, ok = toMetricValue(v); !ok {
+					continue
+				}
+			}
+
+			if tags == nil {
+				tags = logMetricTags(msg)
+			}
+			metricTags := make([]string, 0, len(tags)+len(rule.Tags)+len(rule.GroupBy))
+			metricTags = append(metricTags, tags...)
+			metricTags = append(metricTags, rule.Tags...)
+			for i, name := range rule.GroupBy {
+				if v := submatch(content, match, rule.GroupByIndexes[i]); len(v) > 0 {
+					metricTags = append(metricTags, name+":"+string(v))
+				}
+			}
+
+			switch rule.Type {
+			case config.MetricTypeCount:
+				m.sender.Count(rule.Name, value, msg.Hostname, metricTags)
+			case config.MetricTypeDistribution:
+				m.sender.Distribution(rule.Name, value, msg.Hostname, metricTags)
+			}
+			m.pending.Store(true)
+			drop = drop || rule.DropLog
+		}
+	}
+	return drop
+}
+
+// submatch returns the capture group i of a match, or nil if the group didn't match.
+func submatch(content []byte, match []int, i int) []byte {
+	if 2*i+1 >= len(match) || match[2*i] < 0 {
+		return nil
+	}
+	return content[match[2*i]:match[2*i+1]]
+}
+
+// logMetricTags returns the tags of the metrics generated from msg: the tags of the log along
+// with its service and source.
+func logMetricTags(msg *message.Message) []string {
+	logTags := msg.Tags()
+	tags := make([]string, 0, len(logTags)+2)
+	tags = append(tags, logTags...)
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	return tags
+}
+
+// toMetricValue returns the value of a numeric field, or of a string holding a number.
+func toMetricValue(v interface{}) (float64, bool) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
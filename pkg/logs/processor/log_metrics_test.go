@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+type metricSample struct {
+	typ   string
+	name  string
+	value float64
+	tags  []string
+}
+
+type fakeMetricSender struct {
+	mu      sync.Mutex
+	samples []metricSample
+	commits int
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, metricSample{config.MetricTypeCount, metric, value, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, metricSample{config.MetricTypeDistribution, metric, value, tags})
+}
+
+func (s *fakeMetricSender) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits++
+}
+
+func TestLogMetrics(t *testing.T) {
+	globalRules := []*config.MetricRule{
+		{Name: "logs.errors", Type: config.MetricTypeCount, Pattern: "ERROR"},
+	}
+	require.NoError(t, config.CompileMetricRules(globalRules))
+	sourceRules := []*config.MetricRule{
+		{
+			Name:       "http.request.duration",
+			Type:       config.MetricTypeDistribution,
+			Pattern:    `"(?P<method>[A-Z]+) [^"]*" (?P<code>\d{3}) (?P<duration>[\d.]+)`,
+			ValueGroup: "duration",
+			GroupBy:    []string{"method", "code"},
+			Tags:       []string{"parsed:access_log"},
+			DropLog:    true,
+		},
+		{
+			Name:       "http.response.bytes",
+			Type:       config.MetricTypeCount,
+			ValueField: "http.bytes",
+		},
+	}
+	require.NoError(t, config.CompileMetricRules(sourceRules))
+
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web", Source: "nginx", Tags: []string{"env:prod"}, MetricRules: sourceRules})
+	s := &fakeMetricSender{}
+	m := NewLogMetrics(globalRules, s)
+
+	assert.True(t, m.generate(newMessage([]byte(`"GET /index.html" 200 0.25`), source, "")))
+	assert.False(t, m.generate(newMessage([]byte(`ERROR: "GET /" failed`), source, "")))
+	assert.False(t, m.generate(newMessage([]byte(`{"http":{"bytes":"512"}}`), source, "")))
+	assert.False(t, m.generate(newMessage([]byte(`{"http":{"bytes":"many"}}`), source, "")))
+	assert.False(t, m.generate(newMessage([]byte(`nothing to see`), source, "")))
+
+	logTags := []string{"env:prod", "service:web", "source:nginx"}
+	assert.Equal(t, []metricSample{
+		{config.MetricTypeDistribution, "http.request.duration", 0.25, append(logTags, "parsed:access_log", "method:GET", "code:200")},
+		{config.MetricTypeCount, "logs.errors", 1, logTags},
+		{config.MetricTypeCount, "http.response.bytes", 512, logTags},
+	}, s.samples)
+
+	// metrics are committed when stopping, and only if some were sent
+	m.Start()
+	m.Stop()
+	assert.Equal(t, 1, s.commits)
+	m.commit()
+	assert.Equal(t, 1, s.commits)
+}
+
+func TestLogMetricsDrop(t *testing.T) {
+	rules := []*config.MetricRule{
+		{Name: "logs.debug", Type: config.MetricTypeCount, Pattern: "DEBUG", DropLog: true},
+	}
+	require.NoError(t, config.CompileMetricRules(rules))
+	s := &fakeMetricSender{}
+	p := &Processor{logMetrics: NewLogMetrics(rules, s)}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte("DEBUG some details"), source, "")
+	assert.True(t, p.applyRedactingRules(msg) && p.logMetrics.generate(msg))
+	assert.Len(t, s.samples, 1)
+
+	// a nil LogMetrics generates nothing
+	var m *LogMetrics
+	assert.False(t, m.generate(message.NewMessageWithSource([]byte("DEBUG"), "", source, 0)))
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -18,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -34,6 +36,7 @@ type Processor struct {
 	// the processing rules of the SDS Scanner.
 	ReconfigChan              chan sds.ReconfigureOrder
 	processingRules           []*config.ProcessingRule
+	logMetrics                *LogMetrics
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
@@ -42,6 +45,9 @@ type Processor struct {
 
 	sds sdsProcessor
 
+	// dedupRuns are the current runs of identical logs of the sources deduplicating their logs.
+	dedupRuns map[*sources.LogSource]*dedupRun
+
 	// Telemetry
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
@@ -62,7 +68,7 @@ type sdsProcessor struct {
 
 // New returns an initialized Processor.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
-	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
+	logMetrics *LogMetrics, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
 	pipelineMonitor metrics.PipelineMonitor) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
@@ -73,12 +79,14 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 		outputChan:                outputChan, // strategy input
 		ReconfigChan:              make(chan sds.ReconfigureOrder),
 		processingRules:           processingRules,
+		logMetrics:                logMetrics,
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
 		hostname:                  hostname,
 		pipelineMonitor:           pipelineMonitor,
 		utilization:               pipelineMonitor.MakeUtilizationMonitor("processor"),
+		dedupRuns:                 make(map[*sources.LogSource]*dedupRun),
 
 		sds: sdsProcessor{
 			// will immediately starts buffering if it has been configured as so
@@ -132,6 +140,9 @@ func (p *Processor) run() {
 		p.done <- struct{}{}
 	}()
 
+	dedupTicker := time.NewTicker(dedupFlushInterval)
+	defer dedupTicker.Stop()
+
 	for {
 		select {
 		// Processing, usual main loop
@@ -139,6 +150,7 @@ func (p *Processor) run() {
 
 		case msg, ok := <-p.inputChan:
 			if !ok { // channel has been closed
+				p.flushDedupRuns(true)
 				return
 			}
 
@@ -163,6 +175,14 @@ func (p *Processor) run() {
 			p.mu.Lock()
 			p.applySDSReconfiguration(order)
 			p.mu.Unlock()
+
+		// Deduplication
+		// -------------
+
+		case <-dedupTicker.C:
+			p.mu.Lock()
+			p.flushDedupRuns(false)
+			p.mu.Unlock()
 		}
 	}
 }
@@ -229,36 +249,43 @@ func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
 
-	if toSend := p.applyRedactingRules(msg); toSend {
-		metrics.LogsProcessed.Add(1)
-		metrics.TlmLogsProcessed.Inc()
+	// logs can be dropped once turned into metrics, and are throttled once counted in the metrics
+	if toSend := p.applyRedactingRules(msg) && !p.logMetrics.generate(msg) && p.throttle(msg); toSend {
+		p.forward(msg)
+	}
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
-			return
-		}
-		msg.SetRendered(rendered)
+}
 
-		// report this message to diagnostic receivers (e.g. `stream-logs` command)
-		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+// forward renders and encodes the message, and sends it to the strategy.
+func (p *Processor) forward(msg *message.Message) {
+	metrics.LogsProcessed.Add(1)
+	metrics.TlmLogsProcessed.Inc()
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
+
+	// report this message to diagnostic receivers (e.g. `stream-logs` command)
+	p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
 
-		p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
-		p.outputChan <- msg
-		p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
 
+	p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
+	p.outputChan <- msg
+	p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
-// it applies the change directly on the Message content.
+// it applies the change directly on the Message content. JSON processing rules
+// can also change the status and the tags of the message.
 func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	var content []byte = msg.GetContent()
 
@@ -266,7 +293,15 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	// ---------------------------
 
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	var jsonContent jsonContent
 	for _, rule := range rules {
+		if rule.IsJSONRule() {
+			jsonContent.apply(rule, content, msg)
+			continue
+		}
+		// the rules matching the raw content need the changes of the JSON rules applied so far
+		content = jsonContent.flush(content)
+
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			// if this message matches, we ignore it
@@ -284,6 +319,7 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			}
 		}
 	}
+	content = jsonContent.flush(content)
 
 	// Use the SDS implementation
 	// --------------------------
@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"fmt"
 	"regexp"
 	"sync/atomic"
 	"testing"
@@ -279,6 +280,126 @@ func TestMask(t *testing.T) {
 	}
 }
 
+// JSON processing rules tests
+// ---------------------------
+
+func newJSONSource(rules ...*config.ProcessingRule) sources.LogSource {
+	for i, rule := range rules {
+		rule.Name = fmt.Sprintf("rule-%d", i)
+	}
+	if err := config.CompileProcessingRules(rules); err != nil {
+		panic(err)
+	}
+	return sources.LogSource{Config: &config.LogsConfig{ProcessingRules: rules}}
+}
+
+func TestJSONRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        sources.LogSource
+		input         string
+		output        string
+		status        string
+		tags          []string
+		shouldProcess bool
+	}{
+		{
+			name:          "remap",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONRemapField, Field: "log.msg", Target: "message"}),
+			input:         `{"log":{"msg":"hello <world>","id":12345678901234567890}}`,
+			output:        `{"log":{"id":12345678901234567890},"message":"hello <world>"}`,
+			shouldProcess: true,
+		},
+		{
+			name:          "remap to nested field",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONRemapField, Field: "lvl", Target: "log.level"}),
+			input:         `{"lvl":"warn"}`,
+			output:        `{"log":{"level":"warn"}}`,
+			shouldProcess: true,
+		},
+		{
+			name:          "remap to a field of a non object",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONRemapField, Field: "lvl", Target: "log.level"}),
+			input:         `{"lvl":"warn", "log":"x"}`,
+			output:        `{"lvl":"warn", "log":"x"}`,
+			shouldProcess: true,
+		},
+		{
+			name:          "drop",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONDropFields, Fields: []string{"password", "user.token", "missing"}}),
+			input:         `{"password":"secret","user":{"name":"bob","token":"abc"}}`,
+			output:        `{"user":{"name":"bob"}}`,
+			shouldProcess: true,
+		},
+		{
+			name:          "missing fields leave the content untouched",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONDropFields, Fields: []string{"missing"}}),
+			input:         `{ "b": 1, "a": 2 }`,
+			output:        `{ "b": 1, "a": 2 }`,
+			shouldProcess: true,
+		},
+		{
+			name:          "status and tag",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONFieldToStatus, Field: "level"}, &config.ProcessingRule{Type: config.JSONFieldToTag, Field: "dd.trace_id", Target: "trace_id"}),
+			input:         `{"level":"WARNING","dd":{"trace_id":1234}}`,
+			output:        `{"level":"WARNING","dd":{"trace_id":1234}}`,
+			status:        message.StatusWarning,
+			tags:          []string{"trace_id:1234"},
+			shouldProcess: true,
+		},
+		{
+			name:          "syslog severity status",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONFieldToStatus, Field: "severity"}),
+			input:         `{"severity":3}`,
+			output:        `{"severity":3}`,
+			status:        message.StatusError,
+			shouldProcess: true,
+		},
+		{
+			name:          "unknown status",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONFieldToStatus, Field: "level"}),
+			input:         `{"level":"loud"}`,
+			output:        `{"level":"loud"}`,
+			status:        message.StatusInfo,
+			shouldProcess: true,
+		},
+		{
+			name:          "not json",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONDropFields, Fields: []string{"password"}}, &config.ProcessingRule{Type: config.JSONFieldToTag, Field: "password"}),
+			input:         `password=secret`,
+			output:        `password=secret`,
+			shouldProcess: true,
+		},
+		{
+			name:          "rules matching the raw content see the changes",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.JSONDropFields, Fields: []string{"password"}}, &config.ProcessingRule{Type: config.ExcludeAtMatch, Pattern: "password"}),
+			input:         `{"password":"secret","msg":"hello"}`,
+			output:        `{"msg":"hello"}`,
+			shouldProcess: true,
+		},
+		{
+			name:          "json rules after a mask",
+			source:        newJSONSource(&config.ProcessingRule{Type: config.MaskSequences, Pattern: "secret", ReplacePlaceholder: "[redacted]"}, &config.ProcessingRule{Type: config.JSONRemapField, Field: "password", Target: "pwd"}),
+			input:         `{"password":"secret"}`,
+			output:        `{"pwd":"[redacted]"}`,
+			shouldProcess: true,
+		},
+	}
+
+	p := &Processor{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := newMessage([]byte(test.input), &test.source, "")
+			assert.Equal(t, test.shouldProcess, p.applyRedactingRules(msg))
+			assert.Equal(t, test.output, string(msg.GetContent()))
+			if test.status != "" {
+				assert.Equal(t, test.status, msg.GetStatus())
+			}
+			assert.Equal(t, test.tags, msg.ProcessingTags)
+		})
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	p := &Processor{}
 	source := sources.NewLogSource("", &config.LogsConfig{})
@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+const (
+	// dedupFlushInterval is the interval at which the runs of identical logs older than their
+	// window are flushed.
+	dedupFlushInterval = time.Second
+	// repeatCountTag is the tag holding the number of identical logs collapsed into a log.
+	repeatCountTag = "repeat_count"
+)
+
+// Throttling reasons
+const (
+	throttledByRateLimit = "rate_limit"
+	throttledBySampling  = "sampling"
+	throttledByDedup     = "dedup"
+)
+
+// dedupRun is a run of identical consecutive logs of a source. The first log of the run is sent,
+// the following ones are collapsed into the last one, which is sent tagged with their number once
+// the run ends.
+type dedupRun struct {
+	content []byte
+	start   time.Time
+	// last is the last collapsed log, nil if no log has been collapsed.
+	last  *message.Message
+	count int
+}
+
+// throttle applies the throttling config of the source of msg, and returns true if msg must be
+// sent. A log ending a run of identical logs makes the collapsed logs be sent first.
+func (p *Processor) throttle(msg *message.Message) bool {
+	source := msg.Origin.LogSource
+	if source == nil {
+		return true
+	}
+	throttler := source.Throttler()
+	if throttler == nil {
+		return true
+	}
+
+	if !throttler.Sample(msg.GetStatus()) {
+		p.recordThrottled(throttler.SampledOut, throttledBySampling)
+		return false
+	}
+
+	if throttler.Dedup() {
+		now := time.Now()
+		run, ok := p.dedupRuns[source]
+		if ok && now.Sub(run.start) < throttler.DedupWindow() && bytes.Equal(run.content, msg.GetContent()) {
+			if run.last != nil {
+				// the previous collapsed log is dropped, only the last one is sent
+				p.recordThrottled(throttler.Deduplicated, throttledByDedup)
+			}
+			run.last = msg
+			run.count++
+			return false
+		}
+		if ok {
+			p.flushDedupRun(source, run)
+		}
+		p.dedupRuns[source] = &dedupRun{
+			content: bytes.Clone(msg.GetContent()),
+			start:   now,
+		}
+	}
+
+	if !throttler.Allow() {
+		p.recordThrottled(throttler.RateLimited, throttledByRateLimit)
+		return false
+	}
+	return true
+}
+
+// flushDedupRuns sends the collapsed logs of the runs which are older than their window, or of all
+// the runs if all is true.
+func (p *Processor) flushDedupRuns(all bool) {
+	now := time.Now()
+	for source, run := range p.dedupRuns {
+		throttler := source.Throttler()
+		if all || throttler == nil || now.Sub(run.start) >= throttler.DedupWindow() {
+			p.flushDedupRun(source, run)
+		}
+	}
+}
+
+// flushDedupRun ends the run, sending its last collapsed log tagged with the number of collapsed
+// logs, if any. The collapsed logs aren't subject to the rate limit.
+func (p *Processor) flushDedupRun(source *sources.LogSource, run *dedupRun) {
+	delete(p.dedupRuns, source)
+	if run.last == nil {
+		return
+	}
+	run.last.ProcessingTags = append(run.last.ProcessingTags, repeatCountTag+":"+strconv.Itoa(run.count))
+	p.forward(run.last)
+}
+
+func (p *Processor) recordThrottled(count *status.CountInfo, reason string) {
+	count.Add(1)
+	metrics.LogsThrottled.Add(1)
+	metrics.TlmLogsThrottled.Inc(reason)
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newThrottlingProcessor() *Processor {
+	hostnameComponent, _ := hostnameinterface.NewMock("testHostnameFromEnvVar")
+	pm := metrics.NewNoopPipelineMonitor("")
+	return &Processor{
+		encoder:                   JSONEncoder,
+		outputChan:                make(chan *message.Message, 10),
+		diagnosticMessageReceiver: diagnostic.NewBufferedMessageReceiver(nil, hostnameComponent),
+		pipelineMonitor:           pm,
+		utilization:               pm.MakeUtilizationMonitor("processor"),
+		dedupRuns:                 make(map[*sources.LogSource]*dedupRun),
+	}
+}
+
+func newThrottledSource(t *testing.T, throttling *config.ThrottlingConfig) *sources.LogSource {
+	require.NoError(t, throttling.Validate())
+	return sources.NewLogSource("", &config.LogsConfig{Throttling: throttling})
+}
+
+func TestThrottleSampling(t *testing.T) {
+	p := newThrottlingProcessor()
+	source := newThrottledSource(t, &config.ThrottlingConfig{SampleRates: map[string]float64{"Info": 0}})
+
+	assert.False(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusError)))
+	assert.Equal(t, int64(1), source.Throttler().SampledOut.Get())
+	assert.Equal(t, []string{"1"}, source.GetInfoStatus()["Logs Sampled Out"])
+}
+
+func TestThrottleRateLimit(t *testing.T) {
+	p := newThrottlingProcessor()
+	source := newThrottledSource(t, &config.ThrottlingConfig{RateLimit: 0.001, Burst: 2})
+
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.False(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.Equal(t, int64(1), source.Throttler().RateLimited.Get())
+
+	// sources without throttling config aren't throttled
+	unthrottled := sources.NewLogSource("", &config.LogsConfig{})
+	for i := 0; i < 10; i++ {
+		assert.True(t, p.throttle(newMessage([]byte("hello"), unthrottled, message.StatusInfo)))
+	}
+}
+
+func TestThrottleDedup(t *testing.T) {
+	p := newThrottlingProcessor()
+	source := newThrottledSource(t, &config.ThrottlingConfig{Dedup: true})
+
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.False(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.False(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.Len(t, p.outputChan, 0)
+
+	// a different log ends the run, the collapsed logs are sent first
+	assert.True(t, p.throttle(newMessage([]byte("world"), source, message.StatusInfo)))
+	require.Len(t, p.outputChan, 1)
+	msg := <-p.outputChan
+	assert.Contains(t, string(msg.GetContent()), `"message":"hello"`)
+	assert.Contains(t, string(msg.GetContent()), `"ddtags":"repeat_count:2"`)
+	assert.Equal(t, int64(1), source.Throttler().Deduplicated.Get())
+
+	// a run without collapsed logs sends nothing
+	p.flushDedupRuns(true)
+	assert.Len(t, p.outputChan, 0)
+	assert.Empty(t, p.dedupRuns)
+}
+
+func TestThrottleDedupWindow(t *testing.T) {
+	p := newThrottlingProcessor()
+	source := newThrottledSource(t, &config.ThrottlingConfig{Dedup: true, DedupWindow: "10ms"})
+
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	assert.False(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+	p.flushDedupRuns(false)
+	assert.Len(t, p.outputChan, 0)
+
+	time.Sleep(20 * time.Millisecond)
+	p.flushDedupRuns(false)
+	require.Len(t, p.outputChan, 1)
+	msg := <-p.outputChan
+	assert.Contains(t, msg.ProcessingTags, "repeat_count:1")
+
+	// the run has ended, the same log starts a new one
+	assert.True(t, p.throttle(newMessage([]byte("hello"), source, message.StatusInfo)))
+}
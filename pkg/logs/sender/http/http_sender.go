@@ -78,6 +78,7 @@ func httpDestinationFactory(
 	minConcurrency int,
 	maxConcurrency int,
 ) sender.DestinationFactory {
+	outputDestinations := sender.NewOutputDestinations(endpoints, destinationsContext)
 	return func() *client.Destinations {
 		reliable := []client.Destination{}
 		additionals := []client.Destination{}
@@ -97,6 +98,9 @@ func httpDestinationFactory(
 				additionals = append(additionals, http.NewDestination(endpoint, contentyType, destinationsContext, false, destMeta, cfg, minConcurrency, maxConcurrency, pipelineMonitor))
 			}
 		}
+		if !serverlessMeta.IsEnabled() {
+			return outputDestinations.Destinations(reliable, additionals)
+		}
 		return client.NewDestinations(reliable, additionals)
 	}
 }
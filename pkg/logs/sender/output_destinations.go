@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/kafka"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/spool"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// OutputDestinations builds the destinations of the Kafka endpoints and of the spool, which are
+// added to the destinations of the intake endpoints by the destination factories. The spool is the
+// fallback destination, used while all the reliable destinations are retrying.
+type OutputDestinations struct {
+	endpoints           *config.Endpoints
+	destinationsContext *client.DestinationsContext
+	// spoolWriter is shared by the destinations of all the workers, so that they don't rotate and
+	// delete the files of each other.
+	spoolWriter *spool.Writer
+}
+
+// NewOutputDestinations returns a new OutputDestinations for the Kafka endpoints and the spool
+// of endpoints.
+func NewOutputDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) *OutputDestinations {
+	o := &OutputDestinations{
+		endpoints:           endpoints,
+		destinationsContext: destinationsContext,
+	}
+	if endpoints.Spool != nil {
+		o.spoolWriter = spool.NewWriter(*endpoints.Spool)
+	}
+	return o
+}
+
+// Destinations returns the destinations made of reliable and additionals with a new destination
+// for each Kafka endpoint, depending on whether they are reliable, and the spool as fallback.
+func (o *OutputDestinations) Destinations(reliable []client.Destination, additionals []client.Destination) *client.Destinations {
+	for _, endpoint := range o.endpoints.KafkaEndpoints {
+		destination, err := kafka.NewDestination(endpoint, o.destinationsContext, endpoint.IsReliable(), client.NewNoopDestinationMetadata())
+		if err != nil {
+			log.Errorf("Could not create Kafka destination: %v", err)
+			continue
+		}
+		if endpoint.IsReliable() {
+			reliable = append(reliable, destination)
+		} else {
+			additionals = append(additionals, destination)
+		}
+	}
+	destinations := client.NewDestinations(reliable, additionals)
+	if o.spoolWriter != nil {
+		destinations.Fallback = spool.NewDestination(o.spoolWriter, o.destinationsContext, client.NewNoopDestinationMetadata())
+	}
+	return destinations
+}
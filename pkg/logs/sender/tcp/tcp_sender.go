@@ -54,6 +54,7 @@ func tcpDestinationFactory(
 	status statusinterface.Status,
 ) sender.DestinationFactory {
 	isServerless := serverlessMeta != nil
+	outputDestinations := sender.NewOutputDestinations(endpoints, destinationsContext)
 	return func() *client.Destinations {
 		reliable := []client.Destination{}
 		additionals := []client.Destination{}
@@ -63,6 +64,9 @@ func tcpDestinationFactory(
 		for _, endpoint := range endpoints.GetUnReliableEndpoints() {
 			additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, false, status))
 		}
+		if !isServerless {
+			return outputDestinations.Destinations(reliable, additionals)
+		}
 
 		return client.NewDestinations(reliable, additionals)
 	}
@@ -23,13 +23,26 @@ var (
 	tlmSendWaitTime    = telemetry.NewCounter("logs_sender", "send_wait", []string{}, "Time spent waiting for all sends to finish")
 )
 
+var (
+	// replayInterval is the interval at which the payloads kept by the fallback destination are
+	// sent to the reliable destinations.
+	replayInterval = time.Second
+	// replayBatchSize is the maximum number of payloads replayed at each interval, so that the
+	// replay doesn't hold back the new payloads.
+	replayBatchSize = 100
+)
+
 // worker sends logs to different destinations. Destinations can be either
 // reliable or unreliable. The worker ensures that logs are sent to at least
 // one reliable destination and will block the pipeline if they are in an
 // error state. Unreliable destinations will only send logs when at least
-// one reliable destination is also sending logs. However they do not update
-// the auditor or block the pipeline if they fail. There will always be at
-// least 1 reliable destination (the main destination).
+// one reliable destination, or the fallback destination, is also sending
+// logs. However they do not update the auditor or block the pipeline if
+// they fail. There will always be at least 1 reliable destination (the main
+// destination). When all the reliable destinations are retrying, the
+// payloads are sent to the fallback destination, if any, instead of
+// blocking the pipeline. They don't update the auditor, and they're
+// replayed to the reliable destinations once these are available again.
 type worker struct {
 	config         pkgconfigmodel.Reader
 	inputChan      chan *message.Payload
@@ -102,6 +115,19 @@ func (s *worker) run() {
 
 	reliableDestinations := buildDestinationSenders(s.config, s.destinations.Reliable, reliableOutputChan, s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, noopSink, s.bufferSize)
+	// the payloads of the fallback destination don't update the auditor
+	var fallbackDestination *DestinationSender
+	var replayer client.Replayer
+	var replayTick <-chan time.Time
+	if s.destinations.Fallback != nil {
+		fallbackDestination = NewDestinationSender(s.config, s.destinations.Fallback, noopSink, s.bufferSize)
+		if r, ok := s.destinations.Fallback.(client.Replayer); ok {
+			replayer = r
+			replayTicker := time.NewTicker(replayInterval)
+			defer replayTicker.Stop()
+			replayTick = replayTicker.C
+		}
+	}
 	continueLoop := true
 	for continueLoop {
 		select {
@@ -111,18 +137,15 @@ func (s *worker) run() {
 			senderDoneWg := &sync.WaitGroup{}
 
 			sent := false
+			fellBack := false
 			for !sent {
-				for _, destSender := range reliableDestinations {
-					if destSender.Send(payload) {
-						if destSender.destination.Metadata().ReportingEnabled {
-							s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
-						}
-						sent = true
-						if s.senderDoneChan != nil {
-							senderDoneWg.Add(1)
-							s.senderDoneChan <- senderDoneWg
-						}
-					}
+				sent = s.sendToReliable(payload, reliableDestinations, senderDoneWg)
+
+				if !sent && fallbackDestination != nil && fallbackDestination.Send(payload) {
+					// All the reliable destinations are retrying, the payload is replayed
+					// once they are available again.
+					sent = true
+					fellBack = true
 				}
 
 				if !sent {
@@ -135,8 +158,8 @@ func (s *worker) run() {
 
 			for i, destSender := range reliableDestinations {
 				// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-				// loss on intermittent failures.
-				if !destSender.lastSendSucceeded {
+				// loss on intermittent failures. The payloads kept by the fallback destination are replayed instead.
+				if !destSender.lastSendSucceeded && !fellBack {
 					if !destSender.NonBlockingSend(payload) {
 						tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
 						tlmMessagesDropped.Add(float64(payload.Count()), "true", strconv.Itoa(i))
@@ -167,6 +190,8 @@ func (s *worker) run() {
 				s.flushWg.Done()
 			}
 			s.pipelineMonitor.ReportComponentEgress(payload, "sender")
+		case <-replayTick:
+			s.replay(replayer, reliableDestinations, fallbackDestination)
 		case <-s.done:
 			continueLoop = false
 		}
@@ -179,10 +204,61 @@ func (s *worker) run() {
 	for _, destSender := range unreliableDestinations {
 		destSender.Stop()
 	}
+	if fallbackDestination != nil {
+		fallbackDestination.Stop()
+	}
 	close(noopSink)
 	s.finished <- struct{}{}
 }
 
+// sendToReliable sends the payload to the reliable destinations, and returns true if at least one
+// of them accepted it.
+func (s *worker) sendToReliable(payload *message.Payload, reliableDestinations []*DestinationSender, senderDoneWg *sync.WaitGroup) bool {
+	sent := false
+	for _, destSender := range reliableDestinations {
+		if destSender.Send(payload) {
+			if destSender.destination.Metadata().ReportingEnabled {
+				s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
+			}
+			sent = true
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
+		}
+	}
+	return sent
+}
+
+// replay sends the payloads kept by the fallback destination to the reliable destinations, as long
+// as one of them isn't retrying. A payload which can't be sent is given back to the fallback
+// destination.
+func (s *worker) replay(replayer client.Replayer, reliableDestinations []*DestinationSender, fallbackDestination *DestinationSender) {
+	for i := 0; i < replayBatchSize && anyAvailable(reliableDestinations); i++ {
+		payload := replayer.NextReplay()
+		if payload == nil {
+			return
+		}
+		if s.sendToReliable(payload, reliableDestinations, &sync.WaitGroup{}) {
+			continue
+		}
+		if !fallbackDestination.Send(payload) {
+			tlmPayloadsDropped.Inc("false", "fallback")
+		}
+		return
+	}
+}
+
+// anyAvailable returns true if at least one of the destinations isn't retrying.
+func anyAvailable(destinations []*DestinationSender) bool {
+	for _, destSender := range destinations {
+		if !destSender.isRetrying() {
+			return true
+		}
+	}
+	return false
+}
+
 // Drains the output channel from destinations that don't update the auditor.
 func noopDestinationsSink(bufferSize int) chan *message.Payload {
 	sink := make(chan *message.Payload, bufferSize)
@@ -6,7 +6,9 @@
 package sender
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/spool"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
@@ -35,6 +38,8 @@ func (a *testAuditor) Stop() {
 }
 func (a *testAuditor) GetOffset(_ string) string      { return "" }
 func (a *testAuditor) GetTailingMode(_ string) string { return "" }
+func (a *testAuditor) GetFingerprint(_ string) uint64 { return 0 }
+func (a *testAuditor) KeepAlive(_ string)             {}
 
 func newMessage(content []byte, source *sources.LogSource, status string) *message.Payload {
 	return &message.Payload{
@@ -345,3 +350,76 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	worker.stop()
 }
+
+func TestSenderFallbackWhenMainFails(t *testing.T) {
+	defer func(interval time.Duration) { replayInterval = interval }(replayInterval)
+	replayInterval = 10 * time.Millisecond
+
+	cfg := configmock.New(t)
+	input := make(chan *message.Payload, 1)
+	auditor := &testAuditor{
+		output: make(chan *message.Payload, 1),
+	}
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	defer destinationsCtx.Stop()
+
+	reliableRespond := make(chan int)
+	reliableServer := http.NewTestServerWithOptions(200, 1, true, reliableRespond, cfg)
+
+	spoolPath := t.TempDir()
+	fallback := spool.NewDestination(spool.NewWriter(config.SpoolEndpoint{Path: spoolPath, MaxFileSize: 1024, MaxFiles: 2}), destinationsCtx, client.NewNoopDestinationMetadata())
+
+	destinations := client.NewDestinations([]client.Destination{reliableServer.Destination}, nil)
+	destinations.Fallback = fallback
+
+	worker := newWorker(cfg, input, auditor, destinations, 10, NewMockServerlessMeta(false), metrics.NewNoopPipelineMonitor(""))
+	worker.start()
+
+	// the fallback isn't used while the main destination is available
+	input <- &message.Payload{Encoded: []byte("a")}
+	<-reliableRespond
+	<-auditor.output
+	assert.Empty(t, spoolFiles(t, spoolPath))
+
+	reliableServer.ChangeStatus(500)
+
+	input <- &message.Payload{Encoded: []byte("b")}
+
+	<-reliableRespond // let it respond 500 once
+	<-reliableRespond // its in a loop now, once we respond 500 a second time we know the sender has marked the endpoint as retrying
+
+	// the main destination is retrying, the payload is written to the spool without updating the auditor
+	input <- &message.Payload{Encoded: []byte("c")}
+	<-reliableRespond
+	assert.Eventually(t, func() bool { return len(spoolFiles(t, spoolPath)) == 1 }, time.Second, 10*time.Millisecond)
+	select {
+	case <-auditor.output:
+		assert.Fail(t, "the payloads of the fallback destination shouldn't update the auditor")
+	default:
+	}
+
+	// Recover the main destination
+	reliableServer.ChangeStatus(200)
+	for {
+		if (<-reliableRespond) == 200 {
+			break
+		}
+	}
+	assert.Equal(t, []byte("b"), (<-auditor.output).Encoded)
+
+	// the spooled payload is replayed to the main destination
+	<-reliableRespond
+	assert.Equal(t, []byte("c"), (<-auditor.output).Encoded)
+	assert.Eventually(t, func() bool { return len(spoolFiles(t, spoolPath)) == 0 }, time.Second, 10*time.Millisecond)
+
+	reliableServer.Stop()
+	worker.stop()
+}
+
+func spoolFiles(t *testing.T, path string) []os.DirEntry {
+	entries, err := os.ReadDir(path)
+	assert.NoError(t, err)
+	return entries
+}
@@ -51,6 +51,8 @@ type LogSource struct {
 	LatencyStats     *statstracker.Tracker
 	BytesRead        *status.CountInfo
 	hiddenFromStatus bool
+	// throttler is created on first use, when the config of the source has been validated.
+	throttler *Throttler
 }
 
 // NewLogSource creates a new log source.
@@ -132,6 +134,23 @@ func (s *LogSource) GetInfoStatus() map[string][]string {
 	return s.info.Rendered()
 }
 
+// Throttler returns the throttler applying the throttling config of the source to its logs, nil
+// if the source isn't throttled. The throttled logs counts are displayed on the status page.
+func (s *LogSource) Throttler() *Throttler {
+	if s.Config == nil || s.Config.Throttling == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.throttler == nil {
+		s.throttler = NewThrottler(s.Config.Throttling)
+		s.info.Register(s.throttler.RateLimited)
+		s.info.Register(s.throttler.SampledOut)
+		s.info.Register(s.throttler.Deduplicated)
+	}
+	return s.throttler
+}
+
 // HideFromStatus hides the source from the status output
 func (s *LogSource) HideFromStatus() {
 	s.lock.Lock()
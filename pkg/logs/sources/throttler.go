@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sources
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+// defaultDedupWindow is used when the throttling config hasn't been validated.
+const defaultDedupWindow = 10 * time.Second
+
+// Throttler applies the throttling config of a source to its logs. It's shared by the processors
+// of all the pipelines, so that the rate limit applies to the source as a whole.
+type Throttler struct {
+	config  *config.ThrottlingConfig
+	limiter *rate.Limiter
+	// randFloat returns a random number in [0, 1), it's used to sample the logs.
+	randFloat func() float64
+
+	// RateLimited counts the logs dropped by the rate limit.
+	RateLimited *status.CountInfo
+	// SampledOut counts the logs dropped by the sampling.
+	SampledOut *status.CountInfo
+	// Deduplicated counts the logs collapsed into a previous identical log.
+	Deduplicated *status.CountInfo
+}
+
+// NewThrottler returns a new Throttler for the throttling config.
+func NewThrottler(cfg *config.ThrottlingConfig) *Throttler {
+	limit := rate.Inf
+	burst := 0
+	if cfg.RateLimit > 0 {
+		limit = rate.Limit(cfg.RateLimit)
+		burst = cfg.Burst
+		if burst == 0 {
+			burst = int(math.Ceil(cfg.RateLimit))
+		}
+	}
+	return &Throttler{
+		config:       cfg,
+		limiter:      rate.NewLimiter(limit, burst),
+		randFloat:    rand.Float64,
+		RateLimited:  status.NewCountInfo("Logs Rate Limited"),
+		SampledOut:   status.NewCountInfo("Logs Sampled Out"),
+		Deduplicated: status.NewCountInfo("Logs Deduplicated"),
+	}
+}
+
+// Sample returns true if a log with the given status is kept by the sampling.
+func (t *Throttler) Sample(status string) bool {
+	sampleRate := t.config.SampleRate(status)
+	return sampleRate >= 1 || t.randFloat() < sampleRate
+}
+
+// Allow returns true if a log can be sent without exceeding the rate limit.
+func (t *Throttler) Allow() bool {
+	return t.limiter.Allow()
+}
+
+// Dedup returns true if the identical consecutive logs are collapsed.
+func (t *Throttler) Dedup() bool {
+	return t.config.Dedup
+}
+
+// DedupWindow returns the maximum duration of a run of identical logs collapsed into one.
+func (t *Throttler) DedupWindow() time.Duration {
+	if t.config.DedupWindowDuration > 0 {
+		return t.config.DedupWindowDuration
+	}
+	return defaultDedupWindow
+}
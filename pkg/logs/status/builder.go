@@ -173,6 +173,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.Protocol
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -204,6 +207,7 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 func (b *Builder) getMetricsStatus() map[string]string {
 	var metrics = make(map[string]string)
 	metrics["LogsProcessed"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsProcessed").(*expvar.Int).Value())
+	metrics["LogsThrottled"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsThrottled").(*expvar.Int).Value())
 	metrics["LogsSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsSent").(*expvar.Int).Value())
 	metrics["BytesSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("BytesSent").(*expvar.Int).Value())
 	metrics["RetryCount"] = fmt.Sprintf("%v", b.logsExpVars.Get("RetryCount").(*expvar.Int).Value())
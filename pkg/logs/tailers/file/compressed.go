@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Compressions of the files which can be decompressed by the tailer
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// CompressionFromPath returns the compression of the file at path given by its extension, or an
+// empty string if it's not compressed.
+func CompressionFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return CompressionGzip
+	case ".zst", ".zstd":
+		return CompressionZstd
+	default:
+		return ""
+	}
+}
+
+// setupCompressed opens the compressed file and skips the decompressed bytes up to the offset.
+// The offsets of compressed files are offsets in their decompressed content, and tailing them
+// from the end means skipping their whole content: they don't change once written.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(t.fullpath)
+	if err != nil {
+		return err
+	}
+	var reader io.ReadCloser
+	switch t.compression {
+	case CompressionGzip:
+		reader, err = gzip.NewReader(f)
+	case CompressionZstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(f, zstd.WithDecoderConcurrency(1)); err == nil {
+			reader = zr.IOReadCloser()
+		}
+	default:
+		err = fmt.Errorf("unsupported compression %q", t.compression)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not decompress %q: %w", t.file.Path, err)
+	}
+
+	var skipped int64
+	if whence == io.SeekEnd {
+		skipped, err = io.Copy(io.Discard, reader)
+	} else if skipped, err = io.CopyN(io.Discard, reader, offset); errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		reader.Close()
+		f.Close()
+		return fmt.Errorf("could not decompress %q: %w", t.file.Path, err)
+	}
+
+	t.osFile = f
+	t.decompressor = reader
+	t.lastReadOffset.Store(skipped)
+	t.decodedOffset.Store(skipped)
+	return nil
+}
+
+// readCompressed reads the decompressed content of the file. Once the whole content has been
+// read, it returns io.EOF to stop the tailer: the compressed files don't change once written, and
+// the final offset is committed to the auditor with the last messages.
+func (t *Tailer) readCompressed() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.decompressor.Read(inBuf)
+	if err != nil && err != io.EOF {
+		// the file is corrupted or still being written, it's read again from the last committed
+		// offset when the tailer is restarted
+		t.file.Source.Status().Error(err)
+		return 0, log.Errorf("Could not decompress %q: %v", t.file.Path, err)
+	}
+	if n == 0 {
+		if err == io.EOF {
+			log.Infof("Read the whole content of %q, %d bytes", t.file.Path, t.lastReadOffset.Load())
+			t.isFullyRead.Store(true)
+			return 0, io.EOF
+		}
+		return 0, nil
+	}
+	// when the last bytes are returned with io.EOF, io.EOF is returned again by the next read
+	t.lastReadOffset.Add(int64(n))
+	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	return n, nil
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func writeCompressedFile(t *testing.T, path string, compression string, content string) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		w = zw
+	}
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, CompressionGzip, CompressionFromPath("/var/log/app.log.1.gz"))
+	assert.Equal(t, CompressionZstd, CompressionFromPath("/var/log/app.log.1.zst"))
+	assert.Equal(t, "", CompressionFromPath("/var/log/app.log.1"))
+}
+
+func TestTailCompressedFiles(t *testing.T) {
+	for _, compression := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.log.1")
+			content := "first line\nsecond line\nthird line\n"
+			writeCompressedFile(t, path, compression, content)
+			fingerprint, err := ComputeFingerprint(path, DefaultFingerprintSize, true)
+			require.NoError(t, err)
+
+			outputChan := make(chan *message.Message, 10)
+			tailer := newTestTailer(path, outputChan, 0, fingerprint, compression)
+			assert.Equal(t, FingerprintIdentifier(fingerprint), tailer.Identifier())
+
+			// the offset is an offset in the decompressed content
+			require.NoError(t, tailer.Start(int64(len("first line\n")), io.SeekStart))
+			msg := <-outputChan
+			assert.Equal(t, "second line", string(msg.GetContent()))
+			assert.Equal(t, FingerprintIdentifier(fingerprint), msg.Origin.Identifier)
+			assert.Equal(t, "23", msg.Origin.Offset)
+			msg = <-outputChan
+			assert.Equal(t, "third line", string(msg.GetContent()))
+			assert.Equal(t, "34", msg.Origin.Offset)
+
+			didRotate, err := tailer.DidRotate()
+			require.NoError(t, err)
+			assert.False(t, didRotate)
+
+			// the tailer stops once the whole content is read
+			assert.Eventually(t, tailer.IsFinished, time.Second, 10*time.Millisecond)
+			assert.True(t, tailer.IsFullyRead())
+			assert.Equal(t, int64(len(content)), tailer.DecodedOffset())
+			tailer.Stop()
+
+			// nothing is read when tailing from the end
+			tailer = newTestTailer(path, outputChan, 0, fingerprint, compression)
+			require.NoError(t, tailer.Start(0, io.SeekEnd))
+			select {
+			case msg := <-outputChan:
+				assert.Fail(t, "unexpected message", string(msg.GetContent()))
+			case <-time.After(50 * time.Millisecond):
+			}
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailCorruptedCompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not compressed\n"), 0644))
+
+	tailer := newTestTailer(path, make(chan *message.Message, 10), 0, 1, CompressionGzip)
+	assert.Error(t, tailer.StartFromBeginning())
+
+	// the file is truncated, it's still being written
+	writeCompressedFile(t, path, CompressionGzip, "first line\n")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content[:len(content)-4], 0644))
+
+	outputChan := make(chan *message.Message, 10)
+	tailer = newTestTailer(path, outputChan, 0, 1, CompressionGzip)
+	require.NoError(t, tailer.StartFromBeginning())
+	assert.Eventually(t, tailer.IsFinished, time.Second, 10*time.Millisecond)
+	assert.False(t, tailer.IsFullyRead())
+	tailer.Stop()
+}
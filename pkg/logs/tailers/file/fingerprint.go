@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+// DefaultFingerprintSize is the number of bytes used to compute the fingerprint of the files
+// when fingerprinting is disabled, for the compressed files which are always identified by
+// their fingerprint.
+const DefaultFingerprintSize = 1024
+
+var fingerprintTable = crc64.MakeTable(crc64.ECMA)
+
+// ComputeFingerprint returns the fingerprint of the file at path, a checksum of its first size
+// bytes which identifies the file regardless of its path and inode. It returns 0, an unknown
+// fingerprint, if the file holds fewer bytes, unless it's compressed: compressed files don't
+// change once written and are fingerprinted as a whole when they're smaller.
+func ComputeFingerprint(path string, size int, compressed bool) (uint64, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return fingerprint(f, size, compressed)
+}
+
+// fingerprint returns the checksum of the first size bytes of r, see ComputeFingerprint.
+func fingerprint(r io.ReaderAt, size int, partial bool) (uint64, error) {
+	buf := make([]byte, size)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	if n == 0 || (n < size && !partial) {
+		return 0, nil
+	}
+	sum := crc64.Checksum(buf[:n], fingerprintTable)
+	if sum == 0 {
+		// 0 is the unknown fingerprint
+		sum = 1
+	}
+	return sum, nil
+}
+
+// FingerprintIdentifier returns the identifier in the registry of the file with the given
+// fingerprint. It's used for the files which can't be identified by their path: the compressed
+// files, and the files which have been rotated.
+func FingerprintIdentifier(fingerprint uint64) string {
+	return fmt.Sprintf("fingerprint:%016x", fingerprint)
+}
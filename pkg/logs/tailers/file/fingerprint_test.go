@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func newTestTailer(path string, outputChan chan *message.Message, fingerprintSize int, fingerprint uint64, compression string) *Tailer {
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+		FingerprintSize: fingerprintSize,
+		Fingerprint:     fingerprint,
+		Compression:     compression,
+	})
+	tailer.closeTimeout = 10 * time.Millisecond
+	return tailer
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+
+	// the file is too short
+	fingerprint, err := ComputeFingerprint(path, 16, false)
+	require.NoError(t, err)
+	assert.Zero(t, fingerprint)
+
+	// unless it's compressed
+	compressedFingerprint, err := ComputeFingerprint(path, 16, true)
+	require.NoError(t, err)
+	assert.NotZero(t, compressedFingerprint)
+
+	// only the first bytes are used
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\n"), 0644))
+	fingerprint, err = ComputeFingerprint(path, 16, false)
+	require.NoError(t, err)
+	assert.NotZero(t, fingerprint)
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\nthird line\n"), 0644))
+	sameFingerprint, err := ComputeFingerprint(path, 16, false)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, sameFingerprint)
+
+	require.NoError(t, os.WriteFile(path, []byte("other line\nsecond line\n"), 0644))
+	otherFingerprint, err := ComputeFingerprint(path, 16, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherFingerprint)
+
+	_, err = ComputeFingerprint(filepath.Join(t.TempDir(), "missing.log"), 16, false)
+	assert.Error(t, err)
+
+	assert.Equal(t, "fingerprint:00000000000000ff", FingerprintIdentifier(255))
+}
+
+func TestDidRotateFingerprintChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newTestTailer(path, outputChan, 16, 0, "")
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+	assert.Equal(t, "first line", string((<-outputChan).GetContent()))
+	assert.Zero(t, tailer.Fingerprint())
+
+	// the fingerprint is computed once the file holds enough bytes
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("second line\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	msg := <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+	didRotate, err := tailer.DidRotate()
+	require.NoError(t, err)
+	assert.False(t, didRotate)
+	assert.NotZero(t, tailer.Fingerprint())
+
+	// the file is truncated and written again, with more bytes than were read, in place
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("new content\n", 4)), 0644))
+	didRotate, err = tailer.DidRotate()
+	require.NoError(t, err)
+	assert.True(t, didRotate)
+}
+
+func TestRotatedTailerOffsetsAreKeptUnderFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+	fingerprint, err := ComputeFingerprint(path, 8, false)
+	require.NoError(t, err)
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newTestTailer(path, outputChan, 8, 0, "")
+	// the tailer must read the line written after the rotation before it's stopped
+	tailer.closeTimeout = time.Second
+	require.NoError(t, tailer.StartFromBeginning())
+	msg := <-outputChan
+	assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+	assert.Equal(t, fingerprint, msg.Origin.Fingerprint)
+
+	tailer.StopAfterFileRotation()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("second line\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	msg = <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+	assert.Equal(t, FingerprintIdentifier(fingerprint), msg.Origin.Identifier)
+	assert.Equal(t, "23", msg.Origin.Offset)
+	<-tailer.done
+	assert.Equal(t, int64(23), tailer.DecodedOffset())
+}
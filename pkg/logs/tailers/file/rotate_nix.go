@@ -21,7 +21,14 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+// - replaced by a file with other first bytes, if fingerprinting is enabled, e.g. after a
+// copy-truncate rotation or when the inode of the file was recycled
+//
+// Compressed files are never rotated: their content doesn't change once written.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.compression != "" {
+		return false, nil
+	}
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, fmt.Errorf("open %q: %w", t.fullpath, err)
@@ -43,12 +50,16 @@ func (t *Tailer) DidRotate() (bool, error) {
 
 	recreated := !os.SameFile(fi1, fi2)
 	truncated := fileSize < lastReadOffset
+	t.updateFingerprint(t.osFile)
+	replaced := t.didFingerprintChange(f)
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
 	} else if truncated {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
+	} else if replaced {
+		log.Debugf("File rotation detected due to fingerprint change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
 	}
 
-	return recreated || truncated, nil
+	return recreated || truncated || replaced, nil
 }
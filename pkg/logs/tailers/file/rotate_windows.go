@@ -17,8 +17,12 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read, or by the first bytes of the file changing if fingerprinting is
+// enabled. Compressed files are never rotated: their content doesn't change once written.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.compression != "" {
+		return false, nil
+	}
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, fmt.Errorf("open %q: %w", t.fullpath, err)
@@ -43,5 +47,11 @@ func (t *Tailer) DidRotate() (bool, error) {
 		return true, nil
 	}
 
+	t.updateFingerprint(f)
+	if t.didFingerprintChange(f) {
+		log.Debugf("File rotation detected due to fingerprint change, lastReadOffset=%d, fileSize=%d", offset, sz)
+		return true, nil
+	}
+
 	return false, nil
 }
@@ -62,6 +62,20 @@ type Tailer struct {
 	// is platform-specific, and not every platform will have a non-nil value here.
 	osFile *os.File
 
+	// compression is the compression of the file, empty if it's not compressed.
+	compression string
+
+	// decompressor reads the decompressed content of osFile if the file is compressed.
+	decompressor io.ReadCloser
+
+	// fingerprintSize is the number of bytes used to compute the fingerprint of the file, 0 if
+	// fingerprinting is disabled.
+	fingerprintSize int
+
+	// fingerprint is the fingerprint of the file, 0 until the file holds enough bytes to
+	// compute it.
+	fingerprint *atomic.Uint64
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -99,6 +113,9 @@ type Tailer struct {
 	// didFileRotate is true when we are tailing a file after it has been rotated
 	didFileRotate *atomic.Bool
 
+	// isFullyRead is true when the tailer has read the whole content of its compressed file.
+	isFullyRead *atomic.Bool
+
 	// stop is monitored by the readForever component, and causes it to stop reading
 	// and close the channel to the decoder.
 	stop chan struct{}
@@ -132,6 +149,9 @@ type TailerOptions struct {
 	Rotated         bool                    // Optional
 	TagAdder        tag.EntityTagAdder      // Required
 	PipelineMonitor metrics.PipelineMonitor // Required
+	FingerprintSize int                     // Optional
+	Fingerprint     uint64                  // Optional
+	Compression     string                  // Optional
 }
 
 // NewTailer returns an initialized Tailer, read to be started.
@@ -170,6 +190,9 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		outputChan:             opts.OutputChan,
 		decoder:                opts.Decoder,
 		tagProvider:            tagProvider,
+		compression:            opts.Compression,
+		fingerprintSize:        opts.FingerprintSize,
+		fingerprint:            atomic.NewUint64(opts.Fingerprint),
 		lastReadOffset:         atomic.NewInt64(0),
 		decodedOffset:          atomic.NewInt64(0),
 		sleepDuration:          opts.SleepDuration,
@@ -181,6 +204,7 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		stopForward:            stopForward,
 		isFinished:             atomic.NewBool(false),
 		didFileRotate:          atomic.NewBool(false),
+		isFullyRead:            atomic.NewBool(false),
 		info:                   opts.Info,
 		bytesRead:              bytesRead,
 		movingSum:              movingSum,
@@ -213,6 +237,7 @@ func (t *Tailer) NewRotatedTailer(file *File, outputChan chan *message.Message,
 		Rotated:         true,
 		TagAdder:        tagAdder,
 		PipelineMonitor: pipelineMonitor,
+		FingerprintSize: t.fingerprintSize,
 	}
 
 	return NewTailer(options)
@@ -227,9 +252,24 @@ func (t *Tailer) Identifier() string {
 	//
 	// This is the identifier used in the registry, so changing it will invalidate existing
 	// registry entries on upgrade.
+	if fingerprint := t.fingerprint.Load(); t.compression != "" && fingerprint != 0 {
+		// compressed files are identified by their fingerprint, so that they're only read
+		// once even if they're renamed by later rotations
+		return FingerprintIdentifier(fingerprint)
+	}
 	return fmt.Sprintf("file:%s", t.file.Path)
 }
 
+// Fingerprint returns the fingerprint of the file, 0 if it's unknown.
+func (t *Tailer) Fingerprint() uint64 {
+	return t.fingerprint.Load()
+}
+
+// DecodedOffset returns the offset in the file at which the latest decoded message ends.
+func (t *Tailer) DecodedOffset() int64 {
+	return t.decodedOffset.Load()
+}
+
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
 	err := t.setup(offset, whence)
@@ -292,6 +332,9 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
+		if t.decompressor != nil {
+			t.decompressor.Close()
+		}
 		if t.osFile != nil {
 			t.osFile.Close()
 		}
@@ -339,6 +382,12 @@ func (t *Tailer) IsFinished() bool {
 	return t.isFinished.Load()
 }
 
+// IsFullyRead returns true if the tailer stopped after reading the whole content of its compressed
+// file, which doesn't need to be tailed again.
+func (t *Tailer) IsFullyRead() bool {
+	return t.isFullyRead.Load()
+}
+
 // forwardMessages lets the Tailer forward log messages to the output channel
 func (t *Tailer) forwardMessages() {
 	defer func() {
@@ -349,14 +398,22 @@ func (t *Tailer) forwardMessages() {
 	for output := range t.decoder.OutputChan {
 		offset := t.decodedOffset.Load() + int64(output.RawDataLen)
 		identifier := t.Identifier()
+		fingerprint := t.fingerprint.Load()
 		if t.didFileRotate.Load() {
-			offset = 0
-			identifier = ""
+			if fingerprint != 0 {
+				// the offset is kept under the fingerprint of the file, which may be tailed again
+				// under another path after the rotation
+				identifier = FingerprintIdentifier(fingerprint)
+			} else {
+				offset = 0
+				identifier = ""
+			}
 		}
 		t.decodedOffset.Store(offset)
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		origin.Fingerprint = fingerprint
 
 		tags := make([]string, len(t.tags))
 		copy(tags, t.tags)
@@ -381,6 +438,28 @@ func (t *Tailer) forwardMessages() {
 	}
 }
 
+// updateFingerprint computes the fingerprint of the file from r if it's not known yet, i.e. if
+// the file didn't hold enough bytes when it was last computed.
+func (t *Tailer) updateFingerprint(r io.ReaderAt) {
+	if t.fingerprintSize == 0 || t.fingerprint.Load() != 0 {
+		return
+	}
+	if fingerprint, err := fingerprint(r, t.fingerprintSize, false); err == nil {
+		t.fingerprint.Store(fingerprint)
+	}
+}
+
+// didFingerprintChange returns true if the first bytes of the file read from r aren't the ones
+// of the tailed file, i.e. if another file has replaced it.
+func (t *Tailer) didFingerprintChange(r io.ReaderAt) bool {
+	known := t.fingerprint.Load()
+	if known == 0 {
+		return false
+	}
+	current, err := fingerprint(r, t.fingerprintSize, false)
+	return err == nil && current != known
+}
+
 // getFormattedTime return readable timestamp
 func getFormattedTime() string {
 	now := time.Now()
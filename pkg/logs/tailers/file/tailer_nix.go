@@ -27,6 +27,10 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	// adds metadata to enable users to filter logs by filename
 	t.tags = t.buildTailerTags()
 
+	if t.compression != "" {
+		return t.setupCompressed(offset, whence)
+	}
+
 	log.Info("Opening", t.file.Path, "for tailer key", t.file.GetScanKey())
 	f, err := filesystem.OpenShared(fullpath)
 	if err != nil {
@@ -34,6 +38,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	}
 
 	t.osFile = f
+	t.updateFingerprint(f)
 	ret, _ := f.Seek(offset, whence)
 	t.lastReadOffset.Store(ret)
 	t.decodedOffset.Store(ret)
@@ -44,6 +49,9 @@ func (t *Tailer) setup(offset int64, whence int) error {
 // read lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) read() (int, error) {
+	if t.decompressor != nil {
+		return t.readCompressed()
+	}
 	// keep reading data from file
 	inBuf := make([]byte, 4096)
 	n, err := t.osFile.Read(inBuf)
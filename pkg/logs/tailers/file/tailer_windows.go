@@ -29,11 +29,18 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	// adds metadata to enable users to filter logs by filename
 	t.tags = t.buildTailerTags()
 
+	if t.compression != "" {
+		// the compressed file is kept open to be decompressed as a stream, it isn't written
+		// to anymore
+		return t.setupCompressed(offset, whence)
+	}
+
 	log.Info("Opening ", t.fullpath)
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return err
 	}
+	t.updateFingerprint(f)
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -123,6 +130,9 @@ func (t *Tailer) readAvailable() (int, error) {
 // windows version open and close the file between each call to 'read'. This is
 // needed in order not to block the file and prevent the user from renaming it.
 func (t *Tailer) read() (int, error) {
+	if t.decompressor != nil {
+		return t.readCompressed()
+	}
 	n, err := t.readAvailable()
 	if err == io.EOF || os.IsNotExist(err) {
 		return n, nil
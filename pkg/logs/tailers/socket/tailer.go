@@ -10,11 +10,15 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
@@ -29,19 +33,27 @@ type Tailer struct {
 	outputChan chan *message.Message
 	read       func(*Tailer) ([]byte, string, error)
 	decoder    *decoder.Decoder
-	stop       chan struct{}
-	done       chan struct{}
+	// syslog is true when the data holds syslog messages, whose headers are parsed.
+	syslog bool
+	stop   chan struct{}
+	done   chan struct{}
 }
 
 // NewTailer returns a new Tailer
 func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, string, error)) *Tailer {
+	isSyslog := source.Config.Type == config.SyslogType
+	framing := framer.UTF8Newline
+	if isSyslog {
+		framing = framer.Syslog
+	}
 	return &Tailer{
 		source:     source,
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
 		// tailer info is currently unused for this tailer type.
-		decoder: decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry()),
+		decoder: decoder.NewDecoderWithFraming(sources.NewReplaceableSource(source), noop.New(), framing, nil, status.NewInfoRegistry()),
+		syslog:  isSyslog,
 		stop:    make(chan struct{}, 1),
 		done:    make(chan struct{}, 1),
 	}
@@ -71,11 +83,32 @@ func (t *Tailer) forwardMessages() {
 		if len(output.GetContent()) > 0 {
 			origin := message.NewOrigin(t.source)
 			origin.SetTags(output.ParsingExtra.Tags)
-			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			if t.syslog {
+				parseSyslogHeader(msg, output.ParsingExtra.Tags)
+			}
+			t.outputChan <- msg
 		}
 	}
 }
 
+// parseSyslogHeader sets the status, hostname, service and tags of msg from its syslog header,
+// and strips the header from its content. The tags of the header are added to the given tags.
+// Messages which can't be parsed are left untouched.
+func parseSyslogHeader(msg *message.Message, tags []string) {
+	parsed, err := syslog.Parse(msg.GetContent())
+	if err != nil {
+		return
+	}
+	msg.SetContent(parsed.Msg)
+	msg.Status = parsed.Status()
+	if parsed.Hostname != "" {
+		msg.Hostname = parsed.Hostname
+	}
+	msg.Origin.SetService(parsed.AppName)
+	msg.Origin.SetTags(slices.Concat(tags, parsed.Tags()))
+}
+
 // readForever reads the data from conn.
 func (t *Tailer) readForever() {
 	defer func() {
@@ -103,6 +103,42 @@ func TestSourceHostTagFlagDisabled(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	logsConfig := &config.LogsConfig{
+		Type: config.SyslogType,
+		Tags: []string{"test:tag"},
+	}
+	tailer := NewTailer(sources.NewLogSource("test-source", logsConfig), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+	w.Write([]byte(`51 <165>1 - host app 42 - [meta env="prod"] multi` + "\nline" + "<11>Oct 11 22:14:15 other cron[7]: failed\nnot syslog\n"))
+
+	msg = <-msgChan
+	assert.Equal(t, "multi\nline", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.GetStatus())
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "app", msg.Origin.Service())
+	assert.Equal(t, []string{"syslog_facility:local4", "syslog_procid:42", "meta.env:prod", "test:tag"}, msg.Tags())
+
+	msg = <-msgChan
+	assert.Equal(t, "failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "other", msg.Hostname)
+	assert.Equal(t, "cron", msg.Origin.Service())
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_procid:7", "test:tag"}, msg.Tags())
+
+	msg = <-msgChan
+	assert.Equal(t, "not syslog", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, []string{"test:tag"}, msg.Tags())
+
+	tailer.Stop()
+}
+
 func read(tailer *Tailer) ([]byte, string, error) {
 	inBuf := make([]byte, 4096)
 	n, err := tailer.Conn.Read(inBuf)
@@ -58,7 +58,7 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval, config) // default histogram configuration (no call to `configure`) for now
+			m[contextKey] = newMetricHistogram(sample.Name, interval, config)
 		case HistorateType:
 			m[contextKey] = NewHistorate(interval, config) // internal histogram has the configuration for now
 		case SetType:
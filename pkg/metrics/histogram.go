@@ -95,6 +95,19 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 	}
 }
 
+// newMetricHistogram returns a newly initialized histogram of a metric, configured by the
+// histogram override matching its name if any. The overrides which only convert the histograms to
+// distributions keep the default configuration, for the histograms which aren't converted.
+func newMetricHistogram(name string, interval int64, config pkgconfigmodel.Config) *Histogram {
+	h := NewHistogram(interval, config)
+	if override := getHistogramOverrides(config).match(name); override != nil && !override.isDistributionOnly() {
+		// the percentiles of the overrides are already sorted
+		h.aggregates = override.aggregates
+		h.percentiles = override.percentiles
+	}
+	return h
+}
+
 func (h *Histogram) configure(aggregates []string, percentiles []int) {
 	h.aggregates = aggregates
 	sort.Ints(percentiles)
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// HistogramOverridesSetting is the setting of the per-metric histogram configurations
+	HistogramOverridesSetting = "histogram_overrides"
+
+	matchTypeWildcard = "wildcard"
+	matchTypeRegex    = "regex"
+
+	// maxDistributionCacheSize bounds the number of metric names whose conversion to
+	// distribution is cached.
+	maxDistributionCacheSize = 10000
+)
+
+// histogramOverrides holds the overrides used by the histograms, it's initialized on the first
+// histogram creation
+var histogramOverrides *HistogramOverrides
+
+// HistogramOverrideConfig replaces `histogram_aggregates` and `histogram_percentiles` for the
+// histograms whose name matches, or converts them to distributions.
+type HistogramOverrideConfig struct {
+	Match        string   `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType    string   `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	Aggregates   []string `mapstructure:"aggregates" json:"aggregates" yaml:"aggregates"`
+	Percentiles  []string `mapstructure:"percentiles" json:"percentiles" yaml:"percentiles"`
+	Distribution bool     `mapstructure:"distribution" json:"distribution" yaml:"distribution"`
+}
+
+type histogramOverride struct {
+	regex        *regexp.Regexp
+	aggregates   []string
+	percentiles  []int
+	distribution bool
+}
+
+// isDistributionOnly returns whether the override only converts the histograms to distributions,
+// without configuring their aggregates and percentiles.
+func (o *histogramOverride) isDistributionOnly() bool {
+	return o.distribution && len(o.aggregates) == 0 && len(o.percentiles) == 0
+}
+
+// HistogramOverrides matches the metric names with the configurations of their histograms, the
+// first override matching a name is used.
+type HistogramOverrides struct {
+	overrides        []histogramOverride
+	hasDistributions bool
+
+	distributionCache     map[string]bool
+	distributionCacheLock sync.RWMutex
+}
+
+// GetHistogramOverrideConfigs returns the histogram overrides of the configuration
+func GetHistogramOverrideConfigs(config pkgconfigmodel.Reader) ([]HistogramOverrideConfig, error) {
+	var configs []HistogramOverrideConfig
+	if config.IsSet(HistogramOverridesSetting) {
+		err := structure.UnmarshalKey(config, HistogramOverridesSetting, &configs)
+		if err != nil {
+			return []HistogramOverrideConfig{}, fmt.Errorf("Could not parse %s: %v", HistogramOverridesSetting, err)
+		}
+	}
+	return configs, nil
+}
+
+// NewHistogramOverrides creates and validates the histogram overrides of the configuration
+func NewHistogramOverrides(config pkgconfigmodel.Reader) (*HistogramOverrides, error) {
+	configs, err := GetHistogramOverrideConfigs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &HistogramOverrides{
+		overrides:         make([]histogramOverride, 0, len(configs)),
+		distributionCache: make(map[string]bool),
+	}
+	for i, c := range configs {
+		if c.Match == "" {
+			return nil, fmt.Errorf("histogram override num %d: match is required", i)
+		}
+
+		var pattern string
+		switch c.MatchType {
+		case "", matchTypeWildcard:
+			pattern = "^" + strings.ReplaceAll(regexp.QuoteMeta(c.Match), `\*`, ".*") + "$"
+		case matchTypeRegex:
+			pattern = "^" + c.Match + "$"
+		default:
+			return nil, fmt.Errorf("histogram override num %d: invalid match type `%s`, expected `%s` or `%s`", i, c.MatchType, matchTypeWildcard, matchTypeRegex)
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("histogram override num %d: invalid match `%s`: %v", i, c.Match, err)
+		}
+
+		for _, aggregate := range c.Aggregates {
+			switch aggregate {
+			case maxAgg, minAgg, medianAgg, avgAgg, sumAgg, countAgg:
+			default:
+				return nil, fmt.Errorf("histogram override num %d: invalid aggregate `%s`", i, aggregate)
+			}
+		}
+		percentiles := parsePercentiles(c.Percentiles)
+		if len(percentiles) != len(c.Percentiles) {
+			return nil, fmt.Errorf("histogram override num %d: invalid percentiles %v, they must be between 0 and 1", i, c.Percentiles)
+		}
+		sort.Ints(percentiles)
+
+		o.overrides = append(o.overrides, histogramOverride{
+			regex:        regex,
+			aggregates:   c.Aggregates,
+			percentiles:  percentiles,
+			distribution: c.Distribution,
+		})
+		o.hasDistributions = o.hasDistributions || c.Distribution
+	}
+	return o, nil
+}
+
+// match returns the first override matching the metric name, nil if there's none
+func (o *HistogramOverrides) match(name string) *histogramOverride {
+	if o == nil {
+		return nil
+	}
+	for i := range o.overrides {
+		if o.overrides[i].regex.MatchString(name) {
+			return &o.overrides[i]
+		}
+	}
+	return nil
+}
+
+// HasDistributions returns whether some histograms are converted to distributions
+func (o *HistogramOverrides) HasDistributions() bool {
+	return o != nil && o.hasDistributions
+}
+
+// IsDistribution returns whether the histograms of the metric are converted to distributions
+func (o *HistogramOverrides) IsDistribution(name string) bool {
+	if !o.HasDistributions() {
+		return false
+	}
+
+	o.distributionCacheLock.RLock()
+	distribution, found := o.distributionCache[name]
+	o.distributionCacheLock.RUnlock()
+	if found {
+		return distribution
+	}
+
+	override := o.match(name)
+	distribution = override != nil && override.distribution
+
+	o.distributionCacheLock.Lock()
+	if len(o.distributionCache) < maxDistributionCacheSize {
+		o.distributionCache[name] = distribution
+	}
+	o.distributionCacheLock.Unlock()
+	return distribution
+}
+
+func getHistogramOverrides(config pkgconfigmodel.Config) *HistogramOverrides {
+	// we initialize the overrides on the first histogram creation
+	if histogramOverrides == nil {
+		overrides, err := NewHistogramOverrides(config)
+		if err != nil {
+			log.Errorf("Invalid %s, the histograms use the default configuration: %s", HistogramOverridesSetting, err)
+			overrides = &HistogramOverrides{}
+		}
+		histogramOverrides = overrides
+	}
+	return histogramOverrides
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestHistogramOverrides(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "*.latency", "percentiles": []string{"0.99", "0.5"}},
+		{"match": "*.size", "aggregates": []string{"count"}},
+		{"match": `app\.request\..*`, "match_type": "regex", "distribution": true},
+		{"match": "*.latency", "aggregates": []string{"max"}},
+	})
+
+	overrides, err := NewHistogramOverrides(cfg)
+	require.NoError(t, err)
+	assert.True(t, overrides.HasDistributions())
+
+	override := overrides.match("db.query.latency")
+	require.NotNil(t, override)
+	assert.Empty(t, override.aggregates)
+	assert.Equal(t, []int{50, 99}, override.percentiles)
+
+	override = overrides.match("db.row.size")
+	require.NotNil(t, override)
+	assert.Equal(t, []string{"count"}, override.aggregates)
+	assert.Empty(t, override.percentiles)
+
+	assert.Nil(t, overrides.match("db.query.latency.p99"))
+	assert.Nil(t, overrides.match("app.request"))
+
+	assert.True(t, overrides.IsDistribution("app.request.duration"))
+	assert.True(t, overrides.IsDistribution("app.request.duration"))
+	assert.False(t, overrides.IsDistribution("db.row.size"))
+	assert.False(t, overrides.IsDistribution("other"))
+
+	var noOverrides *HistogramOverrides
+	assert.False(t, noOverrides.HasDistributions())
+	assert.False(t, noOverrides.IsDistribution("app.request.duration"))
+	assert.Nil(t, noOverrides.match("db.query.latency"))
+}
+
+func TestHistogramOverridesErrors(t *testing.T) {
+	for name, override := range map[string]map[string]interface{}{
+		"missing match":       {"percentiles": []string{"0.99"}},
+		"invalid match type":  {"match": "*.latency", "match_type": "glob"},
+		"invalid regex":       {"match": "(latency", "match_type": "regex"},
+		"invalid aggregate":   {"match": "*.latency", "aggregates": []string{"p99"}},
+		"invalid percentile":  {"match": "*.latency", "percentiles": []string{"99"}},
+		"unparsed percentile": {"match": "*.latency", "percentiles": []string{"p99"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := configmock.New(t)
+			cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{override})
+			_, err := NewHistogramOverrides(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHistogramOverridesFlush(t *testing.T) {
+	cfg := setupConfig(t)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "*.latency", "percentiles": []string{"0.99"}},
+		{"match": "*.size", "aggregates": []string{"count"}},
+	})
+	defaultAggregates = nil
+	defaultPercentiles = nil
+	histogramOverrides = nil
+	t.Cleanup(func() { histogramOverrides = nil })
+
+	metrics := MakeContextMetrics()
+	for i, name := range []string{"db.query.latency", "db.row.size", "db.rows"} {
+		for v := 1; v <= 100; v++ {
+			sample := &MetricSample{Name: name, Value: float64(v), Mtype: HistogramType}
+			require.NoError(t, metrics.AddSample(ckey.ContextKey(i), sample, 10, 10, nil, cfg))
+		}
+	}
+
+	series, errs := metrics.Flush(20)
+	require.Empty(t, errs)
+	suffixes := map[ckey.ContextKey][]string{}
+	for _, serie := range series {
+		suffixes[serie.ContextKey] = append(suffixes[serie.ContextKey], serie.NameSuffix)
+	}
+	assert.Equal(t, []string{".99percentile"}, suffixes[0])
+	assert.Equal(t, []string{".count"}, suffixes[1])
+	assert.Equal(t, []string{".max", ".median", ".avg", ".count", ".95percentile"}, suffixes[2])
+}
+
+func TestHistogramDistributionOverridesFlush(t *testing.T) {
+	cfg := setupConfig(t)
+	cfg.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"match": "app.request.*", "distribution": true},
+		{"match": "app.response.*", "distribution": true, "aggregates": []string{"max"}},
+	})
+	defaultAggregates = nil
+	defaultPercentiles = nil
+	histogramOverrides = nil
+	t.Cleanup(func() { histogramOverrides = nil })
+
+	// the histograms which aren't converted to distributions, e.g. the ones of the checks, keep the
+	// default configuration unless the override sets it
+	metrics := MakeContextMetrics()
+	for i, name := range []string{"app.request.duration", "app.response.size"} {
+		for v := 1; v <= 100; v++ {
+			sample := &MetricSample{Name: name, Value: float64(v), Mtype: HistogramType}
+			require.NoError(t, metrics.AddSample(ckey.ContextKey(i), sample, 10, 10, nil, cfg))
+		}
+	}
+
+	series, errs := metrics.Flush(20)
+	require.Empty(t, errs)
+	suffixes := map[ckey.ContextKey][]string{}
+	for _, serie := range series {
+		suffixes[serie.ContextKey] = append(suffixes[serie.ContextKey], serie.NameSuffix)
+	}
+	assert.Equal(t, []string{".max", ".median", ".avg", ".count", ".95percentile"}, suffixes[0])
+	assert.Equal(t, []string{".max"}, suffixes[1])
+}
@@ -0,0 +1,283 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"strings"
+)
+
+// ObfuscateCQLString obfuscates the Cassandra CQL query. Literal values, including UUIDs, blobs,
+// durations, booleans and collections of literals, are replaced with "?", comments are removed
+// and whitespace is normalized. Bind markers and identifiers are kept.
+func (o *Obfuscator) ObfuscateCQLString(query string) (string, error) {
+	return obfuscateQueryLiterals(query, queryDialectCQL, o.opts.CQL.ReplaceDigits)
+}
+
+// queryDialect specifies the query language of a query to obfuscate with obfuscateQueryLiterals.
+type queryDialect int
+
+const (
+	// queryDialectCQL is the Cassandra Query Language.
+	queryDialectCQL queryDialect = iota
+	// queryDialectPartiQL is PartiQL, as used by Amazon DynamoDB.
+	queryDialectPartiQL
+)
+
+// queryToken is a token of a query, as output by obfuscateQueryLiterals.
+type queryToken struct {
+	val string
+	// literal reports whether val is an obfuscated literal or a group of obfuscated literals.
+	literal bool
+}
+
+var (
+	errQueryUnterminatedString  = errors.New("unterminated string literal")
+	errQueryUnterminatedComment = errors.New("unterminated comment")
+	errQueryUnterminatedIdent   = errors.New("unterminated quoted identifier")
+)
+
+// queryGroupClosers maps the opening punctuators of groups to their closing punctuator.
+var queryGroupClosers = map[string]string{"(": ")", "[": "]", "{": "}", "<<": ">>"}
+
+// obfuscateQueryLiterals replaces the literal values of a CQL or PartiQL query with "?". Groups
+// (parenthesized lists, collections, tuples and bags) only holding literals are collapsed to a
+// single "?" so that their length doesn't increase cardinality. If obfuscateDigits is true, digits
+// found in identifiers are replaced as well.
+func obfuscateQueryLiterals(in string, dialect queryDialect, obfuscateDigits bool) (string, error) {
+	var (
+		out    []queryToken
+		groups []int // indexes in out of the currently open groups
+	)
+	emit := func(val string, literal bool) {
+		if n := len(groups); n > 0 && queryGroupClosers[out[groups[n-1]].val] == val {
+			start := groups[n-1]
+			groups = groups[:len(groups)-1]
+			if queryGroupIsLiteral(out[start+1:]) {
+				out = append(out[:start+1], queryToken{val: "?", literal: true})
+				out[start].literal = true
+				literal = true
+			}
+		}
+		out = append(out, queryToken{val: val, literal: literal})
+		if _, ok := queryGroupClosers[val]; ok {
+			groups = append(groups, len(out)-1)
+		}
+	}
+
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(in[i:], "--") || (dialect == queryDialectCQL && strings.HasPrefix(in[i:], "//")):
+			for i < len(in) && in[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(in[i:], "/*"):
+			end := strings.Index(in[i+2:], "*/")
+			if end < 0 {
+				return "", errQueryUnterminatedComment
+			}
+			i += end + 4
+		case c == '\'':
+			end, err := scanQuoted(in, i, '\'')
+			if err != nil {
+				return "", errQueryUnterminatedString
+			}
+			if dialect == queryDialectPartiQL && strings.HasPrefix(strings.TrimLeft(in[end:], " \t\r\n"), ":") {
+				// tuple attribute names are kept
+				emit(in[i:end], false)
+			} else {
+				emit("?", true)
+			}
+			i = end
+		case dialect == queryDialectCQL && strings.HasPrefix(in[i:], "$$"):
+			end := strings.Index(in[i+2:], "$$")
+			if end < 0 {
+				return "", errQueryUnterminatedString
+			}
+			emit("?", true)
+			i += end + 4
+		case dialect == queryDialectPartiQL && c == '`':
+			// Ion literal
+			end := strings.IndexByte(in[i+1:], '`')
+			if end < 0 {
+				return "", errQueryUnterminatedString
+			}
+			emit("?", true)
+			i += end + 2
+		case dialect == queryDialectCQL && isUUIDAt(in, i):
+			emit("?", true)
+			i += 36
+		case c == '"' || isQueryIdentStart(c):
+			end, err := scanQueryIdentifier(in, i)
+			if err != nil {
+				return "", err
+			}
+			ident := in[i:end]
+			i = end
+			if isQueryLiteralKeyword(ident) {
+				emit("?", true)
+				continue
+			}
+			if obfuscateDigits {
+				ident = string(replaceDigits([]byte(ident)))
+			}
+			emit(ident, false)
+		case isDigit(rune(c)) || (c == '-' && i+1 < len(in) && isDigit(rune(in[i+1])) && !queryValueEnds(out)):
+			i++
+			for i < len(in) && (isQueryIdentPart(in[i]) || in[i] == '.' || ((in[i] == '-' || in[i] == '+') && (in[i-1] == 'e' || in[i-1] == 'E'))) {
+				i++
+			}
+			emit("?", true)
+		case c == '?':
+			emit("?", true)
+			i++
+		case c == ':' && i+1 < len(in) && isQueryIdentStart(in[i+1]):
+			// named bind marker
+			end := i + 1
+			for end < len(in) && isQueryIdentPart(in[end]) {
+				end++
+			}
+			emit(in[i:end], false)
+			i = end
+		default:
+			n := 1
+			for _, op := range []string{"<<", ">>", "<=", ">=", "!=", "<>", "||"} {
+				if strings.HasPrefix(in[i:], op) {
+					n = len(op)
+					break
+				}
+			}
+			emit(in[i:i+n], false)
+			i += n
+		}
+	}
+	for len(out) > 0 && out[len(out)-1].val == ";" {
+		out = out[:len(out)-1]
+	}
+
+	var b strings.Builder
+	for i, t := range out {
+		if i > 0 && t.val != "," && t.val != ":" {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.val)
+	}
+	return b.String(), nil
+}
+
+// queryGroupIsLiteral reports whether the tokens of a group only hold literals.
+func queryGroupIsLiteral(tokens []queryToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, t := range tokens {
+		if !t.literal && t.val != "," && t.val != ":" {
+			return false
+		}
+	}
+	return true
+}
+
+// queryValueEnds reports whether the last token output ends a value, in which case a following
+// "-" is a subtraction rather than the sign of a number.
+func queryValueEnds(out []queryToken) bool {
+	if len(out) == 0 {
+		return false
+	}
+	last := out[len(out)-1]
+	if last.literal {
+		return true
+	}
+	switch last.val {
+	case ")", "]", "}", ">>":
+		return true
+	}
+	return isQueryIdentStart(last.val[0]) || last.val[0] == '"'
+}
+
+// scanQuoted returns the offset following the string quoted with q starting at i. Quotes are
+// escaped by doubling them.
+func scanQuoted(in string, i int, q byte) (int, error) {
+	for j := i + 1; j < len(in); j++ {
+		if in[j] != q {
+			continue
+		}
+		if j+1 < len(in) && in[j+1] == q {
+			j++
+			continue
+		}
+		return j + 1, nil
+	}
+	return 0, errQueryUnterminatedString
+}
+
+// scanQueryIdentifier returns the offset following the possibly quoted and qualified identifier
+// starting at i, e.g. keyspace."Table".
+func scanQueryIdentifier(in string, i int) (int, error) {
+	for {
+		if in[i] == '"' {
+			end, err := scanQuoted(in, i, '"')
+			if err != nil {
+				return 0, errQueryUnterminatedIdent
+			}
+			i = end
+		} else {
+			for i < len(in) && isQueryIdentPart(in[i]) {
+				i++
+			}
+		}
+		if i+1 < len(in) && in[i] == '.' && (in[i+1] == '"' || isQueryIdentStart(in[i+1])) {
+			i++
+			continue
+		}
+		return i, nil
+	}
+}
+
+// isQueryLiteralKeyword reports whether the identifier is a keyword denoting a literal value.
+func isQueryLiteralKeyword(ident string) bool {
+	switch strings.ToLower(ident) {
+	case "true", "false", "null", "nan", "infinity":
+		return true
+	}
+	return false
+}
+
+// isUUIDAt reports whether a UUID such as 123e4567-e89b-12d3-a456-426614174000 starts at offset i.
+func isUUIDAt(in string, i int) bool {
+	if len(in)-i < 36 || (i > 0 && isQueryIdentPart(in[i-1])) {
+		return false
+	}
+	for j := 0; j < 36; j++ {
+		c := in[i+j]
+		switch j {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(c) {
+				return false
+			}
+		}
+	}
+	return i+36 == len(in) || !isQueryIdentPart(in[i+36])
+}
+
+func isQueryIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isQueryIdentPart(c byte) bool {
+	return isQueryIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateCQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"SELECT * FROM ks.users WHERE id = 42",
+			"SELECT * FROM ks.users WHERE id = ?",
+		},
+		{
+			"SELECT name, email FROM users WHERE user_id = 123e4567-e89b-12d3-a456-426614174000 AND name = 'O''Brien';",
+			"SELECT name, email FROM users WHERE user_id = ? AND name = ?",
+		},
+		{
+			"INSERT INTO ks.\"Events\" (id, payload, tags, attrs, at, ttl_col)\n  VALUES (now(), 0xCAFEBABE, {'a', 'b'}, {'k': 1.5e-3}, '2024-01-01', true) USING TTL 86400",
+			"INSERT INTO ks.\"Events\" ( id, payload, tags, attrs, at, ttl_col ) VALUES ( now ( ), ?, { ? }, { ? }, ?, ? ) USING TTL ?",
+		},
+		{
+			"SELECT * FROM users WHERE id IN (1, 2, 3) AND tags CONTAINS 'x' AND score > -5 LIMIT 10",
+			"SELECT * FROM users WHERE id IN ( ? ) AND tags CONTAINS ? AND score > ? LIMIT ?",
+		},
+		{
+			"UPDATE users SET visits = visits - 1, history = history + [[1, 2], [3]] WHERE id = ?",
+			"UPDATE users SET visits = visits - ?, history = history + [ ? ] WHERE id = ?",
+		},
+		{
+			"UPDATE users /* inline */ SET d = 1h30m, f = NaN -- comment\n WHERE id = :id // trailing",
+			"UPDATE users SET d = ?, f = ? WHERE id = :id",
+		},
+		{
+			"CREATE FUNCTION f(x int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS $$ return x; $$",
+			"CREATE FUNCTION f ( x int ) RETURNS ? ON ? INPUT RETURNS int LANGUAGE java AS ?",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{CQL: CQLConfig{Enabled: true}})
+			out, err := o.ObfuscateCQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateCQLReplaceDigits(t *testing.T) {
+	o := NewObfuscator(Config{CQL: CQLConfig{Enabled: true, ReplaceDigits: true}})
+	out, err := o.ObfuscateCQLString("SELECT * FROM ks.events_2024 WHERE id = 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM ks.events_? WHERE id = ?", out)
+}
+
+func TestObfuscateCQLErrors(t *testing.T) {
+	o := NewObfuscator(Config{CQL: CQLConfig{Enabled: true}})
+	for _, in := range []string{
+		"SELECT * FROM users WHERE name = 'unterminated",
+		"SELECT * FROM \"users WHERE id = 1",
+		"SELECT * FROM users /* unterminated",
+		"SELECT $$ unterminated",
+	} {
+		_, err := o.ObfuscateCQLString(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestObfuscatePartiQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`SELECT * FROM "Music" WHERE Artist='Acme Band' AND SongTitle IN ['PartiQL Rocks', 'Happy Day']`,
+			`SELECT * FROM "Music" WHERE Artist = ? AND SongTitle IN [ ? ]`,
+		},
+		{
+			`INSERT INTO "Music" VALUE {'Artist': 'Acme Band', 'Awards': 10, 'Tags': <<'a', 'b'>>}`,
+			`INSERT INTO "Music" VALUE { 'Artist': ?, 'Awards': ?, 'Tags': << ? >> }`,
+		},
+		{
+			"UPDATE \"Music\" SET AwardsWon=1 SET Released=`2020-01-01T00:00:00Z` WHERE Artist=? AND SongTitle=?",
+			"UPDATE \"Music\" SET AwardsWon = ? SET Released = ? WHERE Artist = ? AND SongTitle = ?",
+		},
+		{
+			`SELECT "Album"."Name" FROM "Music"."ArtistIndex" WHERE Price <= 9.99 -- cheap`,
+			`SELECT "Album"."Name" FROM "Music"."ArtistIndex" WHERE Price <= ?`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{DynamoDB: DynamoDBConfig{Enabled: true}})
+			out, err := o.ObfuscatePartiQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateDynamoDBExpressionAttributeValues(t *testing.T) {
+	in := `{":id": {"S": "user-42"}, ":tags": {"SS": ["a", "b"]}, ":n": {"N": "7"}}`
+
+	o := NewObfuscator(Config{DynamoDB: DynamoDBConfig{Enabled: true}})
+	assert.Equal(t, `{":id":{"S":"?"},":tags":{"SS":["?","?"]},":n":{"N":"?"}}`, o.ObfuscateDynamoDBExpressionAttributeValues(in))
+
+	o = NewObfuscator(Config{DynamoDB: DynamoDBConfig{Enabled: true, RemoveExpressionAttributeValues: true}})
+	assert.Equal(t, "", o.ObfuscateDynamoDBExpressionAttributeValues(in))
+}
+
+func TestObfuscateKafka(t *testing.T) {
+	o := NewObfuscator(Config{Kafka: KafkaConfig{Enabled: true}})
+	assert.Equal(t, "?", o.ObfuscateKafkaMessageKey("user-42"))
+	assert.Equal(t, "", o.ObfuscateKafkaMessageKey(""))
+	assert.Equal(t, `{"user":{"id":"?","tags":["?"]}}`, o.ObfuscateKafkaMessagePayload(` {"user": {"id": 42, "tags": ["a"]}}`))
+	assert.Equal(t, "?", o.ObfuscateKafkaMessagePayload("plain text payload"))
+
+	o = NewObfuscator(Config{Kafka: KafkaConfig{Enabled: true, KeepMessageKey: true}})
+	assert.Equal(t, "user-42", o.ObfuscateKafkaMessageKey("user-42"))
+}
@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// ObfuscatePartiQLString obfuscates the DynamoDB PartiQL statement query. Literal values,
+// including Ion literals and collections of literals, are replaced with "?", comments are removed
+// and whitespace is normalized. Attribute names of tuples and parameter placeholders are kept.
+func (o *Obfuscator) ObfuscatePartiQLString(query string) (string, error) {
+	return obfuscateQueryLiterals(query, queryDialectPartiQL, false)
+}
+
+// ObfuscateDynamoDBExpressionAttributeValues obfuscates the JSON encoded expression attribute
+// values of a DynamoDB request, e.g. {":id": {"S": "abc"}}, keeping their placeholders and types.
+// An empty string is returned if the values should be removed.
+func (o *Obfuscator) ObfuscateDynamoDBExpressionAttributeValues(values string) string {
+	if o.opts.DynamoDB.RemoveExpressionAttributeValues {
+		return ""
+	}
+	return obfuscateJSONString(values, o.dynamoDBValues)
+}
@@ -0,0 +1,310 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ObfuscateGraphQLString obfuscates and normalizes the GraphQL document query: literal values
+// (strings, numbers, booleans, enums and null) are replaced with "?", lists of literals are
+// collapsed to a single "?", comments are removed, whitespace is normalized and, unless
+// configured otherwise, field aliases are removed. Variable references are kept, as they
+// carry no value. An error is returned if the document can't be tokenized.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) (string, error) {
+	g := &graphQLObfuscator{
+		tok:         newGraphQLTokenizer(query),
+		keepAliases: o.opts.GraphQL.KeepAliases,
+	}
+	if err := g.obfuscate(); err != nil {
+		return "", err
+	}
+	return g.out.String(), nil
+}
+
+// graphQLObfuscator obfuscates a GraphQL document, one token at a time.
+type graphQLObfuscator struct {
+	tok         *graphQLTokenizer
+	cur         graphQLToken
+	keepAliases bool
+
+	out  strings.Builder
+	prev string // last token written to out
+}
+
+// advance moves to the next token.
+func (g *graphQLObfuscator) advance() error {
+	tok, err := g.tok.next()
+	if err != nil {
+		return err
+	}
+	g.cur = tok
+	return nil
+}
+
+// expect checks that the current token is the punctuator p and moves past it.
+func (g *graphQLObfuscator) expect(p string) error {
+	if g.cur.typ == graphQLTokenEOF {
+		return errGraphQLEOF
+	}
+	if !g.cur.is(p) {
+		return fmt.Errorf("expected %q, got %s %q", p, g.cur.typ, g.cur.val)
+	}
+	return g.advance()
+}
+
+// write appends tok to the output, separated from the previous token by a single space
+// where needed for readability.
+func (g *graphQLObfuscator) write(tok string) {
+	if g.out.Len() > 0 && graphQLNeedsSpace(g.prev, tok) {
+		g.out.WriteByte(' ')
+	}
+	g.out.WriteString(tok)
+	g.prev = tok
+}
+
+func graphQLNeedsSpace(prev, tok string) bool {
+	switch prev {
+	case "(", "[", "$", "@":
+		return false
+	case "...":
+		return tok == "on"
+	}
+	switch tok {
+	case "(", ")", "]", ":", "!", ",":
+		return false
+	}
+	return true
+}
+
+// obfuscate walks through the document. Outside of argument and variable definition lists,
+// the document only holds names and punctuators, which are kept, except for aliases.
+func (g *graphQLObfuscator) obfuscate() error {
+	if err := g.advance(); err != nil {
+		return err
+	}
+	for g.cur.typ != graphQLTokenEOF {
+		switch {
+		case g.cur.typ == graphQLTokenName:
+			name := g.cur.val
+			if err := g.advance(); err != nil {
+				return err
+			}
+			if g.cur.is(":") {
+				// name is the alias of the field that follows
+				if err := g.advance(); err != nil {
+					return err
+				}
+				if g.keepAliases {
+					g.write(name)
+					g.write(":")
+				}
+				continue
+			}
+			g.write(name)
+		case g.cur.is("("):
+			if err := g.obfuscateList(); err != nil {
+				return err
+			}
+		case g.cur.isLiteral():
+			// literals are not expected outside of values, obfuscate them nonetheless
+			g.write("?")
+			if err := g.advance(); err != nil {
+				return err
+			}
+		default:
+			g.write(g.cur.val)
+			if err := g.advance(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// obfuscateList obfuscates a parenthesized list of arguments or variable definitions.
+func (g *graphQLObfuscator) obfuscateList() error {
+	if err := g.expect("("); err != nil {
+		return err
+	}
+	g.write("(")
+	for i := 0; !g.cur.is(")"); i++ {
+		if i > 0 {
+			g.write(",")
+		}
+		var err error
+		switch {
+		case g.cur.is("$"):
+			err = g.obfuscateVariableDefinition()
+		case g.cur.typ == graphQLTokenName:
+			err = g.obfuscateArgument()
+		case g.cur.typ == graphQLTokenEOF:
+			err = errGraphQLEOF
+		default:
+			err = fmt.Errorf("unexpected %s %q in list", g.cur.typ, g.cur.val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	g.write(")")
+	return g.advance()
+}
+
+// obfuscateArgument obfuscates an argument of the form "name: value".
+func (g *graphQLObfuscator) obfuscateArgument() error {
+	g.write(g.cur.val)
+	if err := g.advance(); err != nil {
+		return err
+	}
+	if err := g.expect(":"); err != nil {
+		return err
+	}
+	g.write(":")
+	v, err := g.value()
+	if err != nil {
+		return err
+	}
+	g.write(v)
+	return nil
+}
+
+// obfuscateVariableDefinition obfuscates a variable definition of the form
+// "$name: Type = defaultValue @directives", keeping its type.
+func (g *graphQLObfuscator) obfuscateVariableDefinition() error {
+	v, err := g.value()
+	if err != nil {
+		return err
+	}
+	g.write("$")
+	g.write(strings.TrimPrefix(v, "$"))
+	if err := g.expect(":"); err != nil {
+		return err
+	}
+	g.write(":")
+	for g.cur.typ == graphQLTokenName || g.cur.is("[") || g.cur.is("]") || g.cur.is("!") {
+		g.write(g.cur.val)
+		if err := g.advance(); err != nil {
+			return err
+		}
+	}
+	if g.cur.is("=") {
+		g.write("=")
+		if err := g.advance(); err != nil {
+			return err
+		}
+		v, err := g.value()
+		if err != nil {
+			return err
+		}
+		g.write(v)
+	}
+	for g.cur.is("@") {
+		g.write("@")
+		if err := g.advance(); err != nil {
+			return err
+		}
+		if g.cur.typ != graphQLTokenName {
+			return fmt.Errorf("expected directive name, got %s %q", g.cur.typ, g.cur.val)
+		}
+		g.write(g.cur.val)
+		if err := g.advance(); err != nil {
+			return err
+		}
+		if g.cur.is("(") {
+			if err := g.obfuscateList(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value returns the obfuscated form of the value starting at the current token.
+func (g *graphQLObfuscator) value() (string, error) {
+	switch {
+	case g.cur.is("$"):
+		if err := g.advance(); err != nil {
+			return "", err
+		}
+		if g.cur.typ != graphQLTokenName {
+			return "", fmt.Errorf("expected variable name, got %s %q", g.cur.typ, g.cur.val)
+		}
+		name := g.cur.val
+		return "$" + name, g.advance()
+	case g.cur.isLiteral(), g.cur.typ == graphQLTokenName:
+		// names in value position are booleans, enum values or null
+		return "?", g.advance()
+	case g.cur.is("["):
+		return g.listValue()
+	case g.cur.is("{"):
+		return g.objectValue()
+	case g.cur.typ == graphQLTokenEOF:
+		return "", errGraphQLEOF
+	}
+	return "", fmt.Errorf("unexpected %s %q in value", g.cur.typ, g.cur.val)
+}
+
+// listValue returns the obfuscated form of a list value. Lists only holding literals are
+// collapsed to "?" so that their length doesn't increase cardinality.
+func (g *graphQLObfuscator) listValue() (string, error) {
+	if err := g.advance(); err != nil {
+		return "", err
+	}
+	var values []string
+	literals := true
+	for !g.cur.is("]") {
+		v, err := g.value()
+		if err != nil {
+			return "", err
+		}
+		literals = literals && v == "?"
+		values = append(values, v)
+	}
+	if err := g.advance(); err != nil {
+		return "", err
+	}
+	if literals {
+		return "?", nil
+	}
+	return "[" + strings.Join(values, ", ") + "]", nil
+}
+
+// objectValue returns the obfuscated form of an input object value, keeping its field names.
+func (g *graphQLObfuscator) objectValue() (string, error) {
+	if err := g.advance(); err != nil {
+		return "", err
+	}
+	var fields []string
+	for !g.cur.is("}") {
+		if g.cur.typ == graphQLTokenEOF {
+			return "", errGraphQLEOF
+		}
+		if g.cur.typ != graphQLTokenName {
+			return "", fmt.Errorf("expected object field name, got %s %q", g.cur.typ, g.cur.val)
+		}
+		name := g.cur.val
+		if err := g.advance(); err != nil {
+			return "", err
+		}
+		if err := g.expect(":"); err != nil {
+			return "", err
+		}
+		v, err := g.value()
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, name+": "+v)
+	}
+	if err := g.advance(); err != nil {
+		return "", err
+	}
+	if len(fields) == 0 {
+		return "{}", nil
+	}
+	return "{ " + strings.Join(fields, ", ") + " }", nil
+}
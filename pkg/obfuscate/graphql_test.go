@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 42) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			"query GetUser($id: ID!, $first: Int = 10) {\n  user(id: $id) {\n    friends(first: $first, orderBy: {field: NAME, direction: DESC}) { edges { node { name } } }\n  }\n}",
+			`query GetUser($id: ID!, $first: Int = ?) { user(id: $id) { friends(first: $first, orderBy: { field: ?, direction: ? }) { edges { node { name } } } } }`,
+		},
+		{
+			`{ search(text: "Bob \"the\" builder", ids: [1, 2, 3], tags: ["a" "b"], active: true, score: -1.5e3, missing: null) { id } }`,
+			`{ search(text: ?, ids: ?, tags: ?, active: ?, score: ?, missing: ?) { id } }`,
+		},
+		{
+			`{ me: user(id: 1) { first: name, id } }`,
+			`{ user(id: ?) { name id } }`,
+		},
+		{
+			`mutation { createUser(input: {name: "Alice", emails: [{address: "a@b.c"}], roles: [$role, ADMIN]}) { id } }`,
+			`mutation { createUser(input: { name: ?, emails: [{ address: ? }], roles: [$role, ?] }) { id } }`,
+		},
+		{
+			"query Q($ids: [ID!]! = [\"1\"] @deprecated(reason: \"\"\"block \\\"\"\" string\"\"\")) {\n  # a comment with secrets: 1234\n  nodes(ids: $ids) @include(if: $flag) { ...NodeFields ... on User { email } }\n}\nfragment NodeFields on Node { id }",
+			`query Q($ids: [ID!]! = ? @deprecated(reason: ?)) { nodes(ids: $ids) @include(if: $flag) { ...NodeFields ... on User { email } } } fragment NodeFields on Node { id }`,
+		},
+		{
+			`subscription OnEvent { event(filter: {}, limit: []) { id } }`,
+			`subscription OnEvent { event(filter: {}, limit: ?) { id } }`,
+		},
+		{
+			"GetUser",
+			"GetUser",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+			out, err := o.ObfuscateGraphQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateGraphQLKeepAliases(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true, KeepAliases: true}})
+	out, err := o.ObfuscateGraphQLString(`{ me: user(id: 1) { first: name } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{ me: user(id: ?) { first: name } }`, out)
+}
+
+func TestObfuscateGraphQLErrors(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+	for _, in := range []string{
+		`{ user(id: "unterminated) { name } }`,
+		`{ user(id: """unterminated) { name } }`,
+		`{ user(id: 1`,
+		`{ user(id 1) { name } }`,
+		`{ user(input: {name "x"}) { name } }`,
+		`{ user(ids: [1, 2) { name } }`,
+		`{ user(id: 12abc) { name } }`,
+		`{ user(id: 1.) { name } }`,
+		`{ user(id: %) { name } }`,
+		`{ user.name }`,
+		"{ user(id: \"a\nb\") { name } }",
+	} {
+		_, err := o.ObfuscateGraphQLString(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func BenchmarkObfuscateGraphQL(b *testing.B) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+	query := `query GetUser($id: ID!) { user(id: $id) { name friends(first: 10, after: "abc") { edges { node { name } } } } }`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = o.ObfuscateGraphQLString(query)
+	}
+}
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// graphQLTokenType specifies the token type returned by the tokenizer.
+type graphQLTokenType int
+
+const (
+	// graphQLTokenEOF marks the end of the document.
+	graphQLTokenEOF graphQLTokenType = iota
+
+	// graphQLTokenPunctuator is one of ! $ & ( ) ... : = @ [ ] { | }.
+	graphQLTokenPunctuator
+
+	// graphQLTokenName is a name, e.g. a field, argument, type or enum value.
+	graphQLTokenName
+
+	// graphQLTokenInt is an integer literal.
+	graphQLTokenInt
+
+	// graphQLTokenFloat is a float literal.
+	graphQLTokenFloat
+
+	// graphQLTokenString is a string or block string literal.
+	graphQLTokenString
+)
+
+// String implements fmt.Stringer.
+func (t graphQLTokenType) String() string {
+	return map[graphQLTokenType]string{
+		graphQLTokenEOF:        "EOF",
+		graphQLTokenPunctuator: "punctuator",
+		graphQLTokenName:       "name",
+		graphQLTokenInt:        "int",
+		graphQLTokenFloat:      "float",
+		graphQLTokenString:     "string",
+	}[t]
+}
+
+// graphQLToken is a lexical token of a GraphQL document.
+type graphQLToken struct {
+	typ graphQLTokenType
+	val string
+}
+
+// isLiteral reports whether the token holds a scalar literal value.
+func (t graphQLToken) isLiteral() bool {
+	return t.typ == graphQLTokenInt || t.typ == graphQLTokenFloat || t.typ == graphQLTokenString
+}
+
+// is reports whether the token is the punctuator p.
+func (t graphQLToken) is(p string) bool {
+	return t.typ == graphQLTokenPunctuator && t.val == p
+}
+
+// errGraphQLEOF is returned when the document ends in the middle of a token or construct.
+var errGraphQLEOF = errors.New("unexpected end of GraphQL document")
+
+// graphQLTokenizer tokenizes a GraphQL document as specified in
+// https://spec.graphql.org/October2021/#sec-Language.Source-Text. Ignored tokens (whitespace,
+// commas and comments) are skipped.
+type graphQLTokenizer struct {
+	data string
+	off  int
+}
+
+// newGraphQLTokenizer returns a new tokenizer for the given document.
+func newGraphQLTokenizer(data string) *graphQLTokenizer {
+	return &graphQLTokenizer{data: data}
+}
+
+// next returns the next token of the document.
+func (t *graphQLTokenizer) next() (graphQLToken, error) {
+	t.skipIgnored()
+	if t.off >= len(t.data) {
+		return graphQLToken{typ: graphQLTokenEOF}, nil
+	}
+	start := t.off
+	switch ch := t.data[t.off]; {
+	case strings.IndexByte("!$&():=@[]{|}", ch) >= 0:
+		t.off++
+		return graphQLToken{typ: graphQLTokenPunctuator, val: t.data[start:t.off]}, nil
+	case ch == '.':
+		if !strings.HasPrefix(t.data[t.off:], "...") {
+			return graphQLToken{}, fmt.Errorf("unexpected character %q at position %d", ch, t.off)
+		}
+		t.off += 3
+		return graphQLToken{typ: graphQLTokenPunctuator, val: "..."}, nil
+	case ch == '"':
+		return t.scanString()
+	case ch == '-' || isDigit(rune(ch)):
+		return t.scanNumber()
+	case isGraphQLNameStart(ch):
+		for t.off < len(t.data) && isGraphQLNameContinue(t.data[t.off]) {
+			t.off++
+		}
+		return graphQLToken{typ: graphQLTokenName, val: t.data[start:t.off]}, nil
+	default:
+		return graphQLToken{}, fmt.Errorf("unexpected character %q at position %d", ch, t.off)
+	}
+}
+
+// skipIgnored advances past whitespace, line terminators, commas, comments and byte order marks.
+func (t *graphQLTokenizer) skipIgnored() {
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case ' ', '\t', '\n', '\r', ',':
+			t.off++
+		case '#':
+			for t.off < len(t.data) && t.data[t.off] != '\n' && t.data[t.off] != '\r' {
+				t.off++
+			}
+		default:
+			if strings.HasPrefix(t.data[t.off:], "\ufeff") {
+				t.off += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+// scanString scans a string or a block string literal.
+func (t *graphQLTokenizer) scanString() (graphQLToken, error) {
+	start := t.off
+	if strings.HasPrefix(t.data[t.off:], `"""`) {
+		t.off += 3
+		for {
+			i := strings.Index(t.data[t.off:], `"""`)
+			if i < 0 {
+				return graphQLToken{}, errGraphQLEOF
+			}
+			t.off += i + 3
+			if t.data[t.off-4] != '\\' {
+				return graphQLToken{typ: graphQLTokenString, val: t.data[start:t.off]}, nil
+			}
+		}
+	}
+	t.off++
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case '\\':
+			t.off += 2
+		case '"':
+			t.off++
+			return graphQLToken{typ: graphQLTokenString, val: t.data[start:t.off]}, nil
+		case '\n', '\r':
+			return graphQLToken{}, fmt.Errorf("unterminated string at position %d", start)
+		default:
+			t.off++
+		}
+	}
+	return graphQLToken{}, errGraphQLEOF
+}
+
+// scanNumber scans an integer or a float literal.
+func (t *graphQLTokenizer) scanNumber() (graphQLToken, error) {
+	start := t.off
+	typ := graphQLTokenInt
+	if t.data[t.off] == '-' {
+		t.off++
+	}
+	if !t.scanDigits() {
+		return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+	}
+	if t.off < len(t.data) && t.data[t.off] == '.' {
+		typ = graphQLTokenFloat
+		t.off++
+		if !t.scanDigits() {
+			return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+		}
+	}
+	if t.off < len(t.data) && (t.data[t.off] == 'e' || t.data[t.off] == 'E') {
+		typ = graphQLTokenFloat
+		t.off++
+		if t.off < len(t.data) && (t.data[t.off] == '+' || t.data[t.off] == '-') {
+			t.off++
+		}
+		if !t.scanDigits() {
+			return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+		}
+	}
+	if t.off < len(t.data) && (t.data[t.off] == '.' || isGraphQLNameStart(t.data[t.off])) {
+		return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+	}
+	return graphQLToken{typ: typ, val: t.data[start:t.off]}, nil
+}
+
+// scanDigits advances past a sequence of digits and reports whether there was at least one.
+func (t *graphQLTokenizer) scanDigits() bool {
+	start := t.off
+	for t.off < len(t.data) && isDigit(rune(t.data[t.off])) {
+		t.off++
+	}
+	return t.off > start
+}
+
+func isGraphQLNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isGraphQLNameContinue(ch byte) bool {
+	return isGraphQLNameStart(ch) || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLTokenizer(t *testing.T) {
+	tok := newGraphQLTokenizer("\ufeffquery{a(b:-12,c:3.5e-2 d:\"\"\"x\"\"\")...F}# comment")
+	var types []graphQLTokenType
+	var values []string
+	for {
+		tk, err := tok.next()
+		assert.NoError(t, err)
+		if tk.typ == graphQLTokenEOF {
+			break
+		}
+		types = append(types, tk.typ)
+		values = append(values, tk.val)
+	}
+	assert.Equal(t, []string{"query", "{", "a", "(", "b", ":", "-12", "c", ":", "3.5e-2", "d", ":", `"""x"""`, ")", "...", "F", "}"}, values)
+	assert.Equal(t, []graphQLTokenType{
+		graphQLTokenName, graphQLTokenPunctuator, graphQLTokenName, graphQLTokenPunctuator,
+		graphQLTokenName, graphQLTokenPunctuator, graphQLTokenInt,
+		graphQLTokenName, graphQLTokenPunctuator, graphQLTokenFloat,
+		graphQLTokenName, graphQLTokenPunctuator, graphQLTokenString,
+		graphQLTokenPunctuator, graphQLTokenPunctuator, graphQLTokenName, graphQLTokenPunctuator,
+	}, types)
+}
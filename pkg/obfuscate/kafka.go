@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import "strings"
+
+// ObfuscateKafkaMessageKey obfuscates the key of a Kafka message, unless configured otherwise.
+func (o *Obfuscator) ObfuscateKafkaMessageKey(key string) string {
+	if o.opts.Kafka.KeepMessageKey || key == "" {
+		return key
+	}
+	return "?"
+}
+
+// ObfuscateKafkaMessagePayload obfuscates the payload of a Kafka message. JSON payloads keep
+// their structure and have their values replaced with "?", other payloads are replaced entirely.
+func (o *Obfuscator) ObfuscateKafkaMessagePayload(payload string) string {
+	switch trimmed := strings.TrimSpace(payload); {
+	case trimmed == "":
+		return payload
+	case o.kafkaPayload != nil && (trimmed[0] == '{' || trimmed[0] == '['):
+		return obfuscateJSONString(trimmed, o.kafkaPayload)
+	default:
+		return "?"
+	}
+}
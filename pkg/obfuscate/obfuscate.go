@@ -33,6 +33,8 @@ type Obfuscator struct {
 	mongo                *jsonObfuscator // nil if disabled
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
+	dynamoDBValues       *jsonObfuscator // nil if disabled
+	kafkaPayload         *jsonObfuscator // nil if disabled
 	ccObfuscator         *creditCard     // nil if disabled
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// Different SQL engines behave in different ways and the tokenizer needs to be generic.
@@ -101,6 +103,19 @@ type Config struct {
 	// Memcached holds the obfuscation settings for Memcached commands.
 	Memcached MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the obfuscation settings for GraphQL queries.
+	GraphQL GraphQLConfig `mapstructure:"graphql"`
+
+	// CQL holds the obfuscation settings for Cassandra CQL queries.
+	CQL CQLConfig `mapstructure:"cql"`
+
+	// DynamoDB holds the obfuscation settings for DynamoDB PartiQL statements and expression
+	// attribute values.
+	DynamoDB DynamoDBConfig `mapstructure:"dynamodb"`
+
+	// Kafka holds the obfuscation settings for Kafka message keys and payloads.
+	Kafka KafkaConfig `mapstructure:"kafka"`
+
 	// Memcached holds the obfuscation settings for obfuscation of CC numbers in meta.
 	CreditCard CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -259,6 +274,45 @@ type MemcachedConfig struct {
 	KeepCommand bool `mapstructure:"keep_command"`
 }
 
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation
+type GraphQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepAliases specifies whether field aliases should be kept. By
+	// default, they are removed to reduce the cardinality of resources.
+	KeepAliases bool `mapstructure:"keep_aliases"`
+}
+
+// CQLConfig holds the configuration settings for Cassandra CQL obfuscation
+type CQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// ReplaceDigits specifies whether digits in table names and identifiers should be obfuscated.
+	ReplaceDigits bool `mapstructure:"replace_digits"`
+}
+
+// DynamoDBConfig holds the configuration settings for DynamoDB obfuscation
+type DynamoDBConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// RemoveExpressionAttributeValues specifies whether expression attribute
+	// values should be removed entirely instead of having their values obfuscated.
+	RemoveExpressionAttributeValues bool `mapstructure:"remove_expression_attribute_values"`
+}
+
+// KafkaConfig holds the configuration settings for Kafka obfuscation
+type KafkaConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepMessageKey specifies whether message keys should be kept. By
+	// default, they are obfuscated as they often hold identifiers.
+	KeepMessageKey bool `mapstructure:"keep_message_key"`
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -325,6 +379,12 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	if cfg.SQLExecPlanNormalize.Enabled {
 		o.sqlExecPlanNormalize = newJSONObfuscator(&cfg.SQLExecPlanNormalize, &o)
 	}
+	if cfg.DynamoDB.Enabled && !cfg.DynamoDB.RemoveExpressionAttributeValues {
+		o.dynamoDBValues = newJSONObfuscator(&JSONConfig{Enabled: true}, &o)
+	}
+	if cfg.Kafka.Enabled {
+		o.kafkaPayload = newJSONObfuscator(&JSONConfig{Enabled: true}, &o)
+	}
 	if cfg.CreditCard.Enabled {
 		o.ccObfuscator = newCCObfuscator(&cfg.CreditCard)
 	}
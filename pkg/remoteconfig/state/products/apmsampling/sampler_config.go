@@ -17,6 +17,21 @@ type SamplerEnvConfig struct {
 	PrioritySamplerTargetTPS *float64 `json:"priority_sampler_target_TPS"`
 	ErrorsSamplerTargetTPS   *float64 `json:"errors_sampler_target_TPS"`
 	RareSamplerEnabled       *bool    `json:"rare_sampler_enabled"`
+	// TraceSamplingRules is nil when unset. An empty list removes all rules.
+	TraceSamplingRules []TraceSamplingRule `json:"trace_sampling_rules"`
+}
+
+// TraceSamplingRule is a rule used by the trace-agent to keep or drop traces based on the
+// attributes of their spans
+type TraceSamplingRule struct {
+	Name          string            `json:"name"`
+	Service       string            `json:"service"`
+	OperationName string            `json:"operation_name"`
+	Resource      string            `json:"resource"`
+	Tags          map[string]string `json:"tags"`
+	Conditions    []string          `json:"conditions"`
+	AnySpan       bool              `json:"any_span"`
+	SampleRate    *float64          `json:"sample_rate"`
 }
 
 // EnvAndConfig breaks down configuration by environment
@@ -192,6 +192,51 @@ func (series *IterableSeries) MarshalSplitCompressMultiple(config config.Compone
 	return pbs[0].payloads, pbs[1].payloads, pbs[2].payloads, nil
 }
 
+// MarshalSplitCompressByRoute uses the stream compressor to marshal and compress series into one set of
+// payloads per routing rule, in a single pass over the input data. The route function returns the name of the
+// routing rule of a serie, stored in the RoutingRule field of its payloads, or an empty string.
+func (series *IterableSeries) MarshalSplitCompressByRoute(config config.Component, strategy compression.Component, route func(s *metrics.Serie) string) (transaction.BytesPayloads, error) {
+	var routes []string
+	pbs := map[string]*PayloadsBuilder{}
+
+	// Use series.source.MoveNext() instead of series.MoveNext() because this function supports
+	// the serie.NoIndex field.
+	for series.source.MoveNext() {
+		serie := series.source.Current()
+		name := route(serie)
+		pb, found := pbs[name]
+		if !found {
+			builder, err := series.NewPayloadsBuilder(marshaler.NewBufferContext(), config, strategy)
+			if err != nil {
+				return nil, err
+			}
+			pb = &builder
+			if err = pb.startPayload(); err != nil {
+				return nil, err
+			}
+			pbs[name] = pb
+			routes = append(routes, name)
+		}
+		if err := pb.writeSerie(serie); err != nil {
+			return nil, err
+		}
+	}
+
+	payloads := transaction.BytesPayloads{}
+	for _, name := range routes {
+		pb := pbs[name]
+		// if the last payload has any data, flush it
+		if err := pb.finishPayload(); err != nil {
+			return nil, err
+		}
+		for _, payload := range pb.payloads {
+			payload.RoutingRule = name
+		}
+		payloads = append(payloads, pb.payloads...)
+	}
+	return payloads, nil
+}
+
 // NewPayloadsBuilder initializes a new PayloadsBuilder to be used for serializing series into a set of output payloads.
 func (series *IterableSeries) NewPayloadsBuilder(bufferContext *marshaler.BufferContext, config config.Component, strategy compression.Component) (PayloadsBuilder, error) {
 	buf := bufferContext.PrecompressionBuf
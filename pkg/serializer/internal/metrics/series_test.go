@@ -483,6 +483,58 @@ func TestMarshalSplitCompressMultiplePointsLimit(t *testing.T) {
 	}
 }
 
+func TestMarshalSplitCompressByRoute(t *testing.T) {
+	tests := map[string]struct {
+		kind string
+	}{
+		"zlib": {kind: compression.ZlibKind},
+		"zstd": {kind: compression.ZstdKind},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockConfig := mock.New(t)
+			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
+			mockConfig.SetWithoutSource("serializer_max_series_points_per_payload", 100)
+
+			// 100 series, each with 5 points, 10 of them routed to a sandbox
+			rawSeries := metrics.Series{}
+			for i := 0; i < 100; i++ {
+				rawSeries = append(rawSeries, &metrics.Serie{
+					Points: []metrics.Point{
+						{Ts: 12345.0, Value: float64(21.21)},
+						{Ts: 67890.0, Value: float64(12.12)},
+						{Ts: 2222.0, Value: float64(22.12)},
+						{Ts: 333.0, Value: float64(32.12)},
+						{Ts: 444444.0, Value: float64(42.12)},
+					},
+					MType:    metrics.APIGaugeType,
+					Name:     fmt.Sprintf("test.metrics%d", i),
+					Interval: 1,
+					Host:     "localhost",
+				})
+			}
+			series := CreateIterableSeries(CreateSerieSource(rawSeries))
+
+			compressor := metricscompression.NewCompressorReq(metricscompression.Requires{Cfg: mockConfig}).Comp
+			payloads, err := series.MarshalSplitCompressByRoute(mockConfig, compressor, func(s *metrics.Serie) string {
+				if strings.HasSuffix(s.Name, "0") {
+					return "sandbox"
+				}
+				return ""
+			})
+			require.NoError(t, err)
+
+			points := map[string]int{}
+			for _, payload := range payloads {
+				points[payload.RoutingRule] += payload.GetPointCount()
+			}
+			// 90 series in 5 payloads of at most 20 series, and 10 series in one payload
+			require.Len(t, payloads, 6)
+			assert.Equal(t, map[string]int{"": 450, "sandbox": 50}, points)
+		})
+	}
+}
+
 func TestMarshalSplitCompressPointsLimitTooBig(t *testing.T) {
 	tests := map[string]struct {
 		kind string
@@ -133,6 +133,46 @@ func (sl SketchSeriesList) MarshalSplitCompressMultiple(config config.Component,
 	return pb.payloads, pb2.payloads, nil
 }
 
+// MarshalSplitCompressByRoute uses the stream compressor to marshal and compress one sketch list into one set
+// of payloads per routing rule, in a single pass over the input data. The route function returns the name of
+// the routing rule of a sketch series, stored in the RoutingRule field of its payloads, or an empty string.
+func (sl SketchSeriesList) MarshalSplitCompressByRoute(config config.Component, strategy compression.Component, route func(ss *metrics.SketchSeries) string, logger log.Component) (transaction.BytesPayloads, error) {
+	var routes []string
+	pbs := map[string]*payloadsBuilder{}
+
+	for sl.MoveNext() {
+		ss := sl.Current()
+		name := route(ss)
+		pb, found := pbs[name]
+		if !found {
+			builder := newPayloadsBuilder(marshaler.NewBufferContext(), config, strategy, logger)
+			pb = &builder
+			if err := pb.startPayload(); err != nil {
+				return nil, err
+			}
+			pbs[name] = pb
+			routes = append(routes, name)
+		}
+		if err := pb.marshal(ss); err != nil {
+			return nil, err
+		}
+	}
+
+	payloads := transaction.BytesPayloads{}
+	for _, name := range routes {
+		pb := pbs[name]
+		if err := pb.finishPayload(); err != nil {
+			logger.Debugf("Failed to finish payload with err %v", err)
+			return nil, err
+		}
+		for _, payload := range pb.payloads {
+			payload.RoutingRule = name
+		}
+		payloads = append(payloads, pb.payloads...)
+	}
+	return payloads, nil
+}
+
 func newPayloadsBuilder(bufferContext *marshaler.BufferContext, config config.Component, strategy compression.Component, logger log.Component) payloadsBuilder {
 	buf := bufferContext.PrecompressionBuf
 	pb := payloadsBuilder{
@@ -336,6 +336,31 @@ func TestSketchSeriesMarshalSplitCompressMultiple(t *testing.T) {
 
 }
 
+func TestSketchSeriesMarshalSplitCompressByRoute(t *testing.T) {
+	mockConfig := mock.New(t)
+	sl := metrics.NewSketchesSourceTest()
+	for i := 0; i < 3; i++ {
+		sl.Append(Makeseries(i))
+	}
+	sl.Reset()
+
+	serializer := SketchSeriesList{SketchesSource: sl}
+	compressor := metricscompression.NewCompressorReq(metricscompression.Requires{Cfg: mockConfig}).Comp
+	payloads, err := serializer.MarshalSplitCompressByRoute(mockConfig, compressor, func(ss *metrics.SketchSeries) string {
+		if ss.Name == "name.1" {
+			return "sandbox"
+		}
+		return ""
+	}, logmock.New(t))
+	require.NoError(t, err)
+
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "", payloads[0].RoutingRule)
+	assert.Equal(t, 12, payloads[0].GetPointCount())
+	assert.Equal(t, "sandbox", payloads[1].RoutingRule)
+	assert.Equal(t, 6, payloads[1].GetPointCount())
+}
+
 func TestSketchSeriesListMarshalWithOriginMapping(t *testing.T) {
 	sl := metrics.NewSketchesSourceTest()
 
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package otlp exports the series and sketches flushed by the serializer as OTLP metrics to an OTLP/HTTP endpoint,
+// in addition to the Datadog intake.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	// queueSize is the number of requests waiting to be sent, the requests are dropped when the queue is full
+	queueSize = 16
+	// maxRequestSize is the size of the uncompressed requests above which a new request is started
+	maxRequestSize = 4 * 1024 * 1024
+)
+
+var (
+	tlmRequests = telemetry.NewCounter("serializer_otlp_exporter", "requests",
+		[]string{"state"}, "Number of OTLP requests by state: sent, dropped because the queue was full, or error")
+	tlmDataPoints = telemetry.NewCounter("serializer_otlp_exporter", "data_points",
+		nil, "Number of OTLP data points sent")
+)
+
+// Exporter sends OTLP requests to an OTLP/HTTP endpoint. The requests are sent in the background, on a best effort
+// basis: they are dropped when the endpoint is too slow or unavailable, without retries.
+type Exporter struct {
+	log      log.Component
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	requests chan request
+}
+
+type request struct {
+	payload    []byte
+	dataPoints int
+}
+
+// NewExporter returns an exporter configured with the `serializer_otlp_exporter` settings, or nil if the export is
+// disabled.
+func NewExporter(config config.Component, log log.Component) *Exporter {
+	if !config.GetBool("serializer_otlp_exporter.enabled") {
+		return nil
+	}
+
+	e := &Exporter{
+		log:      log,
+		endpoint: config.GetString("serializer_otlp_exporter.endpoint"),
+		headers:  config.GetStringMapString("serializer_otlp_exporter.headers"),
+		client: &http.Client{
+			Timeout:   time.Duration(config.GetInt("serializer_otlp_exporter.timeout")) * time.Second,
+			Transport: httputils.CreateHTTPTransport(config),
+		},
+		requests: make(chan request, queueSize),
+	}
+	log.Infof("Exporting the series and sketches as OTLP metrics to %s", e.endpoint)
+
+	go e.run()
+	return e
+}
+
+// NewRequestBuilder returns a builder whose requests are sent by the exporter.
+func (e *Exporter) NewRequestBuilder() *RequestBuilder {
+	return newRequestBuilder(maxRequestSize, e.submit)
+}
+
+func (e *Exporter) submit(payload []byte, dataPoints int) {
+	select {
+	case e.requests <- request{payload: payload, dataPoints: dataPoints}:
+	default:
+		tlmRequests.Inc("dropped")
+		e.log.Warnf("Dropping an OTLP request of %d data points, the queue of %s is full", dataPoints, e.endpoint)
+	}
+}
+
+func (e *Exporter) run() {
+	for r := range e.requests {
+		if err := e.send(context.Background(), r.payload); err != nil {
+			tlmRequests.Inc("error")
+			e.log.Warnf("Could not send an OTLP request of %d data points: %v", r.dataPoints, err)
+			continue
+		}
+		tlmRequests.Inc("sent")
+		tlmDataPoints.Add(float64(r.dataPoints))
+	}
+}
+
+func (e *Exporter) send(ctx context.Context, payload []byte) error {
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	if _, err := writer.Write(payload); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response from %s: %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package otlp
+
+import (
+	"compress/gzip"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/richardartoul/molecule"
+	"github.com/richardartoul/molecule/src/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// message is a decoded protobuf message, with the values of each field
+type message map[int32][]molecule.Value
+
+func decode(t *testing.T, b []byte) message {
+	m := message{}
+	err := molecule.MessageEach(codec.NewBuffer(b), func(field int32, value molecule.Value) (bool, error) {
+		m[field] = append(m[field], value)
+		return true, nil
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func (m message) messages(t *testing.T, field int32) []message {
+	var messages []message
+	for _, value := range m[field] {
+		messages = append(messages, decode(t, value.Bytes))
+	}
+	return messages
+}
+
+func (m message) message(t *testing.T, field int32) message {
+	messages := m.messages(t, field)
+	require.Len(t, messages, 1, "field %d", field)
+	return messages[0]
+}
+
+// string returns the value of a string field, the empty strings are not written
+func (m message) string(t *testing.T, field int32) string {
+	require.LessOrEqual(t, len(m[field]), 1, "field %d", field)
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].Bytes)
+}
+
+func (m message) number(t *testing.T, field int32) uint64 {
+	require.Len(t, m[field], 1, "field %d", field)
+	return m[field][0].Number
+}
+
+func (m message) double(t *testing.T, field int32) float64 {
+	return math.Float64frombits(m.number(t, field))
+}
+
+// attributes returns the string values of the attributes, the values of the arrays are joined with a comma
+func (m message) attributes(t *testing.T, field int32) map[string]string {
+	attributes := map[string]string{}
+	for _, kv := range m.messages(t, field) {
+		value := kv.message(t, keyValueValue)
+		if _, found := value[anyValueArray]; found {
+			for i, v := range value.message(t, anyValueArray).messages(t, arrayValueValues) {
+				if i > 0 {
+					attributes[kv.string(t, keyValueKey)] += ","
+				}
+				attributes[kv.string(t, keyValueKey)] += v.string(t, anyValueString)
+			}
+		} else {
+			attributes[kv.string(t, keyValueKey)] = value.string(t, anyValueString)
+		}
+	}
+	return attributes
+}
+
+// testCollector is a stand-in for an OTLP collector, which stores the requests it receives
+type testCollector struct {
+	sync.Mutex
+	requests []message
+}
+
+func newTestCollector(t *testing.T) (*testCollector, *httptest.Server) {
+	collector := &testCollector{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		collector.Lock()
+		collector.requests = append(collector.requests, decode(t, body))
+		collector.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+	return collector, ts
+}
+
+func (c *testCollector) get() []message {
+	c.Lock()
+	defer c.Unlock()
+	return append([]message{}, c.requests...)
+}
+
+func newTestExporter(t *testing.T, url string) *Exporter {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("serializer_otlp_exporter.enabled", true)
+	mockConfig.SetWithoutSource("serializer_otlp_exporter.endpoint", url+"/v1/metrics")
+	mockConfig.SetWithoutSource("serializer_otlp_exporter.headers", map[string]string{"Authorization": "secret"})
+	e := NewExporter(mockConfig, logmock.New(t))
+	require.NotNil(t, e)
+	return e
+}
+
+func TestExporterDisabled(t *testing.T) {
+	assert.Nil(t, NewExporter(configmock.New(t), logmock.New(t)))
+}
+
+func TestExportSeries(t *testing.T) {
+	collector, ts := newTestCollector(t)
+	e := newTestExporter(t, ts.URL)
+
+	builder := e.NewRequestBuilder()
+	source := NewSerieSource(metricsserializer.CreateSerieSource(metrics.Series{
+		{
+			Name:   "test.gauge",
+			Host:   "host1",
+			MType:  metrics.APIGaugeType,
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "team:a", "team:b", "solo", "url:http://a"}),
+			Points: []metrics.Point{{Ts: 1700000000, Value: 1.5}},
+		},
+		{
+			Name:     "test.count",
+			Host:     "host2",
+			MType:    metrics.APICountType,
+			Interval: 10,
+			Points:   []metrics.Point{{Ts: 1700000010, Value: -3}},
+		},
+		{
+			Name:   "test.rate",
+			Host:   "host1",
+			MType:  metrics.APIRateType,
+			Points: []metrics.Point{{Ts: 1700000000, Value: 0.5}, {Ts: 1700000015, Value: 0.25}},
+		},
+	}), builder)
+	count := 0
+	for source.MoveNext() {
+		count++
+	}
+	assert.Equal(t, 3, count)
+	require.NoError(t, builder.Flush())
+
+	require.Eventually(t, func() bool { return len(collector.get()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	resourceMetrics := collector.get()[0].messages(t, requestResourceMetrics)
+	require.Len(t, resourceMetrics, 2)
+
+	// host1
+	assert.Equal(t, map[string]string{"host.name": "host1"}, resourceMetrics[0].message(t, resourceMetricsResource).attributes(t, resourceAttributes))
+	scopeMetrics := resourceMetrics[0].message(t, resourceMetricsScopeMetrics)
+	assert.Equal(t, "datadog-agent", scopeMetrics.message(t, scopeMetricsScope).string(t, scopeName))
+	metrics := scopeMetrics.messages(t, scopeMetricsMetrics)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "test.gauge", metrics[0].string(t, metricName))
+	point := metrics[0].message(t, metricGauge).message(t, gaugeDataPoints)
+	assert.Equal(t, uint64(1700000000*time.Second), point.number(t, numberDataPointTimeUnixNano))
+	assert.Equal(t, 1.5, point.double(t, numberDataPointAsDouble))
+	assert.Equal(t, map[string]string{"env": "prod", "team": "a,b", "solo": "", "url": "http://a"}, point.attributes(t, numberDataPointAttributes))
+
+	assert.Equal(t, "test.rate", metrics[1].string(t, metricName))
+	points := metrics[1].message(t, metricGauge).messages(t, gaugeDataPoints)
+	require.Len(t, points, 2)
+	assert.Equal(t, 0.25, points[1].double(t, numberDataPointAsDouble))
+
+	// host2
+	assert.Equal(t, map[string]string{"host.name": "host2"}, resourceMetrics[1].message(t, resourceMetricsResource).attributes(t, resourceAttributes))
+	metric := resourceMetrics[1].message(t, resourceMetricsScopeMetrics).message(t, scopeMetricsMetrics)
+	assert.Equal(t, "test.count", metric.string(t, metricName))
+	sum := metric.message(t, metricSum)
+	assert.Equal(t, uint64(aggregationTemporalityDelta), sum.number(t, sumAggregationTemporality))
+	// is_monotonic is false, the default value, which is not written
+	assert.Empty(t, sum[sumIsMonotonic])
+	point = sum.message(t, sumDataPoints)
+	assert.Equal(t, uint64(1700000000*time.Second), point.number(t, numberDataPointStartTimeUnixNano))
+	assert.Equal(t, uint64(1700000010*time.Second), point.number(t, numberDataPointTimeUnixNano))
+	assert.Equal(t, -3.0, point.double(t, numberDataPointAsDouble))
+}
+
+func TestExportSketches(t *testing.T) {
+	collector, ts := newTestCollector(t)
+	e := newTestExporter(t, ts.URL)
+
+	agent := &quantile.Agent{}
+	for _, v := range []float64{0, 1, 1, 2, 10, -4} {
+		agent.Insert(v, 1)
+	}
+	sketches := metrics.NewSketchesSourceTest()
+	sketches.Append(&metrics.SketchSeries{
+		Name:     "test.distribution",
+		Host:     "host1",
+		Interval: 10,
+		Tags:     tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Points:   []metrics.SketchPoint{{Sketch: agent.Finish(), Ts: 1700000010}},
+	})
+	sketches.Reset()
+
+	builder := e.NewRequestBuilder()
+	source := NewSketchesSource(sketches, builder)
+	for source.MoveNext() {
+	}
+	require.NoError(t, builder.Flush())
+
+	require.Eventually(t, func() bool { return len(collector.get()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	metric := collector.get()[0].message(t, requestResourceMetrics).message(t, resourceMetricsScopeMetrics).message(t, scopeMetricsMetrics)
+	assert.Equal(t, "test.distribution", metric.string(t, metricName))
+	histogram := metric.message(t, metricExponentialHistogram)
+	assert.Equal(t, uint64(aggregationTemporalityDelta), histogram.number(t, exponentialHistogramAggregationTemporality))
+
+	point := histogram.message(t, exponentialHistogramDataPoints)
+	assert.Equal(t, map[string]string{"env": "prod"}, point.attributes(t, exponentialHistogramDataPointAttributes))
+	assert.Equal(t, uint64(1700000000*time.Second), point.number(t, exponentialHistogramDataPointStartTimeUnixNano))
+	assert.Equal(t, uint64(6), point.number(t, exponentialHistogramDataPointCount))
+	assert.Equal(t, 10.0, point.double(t, exponentialHistogramDataPointSum))
+	assert.Equal(t, -4.0, point.double(t, exponentialHistogramDataPointMin))
+	assert.Equal(t, 10.0, point.double(t, exponentialHistogramDataPointMax))
+	assert.Equal(t, uint64(1), point.number(t, exponentialHistogramDataPointZeroCount))
+	// log2(10) * 2^6 is above maxBuckets
+	assert.Equal(t, int64(maxScale-1), codec.DecodeZigZag64(point.number(t, exponentialHistogramDataPointScale)))
+}
+
+func TestToExponentialHistogram(t *testing.T) {
+	config := quantile.Default()
+	sketch := &quantile.Sketch{}
+	sketch.Insert(config, 0, 1, 1, 2, 10, -4)
+	k, n := sketch.Cols()
+
+	h := toExponentialHistogram(k, n)
+	assert.Equal(t, int32(maxScale-1), h.scale)
+	assert.Equal(t, uint64(1), h.zeroCount)
+
+	// the value of each bucket is within the relative accuracy of the sketches of its value
+	valueOf := func(index int32, scale int32) float64 {
+		// the middle of the bucket (base^index, base^(index+1)]
+		return math.Exp2((float64(index) + 0.5) / math.Ldexp(1, int(scale)))
+	}
+	var positive []float64
+	for i, count := range h.positive.counts {
+		for j := uint64(0); j < count; j++ {
+			positive = append(positive, valueOf(h.positive.offset+int32(i), h.scale))
+		}
+	}
+	require.Len(t, positive, 4)
+	for i, expected := range []float64{1, 1, 2, 10} {
+		assert.InEpsilon(t, expected, positive[i], 0.02)
+	}
+	require.Equal(t, []uint64{1}, h.negative.counts)
+	assert.InEpsilon(t, 4, valueOf(h.negative.offset, h.scale), 0.02)
+
+	// the scale decreases when the values are too spread
+	sketch = &quantile.Sketch{}
+	sketch.Insert(config, 1e-6, 1, 1e6)
+	k, n = sketch.Cols()
+	h = toExponentialHistogram(k, n)
+	assert.Less(t, h.scale, int32(maxScale))
+	assert.LessOrEqual(t, len(h.positive.counts), maxBuckets)
+	var nonEmpty []uint64
+	for _, count := range h.positive.counts {
+		if count > 0 {
+			nonEmpty = append(nonEmpty, count)
+		}
+	}
+	assert.Equal(t, []uint64{1, 1, 1}, nonEmpty)
+}
@@ -0,0 +1,509 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"math"
+	"strings"
+
+	"github.com/richardartoul/molecule"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// constants for the protobuf data we will be writing, taken from ExportMetricsServiceRequest in
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/collector/metrics/v1/metrics_service.proto
+// and https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/metrics/v1/metrics.proto
+const (
+	requestResourceMetrics = 1
+
+	resourceMetricsResource     = 1
+	resourceMetricsScopeMetrics = 2
+	resourceAttributes          = 1
+
+	scopeMetricsScope   = 1
+	scopeMetricsMetrics = 2
+	scopeName           = 1
+	scopeVersion        = 2
+
+	metricName                 = 1
+	metricGauge                = 5
+	metricSum                  = 7
+	metricExponentialHistogram = 10
+
+	gaugeDataPoints                            = 1
+	sumDataPoints                              = 1
+	sumAggregationTemporality                  = 2
+	sumIsMonotonic                             = 3
+	exponentialHistogramDataPoints             = 1
+	exponentialHistogramAggregationTemporality = 2
+
+	aggregationTemporalityDelta = 1
+
+	numberDataPointStartTimeUnixNano = 2
+	numberDataPointTimeUnixNano      = 3
+	numberDataPointAsDouble          = 4
+	numberDataPointAttributes        = 7
+
+	exponentialHistogramDataPointAttributes        = 1
+	exponentialHistogramDataPointStartTimeUnixNano = 2
+	exponentialHistogramDataPointTimeUnixNano      = 3
+	exponentialHistogramDataPointCount             = 4
+	exponentialHistogramDataPointSum               = 5
+	exponentialHistogramDataPointScale             = 6
+	exponentialHistogramDataPointZeroCount         = 7
+	exponentialHistogramDataPointPositive          = 8
+	exponentialHistogramDataPointNegative          = 9
+	exponentialHistogramDataPointMin               = 12
+	exponentialHistogramDataPointMax               = 13
+
+	bucketsOffset       = 1
+	bucketsBucketCounts = 2
+
+	keyValueKey      = 1
+	keyValueValue    = 2
+	anyValueString   = 1
+	anyValueArray    = 5
+	arrayValueValues = 1
+)
+
+const (
+	// agentScopeName is the name of the instrumentation scope of the metrics
+	agentScopeName    = "datadog-agent"
+	hostNameAttribute = "host.name"
+	deviceAttribute   = "device"
+)
+
+// RequestBuilder encodes series and sketches into OTLP ExportMetricsServiceRequest payloads, grouped by host. A new
+// request is started when a request reaches its maximum size.
+type RequestBuilder struct {
+	maxRequestSize int
+	submit         func(payload []byte, dataPoints int)
+
+	buf *bytes.Buffer
+	ps  *molecule.ProtoStream
+
+	// hosts are the hosts of metricsByHost in the order they were added
+	hosts []string
+	// metricsByHost are the encoded `metrics` fields of the ScopeMetrics of each host
+	metricsByHost map[string]*bytes.Buffer
+	size          int
+	dataPoints    int
+	// err is the first error of the series and sketches added by the sources
+	err error
+}
+
+func newRequestBuilder(maxRequestSize int, submit func(payload []byte, dataPoints int)) *RequestBuilder {
+	buf := &bytes.Buffer{}
+	return &RequestBuilder{
+		maxRequestSize: maxRequestSize,
+		submit:         submit,
+		buf:            buf,
+		ps:             molecule.NewProtoStream(buf),
+		metricsByHost:  map[string]*bytes.Buffer{},
+	}
+}
+
+// AddSerie adds a serie to the request. Gauges and rates are converted to OTLP gauges, counts to delta sums.
+func (rb *RequestBuilder) AddSerie(serie *metrics.Serie) error {
+	if len(serie.Points) == 0 {
+		return nil
+	}
+
+	rb.buf.Reset()
+	err := rb.ps.Embedded(scopeMetricsMetrics, func(ps *molecule.ProtoStream) error {
+		if err := ps.String(metricName, serie.Name); err != nil {
+			return err
+		}
+
+		writePoints := func(ps *molecule.ProtoStream, dataPoints int) error {
+			for _, p := range serie.Points {
+				err := ps.Embedded(dataPoints, func(ps *molecule.ProtoStream) error {
+					if serie.MType == metrics.APICountType && serie.Interval > 0 {
+						if err := ps.Fixed64(numberDataPointStartTimeUnixNano, unixNano(p.Ts-float64(serie.Interval))); err != nil {
+							return err
+						}
+					}
+					if err := ps.Fixed64(numberDataPointTimeUnixNano, unixNano(p.Ts)); err != nil {
+						return err
+					}
+					if err := ps.Double(numberDataPointAsDouble, p.Value); err != nil {
+						return err
+					}
+					return writeAttributes(ps, numberDataPointAttributes, serie.Tags, serie.Device)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if serie.MType == metrics.APICountType {
+			return ps.Embedded(metricSum, func(ps *molecule.ProtoStream) error {
+				if err := writePoints(ps, sumDataPoints); err != nil {
+					return err
+				}
+				if err := ps.Int32(sumAggregationTemporality, aggregationTemporalityDelta); err != nil {
+					return err
+				}
+				// the counts of the agent can be negative
+				return ps.Bool(sumIsMonotonic, false)
+			})
+		}
+		// the rates are values per second, like gauges
+		return ps.Embedded(metricGauge, func(ps *molecule.ProtoStream) error {
+			return writePoints(ps, gaugeDataPoints)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return rb.addMetric(serie.Host, len(serie.Points))
+}
+
+// AddSketchSeries adds sketches to the request, as OTLP exponential histograms.
+func (rb *RequestBuilder) AddSketchSeries(ss *metrics.SketchSeries) error {
+	if len(ss.Points) == 0 {
+		return nil
+	}
+
+	rb.buf.Reset()
+	err := rb.ps.Embedded(scopeMetricsMetrics, func(ps *molecule.ProtoStream) error {
+		if err := ps.String(metricName, ss.Name); err != nil {
+			return err
+		}
+
+		return ps.Embedded(metricExponentialHistogram, func(ps *molecule.ProtoStream) error {
+			for _, p := range ss.Points {
+				if p.Sketch == nil {
+					continue
+				}
+				err := ps.Embedded(exponentialHistogramDataPoints, func(ps *molecule.ProtoStream) error {
+					return writeSketch(ps, ss, p)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return ps.Int32(exponentialHistogramAggregationTemporality, aggregationTemporalityDelta)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return rb.addMetric(ss.Host, len(ss.Points))
+}
+
+func writeSketch(ps *molecule.ProtoStream, ss *metrics.SketchSeries, p metrics.SketchPoint) error {
+	if err := writeAttributes(ps, exponentialHistogramDataPointAttributes, ss.Tags, ""); err != nil {
+		return err
+	}
+	if ss.Interval > 0 {
+		if err := ps.Fixed64(exponentialHistogramDataPointStartTimeUnixNano, unixNano(float64(p.Ts-ss.Interval))); err != nil {
+			return err
+		}
+	}
+	if err := ps.Fixed64(exponentialHistogramDataPointTimeUnixNano, unixNano(float64(p.Ts))); err != nil {
+		return err
+	}
+
+	b := p.Sketch.Basic
+	if err := ps.Fixed64(exponentialHistogramDataPointCount, uint64(b.Cnt)); err != nil {
+		return err
+	}
+	if err := ps.Double(exponentialHistogramDataPointSum, b.Sum); err != nil {
+		return err
+	}
+
+	k, n := p.Sketch.Cols()
+	h := toExponentialHistogram(k, n)
+	if err := ps.Sint32(exponentialHistogramDataPointScale, h.scale); err != nil {
+		return err
+	}
+	if err := ps.Fixed64(exponentialHistogramDataPointZeroCount, h.zeroCount); err != nil {
+		return err
+	}
+	if err := writeBuckets(ps, exponentialHistogramDataPointPositive, h.positive); err != nil {
+		return err
+	}
+	if err := writeBuckets(ps, exponentialHistogramDataPointNegative, h.negative); err != nil {
+		return err
+	}
+
+	if b.Cnt > 0 {
+		if err := ps.Double(exponentialHistogramDataPointMin, b.Min); err != nil {
+			return err
+		}
+		if err := ps.Double(exponentialHistogramDataPointMax, b.Max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBuckets(ps *molecule.ProtoStream, field int, buckets exponentialBuckets) error {
+	if len(buckets.counts) == 0 {
+		return nil
+	}
+	return ps.Embedded(field, func(ps *molecule.ProtoStream) error {
+		if err := ps.Sint32(bucketsOffset, buckets.offset); err != nil {
+			return err
+		}
+		return ps.Uint64Packed(bucketsBucketCounts, buckets.counts)
+	})
+}
+
+// writeAttributes writes the tags as attributes. The value of a tag is after its first colon, the tags without
+// colon have an empty value. The values of a tag used several times are stored in an array.
+func writeAttributes(ps *molecule.ProtoStream, field int, tags tagset.CompositeTags, device string) error {
+	var keys []string
+	values := map[string][]string{}
+	add := func(key, value string) {
+		if _, found := values[key]; !found {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], value)
+	}
+	tags.ForEach(func(tag string) {
+		key, value, _ := strings.Cut(tag, ":")
+		add(key, value)
+	})
+	if _, found := values[deviceAttribute]; device != "" && !found {
+		add(deviceAttribute, device)
+	}
+
+	for _, key := range keys {
+		if err := writeAttribute(ps, field, key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAttribute(ps *molecule.ProtoStream, field int, key string, values []string) error {
+	return ps.Embedded(field, func(ps *molecule.ProtoStream) error {
+		if err := ps.String(keyValueKey, key); err != nil {
+			return err
+		}
+		return ps.Embedded(keyValueValue, func(ps *molecule.ProtoStream) error {
+			if len(values) == 1 {
+				return ps.String(anyValueString, values[0])
+			}
+			return ps.Embedded(anyValueArray, func(ps *molecule.ProtoStream) error {
+				for _, value := range values {
+					err := ps.Embedded(arrayValueValues, func(ps *molecule.ProtoStream) error {
+						return ps.String(anyValueString, value)
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func (rb *RequestBuilder) addMetric(host string, dataPoints int) error {
+	metrics, found := rb.metricsByHost[host]
+	if !found {
+		metrics = &bytes.Buffer{}
+		rb.metricsByHost[host] = metrics
+		rb.hosts = append(rb.hosts, host)
+	}
+	metrics.Write(rb.buf.Bytes())
+	rb.size += rb.buf.Len()
+	rb.dataPoints += dataPoints
+
+	if rb.size >= rb.maxRequestSize {
+		return rb.Flush()
+	}
+	return nil
+}
+
+func (rb *RequestBuilder) addErr(err error) {
+	if err != nil && rb.err == nil {
+		rb.err = err
+	}
+}
+
+// Flush submits the request being built, if it has any data point, and starts a new one. It also returns the first
+// error of the series and sketches added by the sources.
+func (rb *RequestBuilder) Flush() error {
+	addErr := rb.err
+	rb.err = nil
+	if rb.dataPoints == 0 {
+		return addErr
+	}
+
+	var payload bytes.Buffer
+	ps := molecule.NewProtoStream(&payload)
+	for _, host := range rb.hosts {
+		err := ps.Embedded(requestResourceMetrics, func(ps *molecule.ProtoStream) error {
+			err := ps.Embedded(resourceMetricsResource, func(ps *molecule.ProtoStream) error {
+				if host == "" {
+					return nil
+				}
+				return writeAttribute(ps, resourceAttributes, hostNameAttribute, []string{host})
+			})
+			if err != nil {
+				return err
+			}
+			return ps.Embedded(resourceMetricsScopeMetrics, func(ps *molecule.ProtoStream) error {
+				err := ps.Embedded(scopeMetricsScope, func(ps *molecule.ProtoStream) error {
+					if err := ps.String(scopeName, agentScopeName); err != nil {
+						return err
+					}
+					return ps.String(scopeVersion, version.AgentVersion)
+				})
+				if err != nil {
+					return err
+				}
+				_, err = ps.Write(rb.metricsByHost[host].Bytes())
+				return err
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	rb.submit(payload.Bytes(), rb.dataPoints)
+
+	rb.hosts = nil
+	rb.metricsByHost = map[string]*bytes.Buffer{}
+	rb.size = 0
+	rb.dataPoints = 0
+	return addErr
+}
+
+func unixNano(ts float64) uint64 {
+	if ts <= 0 {
+		return 0
+	}
+	return uint64(ts * 1e9)
+}
+
+// The sketches of the agent use the default configuration of the quantile package: a relative accuracy of 1/128,
+// and a minimum value of 1e-9 which has the key 1. The key k represents the values around gamma^(k-bias).
+var (
+	sketchLog2Gamma = math.Log2(1 + 2.0/128)
+	sketchBias      = -int(math.Floor(math.Log(1e-9)/math.Log1p(2.0/128))) + 1
+)
+
+const (
+	// maxSketchKey is the largest key of the finite values of a sketch
+	maxSketchKey = math.MaxInt16 - 1
+	// maxScale is the initial scale of the exponential histograms, its buckets are narrower than the bins of the
+	// sketches
+	maxScale = 6
+	// minScale is the smallest scale of the exponential histograms
+	minScale = -10
+	// maxBuckets is the number of positive or negative buckets above which the scale is decreased, like the default
+	// of the OpenTelemetry SDKs
+	maxBuckets = 160
+)
+
+type exponentialBuckets struct {
+	offset int32
+	counts []uint64
+}
+
+type exponentialHistogram struct {
+	scale     int32
+	zeroCount uint64
+	positive  exponentialBuckets
+	negative  exponentialBuckets
+}
+
+// toExponentialHistogram converts the bins of a sketch to the buckets of an exponential histogram, with the largest
+// scale at which there are at most maxBuckets positive and negative buckets.
+func toExponentialHistogram(keys []int32, counts []uint32) exponentialHistogram {
+	var h exponentialHistogram
+	// log2 of the absolute value of the bins which are not zero
+	var log2Values []float64
+	var binCounts []uint64
+	var negative []bool
+	for i, k := range keys {
+		if k == 0 {
+			h.zeroCount += uint64(counts[i])
+			continue
+		}
+		abs := k
+		if abs < 0 {
+			abs = -abs
+		}
+		if abs > maxSketchKey {
+			abs = maxSketchKey
+		}
+		log2Values = append(log2Values, float64(int(abs)-sketchBias)*sketchLog2Gamma)
+		binCounts = append(binCounts, uint64(counts[i]))
+		negative = append(negative, k < 0)
+	}
+
+	indexes := make([]int32, len(log2Values))
+	for h.scale = maxScale; ; h.scale-- {
+		// the bucket of index i of an exponential histogram contains the values in (base^i, base^(i+1)],
+		// with base = 2^(2^-scale)
+		scaleFactor := math.Ldexp(1, int(h.scale))
+		for i, l := range log2Values {
+			indexes[i] = int32(math.Ceil(l*scaleFactor)) - 1
+		}
+		if h.scale == minScale || (span(indexes, negative, false) <= maxBuckets && span(indexes, negative, true) <= maxBuckets) {
+			break
+		}
+	}
+
+	h.positive = fillBuckets(indexes, binCounts, negative, false)
+	h.negative = fillBuckets(indexes, binCounts, negative, true)
+	return h
+}
+
+func indexRange(indexes []int32, negative []bool, sign bool) (int32, int32, bool) {
+	var lowest, highest int32
+	found := false
+	for i, index := range indexes {
+		if negative[i] != sign {
+			continue
+		}
+		if !found || index < lowest {
+			lowest = index
+		}
+		if !found || index > highest {
+			highest = index
+		}
+		found = true
+	}
+	return lowest, highest, found
+}
+
+func span(indexes []int32, negative []bool, sign bool) int {
+	lowest, highest, found := indexRange(indexes, negative, sign)
+	if !found {
+		return 0
+	}
+	return int(highest-lowest) + 1
+}
+
+func fillBuckets(indexes []int32, counts []uint64, negative []bool, sign bool) exponentialBuckets {
+	lowest, highest, found := indexRange(indexes, negative, sign)
+	if !found {
+		return exponentialBuckets{}
+	}
+	buckets := exponentialBuckets{offset: lowest, counts: make([]uint64, highest-lowest+1)}
+	for i, index := range indexes {
+		if negative[i] == sign {
+			buckets.counts[index-lowest] += counts[i]
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// serieSource adds the series of a source to a request builder while they are read, the sources can only be iterated
+// over once.
+type serieSource struct {
+	metrics.SerieSource
+	builder *RequestBuilder
+}
+
+// NewSerieSource returns a source of the same series, which are also added to the request builder.
+func NewSerieSource(source metrics.SerieSource, builder *RequestBuilder) metrics.SerieSource {
+	return &serieSource{SerieSource: source, builder: builder}
+}
+
+// MoveNext moves to the next serie and adds it to the request builder.
+func (s *serieSource) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		return false
+	}
+	s.builder.addErr(s.builder.AddSerie(s.Current()))
+	return true
+}
+
+// sketchesSource adds the sketches of a source to a request builder while they are read.
+type sketchesSource struct {
+	metrics.SketchesSource
+	builder *RequestBuilder
+}
+
+// NewSketchesSource returns a source of the same sketches, which are also added to the request builder.
+func NewSketchesSource(source metrics.SketchesSource, builder *RequestBuilder) metrics.SketchesSource {
+	return &sketchesSource{SketchesSource: source, builder: builder}
+}
+
+// MoveNext moves to the next sketch series and adds it to the request builder.
+func (s *sketchesSource) MoveNext() bool {
+	if !s.SketchesSource.MoveNext() {
+		return false
+	}
+	s.builder.addErr(s.builder.AddSketchSeries(s.Current()))
+	return true
+}
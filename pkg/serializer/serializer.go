@@ -17,6 +17,8 @@ import (
 	"time"
 
 	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	orchestratorForwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator/orchestratorinterface"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/process/util/api/headers"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/otlp"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
@@ -127,6 +130,13 @@ type Serializer struct {
 	enableSketchProtobufStream    bool
 	hostname                      string
 	logger                        log.Component
+
+	// routingRules split the series and sketches by metric name, the forwarder sends each payload to the
+	// destinations of its routing rule.
+	routingRules *resolver.RoutingRules
+
+	// otlpExporter also exports the series and sketches as OTLP metrics, it is nil when disabled.
+	otlpExporter *otlp.Exporter
 }
 
 // NewSerializer returns a new Serializer initialized
@@ -158,6 +168,13 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestr
 
 	initExtraHeaders(s)
 
+	routingRules, err := resolver.LoadRoutingRules(config)
+	if err != nil {
+		logger.Errorf("Could not load the forwarder routing rules, the metrics are not routed by name: %v", err)
+	}
+	s.routingRules = routingRules
+	s.otlpExporter = otlp.NewExporter(config, logger)
+
 	if !s.enableEvents {
 		logger.Warn("event payloads are disabled: all events will be dropped")
 	}
@@ -335,6 +352,12 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 		return nil
 	}
 
+	if s.otlpExporter != nil {
+		builder := s.otlpExporter.NewRequestBuilder()
+		serieSource = otlp.NewSerieSource(serieSource, builder)
+		defer s.flushOTLPRequest(builder)
+	}
+
 	seriesSerializer := metricsserializer.CreateIterableSeries(serieSource)
 	useV1API := !s.config.GetBool("use_v2_api.series")
 
@@ -374,6 +397,13 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 			}
 			seriesBytesPayloads = append(seriesBytesPayloads, filtered...)
 			seriesBytesPayloads = append(seriesBytesPayloads, localAutoscalingFaioverPayloads...)
+		} else if s.routingRules.HasMetricRules(endpoints.SeriesEndpoint) {
+			seriesBytesPayloads, err = seriesSerializer.MarshalSplitCompressByRoute(s.config, s.Strategy, func(serie *metrics.Serie) string {
+				return s.routingRules.MetricRoutingRule(endpoints.SeriesEndpoint, serie.Name)
+			})
+			for _, seriesBytesPayload := range seriesBytesPayloads {
+				seriesBytesPayload.Destination = transaction.AllRegions
+			}
 		} else {
 			seriesBytesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(), s.config, s.Strategy)
 			for _, seriesBytesPayload := range seriesBytesPayloads {
@@ -427,6 +457,13 @@ func (s *Serializer) getAutoscalingFailoverMetrics() (bool, map[string]struct{})
 	return autoscalingFailoverEnabled, allowlist
 }
 
+// flushOTLPRequest sends the series or sketches read by the serializer to the OTLP exporter
+func (s *Serializer) flushOTLPRequest(builder *otlp.RequestBuilder) {
+	if err := builder.Flush(); err != nil {
+		s.logger.Errorf("Could not export the metrics as OTLP: %v", err)
+	}
+}
+
 // AreSketchesEnabled returns whether sketches are enabled for serialization
 func (s *Serializer) AreSketchesEnabled() bool {
 	return s.enableSketches
@@ -438,6 +475,12 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 		s.logger.Debug("sketches payloads are disabled: dropping it")
 		return nil
 	}
+	if s.otlpExporter != nil {
+		builder := s.otlpExporter.NewRequestBuilder()
+		sketches = otlp.NewSketchesSource(sketches, builder)
+		defer s.flushOTLPRequest(builder)
+	}
+
 	sketchesSerializer := metricsserializer.SketchSeriesList{SketchesSource: sketches}
 	if s.enableSketchProtobufStream {
 		failoverActive, allowlist := s.getFailoverAllowlist()
@@ -457,6 +500,15 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 			}
 			payloads = append(payloads, filteredPayloads...)
 
+			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufExtraHeadersWithCompression)
+		} else if s.routingRules.HasMetricRules(endpoints.SketchSeriesEndpoint) {
+			payloads, err := sketchesSerializer.MarshalSplitCompressByRoute(s.config, s.Strategy, func(ss *metrics.SketchSeries) string {
+				return s.routingRules.MetricRoutingRule(endpoints.SketchSeriesEndpoint, ss.Name)
+			}, s.logger)
+			if err != nil {
+				return fmt.Errorf("dropping sketch payload: %v", err)
+			}
+
 			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufExtraHeadersWithCompression)
 		} else {
 			payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(), s.config, s.Strategy, s.logger)
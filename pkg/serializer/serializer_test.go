@@ -407,6 +407,33 @@ func TestSendSeries(t *testing.T) {
 	}
 }
 
+func TestSendSeriesWithRoutingRules(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("forwarder_routing_rules", []map[string]interface{}{
+		{
+			"name":         "sandbox",
+			"metrics":      []string{`sandbox\..*`},
+			"destinations": []map[string]interface{}{{"domain": "https://sandbox.example.com"}},
+		},
+	})
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+	matcher := mock.MatchedBy(func(payloads transaction.BytesPayloads) bool {
+		return len(payloads) == 2 && payloads[0].RoutingRule == "" && payloads[1].RoutingRule == "sandbox"
+	})
+	f.On("SubmitSeries", matcher, s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+
+	err := s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{
+		&metrics.Serie{Name: "prod.requests"},
+		&metrics.Serie{Name: "sandbox.requests"},
+		&metrics.Serie{Name: "prod.errors"},
+	}))
+	require.Nil(t, err)
+	f.AssertExpectations(t)
+}
+
 func TestSendSketch(t *testing.T) {
 	tests := map[string]struct {
 		kind string
@@ -24,6 +24,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
 	"github.com/DataDog/datadog-agent/pkg/trace/remoteconfighandler"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
@@ -95,6 +96,7 @@ type Agent struct {
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	RuleSampler           *sampler.RuleSampler
 	SamplerMetrics        *sampler.Metrics
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
@@ -102,6 +104,7 @@ type Agent struct {
 	RemoteConfigHandler   *remoteconfighandler.RemoteConfigHandler
 	TelemetryCollector    telemetry.TelemetryCollector
 	DebugServer           *api.DebugServer
+	Capture               *replay.Capture
 	Statsd                statsd.ClientInterface
 	Timing                timing.Reporter
 
@@ -128,6 +131,10 @@ type Agent struct {
 	ctx context.Context
 
 	firstSpanMap sync.Map
+
+	// traceBuffer holds trace chunks until their whole trace can be sampled. It is nil unless
+	// tail sampling is enabled.
+	traceBuffer *traceBuffer
 }
 
 // SpanModifier is an interface that allows to modify spans while they are
@@ -158,6 +165,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		RareSampler:           sampler.NewRareSampler(conf),
 		NoPrioritySampler:     sampler.NewNoPrioritySampler(conf),
 		ProbabilisticSampler:  sampler.NewProbabilisticSampler(conf),
+		RuleSampler:           sampler.NewRuleSampler(conf),
 		SamplerMetrics:        sampler.NewMetrics(statsd),
 		EventProcessor:        newEventProcessor(conf, statsd),
 		StatsWriter:           statsWriter,
@@ -166,14 +174,16 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		conf:                  conf,
 		ctx:                   ctx,
 		DebugServer:           api.NewDebugServer(conf),
+		Capture:               replay.NewCapture(conf.CapturePath, conf.CaptureMaxBytes),
 		Statsd:                statsd,
 		Timing:                timing,
 	}
-	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler)
+	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler, agnt.RuleSampler)
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
-	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
+	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler, agnt.RuleSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	agnt.traceBuffer = newTraceBuffer(conf, statsd, agnt.flushBufferedTrace)
 	return agnt
 }
 
@@ -190,6 +200,7 @@ func (a *Agent) Run() {
 		a.OTLPReceiver,
 		a.RemoteConfigHandler,
 		a.DebugServer,
+		a.traceBuffer,
 	} {
 		starter.Start()
 	}
@@ -246,6 +257,7 @@ func (a *Agent) work() {
 		if !ok {
 			return
 		}
+		a.Capture.Record(p)
 		a.Process(p)
 	}
 
@@ -259,7 +271,13 @@ func (a *Agent) loop() {
 	if err := a.Receiver.Stop(); err != nil {
 		log.Error(err)
 	}
+	a.stopPipeline()
+}
+
+// stopPipeline stops the processing pipeline of the agent, flushing any pending traces and stats.
+func (a *Agent) stopPipeline() {
 	for _, stopper := range []interface{ Stop() }{
+		a.traceBuffer, // flush buffered traces before stopping the writers
 		a.Concentrator,
 		a.ClientStatsAggregator,
 		a.TraceWriter,
@@ -268,6 +286,7 @@ func (a *Agent) loop() {
 		a.EventProcessor,
 		a.obfuscator,
 		a.DebugServer,
+		a.Capture,
 	} {
 		// Fun with golang nil checks
 		if stopper != nil && !reflect.ValueOf(stopper).IsNil() {
@@ -315,6 +334,7 @@ func (a *Agent) Process(p *api.Payload) {
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, p.ProcessTags)
+	var header *pb.TracerPayload // payload header shared by the buffered chunks, see traceBuffer
 
 	p.TracerPayload.Env = normalize.NormalizeTagValue(p.TracerPayload.Env)
 
@@ -391,6 +411,16 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		if a.traceBuffer != nil {
+			// the chunk is sampled along with the rest of its trace once it is complete
+			if header == nil {
+				header = tracerPayloadHeader(p.TracerPayload)
+			}
+			a.traceBuffer.add(now, &bufferedChunk{pt: pt, source: ts, header: header})
+			p.RemoveChunk(i)
+			continue
+		}
+
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
@@ -574,7 +604,13 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.Processed
 	// For example: We want to maintain the overall trace level sampling decision for a trace with Analytics Events
 	// where a trace might be marked as DroppedTrace true, but we still sent analytics events in that ProcessedTrace.
 	keep, checkAnalyticsEvents := a.traceSampling(now, ts, pt)
+	return keep, a.keepSampledSpans(ts, pt, keep, checkAnalyticsEvents)
+}
 
+// keepSampledSpans applies the trace-level sampling decision to pt. When the trace isn't kept, only
+// its single span sampled spans or, failing that, its analytics events are kept. It returns the number
+// of analytics events found in the trace.
+func (a *Agent) keepSampledSpans(ts *info.TagStats, pt *traceutil.ProcessedTrace, keep bool, checkAnalyticsEvents bool) (numEvents int) {
 	var events []*pb.Span
 	if checkAnalyticsEvents {
 		events = a.getAnalyzedEvents(pt, ts)
@@ -591,7 +627,7 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.Processed
 		}
 	}
 
-	return keep, len(events)
+	return len(events)
 }
 
 // isManualUserDrop returns true if and only if the ProcessedTrace is marked as Priority User Drop
@@ -631,11 +667,13 @@ func (a *Agent) getAnalyzedEvents(pt *traceutil.ProcessedTrace, ts *info.TagStat
 // with the sampling rate.
 //
 // If the agent is set as Error Tracking Standalone, only the ErrorSampler is run (other samplers are bypassed).
-// Otherwise, the rare sampler is run first, catching all rare traces early. If the probabilistic sampler is
-// enabled, it is run on the trace, followed by the error sampler. Otherwise, If the trace has a
-// priority set, the sampling priority is used with the Priority Sampler. When there is no priority
-// set, the NoPrioritySampler is run. Finally, if the trace has not been sampled by the other
-// samplers, the error sampler is run.
+// Otherwise, the trace sampling rules are evaluated first: when a rule matches a trace which wasn't
+// explicitly kept or dropped by the user, its decision is final, and the signatures of the kept
+// traces are recorded by the rare sampler. Then, the rare sampler is run, catching all rare traces
+// early. If the probabilistic sampler is enabled, it is run on the trace, followed by the error
+// sampler. Otherwise, If the trace has a priority set, the sampling priority is used with the
+// Priority Sampler. When there is no priority set, the NoPrioritySampler is run. Finally, if the
+// trace has not been sampled by the other samplers, the error sampler is run.
 func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool) {
 	samplerName := sampler.NameUnknown
 	samplingPriority := sampler.PriorityNone
@@ -652,6 +690,18 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 		return false, false
 	}
 
+	if !isManualDecision(pt.TraceChunk) {
+		if ruleKeep, matched := a.RuleSampler.Sample(pt.TraceChunk, pt.Root); matched {
+			samplerName = sampler.NameRule
+			if ruleKeep {
+				// The signature of a kept trace is counted by the RareSampler, without using its
+				// rate limit, so that the next traces with the same signature aren't rare.
+				a.RareSampler.Record(now, pt.TraceChunk, pt.TracerEnv)
+			}
+			return ruleKeep, ruleKeep
+		}
+	}
+
 	// Run this early to make sure the signature gets counted by the RareSampler.
 	rare := a.RareSampler.Sample(now, pt.TraceChunk, pt.TracerEnv)
 
@@ -718,6 +768,12 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 	return false, true
 }
 
+// isManualDecision returns whether the chunk was explicitly kept or dropped by the user.
+func isManualDecision(chunk *pb.TraceChunk) bool {
+	priority, ok := sampler.GetSamplingPriority(chunk)
+	return ok && (priority == sampler.PriorityUserKeep || priority == sampler.PriorityUserDrop)
+}
+
 func traceContainsError(trace pb.Trace, considerExceptionEvents bool) bool {
 	for _, span := range trace {
 		if span.Error != 0 || (considerExceptionEvents && spanContainsExceptionSpanEvent(span)) {
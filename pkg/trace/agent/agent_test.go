@@ -44,6 +44,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
 	mockStatsd "github.com/DataDog/datadog-go/v5/statsd/mocks"
 
 	"github.com/stretchr/testify/assert"
@@ -1964,6 +1965,109 @@ func TestSample(t *testing.T) {
 	}
 }
 
+func TestSampleWithRules(t *testing.T) {
+	now := time.Now()
+	cfg := &config.AgentConfig{TargetTPS: 5, ErrorTPS: 1000, Features: make(map[string]struct{}), TraceSamplingRules: []*config.TraceSamplingRule{
+		{Name: "keep-server-errors", Conditions: []string{"http.status_code >= 500"}},
+		{Name: "drop-health-checks", Resource: "GET /health*", SampleRate: pointer.Ptr(0.0)},
+	}}
+	genTrace := func(resource, status string, priority sampler.SamplingPriority, err int32) traceutil.ProcessedTrace {
+		root := &pb.Span{
+			Service:  "serv1",
+			Resource: resource,
+			Start:    now.UnixNano(),
+			Duration: (100 * time.Millisecond).Nanoseconds(),
+			Metrics:  map[string]float64{"_top_level": 1},
+			Error:    err,
+			Meta:     map[string]string{"http.status_code": status},
+		}
+		pt := traceutil.ProcessedTrace{TraceChunk: testutil.TraceChunkWithSpan(root), Root: root}
+		pt.TraceChunk.Priority = int32(priority)
+		return pt
+	}
+	statsd := &statsd.NoOpClient{}
+	a := &Agent{
+		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
+		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
+		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
+		RareSampler:       sampler.NewRareSampler(config.New()),
+		RuleSampler:       sampler.NewRuleSampler(cfg),
+		EventProcessor:    newEventProcessor(cfg, statsd),
+		SamplerMetrics:    sampler.NewMetrics(statsd),
+		conf:              cfg,
+	}
+	for name, tt := range map[string]struct {
+		trace traceutil.ProcessedTrace
+		keep  bool
+	}{
+		"autodrop-server-error-kept":    {genTrace("GET /users", "503", sampler.PriorityAutoDrop, 0), true},
+		"autokeep-health-check-dropped": {genTrace("GET /healthz", "200", sampler.PriorityAutoKeep, 0), false},
+		"health-check-error-dropped":    {genTrace("GET /healthz", "200", sampler.PriorityAutoKeep, 1), false},
+		"userkeep-not-overridden":       {genTrace("GET /healthz", "200", sampler.PriorityUserKeep, 0), true},
+		"no-match-default-chain":        {genTrace("GET /users", "200", sampler.PriorityAutoKeep, 0), true},
+		"no-match-autodrop":             {genTrace("GET /users", "200", sampler.PriorityAutoDrop, 0), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			keep, _ := a.sample(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &tt.trace)
+			assert.Equal(t, tt.keep, keep)
+		})
+	}
+}
+
+func TestSampleWithRulesRareSignatures(t *testing.T) {
+	now := time.Now()
+	cfg := &config.AgentConfig{TargetTPS: 5, ErrorTPS: 1000, Features: make(map[string]struct{}), TraceSamplingRules: []*config.TraceSamplingRule{
+		{Name: "keep-checkouts", Resource: "POST /checkout"},
+		{Name: "drop-health-checks", Resource: "GET /health*", SampleRate: pointer.Ptr(0.0)},
+	}}
+	rareCfg := config.New()
+	rareCfg.RareSamplerEnabled = true
+	genTrace := func(resource string, priority sampler.SamplingPriority) traceutil.ProcessedTrace {
+		root := &pb.Span{
+			Service:  "serv1",
+			Name:     "http.request",
+			Resource: resource,
+			Start:    now.UnixNano(),
+			Duration: (100 * time.Millisecond).Nanoseconds(),
+			Metrics:  map[string]float64{"_top_level": 1},
+		}
+		pt := traceutil.ProcessedTrace{TraceChunk: testutil.TraceChunkWithSpan(root), Root: root}
+		pt.TraceChunk.Priority = int32(priority)
+		return pt
+	}
+	statsd := &statsd.NoOpClient{}
+	a := &Agent{
+		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
+		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
+		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
+		RareSampler:       sampler.NewRareSampler(rareCfg),
+		RuleSampler:       sampler.NewRuleSampler(cfg),
+		EventProcessor:    newEventProcessor(cfg, statsd),
+		SamplerMetrics:    sampler.NewMetrics(statsd),
+		conf:              cfg,
+	}
+	sample := func(pt *traceutil.ProcessedTrace) bool {
+		keep, _ := a.sample(now, info.NewReceiverStats().GetTagStats(info.Tags{}), pt)
+		return keep
+	}
+
+	// a trace dropped by a rule isn't marked rare, and its signature isn't counted
+	pt := genTrace("GET /healthz", sampler.PriorityAutoKeep)
+	assert.False(t, sample(&pt))
+	assert.NotContains(t, pt.Root.Metrics, "_dd.rare")
+	pt = genTrace("GET /healthz", sampler.PriorityUserKeep)
+	assert.True(t, sample(&pt))
+	assert.Equal(t, 1.0, pt.Root.Metrics["_dd.rare"])
+
+	// a trace kept by a rule isn't marked rare, but its signature is counted
+	pt = genTrace("POST /checkout", sampler.PriorityAutoDrop)
+	assert.True(t, sample(&pt))
+	assert.NotContains(t, pt.Root.Metrics, "_dd.rare")
+	pt = genTrace("POST /checkout", sampler.PriorityUserKeep)
+	assert.True(t, sample(&pt))
+	assert.NotContains(t, pt.Root.Metrics, "_dd.rare")
+}
+
 func TestSampleManualUserDropNoAnalyticsEvents(t *testing.T) {
 	// This test exists to confirm previous behavior where we did not extract nor tag analytics events on
 	// user manual drop traces
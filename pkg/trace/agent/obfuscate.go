@@ -47,8 +47,25 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		}
 	}
 
+	if a.conf.Obfuscation != nil && a.conf.Obfuscation.DynamoDB.Enabled && transform.IsDynamoDBSpan(span) {
+		if err := transform.ObfuscateDynamoDBSpan(o, span); err != nil {
+			log.Debugf("Error parsing PartiQL statement: %v. Resource: %q", err, span.Resource)
+		}
+	}
+	if a.conf.Obfuscation != nil && a.conf.Obfuscation.Kafka.Enabled && transform.IsKafkaSpan(span) {
+		transform.ObfuscateKafkaSpan(o, span)
+	}
+
 	switch span.Type {
-	case "sql", "cassandra":
+	case "cassandra":
+		if a.conf.Obfuscation != nil && a.conf.Obfuscation.CQL.Enabled {
+			if err := transform.ObfuscateCQLSpan(o, span); err != nil {
+				log.Debugf("Error parsing CQL query: %v. Resource: %q", err, span.Resource)
+			}
+			return
+		}
+		fallthrough
+	case "sql":
 		if span.Resource == "" {
 			return
 		}
@@ -72,6 +89,13 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		if span.Type == "valkey" && a.conf.Obfuscation.Valkey.Enabled {
 			transform.ObfuscateValkeySpan(o, span, a.conf.Obfuscation.Valkey.RemoveAllArgs)
 		}
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		if err := transform.ObfuscateGraphQLSpan(o, span); err != nil {
+			log.Debugf("Error parsing GraphQL query: %v. Resource: %q", err, span.Resource)
+		}
 	case "memcached":
 		if !a.conf.Obfuscation.Memcached.Enabled {
 			return
@@ -160,7 +184,18 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	o := a.lazyInitObfuscator()
 
 	switch b.Type {
-	case "sql", "cassandra":
+	case "cassandra":
+		if a.conf.Obfuscation != nil && a.conf.Obfuscation.CQL.Enabled {
+			oq, err := o.ObfuscateCQLString(b.Resource)
+			if err != nil {
+				log.Debugf("Error obfuscating stats group resource %q: %v", b.Resource, err)
+				oq = transform.TextNonParsableCQL
+			}
+			b.Resource = oq
+			return
+		}
+		fallthrough
+	case "sql":
 		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, b.DBType)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
@@ -170,6 +205,15 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis", "valkey":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		if a.conf.Obfuscation == nil || !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		oq, err := transform.ObfuscateGraphQLQuery(o, b.Resource)
+		if err != nil {
+			log.Debugf("Error obfuscating stats group resource %q: %v", b.Resource, err)
+		}
+		b.Resource = oq
 	}
 }
 
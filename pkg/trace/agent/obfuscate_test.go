@@ -46,6 +46,8 @@ func TestObfuscateStatsGroup(t *testing.T) {
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("valkey", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
+		{statsGroup("graphql", "{ user(id: 1) { name } }"), "{ user(id: 1) { name } }"}, // disabled by default
+		{statsGroup("cassandra", "SELECT 1 FROM db"), "SELECT ? FROM db"},               // obfuscated as SQL when CQL is disabled
 	} {
 		agnt, stop := agentWithDefaults()
 		defer stop()
@@ -296,6 +298,92 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: "42") { name } }`,
+		`query { user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: "42") { name } }`,
+		`query { user(id: "42") { name } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("cql/enabled", testConfig(
+		"cassandra",
+		"cassandra.query",
+		"SELECT * FROM users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+		"SELECT * FROM users WHERE id = ?",
+		&config.ObfuscationConfig{CQL: obfuscate.CQLConfig{Enabled: true}},
+	))
+
+	t.Run("cql/disabled", testConfig(
+		"cassandra",
+		"cassandra.query",
+		"SELECT * FROM users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+		"SELECT * FROM users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("dynamodb/enabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":id": {"S": "42"}}`,
+		`{":id":{"S":"?"}}`,
+		&config.ObfuscationConfig{DynamoDB: obfuscate.DynamoDBConfig{Enabled: true}},
+	))
+
+	t.Run("dynamodb/remove_expression_attribute_values", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":id": {"S": "42"}}`,
+		"",
+		&config.ObfuscationConfig{DynamoDB: obfuscate.DynamoDBConfig{
+			Enabled:                         true,
+			RemoveExpressionAttributeValues: true,
+		}},
+	))
+
+	t.Run("dynamodb/disabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":id": {"S": "42"}}`,
+		`{":id": {"S": "42"}}`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("kafka/enabled", testConfig(
+		"kafka",
+		"messaging.kafka.message.key",
+		"user-42",
+		"?",
+		&config.ObfuscationConfig{Kafka: obfuscate.KafkaConfig{Enabled: true}},
+	))
+
+	t.Run("kafka/keep_message_key", testConfig(
+		"kafka",
+		"messaging.kafka.message.key",
+		"user-42",
+		"user-42",
+		&config.ObfuscationConfig{Kafka: obfuscate.KafkaConfig{
+			Enabled:        true,
+			KeepMessageKey: true,
+		}},
+	))
+
+	t.Run("kafka/disabled", testConfig(
+		"kafka",
+		"messaging.message.body",
+		`{"user": "bob"}`,
+		`{"user": "bob"}`,
+		&config.ObfuscationConfig{},
+	))
+
 	t.Run("creditcard", func(t *testing.T) {
 		for _, tt := range []struct {
 			k, v string
@@ -414,6 +502,147 @@ ORDER BY [b].[Name]`,
 	}
 }
 
+func TestGraphQLObfuscation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.GraphQL.Enabled = true
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+	t.Run("span", func(t *testing.T) {
+		query := "query GetUser($id: ID!) {\n  me: user(id: $id, role: ADMIN) { name }\n}"
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: query,
+			Meta: map[string]string{
+				"graphql.query":             query,
+				"graphql.operation.name":    "GetUser",
+				"graphql.variables.id":      "42",
+				"graphql.variables.user.id": "42",
+			},
+		}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "query GetUser($id: ID!) { user(id: $id, role: ?) { name } }", span.Resource)
+		assert.Equal(t, map[string]string{
+			"graphql.query":          "query GetUser($id: ID!) { user(id: $id, role: ?) { name } }",
+			"graphql.operation.name": "GetUser",
+		}, span.Meta)
+	})
+
+	t.Run("operation-name", func(t *testing.T) {
+		span := &pb.Span{Type: "graphql", Resource: "GetUser"}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "GetUser", span.Resource)
+	})
+
+	t.Run("non-parsable", func(t *testing.T) {
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: `{ user(id: "42) { name } }`,
+			Meta:     map[string]string{"graphql.source": `{ user(id: "42) { name } }`},
+		}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "Non-parsable GraphQL query", span.Resource)
+		assert.Equal(t, "Non-parsable GraphQL query", span.Meta["graphql.source"])
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		b := &pb.ClientGroupedStats{Type: "graphql", Resource: `{ user(id: 1) { name } }`}
+		agnt.obfuscateStatsGroup(b)
+		assert.Equal(t, "{ user(id: ?) { name } }", b.Resource)
+	})
+}
+
+func TestCQLObfuscation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.CQL.Enabled = true
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+	t.Run("span", func(t *testing.T) {
+		query := "SELECT * FROM ks.users WHERE id IN (1, 2) AND name = 'bob'"
+		span := &pb.Span{
+			Type:     "cassandra",
+			Resource: query,
+			Meta:     map[string]string{"cassandra.query": query},
+		}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "SELECT * FROM ks.users WHERE id IN ( ? ) AND name = ?", span.Resource)
+		assert.Equal(t, map[string]string{
+			"cassandra.query": "SELECT * FROM ks.users WHERE id IN ( ? ) AND name = ?",
+			"sql.query":       "SELECT * FROM ks.users WHERE id IN ( ? ) AND name = ?",
+		}, span.Meta)
+	})
+
+	t.Run("non-parsable", func(t *testing.T) {
+		span := &pb.Span{Type: "cassandra", Resource: "SELECT * FROM users WHERE name = 'bob"}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "Non-parsable CQL query", span.Resource)
+		assert.Equal(t, "Non-parsable CQL query", span.Meta["sql.query"])
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		b := &pb.ClientGroupedStats{Type: "cassandra", Resource: "UPDATE users SET tags = {'a'} WHERE id = 1"}
+		agnt.obfuscateStatsGroup(b)
+		assert.Equal(t, "UPDATE users SET tags = { ? } WHERE id = ?", b.Resource)
+	})
+}
+
+func TestDynamoDBObfuscation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.DynamoDB.Enabled = true
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+	span := &pb.Span{
+		Type:     "http",
+		Resource: "DynamoDB.ExecuteStatement",
+		Meta: map[string]string{
+			"aws.service":  "DynamoDB",
+			"db.statement": `SELECT * FROM "Music" WHERE Artist = 'Acme Band'`,
+			"aws.dynamodb.expression_attribute_values": `{":a": {"S": "Acme Band"}}`,
+		},
+	}
+	agnt.obfuscateSpan(span)
+	assert.Equal(t, "DynamoDB.ExecuteStatement", span.Resource)
+	assert.Equal(t, `SELECT * FROM "Music" WHERE Artist = ?`, span.Meta["db.statement"])
+	assert.Equal(t, `{":a":{"S":"?"}}`, span.Meta["aws.dynamodb.expression_attribute_values"])
+
+	other := &pb.Span{Type: "http", Meta: map[string]string{"db.statement": "SELECT 'keep'"}}
+	agnt.obfuscateSpan(other)
+	assert.Equal(t, "SELECT 'keep'", other.Meta["db.statement"])
+}
+
+func TestKafkaObfuscation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.Kafka.Enabled = true
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+	span := &pb.Span{
+		Type:     "queue",
+		Resource: "Produce Topic orders",
+		Meta: map[string]string{
+			"messaging.system":            "kafka",
+			"messaging.kafka.message_key": "order-42",
+			"messaging.message.body":      `{"order": {"id": 42, "email": "bob@example.com"}}`,
+		},
+	}
+	agnt.obfuscateSpan(span)
+	assert.Equal(t, map[string]string{
+		"messaging.system":            "kafka",
+		"messaging.kafka.message_key": "?",
+		"messaging.message.body":      `{"order":{"id":"?","email":"?"}}`,
+	}, span.Meta)
+}
+
 func TestSQLTableNames(t *testing.T) {
 	t.Run("on", func(t *testing.T) {
 		span := &pb.Span{
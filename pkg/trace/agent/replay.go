@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"errors"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+)
+
+// Replay processes payloads which were received earlier, e.g. read from a capture file. It
+// starts the processing pipeline of the agent without its receivers, processes every payload
+// returned by next until it returns io.EOF, and then stops the pipeline, flushing all pending
+// traces and stats to the configured endpoints. It must not be called along with Run.
+func (a *Agent) Replay(next func() (*api.Payload, error)) error {
+	for _, starter := range []interface{ Start() }{
+		a.Concentrator,
+		a.ClientStatsAggregator,
+		a.SamplerMetrics,
+		a.EventProcessor,
+		a.traceBuffer,
+	} {
+		starter.Start()
+	}
+	go a.StatsWriter.Run()
+	defer a.stopPipeline()
+
+	for {
+		p, err := next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		a.Process(p)
+	}
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+func TestReplay(t *testing.T) {
+	newPayloads := func(n int) func() (*api.Payload, error) {
+		return func() (*api.Payload, error) {
+			if n == 0 {
+				return nil, io.EOF
+			}
+			n--
+			span := &pb.Span{
+				TraceID:  uint64(n + 1),
+				SpanID:   1,
+				Service:  "svc",
+				Resource: "SELECT name FROM people WHERE age = 42",
+				Type:     "sql",
+				Start:    time.Now().Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+			chunk := testutil.TraceChunkWithSpan(span)
+			chunk.Priority = int32(sampler.PriorityUserKeep)
+			return &api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+				Source:        info.NewReceiverStats().GetTagStats(info.Tags{Lang: "go"}),
+			}, nil
+		}
+	}
+
+	t.Run("payloads", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+		assert.NoError(t, agnt.Replay(newPayloads(3)))
+		tw := agnt.TraceWriter.(*mockTraceWriter)
+		assert.Len(t, tw.payloads, 3)
+		for _, p := range tw.payloads {
+			assert.Equal(t, "SELECT name FROM people WHERE age = ?", p.TracerPayload.Chunks[0].Spans[0].Resource)
+		}
+		assert.Len(t, agnt.Concentrator.(*mockConcentrator).stats, 3)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+		errRead := errors.New("read error")
+		assert.Equal(t, errRead, agnt.Replay(func() (*api.Payload, error) { return nil, errRead }))
+	})
+}
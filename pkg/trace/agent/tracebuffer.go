@@ -0,0 +1,398 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	// traceBufferTickInterval is the frequency at which expired traces are flushed from the buffer.
+	traceBufferTickInterval = time.Second
+	// traceBufferReportInterval is the frequency at which the buffer reports its metrics.
+	traceBufferReportInterval = 10 * time.Second
+	// maxTraceDecisions bounds the number of sampling decisions remembered for late chunks.
+	maxTraceDecisions = 1 << 16
+)
+
+// Reasons for flushing a trace from the buffer, reported as the "reason" tag of the flush metric.
+const (
+	flushReasonRoot     = "root"
+	flushReasonTimeout  = "timeout"
+	flushReasonEvicted  = "evicted"
+	flushReasonMemory   = "memory"
+	flushReasonShutdown = "shutdown"
+	flushReasonLate     = "late"
+)
+
+// bufferedChunk is a processed trace chunk waiting for the sampling decision of its trace.
+type bufferedChunk struct {
+	pt *traceutil.ProcessedTrace
+	// source holds the stats of the payload the chunk was received with.
+	source *info.TagStats
+	// header holds the tracer payload the chunk was received with, without its chunks.
+	header *pb.TracerPayload
+	size   int
+}
+
+// bufferedTrace holds all the chunks received for a trace.
+type bufferedTrace struct {
+	id       uint64
+	chunks   []*bufferedChunk
+	size     int
+	deadline time.Time
+	elem     *list.Element
+}
+
+// samplingDecision is the outcome of sampling a whole trace.
+type samplingDecision struct {
+	keep                 bool
+	checkAnalyticsEvents bool
+	expire               time.Time
+}
+
+// flushFunc samples and writes the chunks of a trace. When decision is not nil, the trace was
+// already sampled and the decision must be applied as is. It returns the decision that was applied.
+type flushFunc func(now time.Time, chunks []*bufferedChunk, decision *samplingDecision) samplingDecision
+
+// traceBuffer holds trace chunks keyed by trace ID so that traces split across several payloads
+// are sampled as a whole. A trace is sampled once its root span is received, or once it has been
+// buffered for the configured decision wait. Memory is bounded: the oldest traces are sampled early
+// when the buffer is full, and the whole buffer is flushed when the watchdog reports that the agent
+// uses more memory than allowed.
+type traceBuffer struct {
+	wait      time.Duration
+	maxSize   int
+	maxMemory float64
+	flush     flushFunc
+	statsd    statsd.ClientInterface
+	wdInfo    *watchdog.CurrentInfo
+	wdEvery   time.Duration
+
+	mu        sync.Mutex
+	traces    map[uint64]*bufferedTrace
+	order     *list.List // buffered traces, oldest first
+	decisions map[uint64]samplingDecision
+	size      int
+	closed    bool
+	flushed   map[string]int64
+
+	exit chan struct{}
+	done chan struct{}
+}
+
+// newTraceBuffer returns a trace buffer using flush to sample traces, or nil if tail sampling
+// is disabled.
+func newTraceBuffer(conf *config.AgentConfig, statsd statsd.ClientInterface, flush flushFunc) *traceBuffer {
+	if !conf.TailSamplingEnabled {
+		return nil
+	}
+	return &traceBuffer{
+		wait:      conf.TailSamplingDecisionWait,
+		maxSize:   conf.TailSamplingMaxBufferSize,
+		maxMemory: conf.MaxMemory,
+		flush:     flush,
+		statsd:    statsd,
+		wdInfo:    watchdog.NewCurrentInfo(),
+		wdEvery:   conf.WatchdogInterval,
+		traces:    make(map[uint64]*bufferedTrace),
+		order:     list.New(),
+		decisions: make(map[uint64]samplingDecision),
+		flushed:   make(map[string]int64),
+		exit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start starts flushing the expired traces.
+func (b *traceBuffer) Start() {
+	if b == nil {
+		return
+	}
+	go func() {
+		defer watchdog.LogOnPanic(b.statsd)
+		b.loop()
+	}()
+}
+
+// Stop samples all buffered traces and stops the buffer. Chunks added afterwards are sampled
+// right away.
+func (b *traceBuffer) Stop() {
+	if b == nil {
+		return
+	}
+	close(b.exit)
+	<-b.done
+	b.mu.Lock()
+	b.closed = true
+	ready := b.removeAllLocked(flushReasonShutdown)
+	b.mu.Unlock()
+	b.flushAll(time.Now(), ready)
+	b.report()
+}
+
+func (b *traceBuffer) loop() {
+	defer close(b.done)
+	t := time.NewTicker(traceBufferTickInterval)
+	defer t.Stop()
+	var lastReport, lastWatchdog time.Time
+	for {
+		select {
+		case <-b.exit:
+			return
+		case now := <-t.C:
+			b.flushExpired(now)
+			if b.wdEvery > 0 && now.Sub(lastWatchdog) >= b.wdEvery {
+				b.checkMemory(now)
+				lastWatchdog = now
+			}
+			if now.Sub(lastReport) >= traceBufferReportInterval {
+				b.report()
+				lastReport = now
+			}
+		}
+	}
+}
+
+// add buffers c. If c completes its trace, or if its trace was already sampled, it is flushed
+// right away from the calling goroutine.
+func (b *traceBuffer) add(now time.Time, c *bufferedChunk) {
+	id := c.pt.TraceChunk.Spans[0].TraceID
+	c.size = c.pt.TraceChunk.Msgsize()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		b.flush(now, []*bufferedChunk{c}, nil)
+		return
+	}
+	if d, ok := b.decisions[id]; ok && now.Before(d.expire) {
+		// the trace was already sampled: apply the same decision to this late chunk
+		b.flushed[flushReasonLate]++
+		b.mu.Unlock()
+		b.flush(now, []*bufferedChunk{c}, &d)
+		return
+	}
+	t, ok := b.traces[id]
+	if !ok {
+		t = &bufferedTrace{id: id, deadline: now.Add(b.wait)}
+		t.elem = b.order.PushBack(t)
+		b.traces[id] = t
+	}
+	t.chunks = append(t.chunks, c)
+	t.size += c.size
+	b.size += c.size
+
+	var ready [][]*bufferedChunk
+	if hasTraceRoot(c.pt.TraceChunk) {
+		ready = append(ready, b.removeLocked(t, flushReasonRoot))
+	}
+	for b.maxSize > 0 && b.size > b.maxSize && b.order.Len() > 0 {
+		ready = append(ready, b.removeLocked(b.order.Front().Value.(*bufferedTrace), flushReasonEvicted))
+	}
+	b.mu.Unlock()
+	b.flushAll(now, ready)
+}
+
+// flushExpired samples all the traces buffered for longer than the decision wait.
+func (b *traceBuffer) flushExpired(now time.Time) {
+	var ready [][]*bufferedChunk
+	b.mu.Lock()
+	for e := b.order.Front(); e != nil; e = b.order.Front() {
+		t := e.Value.(*bufferedTrace)
+		if now.Before(t.deadline) {
+			break
+		}
+		ready = append(ready, b.removeLocked(t, flushReasonTimeout))
+	}
+	for id, d := range b.decisions {
+		if !now.Before(d.expire) {
+			delete(b.decisions, id)
+		}
+	}
+	b.mu.Unlock()
+	b.flushAll(now, ready)
+}
+
+// checkMemory samples all buffered traces when the agent uses more memory than allowed.
+func (b *traceBuffer) checkMemory(now time.Time) {
+	if b.maxMemory <= 0 {
+		return
+	}
+	if alloc := float64(b.wdInfo.Mem().Alloc); alloc <= b.maxMemory {
+		return
+	}
+	b.mu.Lock()
+	n := len(b.traces)
+	ready := b.removeAllLocked(flushReasonMemory)
+	b.mu.Unlock()
+	if n > 0 {
+		log.Warnf("Memory threshold exceeded, sampling %d buffered traces early", n)
+	}
+	b.flushAll(now, ready)
+}
+
+// removeLocked removes t from the buffer and returns its chunks. b.mu must be held.
+func (b *traceBuffer) removeLocked(t *bufferedTrace, reason string) []*bufferedChunk {
+	delete(b.traces, t.id)
+	b.order.Remove(t.elem)
+	b.size -= t.size
+	b.flushed[reason]++
+	return t.chunks
+}
+
+// removeAllLocked empties the buffer and returns the chunks of all traces. b.mu must be held.
+func (b *traceBuffer) removeAllLocked(reason string) [][]*bufferedChunk {
+	ready := make([][]*bufferedChunk, 0, len(b.traces))
+	for e := b.order.Front(); e != nil; e = b.order.Front() {
+		ready = append(ready, b.removeLocked(e.Value.(*bufferedTrace), reason))
+	}
+	return ready
+}
+
+// flushAll samples the given traces and remembers their decisions for chunks received late.
+func (b *traceBuffer) flushAll(now time.Time, ready [][]*bufferedChunk) {
+	for _, chunks := range ready {
+		d := b.flush(now, chunks, nil)
+		d.expire = now.Add(b.wait)
+		id := chunks[0].pt.TraceChunk.Spans[0].TraceID
+		b.mu.Lock()
+		if !b.closed && len(b.decisions) < maxTraceDecisions {
+			b.decisions[id] = d
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *traceBuffer) report() {
+	b.mu.Lock()
+	traces, size := len(b.traces), b.size
+	flushed := b.flushed
+	b.flushed = make(map[string]int64)
+	b.mu.Unlock()
+
+	_ = b.statsd.Gauge("datadog.trace_agent.tail_sampling.traces", float64(traces), nil, 1)
+	_ = b.statsd.Gauge("datadog.trace_agent.tail_sampling.bytes", float64(size), nil, 1)
+	for reason, n := range flushed {
+		_ = b.statsd.Count("datadog.trace_agent.tail_sampling.flushed", n, []string{"reason:" + reason}, 1)
+	}
+}
+
+// hasTraceRoot reports whether the chunk contains the root span of the whole trace.
+func hasTraceRoot(chunk *pb.TraceChunk) bool {
+	for _, s := range chunk.Spans {
+		if s.ParentID == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// tracerPayloadHeader returns a copy of p without its chunks.
+func tracerPayloadHeader(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.ContainerID,
+		LanguageName:    p.LanguageName,
+		LanguageVersion: p.LanguageVersion,
+		TracerVersion:   p.TracerVersion,
+		RuntimeID:       p.RuntimeID,
+		Tags:            p.Tags,
+		Env:             p.Env,
+		Hostname:        p.Hostname,
+		AppVersion:      p.AppVersion,
+	}
+}
+
+// flushBufferedTrace samples the buffered chunks of a trace as a whole and writes the sampled
+// chunks. When decision is nil, the samplers run on all the spans of the trace.
+func (a *Agent) flushBufferedTrace(now time.Time, chunks []*bufferedChunk, decision *samplingDecision) samplingDecision {
+	var d samplingDecision
+	if decision != nil {
+		d = *decision
+	} else {
+		d.keep, d.checkAnalyticsEvents = a.runSamplers(now, chunks[0].source, wholeTrace(chunks))
+	}
+
+	var (
+		payloads []*writer.SampledChunks
+		byHeader = make(map[*pb.TracerPayload]*writer.SampledChunks)
+	)
+	for _, c := range chunks {
+		c.pt.TraceChunk.DroppedTrace = !d.keep
+		numEvents := a.keepSampledSpans(c.source, c.pt, d.keep, d.checkAnalyticsEvents)
+		if !d.keep && len(c.pt.TraceChunk.Spans) == 0 {
+			// The entire chunk was dropped and no spans were kept.
+			continue
+		}
+		sc, ok := byHeader[c.header]
+		if !ok {
+			sc = &writer.SampledChunks{TracerPayload: tracerPayloadHeader(c.header)}
+			byHeader[c.header] = sc
+			payloads = append(payloads, sc)
+		}
+		if !c.pt.TraceChunk.DroppedTrace {
+			a.setFirstTraceTags(c.pt.Root)
+			sc.SpanCount += int64(len(c.pt.TraceChunk.Spans))
+		}
+		sc.EventCount += int64(numEvents)
+		sc.Size += c.pt.TraceChunk.Msgsize()
+		sc.TracerPayload.Chunks = append(sc.TracerPayload.Chunks, c.pt.TraceChunk)
+	}
+	for _, sc := range payloads {
+		a.TraceWriter.WriteChunks(sc)
+	}
+	return d
+}
+
+// wholeTrace returns a processed trace holding the spans of all the chunks, as seen from the chunk
+// holding the root span.
+func wholeTrace(chunks []*bufferedChunk) traceutil.ProcessedTrace {
+	if len(chunks) == 1 {
+		return *chunks[0].pt
+	}
+	var spans []*pb.Span
+	for _, c := range chunks {
+		spans = append(spans, c.pt.TraceChunk.Spans...)
+	}
+	root := traceutil.GetRoot(spans)
+	rootChunk := chunks[0]
+	for _, c := range chunks {
+		if c.pt.Root == root {
+			rootChunk = c
+			break
+		}
+	}
+	pt := *rootChunk.pt
+	whole := &pb.TraceChunk{
+		Priority: rootChunk.pt.TraceChunk.Priority,
+		Origin:   rootChunk.pt.TraceChunk.Origin,
+		Tags:     rootChunk.pt.TraceChunk.Tags,
+		Spans:    spans,
+	}
+	// chunks flushed before the root span may carry the sampling priority instead
+	for _, c := range chunks {
+		if whole.Priority != int32(sampler.PriorityNone) {
+			break
+		}
+		whole.Priority = c.pt.TraceChunk.Priority
+	}
+	pt.TraceChunk = whole
+	pt.Root = root
+	return pt
+}
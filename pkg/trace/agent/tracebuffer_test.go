@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// recordedFlush records the traces flushed by a traceBuffer.
+type recordedFlush struct {
+	mu        sync.Mutex
+	traces    [][]*bufferedChunk
+	decisions []*samplingDecision
+}
+
+func (r *recordedFlush) flush(_ time.Time, chunks []*bufferedChunk, decision *samplingDecision) samplingDecision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces = append(r.traces, chunks)
+	r.decisions = append(r.decisions, decision)
+	if decision != nil {
+		return *decision
+	}
+	return samplingDecision{keep: true}
+}
+
+func newTestTraceBuffer(maxSize int) (*traceBuffer, *recordedFlush) {
+	r := &recordedFlush{}
+	conf := &config.AgentConfig{
+		TailSamplingEnabled:       true,
+		TailSamplingDecisionWait:  time.Second,
+		TailSamplingMaxBufferSize: maxSize,
+	}
+	return newTraceBuffer(conf, &statsd.NoOpClient{}, r.flush), r
+}
+
+func bufferTestChunk(traceID, spanID, parentID uint64) *bufferedChunk {
+	span := &pb.Span{TraceID: traceID, SpanID: spanID, ParentID: parentID, Service: "svc", Name: "op"}
+	return &bufferedChunk{
+		pt:     &traceutil.ProcessedTrace{TraceChunk: testutil.TraceChunkWithSpan(span), Root: span},
+		source: info.NewReceiverStats().GetTagStats(info.Tags{}),
+		header: &pb.TracerPayload{},
+	}
+}
+
+func TestTraceBufferDisabled(t *testing.T) {
+	b := newTraceBuffer(config.New(), &statsd.NoOpClient{}, nil)
+	assert.Nil(t, b)
+	// nil buffers can be started and stopped
+	b.Start()
+	b.Stop()
+}
+
+func TestTraceBufferRoot(t *testing.T) {
+	b, r := newTestTraceBuffer(0)
+	now := time.Now()
+
+	b.add(now, bufferTestChunk(1, 2, 1))
+	b.add(now, bufferTestChunk(2, 3, 1))
+	assert.Empty(t, r.traces)
+	assert.Len(t, b.traces, 2)
+
+	b.add(now, bufferTestChunk(1, 1, 0))
+	require.Len(t, r.traces, 1)
+	assert.Len(t, r.traces[0], 2)
+	assert.Nil(t, r.decisions[0])
+	assert.Len(t, b.traces, 1)
+	assert.Equal(t, int64(1), b.flushed[flushReasonRoot])
+}
+
+func TestTraceBufferTimeout(t *testing.T) {
+	b, r := newTestTraceBuffer(0)
+	now := time.Now()
+
+	b.add(now, bufferTestChunk(1, 2, 1))
+	b.add(now.Add(500*time.Millisecond), bufferTestChunk(2, 3, 1))
+	b.flushExpired(now.Add(999 * time.Millisecond))
+	assert.Empty(t, r.traces)
+
+	b.flushExpired(now.Add(time.Second))
+	require.Len(t, r.traces, 1)
+	assert.Equal(t, uint64(1), r.traces[0][0].pt.TraceChunk.Spans[0].TraceID)
+
+	b.flushExpired(now.Add(2 * time.Second))
+	assert.Len(t, r.traces, 2)
+	assert.Empty(t, b.traces)
+	assert.Zero(t, b.size)
+	assert.Equal(t, int64(2), b.flushed[flushReasonTimeout])
+}
+
+func TestTraceBufferEviction(t *testing.T) {
+	c := bufferTestChunk(1, 2, 1)
+	size := c.pt.TraceChunk.Msgsize()
+	b, r := newTestTraceBuffer(2 * size)
+	now := time.Now()
+
+	b.add(now, c)
+	b.add(now, bufferTestChunk(2, 2, 1))
+	assert.Empty(t, r.traces)
+
+	// the oldest trace is sampled early to make room
+	b.add(now, bufferTestChunk(3, 2, 1))
+	require.Len(t, r.traces, 1)
+	assert.Equal(t, uint64(1), r.traces[0][0].pt.TraceChunk.Spans[0].TraceID)
+	assert.Len(t, b.traces, 2)
+	assert.Equal(t, 2*size, b.size)
+	assert.Equal(t, int64(1), b.flushed[flushReasonEvicted])
+}
+
+func TestTraceBufferMemory(t *testing.T) {
+	b, r := newTestTraceBuffer(0)
+	now := time.Now()
+	b.add(now, bufferTestChunk(1, 2, 1))
+	b.add(now, bufferTestChunk(2, 2, 1))
+
+	b.checkMemory(now)
+	assert.Empty(t, r.traces, "no memory limit")
+
+	b.maxMemory = 1
+	b.checkMemory(now)
+	assert.Len(t, r.traces, 2)
+	assert.Empty(t, b.traces)
+	assert.Equal(t, int64(2), b.flushed[flushReasonMemory])
+}
+
+func TestTraceBufferLateChunks(t *testing.T) {
+	b, r := newTestTraceBuffer(0)
+	now := time.Now()
+
+	b.add(now, bufferTestChunk(1, 1, 0))
+	require.Len(t, r.traces, 1)
+
+	// chunks received after the decision are flushed right away with the same decision
+	b.add(now.Add(500*time.Millisecond), bufferTestChunk(1, 2, 1))
+	require.Len(t, r.traces, 2)
+	require.NotNil(t, r.decisions[1])
+	assert.True(t, r.decisions[1].keep)
+	assert.Equal(t, int64(1), b.flushed[flushReasonLate])
+
+	// decisions expire after the decision wait
+	b.flushExpired(now.Add(time.Second))
+	assert.Empty(t, b.decisions)
+	b.add(now.Add(time.Second), bufferTestChunk(1, 3, 1))
+	assert.Len(t, r.traces, 2)
+	assert.Len(t, b.traces, 1)
+}
+
+func TestTraceBufferStop(t *testing.T) {
+	b, r := newTestTraceBuffer(0)
+	b.Start()
+	now := time.Now()
+	b.add(now, bufferTestChunk(1, 2, 1))
+	b.add(now, bufferTestChunk(2, 2, 1))
+
+	b.Stop()
+	assert.Len(t, r.traces, 2)
+	assert.Empty(t, b.traces)
+
+	b.add(now, bufferTestChunk(3, 2, 1))
+	assert.Len(t, r.traces, 3)
+	assert.Empty(t, b.traces)
+}
+
+func TestProcessTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	require.NotNil(t, agnt.traceBuffer)
+
+	now := time.Now()
+	span := func(spanID, parentID uint64, err int32) *pb.Span {
+		return &pb.Span{
+			TraceID:  42,
+			SpanID:   spanID,
+			ParentID: parentID,
+			Service:  "checkout",
+			Name:     "http.request",
+			Resource: "GET /cart",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (100 * time.Millisecond).Nanoseconds(),
+			Error:    err,
+		}
+	}
+	payload := func(hostname string, s *pb.Span) *api.Payload {
+		chunk := testutil.TraceChunkWithSpan(s)
+		chunk.Priority = int32(sampler.PriorityAutoDrop)
+		tp := testutil.TracerPayloadWithChunk(chunk)
+		tp.Hostname = hostname
+		return &api.Payload{TracerPayload: tp, Source: info.NewReceiverStats().GetTagStats(info.Tags{})}
+	}
+
+	// the erroneous child chunk is flushed by the tracer before the root chunk
+	agnt.Process(payload("host-a", span(2, 1, 1)))
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+
+	// the whole trace is kept once the root is received, even though the root chunk holds no error
+	agnt.Process(payload("host-b", span(1, 0, 0)))
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 2)
+	for i, hostname := range []string{"host-a", "host-b"} {
+		assert.Equal(t, hostname, payloads[i].TracerPayload.Hostname)
+		require.Len(t, payloads[i].TracerPayload.Chunks, 1)
+		assert.False(t, payloads[i].TracerPayload.Chunks[0].DroppedTrace)
+		assert.Equal(t, int64(1), payloads[i].SpanCount)
+	}
+}
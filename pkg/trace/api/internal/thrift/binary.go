@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package thrift implements a minimal decoder for the Apache Thrift binary protocol, sufficient
+// for reading payloads sent by third-party tracing clients without depending on generated code.
+package thrift
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type specifies the type of a Thrift value, as encoded on the wire.
+type Type byte
+
+// Thrift wire types.
+const (
+	Stop   Type = 0
+	Bool   Type = 2
+	Byte   Type = 3
+	Double Type = 4
+	I16    Type = 6
+	I32    Type = 8
+	I64    Type = 10
+	String Type = 11
+	Struct Type = 12
+	Map    Type = 13
+	Set    Type = 14
+	List   Type = 15
+)
+
+// maxSkipDepth limits the nesting of values skipped by the reader, protecting against
+// maliciously deep payloads.
+const maxSkipDepth = 64
+
+var (
+	// ErrShortBuffer is returned when the payload ends in the middle of a value.
+	ErrShortBuffer = errors.New("thrift: unexpected end of payload")
+
+	// errDepthExceeded is returned when skipping values nested too deeply.
+	errDepthExceeded = errors.New("thrift: maximum nesting depth exceeded")
+)
+
+// Reader reads values encoded with the Thrift binary protocol from a byte slice. Errors are
+// sticky: once an error occurs, all subsequent reads return zero values and Err reports it.
+type Reader struct {
+	b   []byte
+	err error
+}
+
+// NewReader returns a new Reader reading from b.
+func NewReader(b []byte) *Reader {
+	return &Reader{b: b}
+}
+
+// Err returns the first error encountered by the reader.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = ErrShortBuffer
+		r.b = nil
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+// ReadFieldHeader reads the header of the next field of a struct. It returns Stop once the end
+// of the struct has been reached, or on error.
+func (r *Reader) ReadFieldHeader() (typ Type, id int16) {
+	typ = Type(r.readByte())
+	if typ == Stop || r.err != nil {
+		return Stop, 0
+	}
+	return typ, r.ReadI16()
+}
+
+// ReadListHeader reads the header of a list or set, returning the type of its elements and
+// their count.
+func (r *Reader) ReadListHeader() (elem Type, size int) {
+	elem = Type(r.readByte())
+	size = int(r.ReadI32())
+	if r.err == nil && (size < 0 || size > len(r.b)) {
+		// every element takes at least one byte; reject sizes which can't possibly fit
+		r.err = fmt.Errorf("thrift: invalid collection size %d", size)
+	}
+	if r.err != nil {
+		return Stop, 0
+	}
+	return elem, size
+}
+
+// ReadMapHeader reads the header of a map, returning the type of its keys and values along with
+// the number of entries.
+func (r *Reader) ReadMapHeader() (key, value Type, size int) {
+	key = Type(r.readByte())
+	value, size = r.ReadListHeader()
+	return key, value, size
+}
+
+// ReadBool reads a boolean.
+func (r *Reader) ReadBool() bool {
+	return r.readByte() != 0
+}
+
+// ReadI8 reads an 8-bit integer.
+func (r *Reader) ReadI8() int8 {
+	return int8(r.readByte())
+}
+
+func (r *Reader) readByte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// ReadI16 reads a 16-bit integer.
+func (r *Reader) ReadI16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+// ReadI32 reads a 32-bit integer.
+func (r *Reader) ReadI32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+// ReadI64 reads a 64-bit integer.
+func (r *Reader) ReadI64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// ReadDouble reads a 64-bit floating point number.
+func (r *Reader) ReadDouble() float64 {
+	return math.Float64frombits(uint64(r.ReadI64()))
+}
+
+// ReadBinary reads a length-prefixed byte sequence. The returned slice aliases the reader's
+// buffer.
+func (r *Reader) ReadBinary() []byte {
+	return r.next(int(r.ReadI32()))
+}
+
+// ReadString reads a length-prefixed string.
+func (r *Reader) ReadString() string {
+	return string(r.ReadBinary())
+}
+
+// Skip reads and discards a value of type typ.
+func (r *Reader) Skip(typ Type) {
+	r.skip(typ, 0)
+}
+
+func (r *Reader) skip(typ Type, depth int) {
+	if depth > maxSkipDepth {
+		r.err = errDepthExceeded
+		return
+	}
+	switch typ {
+	case Bool, Byte:
+		r.next(1)
+	case I16:
+		r.next(2)
+	case I32:
+		r.next(4)
+	case Double, I64:
+		r.next(8)
+	case String:
+		r.ReadBinary()
+	case Struct:
+		for {
+			ftyp, _ := r.ReadFieldHeader()
+			if ftyp == Stop {
+				return
+			}
+			r.skip(ftyp, depth+1)
+		}
+	case Map:
+		ktyp, vtyp, n := r.ReadMapHeader()
+		for i := 0; i < n && r.err == nil; i++ {
+			r.skip(ktyp, depth+1)
+			r.skip(vtyp, depth+1)
+		}
+	case Set, List:
+		etyp, n := r.ReadListHeader()
+		for i := 0; i < n && r.err == nil; i++ {
+			r.skip(etyp, depth+1)
+		}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("thrift: unknown type %d", typ)
+		}
+	}
+}
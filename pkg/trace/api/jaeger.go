@@ -0,0 +1,533 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/thrift"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const (
+	// jaegerFlagDebug is the bit set in Jaeger span flags for debug (force-sampled) spans.
+	jaegerFlagDebug = 2
+
+	// jaegerRefChildOf is the Jaeger reference type pointing to a span's parent.
+	jaegerRefChildOf = 0
+)
+
+// jaegerProcess holds the process information shared by all spans of a Jaeger batch.
+type jaegerProcess struct {
+	service string
+	tags    map[string]string
+}
+
+// decodeJaegerSpans decodes a Jaeger collector batch, encoded either with the Thrift binary
+// protocol or as protobuf depending on the request's Content-Type, into Datadog spans.
+func decodeJaegerSpans(req *http.Request) ([]*pb.Span, error) {
+	mt := getMediaType(req)
+	switch mt {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary",
+		"application/x-protobuf", "application/protobuf":
+	default:
+		return nil, fmt.Errorf("unsupported media type: %q", mt)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := copyRequestBody(buf, req); err != nil {
+		return nil, err
+	}
+	switch mt {
+	case "application/x-protobuf", "application/protobuf":
+		return unmarshalJaegerProto(buf.Bytes())
+	default:
+		return unmarshalJaegerThrift(buf.Bytes())
+	}
+}
+
+// newJaegerSpan returns a Datadog span with the fields common to both Jaeger encodings.
+func newJaegerSpan(traceIDLow, traceIDHigh, spanID, parentID uint64, name string, start, duration int64) *pb.Span {
+	s := &pb.Span{
+		Name:     name,
+		TraceID:  traceIDLow,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    start,
+		Duration: duration,
+		Meta:     make(map[string]string),
+		Metrics:  make(map[string]float64),
+	}
+	setTraceIDUpper(s, traceIDHigh)
+	return s
+}
+
+// jaegerTag is a decoded Jaeger tag. The value is one of string, bool, []byte, int64 or float64.
+type jaegerTag struct {
+	key   string
+	value interface{}
+}
+
+// String returns the tag's value as a string.
+func (t jaegerTag) String() string {
+	if s, ok := t.value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(t.value)
+}
+
+// setJaegerTag sets the Jaeger tag k on span s. Numeric values are set as metrics, except for
+// the HTTP status code which Datadog expects as a string.
+func setJaegerTag(s *pb.Span, k string, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		s.Meta[k] = v
+	case bool:
+		s.Meta[k] = strconv.FormatBool(v)
+	case []byte:
+		s.Meta[k] = base64.StdEncoding.EncodeToString(v)
+	case int64:
+		if k == "http.status_code" {
+			s.Meta[k] = strconv.FormatInt(v, 10)
+			return
+		}
+		s.Metrics[k] = float64(v)
+	case float64:
+		s.Metrics[k] = v
+	}
+}
+
+// completeJaegerSpan applies the process information and flags to s, and infers the remaining
+// Datadog fields.
+func completeJaegerSpan(s *pb.Span, p *jaegerProcess, flags int64) {
+	if p != nil {
+		s.Service = p.service
+		for k, v := range p.tags {
+			if _, ok := s.Meta[k]; !ok {
+				s.Meta[k] = v
+			}
+		}
+	}
+	if flags&jaegerFlagDebug != 0 {
+		s.Metrics["_sampling_priority_v1"] = float64(sampler.PriorityUserKeep)
+	}
+	kind := s.Meta["span.kind"]
+	delete(s.Meta, "span.kind")
+	completeThirdPartySpan(s, kind)
+}
+
+// unmarshalJaegerThrift decodes a Thrift-encoded jaeger.Batch.
+// See https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift
+func unmarshalJaegerThrift(b []byte) ([]*pb.Span, error) {
+	r := thrift.NewReader(b)
+	var (
+		process jaegerProcess
+		spans   []*pb.Span
+		flags   []int64
+	)
+	for {
+		typ, id := r.ReadFieldHeader()
+		if typ == thrift.Stop {
+			break
+		}
+		switch {
+		case id == 1 && typ == thrift.Struct:
+			readJaegerThriftProcess(r, &process)
+		case id == 2 && typ == thrift.List:
+			_, n := r.ReadListHeader()
+			for i := 0; i < n && r.Err() == nil; i++ {
+				s, f := readJaegerThriftSpan(r)
+				spans = append(spans, s)
+				flags = append(flags, f)
+			}
+		default:
+			r.Skip(typ)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	for i, s := range spans {
+		completeJaegerSpan(s, &process, flags[i])
+	}
+	return spans, nil
+}
+
+func readJaegerThriftProcess(r *thrift.Reader, p *jaegerProcess) {
+	for {
+		typ, id := r.ReadFieldHeader()
+		if typ == thrift.Stop {
+			return
+		}
+		switch {
+		case id == 1 && typ == thrift.String:
+			p.service = r.ReadString()
+		case id == 2 && typ == thrift.List:
+			_, n := r.ReadListHeader()
+			p.tags = make(map[string]string, n)
+			for i := 0; i < n && r.Err() == nil; i++ {
+				t := readJaegerThriftTag(r)
+				p.tags[t.key] = t.String()
+			}
+		default:
+			r.Skip(typ)
+		}
+	}
+}
+
+func readJaegerThriftSpan(r *thrift.Reader) (s *pb.Span, flags int64) {
+	var (
+		traceIDLow, traceIDHigh, spanID, parentID int64
+		refParentID                               int64
+		name                                      string
+		start, duration                           int64
+		tags                                      []jaegerTag
+	)
+	for {
+		typ, id := r.ReadFieldHeader()
+		if typ == thrift.Stop {
+			break
+		}
+		switch {
+		case id == 1 && typ == thrift.I64:
+			traceIDLow = r.ReadI64()
+		case id == 2 && typ == thrift.I64:
+			traceIDHigh = r.ReadI64()
+		case id == 3 && typ == thrift.I64:
+			spanID = r.ReadI64()
+		case id == 4 && typ == thrift.I64:
+			parentID = r.ReadI64()
+		case id == 5 && typ == thrift.String:
+			name = r.ReadString()
+		case id == 6 && typ == thrift.List:
+			_, n := r.ReadListHeader()
+			for i := 0; i < n && r.Err() == nil; i++ {
+				if refType, sid := readJaegerThriftSpanRef(r); refType == jaegerRefChildOf && refParentID == 0 {
+					refParentID = sid
+				}
+			}
+		case id == 7 && typ == thrift.I32:
+			flags = int64(r.ReadI32())
+		case id == 8 && typ == thrift.I64:
+			start = r.ReadI64()
+		case id == 9 && typ == thrift.I64:
+			duration = r.ReadI64()
+		case id == 10 && typ == thrift.List:
+			_, n := r.ReadListHeader()
+			for i := 0; i < n && r.Err() == nil; i++ {
+				tags = append(tags, readJaegerThriftTag(r))
+			}
+		default:
+			r.Skip(typ)
+		}
+	}
+	if parentID == 0 {
+		parentID = refParentID
+	}
+	// Jaeger timestamps and durations are expressed in microseconds
+	s = newJaegerSpan(uint64(traceIDLow), uint64(traceIDHigh), uint64(spanID), uint64(parentID), name, start*1000, duration*1000)
+	for _, t := range tags {
+		setJaegerTag(s, t.key, t.value)
+	}
+	return s, flags
+}
+
+func readJaegerThriftSpanRef(r *thrift.Reader) (refType int32, spanID int64) {
+	for {
+		typ, id := r.ReadFieldHeader()
+		if typ == thrift.Stop {
+			return refType, spanID
+		}
+		switch {
+		case id == 1 && typ == thrift.I32:
+			refType = r.ReadI32()
+		case id == 4 && typ == thrift.I64:
+			spanID = r.ReadI64()
+		default:
+			r.Skip(typ)
+		}
+	}
+}
+
+// readJaegerThriftTag reads a jaeger.Tag.
+func readJaegerThriftTag(r *thrift.Reader) jaegerTag {
+	var (
+		t     jaegerTag
+		vtype int32
+		str   string
+		dbl   float64
+		bl    bool
+		lng   int64
+		bin   []byte
+	)
+	for {
+		typ, id := r.ReadFieldHeader()
+		if typ == thrift.Stop {
+			break
+		}
+		switch {
+		case id == 1 && typ == thrift.String:
+			t.key = r.ReadString()
+		case id == 2 && typ == thrift.I32:
+			vtype = r.ReadI32()
+		case id == 3 && typ == thrift.String:
+			str = r.ReadString()
+		case id == 4 && typ == thrift.Double:
+			dbl = r.ReadDouble()
+		case id == 5 && typ == thrift.Bool:
+			bl = r.ReadBool()
+		case id == 6 && typ == thrift.I64:
+			lng = r.ReadI64()
+		case id == 7 && typ == thrift.String:
+			bin = append([]byte(nil), r.ReadBinary()...)
+		default:
+			r.Skip(typ)
+		}
+	}
+	// TagType: STRING = 0, DOUBLE = 1, BOOL = 2, LONG = 3, BINARY = 4
+	switch vtype {
+	case 0:
+		t.value = str
+	case 1:
+		t.value = dbl
+	case 2:
+		t.value = bl
+	case 3:
+		t.value = lng
+	case 4:
+		t.value = bin
+	}
+	return t
+}
+
+// unmarshalJaegerProto decodes a protobuf-encoded jaeger.api_v2.PostSpansRequest, as sent to the
+// Jaeger collector's CollectorService.
+// See https://github.com/jaegertracing/jaeger-idl/blob/main/proto/api_v2/collector.proto
+func unmarshalJaegerProto(b []byte) ([]*pb.Span, error) {
+	var spans []*pb.Span
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, _ uint64, v []byte) error {
+		if num != 1 {
+			return nil
+		}
+		if typ != protowire.BytesType {
+			return errProtoFieldType
+		}
+		batch, err := unmarshalJaegerProtoBatch(v)
+		spans = append(spans, batch...)
+		return err
+	})
+	return spans, err
+}
+
+// jaegerProtoSpan holds a decoded jaeger.api_v2.Span along with the values which can only be
+// applied once the whole batch has been read.
+type jaegerProtoSpan struct {
+	span    *pb.Span
+	process *jaegerProcess
+	flags   int64
+}
+
+func unmarshalJaegerProtoBatch(b []byte) ([]*pb.Span, error) {
+	var (
+		batchProcess jaegerProcess
+		pspans       []jaegerProtoSpan
+	)
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, _ uint64, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // spans
+			ps, err := unmarshalJaegerProtoSpan(v)
+			if err != nil {
+				return err
+			}
+			pspans = append(pspans, ps)
+		case 2: // process
+			return unmarshalJaegerProtoProcess(v, &batchProcess)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	spans := make([]*pb.Span, 0, len(pspans))
+	for _, ps := range pspans {
+		p := ps.process
+		if p == nil {
+			p = &batchProcess
+		}
+		completeJaegerSpan(ps.span, p, ps.flags)
+		spans = append(spans, ps.span)
+	}
+	return spans, nil
+}
+
+func unmarshalJaegerProtoSpan(b []byte) (jaegerProtoSpan, error) {
+	var (
+		ps              jaegerProtoSpan
+		traceID, spanID []byte
+		parentID        uint64
+		name            string
+		start, duration int64
+		tags            []jaegerTag
+	)
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, u uint64, v []byte) error {
+		switch num {
+		case 1: // trace_id
+			traceID = v
+		case 2: // span_id
+			spanID = v
+		case 3: // operation_name
+			name = string(v)
+		case 4: // references
+			var (
+				refType uint64
+				sid     []byte
+			)
+			if err := rangeProtoFields(v, func(num protowire.Number, _ protowire.Type, u uint64, v []byte) error {
+				switch num {
+				case 2:
+					sid = v
+				case 3:
+					refType = u
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if refType == jaegerRefChildOf && parentID == 0 && len(sid) == 8 {
+				parentID = binary.BigEndian.Uint64(sid)
+			}
+		case 5: // flags
+			ps.flags = int64(u)
+		case 6: // start_time
+			secs, nanos, err := unmarshalProtoTimestamp(v)
+			if err != nil {
+				return err
+			}
+			start = secs*int64(time.Second) + nanos
+		case 7: // duration
+			secs, nanos, err := unmarshalProtoTimestamp(v)
+			if err != nil {
+				return err
+			}
+			duration = secs*int64(time.Second) + nanos
+		case 8: // tags
+			t, err := unmarshalJaegerProtoKeyValue(v)
+			if err != nil {
+				return err
+			}
+			tags = append(tags, t)
+		case 10: // process
+			if typ != protowire.BytesType {
+				return errProtoFieldType
+			}
+			ps.process = &jaegerProcess{}
+			return unmarshalJaegerProtoProcess(v, ps.process)
+		}
+		return nil
+	})
+	if err != nil {
+		return ps, err
+	}
+	if len(traceID) != 16 {
+		return ps, fmt.Errorf("invalid trace ID length %d", len(traceID))
+	}
+	if len(spanID) != 8 {
+		return ps, fmt.Errorf("invalid span ID length %d", len(spanID))
+	}
+	traceIDHigh := binary.BigEndian.Uint64(traceID[:8])
+	traceIDLow := binary.BigEndian.Uint64(traceID[8:])
+	ps.span = newJaegerSpan(traceIDLow, traceIDHigh, binary.BigEndian.Uint64(spanID), parentID, name, start, duration)
+	for _, t := range tags {
+		setJaegerTag(ps.span, t.key, t.value)
+	}
+	return ps, nil
+}
+
+// unmarshalJaegerProtoKeyValue decodes a jaeger.api_v2.KeyValue.
+func unmarshalJaegerProtoKeyValue(b []byte) (jaegerTag, error) {
+	var (
+		kv    jaegerTag
+		vtype uint64
+		str   string
+		bin   []byte
+		num   uint64
+	)
+	err := rangeProtoFields(b, func(n protowire.Number, _ protowire.Type, u uint64, v []byte) error {
+		switch n {
+		case 1:
+			kv.key = string(v)
+		case 2:
+			vtype = u
+		case 3:
+			str = string(v)
+		case 4, 5, 6:
+			num = u
+		case 7:
+			bin = v
+		}
+		return nil
+	})
+	// ValueType: STRING = 0, BOOL = 1, INT64 = 2, FLOAT64 = 3, BINARY = 4
+	switch vtype {
+	case 0:
+		kv.value = str
+	case 1:
+		kv.value = num != 0
+	case 2:
+		kv.value = int64(num)
+	case 3:
+		kv.value = math.Float64frombits(num)
+	case 4:
+		kv.value = append([]byte(nil), bin...)
+	}
+	return kv, err
+}
+
+func unmarshalJaegerProtoProcess(b []byte, p *jaegerProcess) error {
+	return rangeProtoFields(b, func(num protowire.Number, _ protowire.Type, _ uint64, v []byte) error {
+		switch num {
+		case 1:
+			p.service = string(v)
+		case 2:
+			kv, err := unmarshalJaegerProtoKeyValue(v)
+			if err != nil {
+				return err
+			}
+			if p.tags == nil {
+				p.tags = make(map[string]string)
+			}
+			p.tags[kv.key] = kv.String()
+		}
+		return nil
+	})
+}
+
+// unmarshalProtoTimestamp decodes a google.protobuf.Timestamp or google.protobuf.Duration, which
+// share the same wire representation.
+func unmarshalProtoTimestamp(b []byte) (secs, nanos int64, err error) {
+	err = rangeProtoFields(b, func(num protowire.Number, _ protowire.Type, u uint64, _ []byte) error {
+		switch num {
+		case 1:
+			secs = int64(u)
+		case 2:
+			nanos = int64(int32(u))
+		}
+		return nil
+	})
+	return secs, nanos, err
+}
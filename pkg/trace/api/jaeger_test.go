@@ -0,0 +1,311 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/thrift"
+)
+
+// thriftWriter encodes values using the Thrift binary protocol, for building test payloads.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ thrift.Type, id int16) {
+	w.WriteByte(byte(typ))
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(byte(thrift.Stop)) }
+
+func (w *thriftWriter) list(elem thrift.Type, n int) {
+	w.WriteByte(byte(elem))
+	binary.Write(w, binary.BigEndian, int32(n)) //nolint:errcheck
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thrift.I32, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thrift.I64, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(thrift.String, id)
+	binary.Write(w, binary.BigEndian, int32(len(v))) //nolint:errcheck
+	w.WriteString(v)
+}
+
+// tag writes a jaeger.Tag struct; v must be a string, bool, int64 or float64.
+func (w *thriftWriter) tag(k string, v interface{}) {
+	w.str(1, k)
+	switch v := v.(type) {
+	case string:
+		w.i32(2, 0)
+		w.str(3, v)
+	case float64:
+		w.i32(2, 1)
+		w.field(thrift.Double, 4)
+		binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+	case bool:
+		w.i32(2, 2)
+		w.field(thrift.Bool, 5)
+		if v {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case int64:
+		w.i32(2, 3)
+		w.i64(6, v)
+	}
+	w.stop()
+}
+
+func jaegerThriftBatch() []byte {
+	var w thriftWriter
+	// Batch.process
+	w.field(thrift.Struct, 1)
+	w.str(1, "checkout")
+	w.field(thrift.List, 2)
+	w.list(thrift.Struct, 2)
+	w.tag("hostname", "host-a")
+	w.tag("version", "1.2.3")
+	w.stop()
+
+	// Batch.spans
+	w.field(thrift.List, 2)
+	w.list(thrift.Struct, 2)
+
+	w.i64(1, 0x2a)
+	w.i64(2, 0x1)
+	w.i64(3, 0x10)
+	w.i64(4, 0)
+	w.str(5, "HTTP GET")
+	w.i32(7, jaegerFlagDebug|1)
+	w.i64(8, 1700000000000000)
+	w.i64(9, 2000)
+	w.field(thrift.List, 10)
+	w.list(thrift.Struct, 5)
+	w.tag("span.kind", "server")
+	w.tag("http.method", "GET")
+	w.tag("http.status_code", int64(200))
+	w.tag("retry", true)
+	w.tag("version", "2.0.0")
+	// unknown fields are skipped
+	w.str(99, "ignored")
+	w.stop()
+
+	w.i64(1, 0x2a)
+	w.i64(2, 0x1)
+	w.i64(3, 0x11)
+	w.i64(4, 0)
+	w.field(thrift.List, 6)
+	w.list(thrift.Struct, 1)
+	w.i32(1, jaegerRefChildOf)
+	w.i64(2, 0x2a)
+	w.i64(3, 0x1)
+	w.i64(4, 0x10)
+	w.stop()
+	w.str(5, "SELECT")
+	w.i64(8, 1700000000000500)
+	w.i64(9, 300)
+	w.field(thrift.List, 10)
+	w.list(thrift.Struct, 3)
+	w.tag("span.kind", "client")
+	w.tag("db.type", "redis")
+	w.tag("db.rows", 1.5)
+	w.stop()
+
+	w.stop()
+	return w.Bytes()
+}
+
+func TestJaegerThrift(t *testing.T) {
+	spans, err := unmarshalJaegerThrift(jaegerThriftBatch())
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+
+	s := spans[0]
+	assert.Equal(t, uint64(0x2a), s.TraceID)
+	assert.Equal(t, uint64(0x10), s.SpanID)
+	assert.Equal(t, uint64(0), s.ParentID)
+	assert.Equal(t, "checkout", s.Service)
+	assert.Equal(t, "HTTP GET", s.Name)
+	assert.Equal(t, "GET", s.Resource)
+	assert.Equal(t, "web", s.Type)
+	assert.Equal(t, int64(1700000000000000000), s.Start)
+	assert.Equal(t, int64(2000000), s.Duration)
+	assert.Equal(t, "0000000000000001", s.Meta["_dd.p.tid"])
+	assert.Equal(t, "server", s.Meta["span.kind"])
+	assert.Equal(t, "200", s.Meta["http.status_code"])
+	assert.Equal(t, "true", s.Meta["retry"])
+	assert.Equal(t, "host-a", s.Meta["hostname"])
+	assert.Equal(t, "2.0.0", s.Meta["version"], "span tags take precedence over process tags")
+	assert.EqualValues(t, 2, s.Metrics["_sampling_priority_v1"])
+
+	s = spans[1]
+	assert.Equal(t, uint64(0x11), s.SpanID)
+	assert.Equal(t, uint64(0x10), s.ParentID, "parent is taken from the CHILD_OF reference")
+	assert.Equal(t, "redis", s.Type)
+	assert.Equal(t, "client", s.Meta["span.kind"])
+	assert.Equal(t, 1.5, s.Metrics["db.rows"])
+	assert.Equal(t, "1.2.3", s.Meta["version"])
+	assert.NotContains(t, s.Metrics, "_sampling_priority_v1")
+}
+
+func TestJaegerThriftErrors(t *testing.T) {
+	b := jaegerThriftBatch()
+	for i := 1; i < len(b); i += 7 {
+		_, err := unmarshalJaegerThrift(b[:i])
+		assert.Error(t, err, "truncated at %d", i)
+	}
+
+	var w thriftWriter
+	w.field(thrift.List, 2)
+	w.list(thrift.Struct, math.MaxInt32)
+	_, err := unmarshalJaegerThrift(w.Bytes())
+	assert.Error(t, err)
+}
+
+func appendJaegerProtoKeyValue(b []byte, num protowire.Number, k, v string) []byte {
+	var kv []byte
+	kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+	kv = protowire.AppendString(kv, k)
+	kv = protowire.AppendTag(kv, 3, protowire.BytesType)
+	kv = protowire.AppendString(kv, v)
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, kv)
+}
+
+func jaegerProtoRequest() []byte {
+	id := func(n ...uint64) []byte {
+		var b []byte
+		for _, v := range n {
+			b = binary.BigEndian.AppendUint64(b, v)
+		}
+		return b
+	}
+	var ts []byte
+	ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, 1700000000)
+	ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, 5)
+	var dur []byte
+	dur = protowire.AppendTag(dur, 2, protowire.VarintType)
+	dur = protowire.AppendVarint(dur, 1500)
+	var ref []byte
+	ref = protowire.AppendTag(ref, 1, protowire.BytesType)
+	ref = protowire.AppendBytes(ref, id(0, 7))
+	ref = protowire.AppendTag(ref, 2, protowire.BytesType)
+	ref = protowire.AppendBytes(ref, id(3))
+	var process []byte
+	process = protowire.AppendTag(process, 1, protowire.BytesType)
+	process = protowire.AppendString(process, "inventory")
+
+	var span []byte
+	span = protowire.AppendTag(span, 1, protowire.BytesType)
+	span = protowire.AppendBytes(span, id(0, 7))
+	span = protowire.AppendTag(span, 2, protowire.BytesType)
+	span = protowire.AppendBytes(span, id(4))
+	span = protowire.AppendTag(span, 3, protowire.BytesType)
+	span = protowire.AppendString(span, "lookup")
+	span = protowire.AppendTag(span, 4, protowire.BytesType)
+	span = protowire.AppendBytes(span, ref)
+	span = protowire.AppendTag(span, 6, protowire.BytesType)
+	span = protowire.AppendBytes(span, ts)
+	span = protowire.AppendTag(span, 7, protowire.BytesType)
+	span = protowire.AppendBytes(span, dur)
+	span = appendJaegerProtoKeyValue(span, 8, "span.kind", "consumer")
+
+	var batch []byte
+	batch = protowire.AppendTag(batch, 1, protowire.BytesType)
+	batch = protowire.AppendBytes(batch, span)
+	batch = protowire.AppendTag(batch, 2, protowire.BytesType)
+	batch = protowire.AppendBytes(batch, appendJaegerProtoKeyValue(process, 2, "region", "eu"))
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	return protowire.AppendBytes(req, batch)
+}
+
+func TestJaegerProto(t *testing.T) {
+	spans, err := unmarshalJaegerProto(jaegerProtoRequest())
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+
+	s := spans[0]
+	assert.Equal(t, uint64(7), s.TraceID)
+	assert.Equal(t, uint64(4), s.SpanID)
+	assert.Equal(t, uint64(3), s.ParentID)
+	assert.Equal(t, "inventory", s.Service)
+	assert.Equal(t, "lookup", s.Resource)
+	assert.Equal(t, "custom", s.Type)
+	assert.Equal(t, int64(1700000000000000005), s.Start)
+	assert.Equal(t, int64(1500), s.Duration)
+	assert.Equal(t, "consumer", s.Meta["span.kind"])
+	assert.Equal(t, "eu", s.Meta["region"])
+	assert.NotContains(t, s.Meta, "_dd.p.tid")
+}
+
+func TestJaegerProtoBatches(t *testing.T) {
+	// a message field repeated on the wire holds several batches, whose spans are all kept
+	spans, err := unmarshalJaegerProto(append(jaegerProtoRequest(), jaegerProtoRequest()...))
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Equal(t, uint64(4), s.SpanID)
+		assert.Equal(t, "inventory", s.Service)
+	}
+}
+
+func TestJaegerEndpoint(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.JaegerReceiverEnabled = true
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	for ct, body := range map[string][]byte{
+		"application/x-thrift":   jaegerThriftBatch(),
+		"application/x-protobuf": jaegerProtoRequest(),
+	} {
+		t.Run(ct, func(t *testing.T) {
+			resp, err := http.Post(server.URL+"/api/traces", ct, bytes.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+			select {
+			case p := <-r.out:
+				require.Len(t, p.Chunks(), 1)
+				assert.Equal(t, string(jaegerV1), p.Source.EndpointVersion)
+			case <-time.After(time.Second):
+				t.Fatal("no payload received")
+			}
+		})
+	}
+
+	resp, err := http.Post(server.URL+"/api/traces", "application/json", bytes.NewBufferString("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// tagTraceIDUpper specifies the span tag holding the upper 64 bits of a 128-bit trace ID,
+// hex-encoded.
+const tagTraceIDUpper = "_dd.p.tid"
+
+// spanDecoder decodes the body of req, in a third-party tracing format, into a list of
+// Datadog spans. The spans do not need to be grouped by trace.
+type spanDecoder func(req *http.Request) ([]*pb.Span, error)
+
+// handleThirdPartyTraces returns an http.Handler which accepts trace payloads from third-party
+// tracing clients (e.g. Zipkin, Jaeger), decodes them into Datadog spans using decode, and passes
+// them down the same pipeline as native payloads.
+func (r *HTTPReceiver) handleThirdPartyTraces(v Version, decode spanDecoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Header.Get("Sec-Fetch-Site") == "cross-site" {
+			http.Error(w, "cross-site request rejected", http.StatusForbidden)
+			return
+		}
+		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
+		defer req.Body.Close()
+
+		select {
+		case r.recvsem <- struct{}{}:
+		case <-time.After(time.Duration(r.conf.DecoderTimeout) * time.Millisecond):
+			log.Debugf("trace-agent is overwhelmed, a %s payload has been rejected", v)
+			io.Copy(io.Discard, req.Body) //nolint:errcheck
+			w.WriteHeader(http.StatusTooManyRequests)
+			r.tagStats(v, req.Header, "").PayloadRefused.Inc()
+			return
+		}
+		defer func() {
+			<-r.recvsem
+		}()
+
+		start := time.Now()
+		spans, err := decode(req)
+		var service string
+		if len(spans) > 0 {
+			service = spans[0].Service
+		}
+		ts := r.tagStats(v, req.Header, service)
+		defer func(err error) {
+			tags := append(ts.AsTags(), fmt.Sprintf("success:%v", err == nil))
+			_ = r.statsd.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+		}(err)
+		if err != nil {
+			httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w, r.statsd)
+			switch err {
+			case apiutil.ErrLimitedReaderLimitReached:
+				ts.TracesDropped.PayloadTooLarge.Inc()
+			case io.EOF, io.ErrUnexpectedEOF:
+				ts.TracesDropped.EOF.Inc()
+			default:
+				if err, ok := err.(net.Error); ok && err.Timeout() {
+					ts.TracesDropped.Timeout.Inc()
+				} else {
+					ts.TracesDropped.DecodingError.Inc()
+				}
+			}
+			log.Errorf("Cannot decode %s traces payload: %v", v, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		tp := &pb.TracerPayload{
+			LanguageName:    req.Header.Get(header.Lang),
+			LanguageVersion: req.Header.Get(header.LangVersion),
+			ContainerID:     r.containerIDProvider.GetContainerID(req.Context(), req.Header),
+			Chunks:          traceChunksFromSpans(spans),
+			TracerVersion:   req.Header.Get(header.TracerVersion),
+		}
+		ts.TracesReceived.Add(int64(len(tp.Chunks)))
+		ts.TracesBytes.Add(req.Body.(*apiutil.LimitedReader).Count)
+		ts.PayloadAccepted.Inc()
+
+		if ctags := getContainerTags(r.conf.ContainerTags, tp.ContainerID); ctags != "" {
+			tp.Tags = map[string]string{tagContainersTags: ctags}
+		}
+		r.out <- &Payload{
+			Source:        ts,
+			TracerPayload: tp,
+		}
+	})
+}
+
+// parseHexTraceID parses a hex-encoded trace ID of up to 128 bits, returning its lower and
+// upper 64 bits.
+func parseHexTraceID(s string) (low, high uint64, err error) {
+	if len(s) == 0 || len(s) > 32 {
+		return 0, 0, fmt.Errorf("invalid trace ID %q", s)
+	}
+	if len(s) > 16 {
+		if high, err = strconv.ParseUint(s[:len(s)-16], 16, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid trace ID %q: %v", s, err)
+		}
+		s = s[len(s)-16:]
+	}
+	if low, err = strconv.ParseUint(s, 16, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid trace ID %q: %v", s, err)
+	}
+	return low, high, nil
+}
+
+// parseHexSpanID parses a hex-encoded 64-bit span ID. An empty string yields a zero ID.
+func parseHexSpanID(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if len(s) > 16 {
+		return 0, fmt.Errorf("invalid span ID %q", s)
+	}
+	id, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid span ID %q: %v", s, err)
+	}
+	return id, nil
+}
+
+// setTraceIDUpper records the upper 64 bits of a 128-bit trace ID on span s, if set.
+func setTraceIDUpper(s *pb.Span, high uint64) {
+	if high == 0 {
+		return
+	}
+	s.Meta[tagTraceIDUpper] = fmt.Sprintf("%016x", high)
+}
+
+// completeThirdPartySpan fills in the Datadog-specific fields of s which third-party formats have
+// no notion of, based on the span kind and the tags found on it. s.Name must be set to the span's
+// operation name and s.Meta must be non-nil.
+func completeThirdPartySpan(s *pb.Span, kind string) {
+	if kind != "" {
+		s.Meta["span.kind"] = kind
+	}
+	if v, ok := s.Meta["error"]; ok && v != "false" {
+		// Zipkin and OpenTracing based clients report errors through the "error" tag,
+		// which sometimes holds the error message instead of a boolean.
+		s.Error = 1
+		if v != "true" && v != "" {
+			if _, ok := s.Meta["error.msg"]; !ok {
+				s.Meta["error.msg"] = v
+			}
+		}
+		delete(s.Meta, "error")
+	}
+	s.Resource = s.Name
+	if method := s.Meta["http.method"]; method != "" {
+		if route := s.Meta["http.route"]; route != "" {
+			s.Resource = method + " " + route
+		} else if kind == "server" {
+			s.Resource = method
+		}
+	}
+	s.Type = thirdPartySpanType(kind, s.Meta)
+}
+
+// thirdPartySpanType infers a Datadog span type from the span kind and tags.
+func thirdPartySpanType(kind string, meta map[string]string) string {
+	dbSystem := meta["db.system"]
+	if dbSystem == "" {
+		dbSystem = meta["db.type"]
+	}
+	switch {
+	case dbSystem != "":
+		switch strings.ToLower(dbSystem) {
+		case "redis":
+			return "redis"
+		case "memcached":
+			return "memcached"
+		case "mongodb":
+			return "mongodb"
+		case "cassandra":
+			return "cassandra"
+		case "elasticsearch":
+			return "elasticsearch"
+		default:
+			return "db"
+		}
+	case meta["http.method"] != "" || meta["http.url"] != "" || meta["http.path"] != "":
+		if kind == "client" {
+			return "http"
+		}
+		return "web"
+	case kind == "server":
+		return "web"
+	case kind == "client":
+		return "http"
+	default:
+		return "custom"
+	}
+}
+
+// errProtoFieldType is returned when a protobuf field is encoded using an unexpected wire type.
+var errProtoFieldType = errors.New("unexpected protobuf wire type")
+
+// rangeProtoFields calls fn for each field of the protobuf-encoded message b. Depending on the
+// field's wire type, its value is passed either as u (varint and fixed-size types) or as v
+// (length-delimited types). It stops at the first error.
+func rangeProtoFields(b []byte, fn func(num protowire.Number, typ protowire.Type, u uint64, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var (
+			u uint64
+			v []byte
+		)
+		switch typ {
+		case protowire.VarintType:
+			u, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			u, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var u32 uint32
+			u32, n = protowire.ConsumeFixed32(b)
+			u = uint64(u32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, typ, u, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -127,4 +127,25 @@ const (
 	// Response: Service sampling rates (see description in v04).
 	//
 	V07 Version = "v0.7"
+
+	// zipkinV2 API
+	//
+	// Request: Zipkin v2 spans.
+	// 	Content-Type: application/json or application/x-protobuf
+	// 	Payload: A list of Zipkin v2 spans (https://github.com/openzipkin/zipkin-api)
+	//
+	// Response: 202 Accepted, without a body.
+	//
+	zipkinV2 Version = "zipkin_v2"
+
+	// jaegerV1 API
+	//
+	// Request: Jaeger collector batch.
+	// 	Content-Type: application/x-thrift or application/x-protobuf
+	// 	Payload: A jaeger.Batch encoded with the Thrift binary protocol, or a
+	// 	jaeger.api_v2.PostSpansRequest encoded as protobuf (https://github.com/jaegertracing/jaeger-idl)
+	//
+	// Response: 202 Accepted, without a body.
+	//
+	jaegerV1 Version = "jaeger_v1"
 )
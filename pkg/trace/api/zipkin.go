@@ -0,0 +1,238 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// zipkinSpan is a span in the Zipkin v2 model.
+// See https://github.com/openzipkin/zipkin-api/blob/master/zipkin2-api.yaml
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ParentID       string            `json:"parentId"`
+	ID             string            `json:"id"`
+	Kind           string            `json:"kind"`
+	Name           string            `json:"name"`
+	Timestamp      uint64            `json:"timestamp"` // epoch microseconds
+	Duration       uint64            `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+	Debug          bool              `json:"debug"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int32  `json:"port"`
+}
+
+// zipkinProtoKinds maps the Zipkin proto3 Span.Kind enum to its JSON representation.
+var zipkinProtoKinds = []string{"", "CLIENT", "SERVER", "PRODUCER", "CONSUMER"}
+
+// decodeZipkinSpans decodes a Zipkin v2 list of spans, encoded either as JSON or as proto3
+// depending on the request's Content-Type, into Datadog spans.
+func decodeZipkinSpans(req *http.Request) ([]*pb.Span, error) {
+	var (
+		zspans []zipkinSpan
+		err    error
+	)
+	switch mt := getMediaType(req); mt {
+	case "application/x-protobuf", "application/protobuf":
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if _, err = copyRequestBody(buf, req); err != nil {
+			return nil, err
+		}
+		zspans, err = unmarshalZipkinProto(buf.Bytes())
+	case "application/json", "text/json", "":
+		err = json.NewDecoder(req.Body).Decode(&zspans)
+	default:
+		return nil, fmt.Errorf("unsupported media type: %q", mt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	spans := make([]*pb.Span, 0, len(zspans))
+	for i := range zspans {
+		s, err := convertZipkinSpan(&zspans[i])
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, s)
+	}
+	return spans, nil
+}
+
+// convertZipkinSpan converts the Zipkin span zs into a Datadog span.
+func convertZipkinSpan(zs *zipkinSpan) (*pb.Span, error) {
+	traceID, traceIDHigh, err := parseHexTraceID(zs.TraceID)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := parseHexSpanID(zs.ID)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := parseHexSpanID(zs.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	s := &pb.Span{
+		Name:     zs.Name,
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    int64(zs.Timestamp) * 1000,
+		Duration: int64(zs.Duration) * 1000,
+		Meta:     make(map[string]string, len(zs.Tags)+4),
+		Metrics:  make(map[string]float64),
+	}
+	for k, v := range zs.Tags {
+		s.Meta[k] = v
+	}
+	setTraceIDUpper(s, traceIDHigh)
+	if e := zs.LocalEndpoint; e != nil {
+		s.Service = e.ServiceName
+	}
+	if e := zs.RemoteEndpoint; e != nil {
+		if e.ServiceName != "" {
+			s.Meta["peer.service"] = e.ServiceName
+		}
+		if ip := e.IPv4; ip != "" {
+			s.Meta["network.destination.ip"] = ip
+		} else if ip := e.IPv6; ip != "" {
+			s.Meta["network.destination.ip"] = ip
+		}
+		if e.Port != 0 {
+			s.Meta["network.destination.port"] = strconv.Itoa(int(e.Port))
+		}
+	}
+	if zs.Debug {
+		s.Metrics["_sampling_priority_v1"] = float64(sampler.PriorityUserKeep)
+	}
+	completeThirdPartySpan(s, strings.ToLower(zs.Kind))
+	return s, nil
+}
+
+// unmarshalZipkinProto decodes a proto3-encoded zipkin.proto3.ListOfSpans.
+// See https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto
+func unmarshalZipkinProto(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, _ uint64, v []byte) error {
+		if num != 1 {
+			return nil
+		}
+		if typ != protowire.BytesType {
+			return errProtoFieldType
+		}
+		var zs zipkinSpan
+		if err := unmarshalZipkinProtoSpan(v, &zs); err != nil {
+			return err
+		}
+		spans = append(spans, zs)
+		return nil
+	})
+	return spans, err
+}
+
+// unmarshalZipkinProtoSpan decodes a proto3-encoded zipkin.proto3.Span into zs. Identifiers are
+// converted to their hex representation to match the JSON model. Annotations are ignored.
+func unmarshalZipkinProtoSpan(b []byte, zs *zipkinSpan) error {
+	return rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, u uint64, v []byte) error {
+		switch num {
+		case 1: // trace_id
+			zs.TraceID = hex.EncodeToString(v)
+		case 2: // parent_id
+			zs.ParentID = hex.EncodeToString(v)
+		case 3: // id
+			zs.ID = hex.EncodeToString(v)
+		case 4: // kind
+			if u < uint64(len(zipkinProtoKinds)) {
+				zs.Kind = zipkinProtoKinds[u]
+			}
+		case 5: // name
+			zs.Name = string(v)
+		case 6: // timestamp
+			zs.Timestamp = u
+		case 7: // duration
+			zs.Duration = u
+		case 8, 9: // local_endpoint, remote_endpoint
+			if typ != protowire.BytesType {
+				return errProtoFieldType
+			}
+			e, err := unmarshalZipkinProtoEndpoint(v)
+			if err != nil {
+				return err
+			}
+			if num == 8 {
+				zs.LocalEndpoint = e
+			} else {
+				zs.RemoteEndpoint = e
+			}
+		case 11: // tags
+			if typ != protowire.BytesType {
+				return errProtoFieldType
+			}
+			var key, val string
+			if err := rangeProtoFields(v, func(num protowire.Number, _ protowire.Type, _ uint64, v []byte) error {
+				switch num {
+				case 1:
+					key = string(v)
+				case 2:
+					val = string(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if zs.Tags == nil {
+				zs.Tags = make(map[string]string)
+			}
+			zs.Tags[key] = val
+		case 12: // debug
+			zs.Debug = u != 0
+		}
+		return nil
+	})
+}
+
+// unmarshalZipkinProtoEndpoint decodes a proto3-encoded zipkin.proto3.Endpoint.
+func unmarshalZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	var e zipkinEndpoint
+	err := rangeProtoFields(b, func(num protowire.Number, _ protowire.Type, u uint64, v []byte) error {
+		switch num {
+		case 1:
+			e.ServiceName = string(v)
+		case 2:
+			if len(v) == net.IPv4len {
+				e.IPv4 = net.IP(v).String()
+			}
+		case 3:
+			if len(v) == net.IPv6len {
+				e.IPv6 = net.IP(v).String()
+			}
+		case 4:
+			e.Port = int32(u)
+		}
+		return nil
+	})
+	return &e, err
+}
@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+const zipkinTestJSON = `[
+  {
+    "traceId": "5af7183fb1d4cf5f0000000000000002",
+    "id": "0000000000000003",
+    "kind": "SERVER",
+    "name": "get /users/{id}",
+    "timestamp": 1700000000000000,
+    "duration": 1500,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "10.0.0.1"},
+    "remoteEndpoint": {"serviceName": "gateway", "ipv4": "10.0.0.2", "port": 443},
+    "tags": {"http.method": "GET", "http.route": "/users/{id}", "http.status_code": "500", "error": "boom"},
+    "debug": true
+  },
+  {
+    "traceId": "0000000000000002",
+    "parentId": "0000000000000003",
+    "id": "0000000000000004",
+    "kind": "CLIENT",
+    "name": "query",
+    "timestamp": 1700000000000100,
+    "duration": 700,
+    "localEndpoint": {"serviceName": "frontend"},
+    "tags": {"db.system": "postgresql"}
+  }
+]`
+
+func TestConvertZipkinSpans(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinTestJSON))
+	req.Header.Set("Content-Type", "application/json")
+	spans, err := decodeZipkinSpans(req)
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, &pb.Span{
+		Service:  "frontend",
+		Name:     "get /users/{id}",
+		Resource: "GET /users/{id}",
+		Type:     "web",
+		TraceID:  2,
+		SpanID:   3,
+		Start:    1700000000000000000,
+		Duration: 1500000,
+		Error:    1,
+		Meta: map[string]string{
+			"http.method":              "GET",
+			"http.route":               "/users/{id}",
+			"http.status_code":         "500",
+			"error.msg":                "boom",
+			"span.kind":                "server",
+			"peer.service":             "gateway",
+			"network.destination.ip":   "10.0.0.2",
+			"network.destination.port": "443",
+			"_dd.p.tid":                "5af7183fb1d4cf5f",
+		},
+		Metrics: map[string]float64{"_sampling_priority_v1": 2},
+	}, spans[0])
+
+	assert.Equal(t, &pb.Span{
+		Service:  "frontend",
+		Name:     "query",
+		Resource: "query",
+		Type:     "db",
+		TraceID:  2,
+		SpanID:   4,
+		ParentID: 3,
+		Start:    1700000000000100000,
+		Duration: 700000,
+		Meta: map[string]string{
+			"db.system": "postgresql",
+			"span.kind": "client",
+		},
+		Metrics: map[string]float64{},
+	}, spans[1])
+}
+
+func TestDecodeZipkinErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		contentType string
+		body        string
+	}{
+		"bad-trace-id":  {"application/json", `[{"traceId":"xyz","id":"1"}]`},
+		"long-trace-id": {"application/json", `[{"traceId":"000000000000000000000000000000001","id":"1"}]`},
+		"bad-span-id":   {"application/json", `[{"traceId":"1","id":"00000000000000001"}]`},
+		"bad-json":      {"application/json", `{`},
+		"bad-proto":     {"application/x-protobuf", "\x0a\xff"},
+		"media-type":    {"text/plain", `[]`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			_, err := decodeZipkinSpans(req)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// appendZipkinProtoSpan appends a proto3-encoded zipkin.proto3.Span as field 1 of a ListOfSpans.
+func appendZipkinProtoSpan(b []byte, traceID, parentID, id string, kind uint64, name string, local, remote []byte, tags map[string]string) []byte {
+	mustHex := func(s string) []byte {
+		v, err := hex.DecodeString(s)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	var span []byte
+	span = protowire.AppendTag(span, 1, protowire.BytesType)
+	span = protowire.AppendBytes(span, mustHex(traceID))
+	if parentID != "" {
+		span = protowire.AppendTag(span, 2, protowire.BytesType)
+		span = protowire.AppendBytes(span, mustHex(parentID))
+	}
+	span = protowire.AppendTag(span, 3, protowire.BytesType)
+	span = protowire.AppendBytes(span, mustHex(id))
+	span = protowire.AppendTag(span, 4, protowire.VarintType)
+	span = protowire.AppendVarint(span, kind)
+	span = protowire.AppendTag(span, 5, protowire.BytesType)
+	span = protowire.AppendString(span, name)
+	span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+	span = protowire.AppendFixed64(span, 1700000000000000)
+	span = protowire.AppendTag(span, 7, protowire.VarintType)
+	span = protowire.AppendVarint(span, 250)
+	if local != nil {
+		span = protowire.AppendTag(span, 8, protowire.BytesType)
+		span = protowire.AppendBytes(span, local)
+	}
+	if remote != nil {
+		span = protowire.AppendTag(span, 9, protowire.BytesType)
+		span = protowire.AppendBytes(span, remote)
+	}
+	for k, v := range tags {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, v)
+		span = protowire.AppendTag(span, 11, protowire.BytesType)
+		span = protowire.AppendBytes(span, entry)
+	}
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, span)
+}
+
+func zipkinProtoEndpoint(service string, ip net.IP, port uint64) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, service)
+	if ip != nil {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, ip.To4())
+	}
+	if port != 0 {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, port)
+	}
+	return b
+}
+
+func TestZipkinProto(t *testing.T) {
+	payload := appendZipkinProtoSpan(nil, "0000000000000001000000000000000a", "", "000000000000000b", 3, "send",
+		zipkinProtoEndpoint("producer", nil, 0),
+		zipkinProtoEndpoint("kafka", net.ParseIP("192.168.1.1"), 9092),
+		map[string]string{"messaging.system": "kafka"})
+	payload = appendZipkinProtoSpan(payload, "000000000000000a", "000000000000000b", "000000000000000c", 4, "receive",
+		zipkinProtoEndpoint("consumer", nil, 0), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	spans, err := decodeZipkinSpans(req)
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, uint64(10), spans[0].TraceID)
+	assert.Equal(t, uint64(11), spans[0].SpanID)
+	assert.Equal(t, "producer", spans[0].Service)
+	assert.Equal(t, "send", spans[0].Name)
+	assert.Equal(t, int64(1700000000000000000), spans[0].Start)
+	assert.Equal(t, int64(250000), spans[0].Duration)
+	assert.Equal(t, "producer", spans[0].Meta["span.kind"])
+	assert.Equal(t, "kafka", spans[0].Meta["peer.service"])
+	assert.Equal(t, "kafka", spans[0].Meta["messaging.system"])
+	assert.Equal(t, "192.168.1.1", spans[0].Meta["network.destination.ip"])
+	assert.Equal(t, "9092", spans[0].Meta["network.destination.port"])
+	assert.Equal(t, "0000000000000001", spans[0].Meta["_dd.p.tid"])
+
+	assert.Equal(t, uint64(10), spans[1].TraceID)
+	assert.Equal(t, uint64(11), spans[1].ParentID)
+	assert.Equal(t, "consumer", spans[1].Service)
+	assert.Equal(t, "consumer", spans[1].Meta["span.kind"])
+	assert.Equal(t, "custom", spans[1].Type)
+}
+
+func TestZipkinEndpoint(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.ZipkinReceiverEnabled = true
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString(zipkinTestJSON))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		require.Len(t, p.Chunks(), 1)
+		assert.Len(t, p.Chunk(0).Spans, 2)
+		assert.Equal(t, string(zipkinV2), p.Source.EndpointVersion)
+		assert.Equal(t, "frontend", p.Source.Service)
+	case <-time.After(time.Second):
+		t.Fatal("no payload received")
+	}
+
+	resp, err = http.Get(server.URL + "/api/v2/spans")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString(`[{"traceId":"zz"}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestZipkinEndpointDisabled(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	r.buildMux()
+	assert.NotContains(t, r.Handlers, "/api/v2/spans")
+	assert.NotContains(t, r.Handlers, "/api/traces")
+}
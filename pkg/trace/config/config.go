@@ -128,6 +128,22 @@ type ObfuscationConfig struct {
 	// for spans of type "valkey".
 	Valkey obfuscate.ValkeyConfig `mapstructure:"valkey"`
 
+	// GraphQL holds the configuration for obfuscating the resource and the "graphql.query"
+	// and "graphql.source" tags of spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
+	// CQL holds the configuration for obfuscating the resource and the "cassandra.query"
+	// tag of spans of type "cassandra". If disabled, they are obfuscated as SQL.
+	CQL obfuscate.CQLConfig `mapstructure:"cql"`
+
+	// DynamoDB holds the configuration for obfuscating the PartiQL statements and the
+	// expression attribute values of DynamoDB spans.
+	DynamoDB obfuscate.DynamoDBConfig `mapstructure:"dynamodb"`
+
+	// Kafka holds the configuration for obfuscating the message keys and payloads
+	// of Kafka producer and consumer spans.
+	Kafka obfuscate.KafkaConfig `mapstructure:"kafka"`
+
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
@@ -171,6 +187,10 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		HTTP:                 o.HTTP,
 		Redis:                o.Redis,
 		Valkey:               o.Valkey,
+		GraphQL:              o.GraphQL,
+		CQL:                  o.CQL,
+		DynamoDB:             o.DynamoDB,
+		Kafka:                o.Kafka,
 		Memcached:            o.Memcached,
 		CreditCard:           o.CreditCards,
 		Logger:               new(debugLogger),
@@ -213,6 +233,37 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// TraceSamplingRule specifies a rule used by the agent to keep or drop traces based on the
+// attributes of their spans. Rules are evaluated in order and the first matching rule decides
+// the fate of the trace.
+type TraceSamplingRule struct {
+	// Name identifies the rule in logs and metrics.
+	Name string `mapstructure:"name" json:"name"`
+
+	// Service, OperationName and Resource are glob patterns matched against the span's
+	// service, operation name and resource. "*" matches any sequence of characters and "?"
+	// matches a single character. Empty patterns match everything.
+	Service       string `mapstructure:"service" json:"service"`
+	OperationName string `mapstructure:"operation_name" json:"operation_name"`
+	Resource      string `mapstructure:"resource" json:"resource"`
+
+	// Tags maps tag names to glob patterns matched against the span's meta or metrics.
+	Tags map[string]string `mapstructure:"tags" json:"tags"`
+
+	// Conditions are comparisons of the form "<attribute> <operator> <value>", such as
+	// "http.status_code >= 500" or "duration > 1s". Supported operators are ==, !=, <, <=,
+	// > and >=.
+	Conditions []string `mapstructure:"conditions" json:"conditions"`
+
+	// AnySpan makes the rule match when any span of the trace chunk matches, instead of
+	// only its root span.
+	AnySpan bool `mapstructure:"any_span" json:"any_span"`
+
+	// SampleRate is the rate at which traces matching the rule are kept. A rate of 0 drops
+	// them all. It defaults to 1.
+	SampleRate *float64 `mapstructure:"sample_rate" json:"sample_rate"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -361,6 +412,14 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// TraceSamplingRules are evaluated by the Rule Sampler before any other sampler.
+	TraceSamplingRules []*TraceSamplingRule
+
+	// Tail sampling configuration
+	TailSamplingEnabled       bool
+	TailSamplingDecisionWait  time.Duration // how long chunks are buffered waiting for the rest of their trace
+	TailSamplingMaxBufferSize int           // maximum size in bytes of the buffered chunks
+
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
@@ -377,6 +436,11 @@ type AgentConfig struct {
 	MaxConnections  int   // specifies the maximum number of concurrent incoming connections allowed.
 	DecoderTimeout  int   // specifies the maximum time in milliseconds that the decoders will wait for a turn to accept a payload before returning 429
 
+	// ZipkinReceiverEnabled specifies whether the Zipkin v2 intake endpoint (/api/v2/spans) is enabled.
+	ZipkinReceiverEnabled bool
+	// JaegerReceiverEnabled specifies whether the Jaeger collector intake endpoint (/api/traces) is enabled.
+	JaegerReceiverEnabled bool
+
 	WindowsPipeName        string
 	PipeBufferSize         int
 	PipeSecurityDescriptor string
@@ -503,6 +567,14 @@ type AgentConfig struct {
 	// DebugServerPort defines the port used by the debug server
 	DebugServerPort int
 
+	// CapturePath is the directory in which payload captures, started through the debug
+	// server, are written.
+	CapturePath string
+
+	// CaptureMaxBytes is the maximum size of a capture file, a capture is stopped once its file
+	// reaches it. There is no size limit if it isn't positive.
+	CaptureMaxBytes int64
+
 	// Install Signature
 	InstallSignature InstallSignatureConfig
 
@@ -565,6 +637,9 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSamplingDecisionWait:  5 * time.Second,
+		TailSamplingMaxBufferSize: 64 * 1024 * 1024, // 64MB
+
 		ErrorTrackingStandalone: false,
 
 		ReceiverEnabled:        true,
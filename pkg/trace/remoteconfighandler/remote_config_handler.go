@@ -34,12 +34,17 @@ type rareSampler interface {
 	SetEnabled(enabled bool)
 }
 
+type ruleSampler interface {
+	UpdateRules(rules []*config.TraceSamplingRule) error
+}
+
 // RemoteConfigHandler holds pointers to samplers that need to be updated when APM remote config changes
 type RemoteConfigHandler struct {
 	remoteClient                  config.RemoteClient
 	prioritySampler               prioritySampler
 	errorsSampler                 errorsSampler
 	rareSampler                   rareSampler
+	ruleSampler                   ruleSampler
 	agentConfig                   *config.AgentConfig
 	configState                   *state.AgentConfigState
 	configHTTPClient              *http.Client
@@ -47,7 +52,7 @@ type RemoteConfigHandler struct {
 }
 
 // New creates a new RemoteConfigHandler
-func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler) *RemoteConfigHandler {
+func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler, ruleSampler ruleSampler) *RemoteConfigHandler {
 	if conf.RemoteConfigClient == nil {
 		return nil
 	}
@@ -68,6 +73,7 @@ func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler
 		prioritySampler: prioritySampler,
 		rareSampler:     rareSampler,
 		errorsSampler:   errorsSampler,
+		ruleSampler:     ruleSampler,
 		agentConfig:     conf,
 		configState: &state.AgentConfigState{
 			FallbackLogLevel: level.String(),
@@ -230,4 +236,31 @@ func (h *RemoteConfigHandler) updateSamplers(config apmsampling.SamplerConfig) {
 		rareSamplerEnabled = h.agentConfig.RareSamplerEnabled
 	}
 	h.rareSampler.SetEnabled(rareSamplerEnabled)
+
+	traceSamplingRules := h.agentConfig.TraceSamplingRules
+	if confForEnv != nil && confForEnv.TraceSamplingRules != nil {
+		traceSamplingRules = convertTraceSamplingRules(confForEnv.TraceSamplingRules)
+	} else if config.AllEnvs.TraceSamplingRules != nil {
+		traceSamplingRules = convertTraceSamplingRules(config.AllEnvs.TraceSamplingRules)
+	}
+	if err := h.ruleSampler.UpdateRules(traceSamplingRules); err != nil {
+		log.Errorf("couldn't apply the trace sampling rules from remote configuration: %v", err)
+	}
+}
+
+func convertTraceSamplingRules(rules []apmsampling.TraceSamplingRule) []*config.TraceSamplingRule {
+	out := make([]*config.TraceSamplingRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, &config.TraceSamplingRule{
+			Name:          r.Name,
+			Service:       r.Service,
+			OperationName: r.OperationName,
+			Resource:      r.Resource,
+			Tags:          r.Tags,
+			Conditions:    r.Conditions,
+			AnySpan:       r.AnySpan,
+			SampleRate:    r.SampleRate,
+		})
+	}
+	return out
 }
@@ -33,9 +33,10 @@ func TestStart(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
 	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
@@ -57,10 +58,11 @@ func TestPrioritySampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -76,6 +78,7 @@ func TestPrioritySampler(t *testing.T) {
 	prioritySampler.EXPECT().UpdateTargetTPS(float64(42)).Times(1)
 	errorsSampler.EXPECT().UpdateTargetTPS(float64(41)).Times(1)
 	rareSampler.EXPECT().SetEnabled(true).Times(1)
+	ruleSampler.EXPECT().UpdateRules(gomock.Nil()).Times(1)
 
 	h.onUpdate(map[string]state.RawConfig{"datadog/2/APM_SAMPLING/samplerconfig/config": config}, applyEmpty)
 
@@ -88,10 +91,11 @@ func TestErrorsSampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -107,6 +111,7 @@ func TestErrorsSampler(t *testing.T) {
 	prioritySampler.EXPECT().UpdateTargetTPS(float64(41)).Times(1)
 	errorsSampler.EXPECT().UpdateTargetTPS(float64(42)).Times(1)
 	rareSampler.EXPECT().SetEnabled(true).Times(1)
+	ruleSampler.EXPECT().UpdateRules(gomock.Nil()).Times(1)
 
 	h.onUpdate(map[string]state.RawConfig{"datadog/2/APM_SAMPLING/samplerconfig/config": config}, applyEmpty)
 
@@ -119,10 +124,11 @@ func TestRareSampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -138,6 +144,7 @@ func TestRareSampler(t *testing.T) {
 	prioritySampler.EXPECT().UpdateTargetTPS(float64(41)).Times(1)
 	errorsSampler.EXPECT().UpdateTargetTPS(float64(41)).Times(1)
 	rareSampler.EXPECT().SetEnabled(false).Times(1)
+	ruleSampler.EXPECT().UpdateRules(gomock.Nil()).Times(1)
 
 	h.onUpdate(map[string]state.RawConfig{"datadog/2/APM_SAMPLING/samplerconfig/config": config}, applyEmpty)
 
@@ -150,10 +157,11 @@ func TestEnvPrecedence(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DefaultEnv: "agent-env", DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -179,18 +187,79 @@ func TestEnvPrecedence(t *testing.T) {
 	prioritySampler.EXPECT().UpdateTargetTPS(float64(43)).Times(1)
 	errorsSampler.EXPECT().UpdateTargetTPS(float64(43)).Times(1)
 	rareSampler.EXPECT().SetEnabled(false).Times(1)
+	ruleSampler.EXPECT().UpdateRules(gomock.Nil()).Times(1)
 
 	h.onUpdate(map[string]state.RawConfig{"datadog/2/APM_SAMPLING/samplerconfig/config": config}, applyEmpty)
 
 	ctrl.Finish()
 }
 
+func TestTraceSamplingRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
+	pkglog.SetupLogger(pkglog.Default(), "debug")
+
+	localRules := []*config.TraceSamplingRule{{Name: "local", Service: "web"}}
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DefaultEnv: "agent-env", DebugServerPort: 1, TraceSamplingRules: localRules}
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
+
+	prioritySampler.EXPECT().UpdateTargetTPS(gomock.Any()).AnyTimes()
+	errorsSampler.EXPECT().UpdateTargetTPS(gomock.Any()).AnyTimes()
+	rareSampler.EXPECT().SetEnabled(gomock.Any()).AnyTimes()
+
+	update := func(payload apmsampling.SamplerConfig) {
+		raw, _ := json.Marshal(payload)
+		h.onUpdate(map[string]state.RawConfig{"datadog/2/APM_SAMPLING/samplerconfig/config": {Config: raw}}, applyEmpty)
+	}
+
+	// env specific rules take precedence
+	ruleSampler.EXPECT().UpdateRules([]*config.TraceSamplingRule{{
+		Name:       "errors",
+		Conditions: []string{"http.status_code >= 500"},
+		SampleRate: pointer.Ptr(1.0),
+	}}).Return(nil).Times(1)
+	update(apmsampling.SamplerConfig{
+		AllEnvs: apmsampling.SamplerEnvConfig{
+			TraceSamplingRules: []apmsampling.TraceSamplingRule{{Name: "all"}},
+		},
+		ByEnv: []apmsampling.EnvAndConfig{{
+			Env: "agent-env",
+			Config: apmsampling.SamplerEnvConfig{
+				TraceSamplingRules: []apmsampling.TraceSamplingRule{{
+					Name:       "errors",
+					Conditions: []string{"http.status_code >= 500"},
+					SampleRate: pointer.Ptr(1.0),
+				}},
+			},
+		}},
+	})
+
+	// an empty list removes all rules
+	ruleSampler.EXPECT().UpdateRules([]*config.TraceSamplingRule{}).Return(nil).Times(1)
+	update(apmsampling.SamplerConfig{
+		AllEnvs: apmsampling.SamplerEnvConfig{
+			TraceSamplingRules: []apmsampling.TraceSamplingRule{},
+		},
+	})
+
+	// fall back to the local configuration
+	ruleSampler.EXPECT().UpdateRules(localRules).Return(nil).Times(1)
+	update(apmsampling.SamplerConfig{})
+
+	ctrl.Finish()
+}
+
 func TestLogLevel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	remoteClient := NewMockRemoteClient(ctrl)
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	ruleSampler := NewMockruleSampler(ctrl)
 
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -208,7 +277,7 @@ func TestLogLevel(t *testing.T) {
 			return "fakeToken"
 		},
 	}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, ruleSampler)
 
 	layer := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {"log_level": "debug"}}`)}
 	configOrder := state.RawConfig{Config: []byte(`{"internal_order": ["layer1", "layer2"]}`)}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+const (
+	// DefaultCaptureDuration is the duration of a capture when none is given.
+	DefaultCaptureDuration = time.Minute
+	// MaxCaptureDuration is the maximum duration of a capture.
+	MaxCaptureDuration = time.Hour
+
+	fileTemplate = "trace-capture-%d"
+)
+
+// ErrCaptureOngoing is returned when starting a capture while another one is ongoing.
+var ErrCaptureOngoing = errors.New("a capture is already ongoing")
+
+// Capture writes the payloads received by the trace-agent to a capture file, for a limited
+// duration and up to a maximum size. Payloads are captured as decoded by the receivers, before any processing, and
+// thus before obfuscation: capture files may hold sensitive data. It is safe for concurrent use.
+type Capture struct {
+	dir      string
+	maxBytes int64
+	ongoing  atomic.Bool // fast path for Record
+
+	mu    sync.Mutex // guards the fields below
+	f     *os.File
+	w     *Writer
+	path  string
+	timer *time.Timer
+	count int
+}
+
+// NewCapture returns a new Capture writing capture files to dir. A capture is stopped once its
+// file reaches maxBytes; there is no size limit if maxBytes isn't positive.
+func NewCapture(dir string, maxBytes int64) *Capture {
+	return &Capture{dir: dir, maxBytes: maxBytes}
+}
+
+// Start starts capturing payloads to a new capture file for the duration d, and returns the
+// path of the file.
+func (c *Capture) Start(d time.Duration) (string, error) {
+	if d <= 0 || d > MaxCaptureDuration {
+		return "", fmt.Errorf("capture duration must be within (0, %s], got %s", MaxCaptureDuration, d)
+	}
+	if c.dir == "" {
+		return "", errors.New("no capture path configured")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f != nil {
+		return "", ErrCaptureOngoing
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.dir, fmt.Sprintf(fileTemplate, time.Now().UnixNano()))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	c.f, c.w, c.path, c.count = f, w, path, 0
+	c.timer = time.AfterFunc(d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.f == f {
+			// the capture wasn't stopped and replaced by another one in the meantime
+			c.stopLocked()
+		}
+	})
+	c.ongoing.Store(true)
+	log.Infof("Started capturing trace payloads to %s for %s", path, d)
+	return path, nil
+}
+
+// Record writes p to the capture file if a capture is ongoing. As the processing of a payload
+// modifies it, it must be called before p is processed.
+func (c *Capture) Record(p *api.Payload) {
+	if c == nil || !c.ongoing.Load() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.w == nil {
+		return
+	}
+	if err := c.w.Write(Record{Time: time.Now(), Payload: p}); err != nil {
+		log.Errorf("Error writing to capture file %s, stopping capture: %v", c.path, err)
+		c.stopLocked()
+		return
+	}
+	c.count++
+	if c.maxBytes > 0 && c.w.Size() >= c.maxBytes {
+		log.Infof("Capture file %s reached the maximum size of %d bytes", c.path, c.maxBytes)
+		c.stopLocked()
+	}
+}
+
+// Stop stops the ongoing capture, if any.
+func (c *Capture) Stop() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+}
+
+func (c *Capture) stopLocked() {
+	if c.f == nil {
+		return
+	}
+	c.ongoing.Store(false)
+	c.timer.Stop()
+	if err := c.w.Flush(); err != nil {
+		log.Errorf("Error flushing capture file %s: %v", c.path, err)
+	}
+	if err := c.f.Close(); err != nil {
+		log.Errorf("Error closing capture file %s: %v", c.path, err)
+	}
+	log.Infof("Stopped capturing trace payloads to %s: %d payloads captured", c.path, c.count)
+	c.f, c.w, c.timer = nil, nil, nil
+}
+
+// ServeHTTP starts a capture on POST requests. The duration of the capture can be given
+// with the "duration" query parameter, e.g. "?duration=30s". The path of the capture file
+// is returned in a JSON object.
+func (c *Capture) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d := DefaultCaptureDuration
+	if v := req.URL.Query().Get("duration"); v != "" {
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid duration %q: %v", v, err), http.StatusBadRequest)
+			return
+		}
+	}
+	if d <= 0 || d > MaxCaptureDuration {
+		http.Error(w, fmt.Sprintf("duration must be within (0, %s]", MaxCaptureDuration), http.StatusBadRequest)
+		return
+	}
+	path, err := c.Start(d)
+	switch {
+	case errors.Is(err, ErrCaptureOngoing):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"path":     path,
+		"duration": d.String(),
+	})
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll returns the number of records of the capture file at path.
+func readAll(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := NewReader(f)
+	require.NoError(t, err)
+	n := 0
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			return n
+		}
+		require.NoError(t, err)
+		n++
+	}
+}
+
+func TestCapture(t *testing.T) {
+	c := NewCapture(t.TempDir(), 0)
+
+	c.Record(testPayload("go")) // no ongoing capture
+	path, err := c.Start(time.Minute)
+	require.NoError(t, err)
+	_, err = c.Start(time.Minute)
+	assert.Equal(t, ErrCaptureOngoing, err)
+
+	c.Record(testPayload("go"))
+	c.Record(testPayload("java"))
+	c.Stop()
+	c.Record(testPayload("go")) // capture stopped
+	assert.Equal(t, 2, readAll(t, path))
+
+	_, err = c.Start(0)
+	assert.Error(t, err)
+	_, err = c.Start(2 * MaxCaptureDuration)
+	assert.Error(t, err)
+}
+
+func TestCaptureExpires(t *testing.T) {
+	c := NewCapture(t.TempDir(), 0)
+	path, err := c.Start(10 * time.Millisecond)
+	require.NoError(t, err)
+	c.Record(testPayload("go"))
+	assert.Eventually(t, func() bool { return !c.ongoing.Load() }, time.Second, 5*time.Millisecond)
+	c.Record(testPayload("go"))
+	assert.Equal(t, 1, readAll(t, path))
+}
+
+func TestCaptureMaxBytes(t *testing.T) {
+	c := NewCapture(t.TempDir(), 1)
+	path, err := c.Start(time.Minute)
+	require.NoError(t, err)
+	c.Record(testPayload("go"))
+	assert.False(t, c.ongoing.Load())
+	c.Record(testPayload("go")) // capture stopped
+	assert.Equal(t, 1, readAll(t, path))
+
+	// a new capture can be started once the previous one reached the limit
+	_, err = c.Start(time.Minute)
+	require.NoError(t, err)
+	c.Stop()
+}
+
+func TestCaptureServeHTTP(t *testing.T) {
+	c := NewCapture(t.TempDir(), 0)
+	defer c.Stop()
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/capture", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/capture?duration=forever", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/capture?duration=30s", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "30s", resp["duration"])
+	assert.FileExists(t, resp["path"])
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/capture", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
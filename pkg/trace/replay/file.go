@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements the capture of the payloads received by the trace-agent to
+// a file and the reading of such files, so that they can be replayed offline.
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+)
+
+// A capture file starts with a header made of fileMagic followed by the version of the
+// file format. It is followed by records, each of them prefixed by its length as a little
+// endian uint32. A record holds:
+//
+//	time     int64, little endian, the time at which the payload was received in Unix nanoseconds
+//	metaLen  uint32, little endian, the length of meta
+//	meta     JSON encoded recordMeta
+//	payload  protobuf encoded pb.TracerPayload, up to the end of the record
+const (
+	// FileVersion is the version of the capture file format written by Writer.
+	FileVersion uint8 = 1
+
+	// maxRecordSize is the maximum size of a record accepted by Reader.
+	maxRecordSize = 512 * 1024 * 1024
+)
+
+var fileMagic = []byte("DDTRCAP")
+
+// ErrInvalidFile is returned by NewReader when the given file isn't a trace-agent capture.
+var ErrInvalidFile = errors.New("not a trace-agent capture file")
+
+// Record is a payload received by the trace-agent, as stored in a capture file.
+type Record struct {
+	// Time is the time at which the payload was received.
+	Time time.Time
+	// Payload is the payload, as decoded by the receiver.
+	Payload *api.Payload
+}
+
+// recordMeta holds the fields of an api.Payload other than its TracerPayload.
+type recordMeta struct {
+	Source                 info.Tags `json:"source"`
+	ClientComputedTopLevel bool      `json:"client_computed_top_level,omitempty"`
+	ClientComputedStats    bool      `json:"client_computed_stats,omitempty"`
+	ClientDroppedP0s       int64     `json:"client_dropped_p0s,omitempty"`
+	ProcessTags            string    `json:"process_tags,omitempty"`
+}
+
+// Writer writes records to a capture file. It is not safe for concurrent use.
+type Writer struct {
+	w    *bufio.Writer
+	buf  []byte
+	size int64
+}
+
+// NewWriter writes the capture file header to w and returns a Writer writing records to it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(fileMagic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(FileVersion); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw, size: int64(len(fileMagic) + 1)}, nil
+}
+
+// Write encodes r and writes it. The record's payload is not modified.
+func (w *Writer) Write(r Record) error {
+	meta := recordMeta{
+		ClientComputedTopLevel: r.Payload.ClientComputedTopLevel,
+		ClientComputedStats:    r.Payload.ClientComputedStats,
+		ClientDroppedP0s:       r.Payload.ClientDroppedP0s,
+		ProcessTags:            r.Payload.ProcessTags,
+	}
+	if r.Payload.Source != nil {
+		meta.Source = r.Payload.Source.Tags
+	}
+	metab, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	tpb, err := r.Payload.TracerPayload.MarshalVT()
+	if err != nil {
+		return err
+	}
+	size := 8 + 4 + len(metab) + len(tpb)
+	w.buf = binary.LittleEndian.AppendUint32(w.buf[:0], uint32(size))
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, uint64(r.Time.UnixNano()))
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(len(metab)))
+	w.buf = append(w.buf, metab...)
+	w.buf = append(w.buf, tpb...)
+	n, err := w.w.Write(w.buf)
+	w.size += int64(n)
+	return err
+}
+
+// Size returns the number of bytes written so far, including the file header.
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads the records of a capture file.
+type Reader struct {
+	r       *bufio.Reader
+	version uint8
+	stats   *info.ReceiverStats
+}
+
+// NewReader reads and validates the capture file header from r and returns a Reader reading
+// records from it.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(fileMagic)+1)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrInvalidFile
+		}
+		return nil, err
+	}
+	if string(hdr[:len(fileMagic)]) != string(fileMagic) {
+		return nil, ErrInvalidFile
+	}
+	version := hdr[len(fileMagic)]
+	if version == 0 || version > FileVersion {
+		return nil, fmt.Errorf("unsupported capture file version %d", version)
+	}
+	return &Reader{r: br, version: version, stats: info.NewReceiverStats()}, nil
+}
+
+// Version returns the version of the file format.
+func (r *Reader) Version() uint8 {
+	return r.version
+}
+
+// Next returns the next record. It returns io.EOF when there are no more records.
+func (r *Reader) Next() (Record, error) {
+	var sizeb [4]byte
+	if _, err := io.ReadFull(r.r, sizeb[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, fmt.Errorf("truncated record: %w", err)
+		}
+		return Record{}, err
+	}
+	size := binary.LittleEndian.Uint32(sizeb[:])
+	if size < 12 || size > maxRecordSize {
+		return Record{}, fmt.Errorf("invalid record size %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Record{}, fmt.Errorf("truncated record: %w", err)
+	}
+	ts := int64(binary.LittleEndian.Uint64(data))
+	metaLen := binary.LittleEndian.Uint32(data[8:])
+	if uint64(metaLen) > uint64(size-12) {
+		return Record{}, fmt.Errorf("invalid record metadata size %d", metaLen)
+	}
+	var meta recordMeta
+	if err := json.Unmarshal(data[12:12+metaLen], &meta); err != nil {
+		return Record{}, fmt.Errorf("invalid record metadata: %w", err)
+	}
+	var tp pb.TracerPayload
+	if err := tp.UnmarshalVT(data[12+metaLen:]); err != nil {
+		return Record{}, fmt.Errorf("invalid record payload: %w", err)
+	}
+	return Record{
+		Time: time.Unix(0, ts),
+		Payload: &api.Payload{
+			Source:                 r.stats.GetTagStats(meta.Source),
+			TracerPayload:          &tp,
+			ClientComputedTopLevel: meta.ClientComputedTopLevel,
+			ClientComputedStats:    meta.ClientComputedStats,
+			ClientDroppedP0s:       meta.ClientDroppedP0s,
+			ProcessTags:            meta.ProcessTags,
+		},
+	}, nil
+}
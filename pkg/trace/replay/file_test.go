@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+)
+
+func testPayload(lang string) *api.Payload {
+	return &api.Payload{
+		Source: info.NewReceiverStats().GetTagStats(info.Tags{Lang: lang, TracerVersion: "1.2.3", EndpointVersion: "v0.4"}),
+		TracerPayload: &pb.TracerPayload{
+			LanguageName: lang,
+			Env:          "prod",
+			Chunks:       testutil.GetTestTraceChunks(2, 3, true),
+			Tags:         map[string]string{"_dd.tags.container": "a:b"},
+		},
+		ClientComputedStats: true,
+		ClientDroppedP0s:    12,
+		ProcessTags:         "entrypoint.name:app",
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	now := time.Unix(0, time.Now().UnixNano())
+	payloads := []*api.Payload{testPayload("go"), testPayload("python")}
+	for i, p := range payloads {
+		require.NoError(t, w.Write(Record{Time: now.Add(time.Duration(i) * time.Second), Payload: p}))
+	}
+	require.NoError(t, w.Flush())
+
+	r, err := NewReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, FileVersion, r.Version())
+	for i, p := range payloads {
+		rec, err := r.Next()
+		require.NoError(t, err)
+		assert.True(t, now.Add(time.Duration(i)*time.Second).Equal(rec.Time))
+		assert.Equal(t, p.Source.Tags, rec.Payload.Source.Tags)
+		assert.True(t, proto.Equal(p.TracerPayload, rec.Payload.TracerPayload))
+		assert.Equal(t, p.ClientComputedTopLevel, rec.Payload.ClientComputedTopLevel)
+		assert.Equal(t, p.ClientComputedStats, rec.Payload.ClientComputedStats)
+		assert.Equal(t, p.ClientDroppedP0s, rec.Payload.ClientDroppedP0s)
+		assert.Equal(t, p.ProcessTags, rec.Payload.ProcessTags)
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderErrors(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(nil))
+		assert.Equal(t, ErrInvalidFile, err)
+	})
+
+	t.Run("magic", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte("NOTACAPTURE")))
+		assert.Equal(t, ErrInvalidFile, err)
+	})
+
+	t.Run("version", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(append([]byte("DDTRCAP"), FileVersion+1)))
+		assert.ErrorContains(t, err, "unsupported capture file version")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(Record{Time: time.Now(), Payload: testPayload("go")}))
+		require.NoError(t, w.Flush())
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-10]))
+		require.NoError(t, err)
+		_, err = r.Next()
+		assert.ErrorContains(t, err, "truncated record")
+	})
+}
@@ -77,6 +77,16 @@ func (e *RareSampler) Sample(now time.Time, t *pb.TraceChunk, env string) bool {
 	return e.handleTrace(now, env, t)
 }
 
+// Record records the signatures of a trace kept by another sampler, so that the next traces with
+// the same signatures aren't considered rare. It doesn't use the rate limit nor mark the trace as
+// rare.
+func (e *RareSampler) Record(now time.Time, t *pb.TraceChunk, env string) {
+	if !e.enabled.Load() {
+		return
+	}
+	e.handlePriorityTrace(now, env, t, e.ttl)
+}
+
 // SetEnabled marks the sampler as enabled or disabled
 func (e *RareSampler) SetEnabled(enabled bool) {
 	e.enabled.Store(enabled)
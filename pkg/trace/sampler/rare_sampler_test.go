@@ -76,6 +76,22 @@ func TestConsideredSpans(t *testing.T) {
 	}
 }
 
+func TestRecord(t *testing.T) {
+	c := config.New()
+	c.RareSamplerEnabled = true
+	testTime := time.Unix(13829192398, 0)
+	e := NewRareSampler(c)
+
+	span := &pb.Span{Service: "s1", Resource: "r1", Metrics: map[string]float64{"_top_level": 1}}
+	e.Record(testTime, getTraceChunkWithSpanAndPriority(span, PriorityAutoKeep), "")
+	assert.NotContains(t, span.Metrics, rareKey)
+	assert.Equal(t, int64(0), e.hits.Load())
+
+	span = &pb.Span{Service: "s1", Resource: "r1", Metrics: map[string]float64{"_top_level": 1}}
+	assert.False(t, e.Sample(testTime, getTraceChunkWithSpanAndPriority(span, PriorityNone), ""))
+	assert.True(t, e.Sample(testTime.Add(c.RareSamplerCooldownPeriod+time.Nanosecond), getTraceChunkWithSpanAndPriority(span, PriorityNone), ""))
+}
+
 func TestRareSamplerRace(_ *testing.T) {
 	e := NewRareSampler(config.New())
 	for i := 0; i < 2; i++ {
@@ -0,0 +1,334 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// MetricsRuleHits is the metric name for the number of traces matched by a sampling rule.
+	MetricsRuleHits = "datadog.trace_agent.sampler.rule.hits"
+	// MetricsRuleCount is the metric name for the number of sampling rules currently in use.
+	MetricsRuleCount = "datadog.trace_agent.sampler.rule.count"
+
+	// agentRuleRateKey is the metric set on the root span of traces kept by a sampling rule, holding
+	// the rule's sample rate.
+	agentRuleRateKey = "_dd.agent_rule_psr"
+)
+
+// RuleSampler keeps or drops traces according to rules matching the attributes of their spans.
+// It runs before all other samplers: when a rule matches a trace, its decision is final.
+// Rules can be updated at runtime, e.g. through remote configuration.
+type RuleSampler struct {
+	mu    sync.RWMutex
+	rules []*samplingRule
+}
+
+// NewRuleSampler returns a RuleSampler evaluating the rules found in the configuration.
+// Invalid rules are reported and ignored altogether.
+func NewRuleSampler(conf *config.AgentConfig) *RuleSampler {
+	s := &RuleSampler{}
+	if err := s.UpdateRules(conf.TraceSamplingRules); err != nil {
+		log.Errorf("Ignoring trace sampling rules: %v", err)
+	}
+	return s
+}
+
+// UpdateRules replaces the rules evaluated by the sampler. If any of the rules is invalid, an
+// error is returned and the current rules are left untouched.
+func (s *RuleSampler) UpdateRules(rules []*config.TraceSamplingRule) error {
+	compiled, err := compileSamplingRules(rules)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.rules = compiled
+	s.mu.Unlock()
+	return nil
+}
+
+// Sample evaluates the rules against the trace chunk, in order. It returns whether a rule matched
+// and, if so, whether the trace should be kept.
+func (s *RuleSampler) Sample(chunk *pb.TraceChunk, root *pb.Span) (keep bool, matched bool) {
+	if s == nil {
+		return false, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.rules {
+		if !r.matchChunk(chunk, root) {
+			continue
+		}
+		r.hits.Inc()
+		keep = SampleByRate(root.TraceID, r.rate)
+		if keep {
+			setMetric(root, agentRuleRateKey, r.rate)
+		}
+		return keep, true
+	}
+	return false, false
+}
+
+func (s *RuleSampler) report(statsd statsd.ClientInterface) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.rules {
+		if hits := r.hits.Swap(0); hits > 0 {
+			_ = statsd.Count(MetricsRuleHits, hits, []string{"rule:" + r.name}, 1)
+		}
+	}
+	_ = statsd.Gauge(MetricsRuleCount, float64(len(s.rules)), nil, 1)
+}
+
+// samplingRule is the compiled form of a config.TraceSamplingRule.
+type samplingRule struct {
+	name       string
+	service    *regexp.Regexp
+	operation  *regexp.Regexp
+	resource   *regexp.Regexp
+	tags       map[string]*regexp.Regexp
+	conditions []ruleCondition
+	anySpan    bool
+	rate       float64
+	hits       *atomic.Int64
+}
+
+func compileSamplingRules(rules []*config.TraceSamplingRule) ([]*samplingRule, error) {
+	compiled := make([]*samplingRule, 0, len(rules))
+	for i, r := range rules {
+		if r == nil {
+			continue
+		}
+		name := r.Name
+		if name == "" {
+			name = "rule_" + strconv.Itoa(i)
+		}
+		sr := &samplingRule{
+			name:      name,
+			service:   globToRegexp(r.Service),
+			operation: globToRegexp(r.OperationName),
+			resource:  globToRegexp(r.Resource),
+			anySpan:   r.AnySpan,
+			rate:      1,
+			hits:      atomic.NewInt64(0),
+		}
+		if r.SampleRate != nil {
+			if *r.SampleRate < 0 || *r.SampleRate > 1 {
+				return nil, fmt.Errorf("rule %q: sample_rate must be between 0 and 1, got %v", name, *r.SampleRate)
+			}
+			sr.rate = *r.SampleRate
+		}
+		if len(r.Tags) > 0 {
+			sr.tags = make(map[string]*regexp.Regexp, len(r.Tags))
+			for k, v := range r.Tags {
+				sr.tags[k] = globToRegexp(v)
+			}
+		}
+		for _, c := range r.Conditions {
+			rc, err := parseRuleCondition(c)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %v", name, err)
+			}
+			sr.conditions = append(sr.conditions, rc)
+		}
+		compiled = append(compiled, sr)
+	}
+	return compiled, nil
+}
+
+func (r *samplingRule) matchChunk(chunk *pb.TraceChunk, root *pb.Span) bool {
+	if !r.anySpan {
+		return r.matchSpan(root)
+	}
+	for _, s := range chunk.Spans {
+		if r.matchSpan(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *samplingRule) matchSpan(s *pb.Span) bool {
+	if !matchGlob(r.service, s.Service) || !matchGlob(r.operation, s.Name) || !matchGlob(r.resource, s.Resource) {
+		return false
+	}
+	for k, re := range r.tags {
+		if v, ok := s.Meta[k]; ok {
+			if !matchGlob(re, v) {
+				return false
+			}
+			continue
+		}
+		v, ok := s.Metrics[k]
+		if !ok || !matchGlob(re, strconv.FormatFloat(v, 'f', -1, 64)) {
+			return false
+		}
+	}
+	for _, c := range r.conditions {
+		if !c.match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// globToRegexp compiles a glob pattern where "*" matches any sequence of characters and "?"
+// matches a single character. It returns nil for patterns matching everything.
+func globToRegexp(glob string) *regexp.Regexp {
+	if glob == "" || glob == "*" {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range strings.SplitAfter(glob, "*") {
+		star := strings.HasSuffix(part, "*")
+		part = strings.TrimSuffix(part, "*")
+		for i, q := range strings.Split(part, "?") {
+			if i > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(regexp.QuoteMeta(q))
+		}
+		if star {
+			b.WriteString(".*")
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func matchGlob(re *regexp.Regexp, v string) bool {
+	return re == nil || re.MatchString(v)
+}
+
+// ruleOperator is a comparison operator used in rule conditions.
+type ruleOperator string
+
+const (
+	ruleOpEqual        ruleOperator = "=="
+	ruleOpNotEqual     ruleOperator = "!="
+	ruleOpLess         ruleOperator = "<"
+	ruleOpLessEqual    ruleOperator = "<="
+	ruleOpGreater      ruleOperator = ">"
+	ruleOpGreaterEqual ruleOperator = ">="
+)
+
+// ruleCondition compares a span attribute with a value.
+type ruleCondition struct {
+	attribute string
+	op        ruleOperator
+	value     string
+	// num holds the value parsed as a number, valid when isNum is true.
+	num   float64
+	isNum bool
+}
+
+// parseRuleCondition parses a condition of the form "<attribute> <operator> <value>". The value
+// may contain spaces and may be quoted. Durations are expressed as Go durations, e.g. "250ms".
+func parseRuleCondition(s string) (ruleCondition, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return ruleCondition{}, fmt.Errorf("invalid condition %q: expected \"<attribute> <operator> <value>\"", s)
+	}
+	c := ruleCondition{attribute: fields[0], op: ruleOperator(fields[1])}
+	// keep inner spaces of the value intact
+	rest := s[strings.Index(s, fields[0])+len(fields[0]):]
+	rest = strings.TrimSpace(rest[strings.Index(rest, fields[1])+len(fields[1]):])
+	if unquoted, err := strconv.Unquote(rest); err == nil {
+		rest = unquoted
+	}
+	c.value = rest
+	switch c.op {
+	case ruleOpEqual, ruleOpNotEqual, ruleOpLess, ruleOpLessEqual, ruleOpGreater, ruleOpGreaterEqual:
+	default:
+		return c, fmt.Errorf("invalid condition %q: unknown operator %q", s, c.op)
+	}
+	if c.attribute == "duration" {
+		d, err := time.ParseDuration(c.value)
+		if err != nil {
+			return c, fmt.Errorf("invalid condition %q: %v", s, err)
+		}
+		c.num, c.isNum = float64(d.Nanoseconds()), true
+	} else if f, err := strconv.ParseFloat(c.value, 64); err == nil {
+		c.num, c.isNum = f, true
+	}
+	if !c.isNum && c.op != ruleOpEqual && c.op != ruleOpNotEqual {
+		return c, fmt.Errorf("invalid condition %q: operator %s requires a numeric value", s, c.op)
+	}
+	return c, nil
+}
+
+// match reports whether the span satisfies the condition. Conditions on attributes missing from
+// the span are never satisfied.
+func (c ruleCondition) match(s *pb.Span) bool {
+	str, num, isNum, ok := spanAttribute(s, c.attribute)
+	if !ok {
+		return false
+	}
+	if c.isNum && isNum {
+		switch c.op {
+		case ruleOpEqual:
+			return num == c.num
+		case ruleOpNotEqual:
+			return num != c.num
+		case ruleOpLess:
+			return num < c.num
+		case ruleOpLessEqual:
+			return num <= c.num
+		case ruleOpGreater:
+			return num > c.num
+		case ruleOpGreaterEqual:
+			return num >= c.num
+		}
+	}
+	switch c.op {
+	case ruleOpEqual:
+		return str == c.value
+	case ruleOpNotEqual:
+		return str != c.value
+	}
+	return false
+}
+
+// spanAttribute returns the value of the named attribute of the span, both as a string and, when
+// possible, as a number. Span fields take precedence over meta and metrics.
+func spanAttribute(s *pb.Span, name string) (str string, num float64, isNum bool, ok bool) {
+	switch name {
+	case "service":
+		return s.Service, 0, false, true
+	case "operation_name":
+		return s.Name, 0, false, true
+	case "resource_name":
+		return s.Resource, 0, false, true
+	case "type":
+		return s.Type, 0, false, true
+	case "duration":
+		return strconv.FormatInt(s.Duration, 10), float64(s.Duration), true, true
+	case "error":
+		return strconv.Itoa(int(s.Error)), float64(s.Error), true, true
+	}
+	if v, ok := s.Meta[name]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		return v, f, err == nil, true
+	}
+	if v, ok := s.Metrics[name]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64), v, true, true
+	}
+	return "", 0, false, false
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+
+	mockStatsd "github.com/DataDog/datadog-go/v5/statsd/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/util/pointer"
+)
+
+func ruleTestChunk() (*pb.TraceChunk, *pb.Span) {
+	root := &pb.Span{
+		TraceID:  1,
+		SpanID:   1,
+		Service:  "web-store",
+		Name:     "http.request",
+		Resource: "GET /api/users/?",
+		Duration: 1500000000,
+		Meta:     map[string]string{"http.status_code": "503", "env": "prod"},
+		Metrics:  map[string]float64{"_top_level": 1},
+	}
+	child := &pb.Span{
+		TraceID:  1,
+		SpanID:   2,
+		ParentID: 1,
+		Service:  "postgres",
+		Name:     "postgres.query",
+		Resource: "SELECT * FROM users",
+		Duration: 1000,
+		Error:    1,
+		Metrics:  map[string]float64{"db.row_count": 42},
+	}
+	return &pb.TraceChunk{Spans: []*pb.Span{root, child}}, root
+}
+
+func TestRuleSamplerMatch(t *testing.T) {
+	for name, tt := range map[string]struct {
+		rule    config.TraceSamplingRule
+		matched bool
+	}{
+		"empty":                {config.TraceSamplingRule{}, true},
+		"service-glob":         {config.TraceSamplingRule{Service: "web-*"}, true},
+		"service-mismatch":     {config.TraceSamplingRule{Service: "api-*"}, false},
+		"operation":            {config.TraceSamplingRule{OperationName: "http.request"}, true},
+		"resource-glob":        {config.TraceSamplingRule{Resource: "GET /api/*"}, true},
+		"resource-single-char": {config.TraceSamplingRule{Resource: "GET /api/users/?"}, true},
+		"resource-meta-chars":  {config.TraceSamplingRule{Resource: "GET /api/(users)"}, false},
+		"tags":                 {config.TraceSamplingRule{Tags: map[string]string{"env": "prod", "http.status_code": "5??"}}, true},
+		"tags-missing":         {config.TraceSamplingRule{Tags: map[string]string{"region": "*"}}, false},
+		"status-condition":     {config.TraceSamplingRule{Conditions: []string{"http.status_code >= 500"}}, true},
+		"status-mismatch":      {config.TraceSamplingRule{Conditions: []string{"http.status_code < 500"}}, false},
+		"duration-condition":   {config.TraceSamplingRule{Conditions: []string{"duration > 1s"}}, true},
+		"duration-mismatch":    {config.TraceSamplingRule{Conditions: []string{"duration > 2s"}}, false},
+		"string-condition":     {config.TraceSamplingRule{Conditions: []string{`resource_name == "GET /api/users/?"`}}, true},
+		"not-equal":            {config.TraceSamplingRule{Conditions: []string{"service != web-store"}}, false},
+		"missing-attribute":    {config.TraceSamplingRule{Conditions: []string{"peer.service != foo"}}, false},
+		"root-only":            {config.TraceSamplingRule{Conditions: []string{"error == 1"}}, false},
+		"any-span":             {config.TraceSamplingRule{Conditions: []string{"error == 1"}, AnySpan: true}, true},
+		"any-span-metrics":     {config.TraceSamplingRule{Conditions: []string{"db.row_count > 10"}, AnySpan: true}, true},
+		"all-criteria":         {config.TraceSamplingRule{Service: "web-store", Conditions: []string{"http.status_code >= 500", "duration > 2s"}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &RuleSampler{}
+			require.NoError(t, s.UpdateRules([]*config.TraceSamplingRule{&tt.rule}))
+			chunk, root := ruleTestChunk()
+			keep, matched := s.Sample(chunk, root)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.matched, keep)
+		})
+	}
+}
+
+func TestRuleSamplerOrder(t *testing.T) {
+	s := &RuleSampler{}
+	require.NoError(t, s.UpdateRules([]*config.TraceSamplingRule{
+		{Name: "drop-health", Resource: "GET /health", SampleRate: pointer.Ptr(0.0)},
+		{Name: "keep-errors", Conditions: []string{"http.status_code >= 500"}, SampleRate: pointer.Ptr(1.0)},
+		{Name: "drop-web", Service: "web-*", SampleRate: pointer.Ptr(0.0)},
+	}))
+
+	chunk, root := ruleTestChunk()
+	keep, matched := s.Sample(chunk, root)
+	assert.True(t, matched)
+	assert.True(t, keep)
+	assert.Equal(t, 1.0, root.Metrics[agentRuleRateKey])
+
+	chunk, root = ruleTestChunk()
+	root.Meta["http.status_code"] = "200"
+	keep, matched = s.Sample(chunk, root)
+	assert.True(t, matched)
+	assert.False(t, keep)
+	assert.NotContains(t, root.Metrics, agentRuleRateKey)
+
+	chunk, root = ruleTestChunk()
+	root.Service = "api"
+	root.Meta["http.status_code"] = "200"
+	_, matched = s.Sample(chunk, root)
+	assert.False(t, matched)
+}
+
+func TestRuleSamplerRate(t *testing.T) {
+	s := &RuleSampler{}
+	require.NoError(t, s.UpdateRules([]*config.TraceSamplingRule{{SampleRate: pointer.Ptr(0.25)}}))
+
+	var kept int
+	for i := uint64(1); i <= 10000; i++ {
+		chunk, root := ruleTestChunk()
+		root.TraceID = i * 0x9E3779B97F4A7C15
+		keep, matched := s.Sample(chunk, root)
+		assert.True(t, matched)
+		if keep {
+			kept++
+			assert.Equal(t, 0.25, root.Metrics[agentRuleRateKey])
+		}
+	}
+	assert.InDelta(t, 2500, kept, 250)
+}
+
+func TestRuleSamplerInvalidRules(t *testing.T) {
+	for name, rule := range map[string]config.TraceSamplingRule{
+		"rate":             {SampleRate: pointer.Ptr(1.5)},
+		"negative-rate":    {SampleRate: pointer.Ptr(-1.0)},
+		"incomplete":       {Conditions: []string{"http.status_code >="}},
+		"operator":         {Conditions: []string{"http.status_code => 500"}},
+		"non-numeric":      {Conditions: []string{"service > web"}},
+		"invalid-duration": {Conditions: []string{"duration > 10"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &RuleSampler{}
+			require.NoError(t, s.UpdateRules([]*config.TraceSamplingRule{{Service: "web-store"}}))
+			assert.Error(t, s.UpdateRules([]*config.TraceSamplingRule{&rule}))
+
+			// the previous rules are kept
+			chunk, root := ruleTestChunk()
+			_, matched := s.Sample(chunk, root)
+			assert.True(t, matched)
+		})
+	}
+
+	s := NewRuleSampler(&config.AgentConfig{TraceSamplingRules: []*config.TraceSamplingRule{{SampleRate: pointer.Ptr(2.0)}}})
+	chunk, root := ruleTestChunk()
+	_, matched := s.Sample(chunk, root)
+	assert.False(t, matched)
+}
+
+func TestRuleSamplerReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	statsdClient := mockStatsd.NewMockClientInterface(ctrl)
+	s := NewRuleSampler(&config.AgentConfig{TraceSamplingRules: []*config.TraceSamplingRule{
+		{Name: "web", Service: "web-*"},
+		{Service: "api"},
+	}})
+	chunk, root := ruleTestChunk()
+	s.Sample(chunk, root)
+	s.Sample(chunk, root)
+
+	statsdClient.EXPECT().Count(MetricsRuleHits, int64(2), []string{"rule:web"}, float64(1)).Times(1)
+	statsdClient.EXPECT().Gauge(MetricsRuleCount, float64(2), nil, float64(1)).Times(1)
+	s.report(statsdClient)
+
+	// counters are reset after each report
+	statsdClient.EXPECT().Gauge(MetricsRuleCount, float64(2), nil, float64(1)).Times(1)
+	s.report(statsdClient)
+}
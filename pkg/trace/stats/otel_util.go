@@ -140,7 +140,15 @@ func obfuscateSpanForConcentrator(o *obfuscate.Obfuscator, span *pb.Span, conf *
 		return
 	}
 	switch span.Type {
-	case "sql", "cassandra":
+	case "cassandra":
+		if conf.Obfuscation.CQL.Enabled {
+			if err := transform.ObfuscateCQLSpan(o, span); err != nil {
+				log.Debugf("Error parsing CQL query: %v. Resource: %q", err, span.Resource)
+			}
+			return
+		}
+		fallthrough
+	case "sql":
 		_, err := transform.ObfuscateSQLSpan(o, span)
 		if err != nil {
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -6,6 +6,8 @@
 package transform
 
 import (
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -30,11 +32,45 @@ const (
 	TagHTTPURL = "http.url"
 	// TagDBMS represents a DBMS tag
 	TagDBMS = "db.type"
+	// TagGraphQLQuery represents a GraphQL query tag
+	TagGraphQLQuery = "graphql.query"
+	// TagGraphQLSource represents a GraphQL document source tag
+	TagGraphQLSource = "graphql.source"
+	// TagGraphQLVariablesPrefix is the prefix of the tags holding the variables of a GraphQL operation
+	TagGraphQLVariablesPrefix = "graphql.variables."
+	// TagCassandraQuery represents a Cassandra CQL query tag
+	TagCassandraQuery = "cassandra.query"
+	// TagDBStatement represents a database statement tag
+	TagDBStatement = "db.statement"
+	// TagDBQueryText represents a database query text tag
+	TagDBQueryText = "db.query.text"
+	// TagDBSystem represents a database system tag
+	TagDBSystem = "db.system"
+	// TagAWSService represents an AWS service tag
+	TagAWSService = "aws.service"
+	// TagAWSServiceLegacy represents an AWS service tag, as set by older tracers
+	TagAWSServiceLegacy = "aws_service"
+	// TagDynamoDBExpressionAttributeValues represents a DynamoDB expression attribute values tag
+	TagDynamoDBExpressionAttributeValues = "aws.dynamodb.expression_attribute_values"
+	// TagMessagingSystem represents a messaging system tag
+	TagMessagingSystem = "messaging.system"
+	// TagKafkaMessageKey represents a Kafka message key tag
+	TagKafkaMessageKey = "messaging.kafka.message.key"
+	// TagKafkaMessageKeyLegacy represents a Kafka message key tag, as set by older instrumentations
+	TagKafkaMessageKeyLegacy = "messaging.kafka.message_key"
+	// TagMessagingMessageBody represents a message payload tag
+	TagMessagingMessageBody = "messaging.message.body"
 )
 
 const (
 	// TextNonParsable is the error text used when a query is non-parsable
 	TextNonParsable = "Non-parsable SQL query"
+	// TextNonParsableGraphQL is the error text used when a GraphQL query is non-parsable
+	TextNonParsableGraphQL = "Non-parsable GraphQL query"
+	// TextNonParsableCQL is the error text used when a CQL query is non-parsable
+	TextNonParsableCQL = "Non-parsable CQL query"
+	// TextNonParsablePartiQL is the error text used when a PartiQL statement is non-parsable
+	TextNonParsablePartiQL = "Non-parsable PartiQL statement"
 )
 
 // ObfuscateSQLSpan obfuscates a SQL span using pkg/obfuscate logic
@@ -80,3 +116,116 @@ func ObfuscateValkeySpan(o *obfuscate.Obfuscator, span *pb.Span, removeAllArgs b
 	}
 	span.Meta[TagValkeyRawCommand] = o.ObfuscateRedisString(span.Meta[TagValkeyRawCommand])
 }
+
+// ObfuscateGraphQLQuery obfuscates a GraphQL document using pkg/obfuscate logic. Strings which
+// aren't documents, such as operation names, are returned untouched.
+func ObfuscateGraphQLQuery(o *obfuscate.Obfuscator, query string) (string, error) {
+	if !strings.Contains(query, "{") {
+		return query, nil
+	}
+	oq, err := o.ObfuscateGraphQLString(query)
+	if err != nil {
+		// discard the query to avoid leaking its values.
+		return TextNonParsableGraphQL, err
+	}
+	return oq, nil
+}
+
+// ObfuscateGraphQLSpan obfuscates the resource and query tags of a GraphQL span using
+// pkg/obfuscate logic, and removes the tags holding the operation's variables.
+func ObfuscateGraphQLSpan(o *obfuscate.Obfuscator, span *pb.Span) error {
+	var err error
+	if span.Resource != "" {
+		span.Resource, err = ObfuscateGraphQLQuery(o, span.Resource)
+	}
+	for k, v := range span.Meta {
+		switch {
+		case k == TagGraphQLQuery || k == TagGraphQLSource:
+			oq, qerr := ObfuscateGraphQLQuery(o, v)
+			if qerr != nil && err == nil {
+				err = qerr
+			}
+			span.Meta[k] = oq
+		case strings.HasPrefix(k, TagGraphQLVariablesPrefix):
+			delete(span.Meta, k)
+		}
+	}
+	return err
+}
+
+// ObfuscateCQLSpan obfuscates the resource and query tags of a Cassandra span using pkg/obfuscate
+// logic. As with SQL spans, the obfuscated query is also set in the "sql.query" tag.
+func ObfuscateCQLSpan(o *obfuscate.Obfuscator, span *pb.Span) error {
+	var err error
+	obfuscateQuery := func(query string) string {
+		oq, qerr := o.ObfuscateCQLString(query)
+		if qerr != nil {
+			// we have an error, discard the query to avoid polluting user resources.
+			err = qerr
+			return TextNonParsableCQL
+		}
+		return oq
+	}
+	if span.Resource != "" {
+		span.Resource = obfuscateQuery(span.Resource)
+		traceutil.SetMeta(span, TagSQLQuery, span.Resource)
+	}
+	for _, k := range []string{TagCassandraQuery, TagDBStatement} {
+		if v := span.Meta[k]; v != "" {
+			span.Meta[k] = obfuscateQuery(v)
+		}
+	}
+	return err
+}
+
+// IsDynamoDBSpan reports whether span is a call to Amazon DynamoDB.
+func IsDynamoDBSpan(span *pb.Span) bool {
+	return span.Type == "dynamodb" ||
+		strings.EqualFold(span.Meta[TagAWSService], "dynamodb") ||
+		strings.EqualFold(span.Meta[TagAWSServiceLegacy], "dynamodb") ||
+		span.Meta[TagDBSystem] == "dynamodb"
+}
+
+// ObfuscateDynamoDBSpan obfuscates the PartiQL statement and expression attribute values tags
+// of a DynamoDB span using pkg/obfuscate logic.
+func ObfuscateDynamoDBSpan(o *obfuscate.Obfuscator, span *pb.Span) error {
+	var err error
+	for _, k := range []string{TagDBStatement, TagDBQueryText} {
+		v := span.Meta[k]
+		if v == "" {
+			continue
+		}
+		oq, qerr := o.ObfuscatePartiQLString(v)
+		if qerr != nil {
+			err = qerr
+			oq = TextNonParsablePartiQL
+		}
+		span.Meta[k] = oq
+	}
+	if v := span.Meta[TagDynamoDBExpressionAttributeValues]; v != "" {
+		if ov := o.ObfuscateDynamoDBExpressionAttributeValues(v); ov != "" {
+			span.Meta[TagDynamoDBExpressionAttributeValues] = ov
+		} else {
+			delete(span.Meta, TagDynamoDBExpressionAttributeValues)
+		}
+	}
+	return err
+}
+
+// IsKafkaSpan reports whether span is a Kafka producer or consumer span.
+func IsKafkaSpan(span *pb.Span) bool {
+	return span.Type == "kafka" || span.Meta[TagMessagingSystem] == "kafka"
+}
+
+// ObfuscateKafkaSpan obfuscates the message key and payload tags of a Kafka span using
+// pkg/obfuscate logic.
+func ObfuscateKafkaSpan(o *obfuscate.Obfuscator, span *pb.Span) {
+	for _, k := range []string{TagKafkaMessageKey, TagKafkaMessageKeyLegacy} {
+		if v, ok := span.Meta[k]; ok {
+			span.Meta[k] = o.ObfuscateKafkaMessageKey(v)
+		}
+	}
+	if v, ok := span.Meta[TagMessagingMessageBody]; ok {
+		span.Meta[TagMessagingMessageBody] = o.ObfuscateKafkaMessagePayload(v)
+	}
+}